@@ -0,0 +1,378 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// Các transaction quản trị access policy luôn chỉ dành cho Super Admin và không thể bị ghi đè,
+// tránh trường hợp một quy tắc sai khóa luôn khả năng sửa chính sách. Quy tắc của chúng luôn lấy từ
+// chính sách mặc định, kể cả khi access policy chưa được khởi tạo trên ledger.
+var lockedAccessRules = map[string]bool{
+	"InitAccessPolicy": true,
+	"SetAccessRule":    true,
+	"ResetAccessRule":  true,
+}
+
+var (
+	adminRoles       = []string{"admin"}
+	adminWorkerRoles = []string{"admin", "worker"}
+	adminDriverRoles = []string{"admin", "driver"}
+	superadminRoles  = []string{"superadmin"}
+	regulatorRoles   = []string{RegulatorRole}
+
+	supplyChainMSPs = []string{MeatSupplyOrgMSP}
+	regulatorMSPs   = []string{RegulatorOrgMSP}
+)
+
+// defaultAccessRules là chính sách mặc định cho mọi transaction của SmartContract, được InitAccessPolicy ghi lên ledger;
+// sau đó access policy được đọc từ ledger. Danh sách MSP ở đây là giới hạn trên mà quy tắc trên ledger không mở rộng được.
+// Quy tắc không có vai trò nào nghĩa là mọi vai trò của các MSP được liệt kê đều được gọi (các truy vấn,
+// hoặc transaction tự kiểm tra danh tính như tài xế được chỉ định). Mọi transaction ghi dữ liệu chuỗi cung ứng
+// chỉ dành cho MeatSupplyOrgMSP, nên cơ quan quản lý chỉ đọc được và chỉ ghi qua các transaction quản lý.
+var defaultAccessRules = []AccessRule{
+	// Trang trại
+	{Transaction: "CreateFarmingBatch", Roles: adminWorkerRoles, FacilityTypes: []string{FacilityTypeFarm}, MSPs: supplyChainMSPs},
+	{Transaction: "UpdateFarmingDetails", Roles: adminWorkerRoles, MSPs: supplyChainMSPs},
+	{Transaction: "AddFeedToFarmingBatch", Roles: adminWorkerRoles, MSPs: supplyChainMSPs},
+	{Transaction: "AddMedicationToFarmingBatch", Roles: adminWorkerRoles, MSPs: supplyChainMSPs},
+	{Transaction: "UpdateAverageWeight", Roles: adminWorkerRoles, MSPs: supplyChainMSPs},
+	{Transaction: "UpdateHarvestDate", Roles: adminWorkerRoles, MSPs: supplyChainMSPs},
+	{Transaction: "UpdateExpectedHarvestDate", Roles: adminWorkerRoles, MSPs: supplyChainMSPs},
+	{Transaction: "AddCertificatesToFarmingBatch", Roles: adminWorkerRoles, MSPs: supplyChainMSPs},
+
+	// Chế biến, lưu kho, bán lẻ
+	{Transaction: "ProcessAndSplitBatch", Roles: adminWorkerRoles, MSPs: supplyChainMSPs},
+	{Transaction: "UpdateStorageInfo", Roles: adminWorkerRoles, MSPs: supplyChainMSPs},
+	{Transaction: "SplitBatchToUnits", Roles: adminWorkerRoles, MSPs: supplyChainMSPs},
+	{Transaction: "MarkAsSold", Roles: adminWorkerRoles, MSPs: supplyChainMSPs},
+	{Transaction: "ReleaseAssetHold", Roles: adminRoles, MSPs: supplyChainMSPs},
+	{Transaction: "ReserveAsset", Roles: adminWorkerRoles, MSPs: supplyChainMSPs},
+	{Transaction: "UnreserveAsset", Roles: adminWorkerRoles, MSPs: supplyChainMSPs},
+
+	// Vận chuyển
+	{Transaction: "CreateShipment", Roles: adminDriverRoles, MSPs: supplyChainMSPs},
+	{Transaction: "AddPickupProof", MSPs: supplyChainMSPs},
+	{Transaction: "ConfirmPickup", Roles: adminWorkerRoles, MSPs: supplyChainMSPs},
+	{Transaction: "StartShipment", Roles: adminDriverRoles, MSPs: supplyChainMSPs},
+	{Transaction: "AddDeliveryProof", MSPs: supplyChainMSPs},
+	{Transaction: "ConfirmShipmentDelivery", Roles: adminWorkerRoles, MSPs: supplyChainMSPs},
+	{Transaction: "ImportEPCIS", Roles: adminWorkerRoles, MSPs: supplyChainMSPs},
+	{Transaction: "AllowOutOfOrderStop", Roles: adminRoles, MSPs: supplyChainMSPs},
+	{Transaction: "ResealShipment", Roles: adminWorkerRoles, MSPs: supplyChainMSPs},
+	{Transaction: "CancelShipment", Roles: adminRoles, MSPs: supplyChainMSPs},
+	{Transaction: "ReturnShipment", Roles: adminRoles, MSPs: supplyChainMSPs},
+	{Transaction: "ForceCloseShipment", Roles: adminRoles, MSPs: supplyChainMSPs},
+	{Transaction: "RecordLocationPings", MSPs: supplyChainMSPs},
+
+	// Đơn đặt hàng, điều khoản thương mại, hóa đơn
+	{Transaction: "CreatePurchaseOrder", Roles: adminWorkerRoles, MSPs: supplyChainMSPs},
+	{Transaction: "AcceptPurchaseOrder", Roles: adminRoles, MSPs: supplyChainMSPs},
+	{Transaction: "RejectPurchaseOrder", Roles: adminRoles, MSPs: supplyChainMSPs},
+	{Transaction: "FulfilPurchaseOrder", Roles: adminRoles, MSPs: supplyChainMSPs},
+	{Transaction: "SetPurchaseOrderTerms", Roles: adminRoles, MSPs: supplyChainMSPs},
+	{Transaction: "SetAssetSupplyContract", Roles: adminRoles, MSPs: supplyChainMSPs},
+	{Transaction: "SetShipmentTerms", Roles: adminRoles, MSPs: supplyChainMSPs},
+	{Transaction: "IssueInvoice", Roles: adminRoles, MSPs: supplyChainMSPs},
+	{Transaction: "DisputeInvoice", Roles: adminRoles, MSPs: supplyChainMSPs},
+	{Transaction: "AcceptInvoice", Roles: adminRoles, MSPs: supplyChainMSPs},
+	{Transaction: "MarkInvoicePaid", Roles: adminRoles, MSPs: supplyChainMSPs},
+
+	// Danh mục sản phẩm và cấu hình kênh
+	{Transaction: "CreateProduct", Roles: superadminRoles, MSPs: supplyChainMSPs},
+	{Transaction: "UpdateProduct", Roles: superadminRoles, MSPs: supplyChainMSPs},
+	{Transaction: "ActivateProduct", Roles: superadminRoles, MSPs: supplyChainMSPs},
+	{Transaction: "DeactivateProduct", Roles: superadminRoles, MSPs: supplyChainMSPs},
+	{Transaction: "SetProductGTIN", Roles: superadminRoles, MSPs: supplyChainMSPs},
+	{Transaction: "SetGeofenceConfig", Roles: superadminRoles, MSPs: supplyChainMSPs},
+	{Transaction: "InitAccessPolicy", Roles: superadminRoles, MSPs: supplyChainMSPs},
+	{Transaction: "SetAccessRule", Roles: superadminRoles, MSPs: supplyChainMSPs},
+	{Transaction: "ResetAccessRule", Roles: superadminRoles, MSPs: supplyChainMSPs},
+
+	// Cơ quan quản lý: chỉ ghi sự kiện quản lý, tạm giữ và thu hồi
+	{Transaction: "RecordRegulatoryEvent", Roles: regulatorRoles, MSPs: regulatorMSPs},
+	{Transaction: "PlaceRegulatoryHold", Roles: regulatorRoles, MSPs: regulatorMSPs},
+	{Transaction: "ReleaseRegulatoryHold", Roles: regulatorRoles, MSPs: regulatorMSPs},
+	{Transaction: "RecallAsset", Roles: regulatorRoles, MSPs: regulatorMSPs},
+
+	// Truy vấn
+	{Transaction: "GetAsset"},
+	{Transaction: "GetAssetAtFarmByID"},
+	{Transaction: "GetAssetWithFullHistory"},
+	{Transaction: "GetConsumerTrace"},
+	{Transaction: "GetDigitalLink"},
+	{Transaction: "ResolveDigitalLink"},
+	{Transaction: "ExportEPCIS"},
+	{Transaction: "GetLineageGraph"},
+	{Transaction: "GetAllowedActions"},
+	{Transaction: "QueryAssetsByFacility"},
+	{Transaction: "QueryAssetsAtProcessorByStatus"},
+	{Transaction: "QueryAssetsAtRetailerByStatus"},
+	{Transaction: "QueryAssetsByFacilityAndSKU"},
+	{Transaction: "GetShipment"},
+	{Transaction: "QueryShipmentsByDriver"},
+	{Transaction: "QueryShipmentsByFacility"},
+	{Transaction: "QueryLateShipments"},
+	{Transaction: "GetShipmentTrack"},
+	{Transaction: "GetProduct"},
+	{Transaction: "QueryProducts"},
+	{Transaction: "GetPurchaseOrder"},
+	{Transaction: "QueryPurchaseOrdersByFacility"},
+	{Transaction: "GetCommercialTerms", MSPs: supplyChainMSPs},
+	{Transaction: "VerifyCommercialTerms"},
+	{Transaction: "GetInvoice"},
+	{Transaction: "QueryInvoicesByFacility"},
+	{Transaction: "GetGeofenceConfig"},
+	{Transaction: "GetAccessPolicy"},
+	{Transaction: "GetRegulatoryStatus"},
+}
+
+// InitAccessPolicy ghi access policy lên ledger: mọi transaction chưa có quy tắc trên ledger nhận quy tắc mặc định,
+// các quy tắc đã có được giữ nguyên. Gọi một lần sau khi triển khai chaincode và sau mỗi lần nâng cấp có thêm transaction.
+// Chỉ Super Admin mới có quyền gọi.
+func (s *SmartContract) InitAccessPolicy(ctx contractapi.TransactionContextInterface) error {
+	policy, err := readAccessPolicy(ctx)
+	if err != nil {
+		return err
+	}
+	if policy == nil {
+		policy = &AccessPolicy{ObjectType: "AccessPolicy"}
+	}
+	for _, rule := range defaultAccessRules {
+		if findAccessRule(policy.Rules, rule.Transaction) == nil {
+			policy.Rules = append(policy.Rules, rule)
+		}
+	}
+	return putAccessPolicy(ctx, policy)
+}
+
+// SetAccessRule thêm hoặc ghi đè quy tắc truy cập của một transaction trong access policy trên ledger.
+// Chỉ Super Admin mới có quyền gọi.
+func (s *SmartContract) SetAccessRule(ctx contractapi.TransactionContextInterface, ruleJSON string) error {
+	var rule AccessRule
+	if err := json.Unmarshal([]byte(ruleJSON), &rule); err != nil {
+		return fmt.Errorf("failed to unmarshal ruleJSON: %v", err)
+	}
+	if findAccessRule(defaultAccessRules, rule.Transaction) == nil {
+		return fmt.Errorf("unknown transaction '%s'", rule.Transaction)
+	}
+	if lockedAccessRules[rule.Transaction] {
+		return fmt.Errorf("the access rule of %s cannot be changed", rule.Transaction)
+	}
+
+	policy, err := requireAccessPolicy(ctx)
+	if err != nil {
+		return err
+	}
+	if existing := findAccessRule(policy.Rules, rule.Transaction); existing != nil {
+		*existing = rule
+	} else {
+		policy.Rules = append(policy.Rules, rule)
+	}
+	return putAccessPolicy(ctx, policy)
+}
+
+// ResetAccessRule đưa quy tắc của một transaction trên ledger về quy tắc mặc định.
+// Chỉ Super Admin mới có quyền gọi.
+func (s *SmartContract) ResetAccessRule(ctx contractapi.TransactionContextInterface, transaction string) error {
+	defaultRule := findAccessRule(defaultAccessRules, transaction)
+	if defaultRule == nil {
+		return fmt.Errorf("unknown transaction '%s'", transaction)
+	}
+	policy, err := requireAccessPolicy(ctx)
+	if err != nil {
+		return err
+	}
+	rule := findAccessRule(policy.Rules, transaction)
+	if rule == nil {
+		policy.Rules = append(policy.Rules, *defaultRule)
+		return putAccessPolicy(ctx, policy)
+	}
+	if reflect.DeepEqual(*rule, *defaultRule) {
+		return fmt.Errorf("transaction %s has no access rule override", transaction)
+	}
+	*rule = *defaultRule
+	return putAccessPolicy(ctx, policy)
+}
+
+// GetAccessPolicy trả về access policy hiện hành đọc từ ledger, sắp theo tên transaction.
+func (s *SmartContract) GetAccessPolicy(ctx contractapi.TransactionContextInterface) ([]AccessRule, error) {
+	policy, err := requireAccessPolicy(ctx)
+	if err != nil {
+		return nil, err
+	}
+	rules := make([]AccessRule, 0, len(policy.Rules))
+	for _, rule := range policy.Rules {
+		if lockedAccessRules[rule.Transaction] {
+			rule = *findAccessRule(defaultAccessRules, rule.Transaction)
+		}
+		rules = append(rules, rule)
+	}
+	sort.Slice(rules, func(i, j int) bool {
+		return rules[i].Transaction < rules[j].Transaction
+	})
+	return rules, nil
+}
+
+// enforceAccessPolicy là hàm BeforeTransaction của SmartContract: tìm quy tắc của transaction được gọi
+// trong access policy hiện hành và từ chối nếu danh tính người gọi không thỏa mãn.
+func enforceAccessPolicy(ctx contractapi.TransactionContextInterface) error {
+	function, _ := ctx.GetStub().GetFunctionAndParameters()
+	if i := strings.LastIndex(function, ":"); i >= 0 {
+		function = function[i+1:]
+	}
+	// contractapi chấp nhận tên hàm viết thường chữ cái đầu
+	if runes := []rune(function); len(runes) > 0 {
+		runes[0] = unicode.ToUpper(runes[0])
+		function = string(runes)
+	}
+
+	return requireTransactionAccess(ctx, function)
+}
+
+// requireTransactionAccess kiểm tra danh tính người gọi theo quy tắc hiện hành của một transaction.
+// Ngoài BeforeTransaction, hàm cũng được dùng khi một transaction thực hiện nghiệp vụ của transaction khác
+// (ví dụ ImportEPCIS), để người gọi không vượt qua được access policy của nghiệp vụ đó.
+func requireTransactionAccess(ctx contractapi.TransactionContextInterface, function string) error {
+	rule := findAccessRule(defaultAccessRules, function)
+	if rule == nil {
+		return fmt.Errorf("no access rule is defined for transaction %s", function)
+	}
+	// Quy tắc trên ledger chỉ được thu hẹp danh sách MSP của chính sách mặc định, không được mở rộng;
+	// nhờ đó cơ quan quản lý không bao giờ ghi được dữ liệu chuỗi cung ứng.
+	if len(rule.MSPs) > 0 {
+		if err := requireMSP(ctx, rule.MSPs...); err != nil {
+			auditDeniedAccess(ctx, function, err)
+			return fmt.Errorf("access to %s denied: %v", function, err)
+		}
+	}
+	if !lockedAccessRules[function] {
+		policy, err := requireAccessPolicy(ctx)
+		if err != nil {
+			return err
+		}
+		rule = findAccessRule(policy.Rules, function)
+		if rule == nil {
+			return fmt.Errorf("the access policy on the ledger has no rule for transaction %s, a superadmin must call InitAccessPolicy", function)
+		}
+	}
+	if err := checkAccessRule(ctx, rule); err != nil {
+		auditDeniedAccess(ctx, function, err)
+		return fmt.Errorf("access to %s denied: %v", function, err)
+	}
+	return nil
+}
+
+// auditDeniedAccess ghi một dòng nhật ký có cấu trúc cho mỗi lần truy cập bị từ chối.
+// Transaction bị từ chối không được commit nên không thể ghi vào world state; nhật ký của
+// chaincode container là nơi lưu vết các lần thử trái phép để đối soát.
+func auditDeniedAccess(ctx contractapi.TransactionContextInterface, function string, reason error) {
+	entry := map[string]interface{}{
+		"audit":       "ACCESS_DENIED",
+		"txID":        ctx.GetStub().GetTxID(),
+		"transaction": function,
+		"reason":      reason.Error(),
+	}
+	if mspID, err := ctx.GetClientIdentity().GetMSPID(); err == nil {
+		entry["mspID"] = mspID
+	}
+	if enrollmentID, err := getEnrollmentID(ctx); err == nil {
+		entry["enrollmentID"] = enrollmentID
+	}
+	if role, found, _ := ctx.GetClientIdentity().GetAttributeValue("role"); found {
+		entry["claimedRole"] = role
+	}
+	if ts, err := ctx.GetStub().GetTxTimestamp(); err == nil {
+		entry["timestamp"] = time.Unix(ts.Seconds, int64(ts.Nanos)).UTC().Format(time.RFC3339)
+	}
+	entryJSON, _ := json.Marshal(entry)
+	log.Println(string(entryJSON))
+}
+
+// --- Các hàm hỗ trợ cho access policy ---
+
+// checkAccessRule kiểm tra danh tính người gọi theo vai trò, loại cơ sở và MSP của quy tắc.
+func checkAccessRule(ctx contractapi.TransactionContextInterface, rule *AccessRule) error {
+	if rule == nil {
+		return fmt.Errorf("no access rule is defined")
+	}
+	if len(rule.MSPs) > 0 {
+		if err := requireMSP(ctx, rule.MSPs...); err != nil {
+			return err
+		}
+	}
+	if len(rule.Roles) > 0 {
+		if err := requireRole(ctx, rule.Roles...); err != nil {
+			return err
+		}
+	}
+	if len(rule.FacilityTypes) > 0 {
+		if err := requireFacilityType(ctx, rule.FacilityTypes...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// findAccessRule trả về quy tắc của transaction trong danh sách, hoặc nil nếu không có.
+func findAccessRule(rules []AccessRule, transaction string) *AccessRule {
+	for i := range rules {
+		if rules[i].Transaction == transaction {
+			return &rules[i]
+		}
+	}
+	return nil
+}
+
+// Đọc access policy từ world state; trả về nil nếu access policy chưa được khởi tạo.
+func readAccessPolicy(ctx contractapi.TransactionContextInterface) (*AccessPolicy, error) {
+	key, err := ctx.GetStub().CreateCompositeKey("Config", []string{"accessPolicy"})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create composite key: %v", err)
+	}
+	policyJSON, err := ctx.GetStub().GetState(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read from world state: %v", err)
+	}
+	if policyJSON == nil {
+		return nil, nil
+	}
+	var policy AccessPolicy
+	if err := json.Unmarshal(policyJSON, &policy); err != nil {
+		return nil, err
+	}
+	return &policy, nil
+}
+
+// requireAccessPolicy đọc access policy từ world state và báo lỗi nếu access policy chưa được khởi tạo.
+func requireAccessPolicy(ctx contractapi.TransactionContextInterface) (*AccessPolicy, error) {
+	policy, err := readAccessPolicy(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if policy == nil {
+		return nil, fmt.Errorf("the access policy has not been initialized, a superadmin must call InitAccessPolicy")
+	}
+	return policy, nil
+}
+
+// Lưu access policy vào world state.
+func putAccessPolicy(ctx contractapi.TransactionContextInterface, policy *AccessPolicy) error {
+	policyJSON, err := json.Marshal(policy)
+	if err != nil {
+		return err
+	}
+	key, err := ctx.GetStub().CreateCompositeKey("Config", []string{"accessPolicy"})
+	if err != nil {
+		return fmt.Errorf("failed to create composite key: %v", err)
+	}
+	return ctx.GetStub().PutState(key, policyJSON)
+}
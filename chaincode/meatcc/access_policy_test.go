@@ -0,0 +1,136 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+// ruleJSON mã hóa một AccessRule thành tham số của SetAccessRule.
+func ruleJSON(t *testing.T, rule AccessRule) string {
+	ruleBytes, err := json.Marshal(rule)
+	if err != nil {
+		t.Fatalf("failed to marshal access rule: %v", err)
+	}
+	return string(ruleBytes)
+}
+
+// newBeefProduct là tham số CreateProduct của một sản phẩm chưa có trong danh mục.
+var newBeefProduct = []interface{}{"BEEF", "Beef", "", "kg", "BEEF", "RAW_MATERIAL", Weight{Value: 1, Unit: "kg"}}
+
+// Mọi transaction của SmartContract phải có quy tắc mặc định, nếu không BeforeTransaction sẽ từ chối mọi lời gọi.
+func TestEveryTransactionHasDefaultAccessRule(t *testing.T) {
+	for _, name := range contractTransactions() {
+		if findAccessRule(defaultAccessRules, name) == nil {
+			t.Errorf("transaction %s has no default access rule", name)
+		}
+	}
+	seen := map[string]bool{}
+	for _, rule := range defaultAccessRules {
+		if seen[rule.Transaction] {
+			t.Errorf("transaction %s has more than one default access rule", rule.Transaction)
+		}
+		seen[rule.Transaction] = true
+	}
+}
+
+func TestEnforceAccessPolicy(t *testing.T) {
+	runTransactionCases(t, func(c *testChannel) { c.withCatalog() }, []transactionCase{
+		{name: "role not allowed", identity: driver, function: "CreateFarmingBatch", args: []interface{}{"FARM-BATCH-9", "Pork carcass", skuPorkCarcass, Quantity{Unit: "head", Value: 1}, testFarmDetails("FARM-1"), Weight{Value: 90, Unit: "kg"}},
+			wantErr: "access to CreateFarmingBatch denied: caller with role 'driver' is not authorized"},
+		{name: "facility type not allowed", identity: processorAdmin, function: "CreateFarmingBatch", args: []interface{}{"FARM-BATCH-9", "Pork carcass", skuPorkCarcass, Quantity{Unit: "head", Value: 1}, testFarmDetails("PROC-1"), Weight{Value: 90, Unit: "kg"}},
+			wantErr: "access to CreateFarmingBatch denied: caller from facility type 'PROCESSOR' is not authorized for this action"},
+		{name: "regulator cannot write supply chain data", identity: regulator, function: "CreateProduct", args: newBeefProduct,
+			wantErr: "access to CreateProduct denied: caller from organization 'RegulatorOrgMSP' is not authorized"},
+		{name: "supply chain cannot place regulatory holds", identity: superadmin, function: "PlaceRegulatoryHold", args: []interface{}{"FARM-BATCH-1", "Test"},
+			wantErr: "access to PlaceRegulatoryHold denied: caller from organization 'MeatSupplyOrgMSP' is not authorized"},
+		{name: "queries are open", identity: regulator, function: "GetProduct", args: []interface{}{skuPorkLoin}},
+	})
+
+	t.Run("identity without role", func(t *testing.T) {
+		c := newTestChannel(t)
+		anonymous := &mockIdentity{mspID: MeatSupplyOrgMSP, commonName: "anonymous", attributes: map[string]string{}}
+		_, err := c.submit(anonymous, "CreateProduct", newBeefProduct...)
+		expectError(t, err, "access to CreateProduct denied: the client identity does not have a 'role' attribute")
+	})
+}
+
+func TestSetAccessRule(t *testing.T) {
+	runTransactionCases(t, func(c *testChannel) {}, []transactionCase{
+		{name: "superadmin narrows a rule", identity: superadmin, function: "SetAccessRule",
+			args: []interface{}{ruleJSON(t, AccessRule{Transaction: "MarkAsSold", Roles: adminRoles, MSPs: supplyChainMSPs})}},
+		{name: "unknown transaction", identity: superadmin, function: "SetAccessRule", args: []interface{}{ruleJSON(t, AccessRule{Transaction: "DropTables"})},
+			wantErr: "unknown transaction 'DropTables'"},
+		{name: "policy administration is locked", identity: superadmin, function: "SetAccessRule", args: []interface{}{ruleJSON(t, AccessRule{Transaction: "SetAccessRule"})},
+			wantErr: "the access rule of SetAccessRule cannot be changed"},
+		{name: "malformed rule", identity: superadmin, function: "SetAccessRule", args: []interface{}{"{"},
+			wantErr: "failed to unmarshal ruleJSON"},
+		{name: "admin denied", identity: farmAdmin, function: "SetAccessRule", args: []interface{}{ruleJSON(t, AccessRule{Transaction: "MarkAsSold"})},
+			wantErr: "access to SetAccessRule denied"},
+		{name: "nothing to reset", identity: superadmin, function: "ResetAccessRule", args: []interface{}{"MarkAsSold"},
+			wantErr: "transaction MarkAsSold has no access rule override"},
+	})
+
+	t.Run("override applies until reset", func(t *testing.T) {
+		c := newTestChannel(t)
+		atRetailer(c)
+		c.mustSubmit(retailerWorker, "SplitBatchToUnits", "RETAIL-BATCH-0", 1, "UNIT-")
+		c.mustSubmit(superadmin, "SetAccessRule", ruleJSON(t, AccessRule{Transaction: "MarkAsSold", Roles: adminRoles, MSPs: supplyChainMSPs}))
+
+		policy := c.mustEvaluate(regulator, "GetAccessPolicy").([]AccessRule)
+		if rule := findAccessRule(policy, "MarkAsSold"); rule == nil || len(rule.Roles) != 1 || rule.Roles[0] != "admin" {
+			t.Fatalf("override is not part of the effective policy: %+v", rule)
+		}
+		if len(policy) != len(defaultAccessRules) {
+			t.Fatalf("expected %d rules, got %d", len(defaultAccessRules), len(policy))
+		}
+		_, err := c.submit(retailerWorker, "MarkAsSold", "UNIT-1", "{}")
+		expectError(t, err, "access to MarkAsSold denied: caller with role 'worker' is not authorized")
+
+		c.mustSubmit(superadmin, "ResetAccessRule", "MarkAsSold")
+		c.mustSubmit(retailerWorker, "MarkAsSold", "UNIT-1", "{}")
+	})
+
+	t.Run("overrides cannot widen the MSP list", func(t *testing.T) {
+		c := newTestChannel(t)
+		c.withCatalog()
+		c.mustSubmit(superadmin, "SetAccessRule", ruleJSON(t, AccessRule{Transaction: "CreateProduct", MSPs: []string{MeatSupplyOrgMSP, RegulatorOrgMSP}}))
+		_, err := c.submit(regulator, "CreateProduct", newBeefProduct...)
+		expectError(t, err, "access to CreateProduct denied: caller from organization 'RegulatorOrgMSP' is not authorized")
+	})
+}
+
+func TestInitAccessPolicy(t *testing.T) {
+	t.Run("transactions are denied until the policy is initialized", func(t *testing.T) {
+		c := newUninitializedChannel(t)
+		_, err := c.submit(superadmin, "CreateProduct", newBeefProduct...)
+		expectError(t, err, "the access policy has not been initialized, a superadmin must call InitAccessPolicy")
+		_, err = c.submit(farmAdmin, "InitAccessPolicy")
+		expectError(t, err, "access to InitAccessPolicy denied")
+
+		c.mustSubmit(superadmin, "InitAccessPolicy")
+		c.mustSubmit(superadmin, "CreateProduct", newBeefProduct...)
+		policy := c.mustEvaluate(regulator, "GetAccessPolicy").([]AccessRule)
+		if len(policy) != len(defaultAccessRules) {
+			t.Fatalf("expected %d rules on the ledger, got %d", len(defaultAccessRules), len(policy))
+		}
+	})
+
+	t.Run("rules are read from the ledger", func(t *testing.T) {
+		c := newTestChannel(t)
+		c.withCatalog()
+		key, _ := c.stub.CreateCompositeKey("Config", []string{"accessPolicy"})
+		c.seed(key, AccessPolicy{ObjectType: "AccessPolicy", Rules: []AccessRule{{Transaction: "CreateProduct", Roles: adminRoles, MSPs: supplyChainMSPs}}})
+		_, err := c.submit(superadmin, "CreateProduct", newBeefProduct...)
+		expectError(t, err, "access to CreateProduct denied: caller with role 'superadmin' is not authorized")
+		_, err = c.evaluate(superadmin, "GetProduct", skuPorkLoin)
+		expectError(t, err, "the access policy on the ledger has no rule for transaction GetProduct, a superadmin must call InitAccessPolicy")
+
+		// Khởi tạo lại chỉ bổ sung quy tắc còn thiếu, giữ nguyên quy tắc đã có trên ledger
+		c.mustSubmit(superadmin, "InitAccessPolicy")
+		c.mustEvaluate(superadmin, "GetProduct", skuPorkLoin)
+		_, err = c.submit(superadmin, "CreateProduct", newBeefProduct...)
+		expectError(t, err, "access to CreateProduct denied")
+		c.mustSubmit(superadmin, "ResetAccessRule", "CreateProduct")
+		c.mustSubmit(superadmin, "CreateProduct", newBeefProduct...)
+	})
+}
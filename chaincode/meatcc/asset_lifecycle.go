@@ -0,0 +1,210 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// Các trạng thái trong vòng đời của một MeatAsset.
+const (
+	AssetStatusAtFarm                  = "AT_FARM"
+	AssetStatusAtProcessor             = "AT_PROCESSOR"
+	AssetStatusPackaged                = "PACKAGED"
+	AssetStatusProcessedAndSplit       = "PROCESSED_AND_SPLIT"
+	AssetStatusPartiallyShipped        = "PARTIALLY_SHIPPED"
+	AssetStatusShippedFull             = "SHIPPED_FULL"
+	AssetStatusAtRetailer              = "AT_RETAILER"
+	AssetStatusOnShelf                 = "ON_SHELF"
+	AssetStatusSplitIntoUnitsCompleted = "SPLIT_INTO_UNITS_COMPLETED"
+	AssetStatusSold                    = "SOLD"
+	AssetStatusAtWarehouse             = "AT_WAREHOUSE"
+	AssetStatusReceived                = "RECEIVED"
+	AssetStatusOnHold                  = "ON_HOLD"
+)
+
+// Các loại cơ sở (thuộc tính 'facilityType' trong chứng chỉ của client).
+const (
+	FacilityTypeFarm      = "FARM"
+	FacilityTypeProcessor = "PROCESSOR"
+	FacilityTypeWarehouse = "WAREHOUSE"
+	FacilityTypeRetailer  = "RETAILER"
+)
+
+// Các trạng thái mà asset còn hàng tại cơ sở và có thể được bốc lên xe.
+var shippableAssetStatuses = []string{
+	AssetStatusAtFarm,
+	AssetStatusAtProcessor,
+	AssetStatusPackaged,
+	AssetStatusAtWarehouse,
+	AssetStatusAtRetailer,
+	AssetStatusReceived,
+	AssetStatusPartiallyShipped,
+}
+
+// Các trạng thái asset còn nằm tại một cơ sở (dùng cho cập nhật lưu kho và gỡ tạm giữ).
+var storedAssetStatuses = []string{
+	AssetStatusAtFarm,
+	AssetStatusAtProcessor,
+	AssetStatusPackaged,
+	AssetStatusAtWarehouse,
+	AssetStatusAtRetailer,
+	AssetStatusReceived,
+	AssetStatusPartiallyShipped,
+	AssetStatusOnShelf,
+}
+
+// Tất cả các trạng thái của asset.
+var allAssetStatuses = []string{
+	AssetStatusAtFarm,
+	AssetStatusAtProcessor,
+	AssetStatusPackaged,
+	AssetStatusProcessedAndSplit,
+	AssetStatusPartiallyShipped,
+	AssetStatusShippedFull,
+	AssetStatusAtRetailer,
+	AssetStatusOnShelf,
+	AssetStatusSplitIntoUnitsCompleted,
+	AssetStatusSold,
+	AssetStatusAtWarehouse,
+	AssetStatusReceived,
+	AssetStatusOnHold,
+}
+
+// assetTransition mô tả một thao tác được phép trên asset: transaction nào, ghi sự kiện gì,
+// từ trạng thái nào sang trạng thái nào, bởi loại cơ sở nào. Vai trò được phép gọi transaction
+// được quy định trong access policy.
+type assetTransition struct {
+	Action        string   // Tên transaction của SmartContract
+	EventType     string   // Loại sự kiện ghi vào History ("" nếu transaction không ghi sự kiện)
+	From          []string // Trạng thái nguồn được phép
+	To            []string // Trạng thái đích được phép (rỗng = giữ nguyên trạng thái)
+	FacilityTypes []string // Loại cơ sở của người gọi được phép (rỗng = mọi loại)
+	OwnerOnly     bool     // Người gọi phải thuộc cơ sở sở hữu asset
+	ViaShipment   bool     // Thao tác phát sinh từ transaction của shipment, không hiển thị trong GetAllowedActions
+}
+
+// assetLifecycle là định nghĩa tập trung các chuyển trạng thái hợp lệ của MeatAsset.
+var assetLifecycle = []assetTransition{
+	// Giai đoạn trang trại
+	{Action: "UpdateFarmingDetails", From: []string{AssetStatusAtFarm}, FacilityTypes: []string{FacilityTypeFarm}, OwnerOnly: true},
+	{Action: "AddFeedToFarmingBatch", From: []string{AssetStatusAtFarm}, FacilityTypes: []string{FacilityTypeFarm}, OwnerOnly: true},
+	{Action: "AddMedicationToFarmingBatch", From: []string{AssetStatusAtFarm}, FacilityTypes: []string{FacilityTypeFarm}, OwnerOnly: true},
+	{Action: "UpdateAverageWeight", From: []string{AssetStatusAtFarm}, FacilityTypes: []string{FacilityTypeFarm}, OwnerOnly: true},
+	{Action: "UpdateHarvestDate", From: []string{AssetStatusAtFarm}, FacilityTypes: []string{FacilityTypeFarm}, OwnerOnly: true},
+	{Action: "UpdateExpectedHarvestDate", From: []string{AssetStatusAtFarm}, FacilityTypes: []string{FacilityTypeFarm}, OwnerOnly: true},
+	{Action: "AddCertificatesToFarmingBatch", From: []string{AssetStatusAtFarm}, FacilityTypes: []string{FacilityTypeFarm}, OwnerOnly: true},
+
+	// Chế biến, bán lẻ
+	{Action: "ProcessAndSplitBatch", EventType: "PROCESSING", From: []string{AssetStatusAtProcessor}, To: []string{AssetStatusProcessedAndSplit}, FacilityTypes: []string{FacilityTypeProcessor}, OwnerOnly: true},
+	{Action: "SplitBatchToUnits", EventType: "SPLIT_INTO_UNITS", From: []string{AssetStatusAtRetailer}, To: []string{AssetStatusSplitIntoUnitsCompleted}, FacilityTypes: []string{FacilityTypeRetailer}, OwnerOnly: true},
+	{Action: "MarkAsSold", EventType: "SOLD", From: []string{AssetStatusOnShelf}, To: []string{AssetStatusSold}, FacilityTypes: []string{FacilityTypeRetailer}, OwnerOnly: true},
+	{Action: "UpdateStorageInfo", EventType: "STORAGE_UPDATE", From: storedAssetStatuses, OwnerOnly: true},
+
+	// Vận chuyển
+	{Action: "ConfirmPickup", EventType: "PICKED_UP_FOR_SHIPMENT", From: shippableAssetStatuses, OwnerOnly: true},
+	{Action: "CreateShipment", EventType: "BOOKED_ON_SHIPMENT", From: shippableAssetStatuses, ViaShipment: true},
+	// Giải phóng giữ chỗ khi lấy hàng, hủy, trả về hoặc đóng cưỡng bức lô hàng
+	{EventType: "BOOKING_RELEASED", From: allAssetStatuses, ViaShipment: true},
+	// Một asset có thể được bốc lên nhiều lô hàng: lô khởi hành sau vẫn hợp lệ khi lô trước đã lấy hết hàng (SHIPPED_FULL)
+	{Action: "StartShipment", EventType: "SHIPPING_STARTED", From: append([]string{AssetStatusShippedFull}, shippableAssetStatuses...), To: []string{AssetStatusPartiallyShipped, AssetStatusShippedFull}, ViaShipment: true},
+	{Action: "CancelShipment", EventType: "SHIPMENT_CANCELLED", From: shippableAssetStatuses, ViaShipment: true},
+	{Action: "CancelShipment", EventType: "SHIPMENT_CANCELLED", From: []string{AssetStatusShippedFull}, To: []string{AssetStatusPartiallyShipped}, ViaShipment: true},
+	{Action: "ReturnShipment", EventType: "RETURNED_FROM_SHIPMENT", From: []string{AssetStatusPartiallyShipped, AssetStatusShippedFull}, To: shippableAssetStatuses, ViaShipment: true},
+	// Lô hàng khác của cùng asset đã được trả về trước và khôi phục trạng thái của asset
+	{Action: "ReturnShipment", EventType: "RETURNED_FROM_SHIPMENT", From: shippableAssetStatuses, ViaShipment: true},
+
+	// Giữ chỗ số lượng cho đơn hàng hoặc lô hàng
+	{Action: "ReserveAsset", EventType: "RESERVED", From: shippableAssetStatuses, OwnerOnly: true},
+	{Action: "UnreserveAsset", EventType: "UNRESERVED", From: allAssetStatuses, OwnerOnly: true},
+
+	// Điều khoản thương mại riêng tư
+	{Action: "SetAssetSupplyContract", EventType: "COMMERCIAL_TERMS_ATTACHED", From: allAssetStatuses, OwnerOnly: true},
+
+	// Tạm giữ
+	{Action: "ReleaseAssetHold", EventType: "HOLD_RELEASED", From: []string{AssetStatusOnHold}, To: storedAssetStatuses, OwnerOnly: true},
+}
+
+// GetAllowedActions trả về danh sách transaction mà người gọi được phép thực hiện trên asset ngay lúc này,
+// dựa trên trạng thái asset, access policy hiện hành và quyền sở hữu của người gọi.
+func (s *SmartContract) GetAllowedActions(ctx contractapi.TransactionContextInterface, assetID string) ([]AllowedAction, error) {
+	asset, err := s.readAsset(ctx, assetID)
+	if err != nil {
+		return nil, err
+	}
+	policy, err := s.GetAccessPolicy(ctx)
+	if err != nil {
+		return nil, err
+	}
+	facilityID, _, _ := getClientAttribute(ctx, "facilityID")
+	facilityType, _, _ := getClientAttribute(ctx, "facilityType")
+
+	actions := []AllowedAction{}
+	for _, transition := range assetLifecycle {
+		if transition.ViaShipment || !containsString(transition.From, asset.Status) {
+			continue
+		}
+		if len(transition.FacilityTypes) > 0 && !containsString(transition.FacilityTypes, facilityType) {
+			continue
+		}
+		if checkAccessRule(ctx, findAccessRule(policy, transition.Action)) != nil {
+			continue
+		}
+		if transition.OwnerOnly && asset.OwnerOrg != facilityID {
+			continue
+		}
+		if requireNoRegulatoryBlock(ctx, asset, transition.EventType) != nil {
+			continue
+		}
+		nextStatuses := transition.To
+		if len(nextStatuses) == 0 {
+			nextStatuses = []string{asset.Status}
+		}
+		actions = append(actions, AllowedAction{
+			Transaction:  transition.Action,
+			NextStatuses: nextStatuses,
+		})
+	}
+	return actions, nil
+}
+
+// requireAssetAction kiểm tra transaction action được phép trên asset ở trạng thái hiện tại
+// và với loại cơ sở của người gọi.
+func requireAssetAction(ctx contractapi.TransactionContextInterface, asset *MeatAsset, action string) error {
+	facilityType, _, _ := getClientAttribute(ctx, "facilityType")
+	for _, transition := range assetLifecycle {
+		if transition.Action != action {
+			continue
+		}
+		if !containsString(transition.From, asset.Status) {
+			return fmt.Errorf("%s is not allowed for asset %s with status '%s'", action, asset.AssetID, asset.Status)
+		}
+		if len(transition.FacilityTypes) > 0 && !containsString(transition.FacilityTypes, facilityType) {
+			return fmt.Errorf("%s is not allowed for facility type '%s'", action, facilityType)
+		}
+		return nil
+	}
+	return fmt.Errorf("action %s is not defined in the asset lifecycle", action)
+}
+
+// requireAssetTransition kiểm tra sự kiện eventType được phép chuyển asset từ trạng thái hiện tại sang newStatus
+// với loại cơ sở của người gọi và asset không bị cơ quan quản lý chặn. Được gọi bên trong addEvent cho mọi sự kiện.
+func requireAssetTransition(ctx contractapi.TransactionContextInterface, asset *MeatAsset, eventType string, newStatus string) error {
+	facilityType, _, _ := getClientAttribute(ctx, "facilityType")
+	for _, transition := range assetLifecycle {
+		if transition.EventType != eventType || !containsString(transition.From, asset.Status) {
+			continue
+		}
+		if len(transition.To) == 0 && newStatus != asset.Status {
+			continue
+		}
+		if len(transition.To) > 0 && !containsString(transition.To, newStatus) {
+			continue
+		}
+		if len(transition.FacilityTypes) > 0 && !containsString(transition.FacilityTypes, facilityType) {
+			continue
+		}
+		return requireNoRegulatoryBlock(ctx, asset, eventType)
+	}
+	return fmt.Errorf("event %s cannot move asset %s from '%s' to '%s' for facility type '%s'", eventType, asset.AssetID, asset.Status, newStatus, facilityType)
+}
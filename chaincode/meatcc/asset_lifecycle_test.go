@@ -0,0 +1,76 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+// allowedTransactions trả về tên các transaction trong kết quả của GetAllowedActions.
+func allowedTransactions(actions []AllowedAction) []string {
+	names := []string{}
+	for _, action := range actions {
+		names = append(names, action.Transaction)
+	}
+	return names
+}
+
+// Mỗi thao tác trong assetLifecycle phải là một transaction của SmartContract và chỉ dùng các trạng thái đã khai báo.
+func TestAssetLifecycleDefinition(t *testing.T) {
+	transactions := contractTransactions()
+	for _, transition := range assetLifecycle {
+		if transition.Action != "" && !containsString(transactions, transition.Action) {
+			t.Errorf("lifecycle action %s is not a contract transaction", transition.Action)
+		}
+		for _, status := range append(append([]string{}, transition.From...), transition.To...) {
+			if !containsString(allAssetStatuses, status) {
+				t.Errorf("lifecycle action %s uses unknown status '%s'", transition.Action, status)
+			}
+		}
+	}
+}
+
+func TestGetAllowedActions(t *testing.T) {
+	c := newTestChannel(t)
+	atRetailer(c)
+	c.createFarmBatch(farmAdmin, "FARM-BATCH-2", skuPorkCarcass, Quantity{Unit: "head", Value: 4})
+
+	cases := []struct {
+		name     string
+		identity *mockIdentity
+		assetID  string
+		want     []string
+	}{
+		{name: "farm owner", identity: farmAdmin, assetID: "FARM-BATCH-2", want: []string{
+			"UpdateFarmingDetails", "AddFeedToFarmingBatch", "AddMedicationToFarmingBatch", "UpdateAverageWeight",
+			"UpdateHarvestDate", "UpdateExpectedHarvestDate", "AddCertificatesToFarmingBatch",
+			"UpdateStorageInfo", "ConfirmPickup", "ReserveAsset", "UnreserveAsset", "SetAssetSupplyContract"}},
+		{name: "farm worker cannot sign contracts", identity: farmWorker, assetID: "FARM-BATCH-2", want: []string{
+			"UpdateFarmingDetails", "AddFeedToFarmingBatch", "AddMedicationToFarmingBatch", "UpdateAverageWeight",
+			"UpdateHarvestDate", "UpdateExpectedHarvestDate", "AddCertificatesToFarmingBatch",
+			"UpdateStorageInfo", "ConfirmPickup", "ReserveAsset", "UnreserveAsset"}},
+		{name: "other farm", identity: otherFarmAdmin, assetID: "FARM-BATCH-2", want: []string{}},
+		{name: "retailer batch", identity: retailerWorker, assetID: "RETAIL-BATCH-0", want: []string{
+			"SplitBatchToUnits", "UpdateStorageInfo", "ConfirmPickup", "ReserveAsset", "UnreserveAsset"}},
+		{name: "shipped asset", identity: processorAdmin, assetID: "TRAY-1", want: []string{"UnreserveAsset", "SetAssetSupplyContract"}},
+		{name: "regulator", identity: regulator, assetID: "RETAIL-BATCH-0", want: []string{}},
+	}
+	for _, tc := range cases {
+		actions := c.mustEvaluate(tc.identity, "GetAllowedActions", tc.assetID).([]AllowedAction)
+		if got := allowedTransactions(actions); !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("%s: expected %v, got %v", tc.name, tc.want, got)
+		}
+	}
+
+	actions := c.mustEvaluate(retailerWorker, "GetAllowedActions", "RETAIL-BATCH-0").([]AllowedAction)
+	if actions[0].Transaction != "SplitBatchToUnits" || !reflect.DeepEqual(actions[0].NextStatuses, []string{AssetStatusSplitIntoUnitsCompleted}) ||
+		!reflect.DeepEqual(actions[1].NextStatuses, []string{AssetStatusAtRetailer}) {
+		t.Fatalf("unexpected next statuses %+v", actions)
+	}
+
+	// Asset bị tạm giữ chỉ còn các thao tác không bị cơ quan quản lý chặn
+	c.mustSubmit(regulator, "PlaceRegulatoryHold", "RETAIL-BATCH-0", "Pending lab result")
+	actions = c.mustEvaluate(retailerWorker, "GetAllowedActions", "RETAIL-BATCH-0").([]AllowedAction)
+	if got := allowedTransactions(actions); !reflect.DeepEqual(got, []string{"UpdateStorageInfo", "UnreserveAsset"}) {
+		t.Fatalf("unexpected actions under regulatory hold %v", got)
+	}
+}
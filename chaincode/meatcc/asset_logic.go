@@ -10,10 +10,7 @@ import (
 
 // Tạo một lô thịt mới tại trang trại, lưu thông tin số lượng và chi tiết trang trại, đồng thời ghi lại sự kiện FARMING.
 func (s *SmartContract) CreateFarmingBatch(ctx contractapi.TransactionContextInterface, assetID string, productName string, sku string, quantityJSON string, farmDetailsJSON string, averageWeightJSON string) error {
-	if err := requireRole(ctx, "admin", "worker"); err != nil {
-		return err
-	}
-	callerOrg, _, _ := ctx.GetClientIdentity().GetAttributeValue("facilityID")
+	callerOrg, _, _ := getClientAttribute(ctx, "facilityID")
 
 	exists, err := s.assetExists(ctx, assetID)
 	if err != nil {
@@ -50,29 +47,33 @@ func (s *SmartContract) CreateFarmingBatch(ctx contractapi.TransactionContextInt
 		AverageWeight:    averageWeight,
 		ParentAssetIDs:   []string{},
 		ProductName:      productName,
-		Status:           "AT_FARM",
+		Status:           AssetStatusAtFarm,
 		OwnerOrg:         callerOrg,
 		OriginalQuantity: quantity,
 		CurrentQuantity:  quantity,
 		History:          []Event{*event},
 	}
+	// Lô nuôi được cấp GTIN và số lô theo mã asset, để có thể tham chiếu bằng GS1 Digital Link
+	if err := s.assignGS1Identifiers(ctx, &asset, assetID, ""); err != nil {
+		return err
+	}
+	if err := setAssetEndorsementPolicy(ctx, &asset); err != nil {
+		return err
+	}
 
 	return s.updateAsset(ctx, &asset)
 }
 
 // Xử lý và tách một lô thịt thành nhiều lô con, cập nhật sự kiện PROCESSING cho lô cha và tạo các lô con mới.
 func (s *SmartContract) ProcessAndSplitBatch(ctx contractapi.TransactionContextInterface, parentAssetID string, childAssetsJSON string, processingDetailsJSON string) error {
-	if err := requireRole(ctx, "admin", "worker"); err != nil {
-		return err
-	}
 	
 	parentAsset, err := s.readAsset(ctx, parentAssetID)
 	if err != nil {
 		return err
 	}
 
-	if parentAsset.Status != "AT_PROCESSOR" {
-		return fmt.Errorf("asset %s with status '%s' cannot be split into units", parentAssetID, parentAsset.Status)
+	if err := requireAssetAction(ctx, parentAsset, "ProcessAndSplitBatch"); err != nil {
+		return err
 	}
 
 	if err := requireOwnership(ctx, parentAsset); err != nil {
@@ -84,7 +85,7 @@ func (s *SmartContract) ProcessAndSplitBatch(ctx contractapi.TransactionContextI
 		return fmt.Errorf("failed to unmarshal processingDetailsJSON: %v", err)
 	}
 
-	err = s.addEvent(ctx, parentAsset, "PROCESSING", "PROCESSED_AND_SPLIT", processingDetails)
+	err = s.addEvent(ctx, parentAsset, "PROCESSING", AssetStatusProcessedAndSplit, processingDetails)
 	if err != nil {
 		return err 
 	}
@@ -130,12 +131,20 @@ func (s *SmartContract) ProcessAndSplitBatch(ctx contractapi.TransactionContextI
 			AverageWeight:    averageWeight,
 			ParentAssetIDs:   []string{parentAssetID},
 			ProductName:      child.ProductName,
-			Status:           "PACKAGED",
+			Status:           AssetStatusPackaged,
 			OwnerOrg:         parentAsset.OwnerOrg,
+			OwnerMSP:         parentAsset.OwnerMSP,
 			OriginalQuantity: child.Quantity,
 			CurrentQuantity:  child.Quantity,
 			History:          []Event{*creationEvent},
 		}
+		// Mỗi lô con (thùng/pallet) được cấp GTIN và số lô theo mã asset của nó
+		if err := s.assignGS1Identifiers(ctx, &newChildAsset, child.AssetID, ""); err != nil {
+			return err
+		}
+		if err := setAssetEndorsementPolicy(ctx, &newChildAsset); err != nil {
+			return err
+		}
 		err = s.updateAsset(ctx, &newChildAsset)
 		if err != nil {
 			return err
@@ -147,9 +156,6 @@ func (s *SmartContract) ProcessAndSplitBatch(ctx contractapi.TransactionContextI
 
 // Cập nhật thông tin trang trại cho một lô thịt đang ở trạng thái AT_FARM, chỉnh sửa sự kiện FARMING trong lịch sử.
 func (s *SmartContract) UpdateFarmingDetails(ctx contractapi.TransactionContextInterface, assetID string, updatedFarmDetailsJSON string) error {
-	if err := requireRole(ctx, "admin", "worker"); err != nil {
-		return err
-	}
 	asset, err := s.readAsset(ctx, assetID)
 	if err != nil {
 		return err
@@ -157,8 +163,8 @@ func (s *SmartContract) UpdateFarmingDetails(ctx contractapi.TransactionContextI
 	if err := requireOwnership(ctx, asset); err != nil {
 		return err
 	}
-	if asset.Status != "AT_FARM" {
-		return fmt.Errorf("asset %s with status '%s' cannot be updated by the farm", assetID, asset.Status)
+	if err := requireAssetAction(ctx, asset, "UpdateFarmingDetails"); err != nil {
+		return err
 	}
 
 	// === LOGIC MERGE MỚI ===
@@ -202,14 +208,11 @@ func (s *SmartContract) AddFeedToFarmingBatch(ctx contractapi.TransactionContext
 	asset, err := s.readAsset(ctx, assetID)
 	if err != nil { return err }
 	// ... (kiểm tra quyền, trạng thái AT_FARM) ...
-	if err := requireRole(ctx, "admin", "worker"); err != nil {
-		return err
-	}
 	if err := requireOwnership(ctx, asset); err != nil {
 		return err
 	}
-	if asset.Status != "AT_FARM" {
-		return fmt.Errorf("asset %s with status '%s' cannot be updated by the farm", assetID, asset.Status)
+	if err := requireAssetAction(ctx, asset, "AddFeedToFarmingBatch"); err != nil {
+		return err
 	}
 
 	var newFeed Feed
@@ -248,14 +251,11 @@ func (s *SmartContract) AddMedicationToFarmingBatch(ctx contractapi.TransactionC
 	asset, err := s.readAsset(ctx, assetID)
 	if err != nil { return err }
 	// ... (kiểm tra quyền, trạng thái AT_FARM) ...
-	if err := requireRole(ctx, "admin", "worker"); err != nil {
-		return err
-	}
 	if err := requireOwnership(ctx, asset); err != nil {
 		return err
 	}
-	if asset.Status != "AT_FARM" {
-		return fmt.Errorf("asset %s with status '%s' cannot be updated by the farm", assetID, asset.Status)
+	if err := requireAssetAction(ctx, asset, "AddMedicationToFarmingBatch"); err != nil {
+		return err
 	}
 	var newMedication Medication
 	if err := json.Unmarshal([]byte(medicationJSON), &newMedication); err != nil {
@@ -286,9 +286,6 @@ func (s *SmartContract) AddMedicationToFarmingBatch(ctx contractapi.TransactionC
 
 // UpdateAverageWeight cập nhật trọng lượng trung bình của một lô thịt.
 func (s *SmartContract) UpdateAverageWeight(ctx contractapi.TransactionContextInterface, assetID string, averageWeightJSON string) error {
-	if err := requireRole(ctx, "admin", "worker"); err != nil {
-		return err
-	}
 	asset, err := s.readAsset(ctx, assetID)
 	if err != nil {
 		return err
@@ -296,8 +293,8 @@ func (s *SmartContract) UpdateAverageWeight(ctx contractapi.TransactionContextIn
 	if err := requireOwnership(ctx, asset); err != nil {
 		return err
 	}
-	if asset.Status != "AT_FARM" {
-		return fmt.Errorf("asset %s with status '%s' cannot be updated by the farm", assetID, asset.Status)
+	if err := requireAssetAction(ctx, asset, "UpdateAverageWeight"); err != nil {
+		return err
 	}
 	var newAverageWeight Weight
 	if err := json.Unmarshal([]byte(averageWeightJSON), &newAverageWeight); err != nil {
@@ -310,9 +307,6 @@ func (s *SmartContract) UpdateAverageWeight(ctx contractapi.TransactionContextIn
 // UpdateHarvestDate cập nhật ngày thu hoạch thực tế.
 func (s *SmartContract) UpdateHarvestDate(ctx contractapi.TransactionContextInterface, assetID string, harvestDate string) error {
 	// ... (logic tương tự, chỉ cập nhật một trường) ...
-	if err := requireRole(ctx, "admin", "worker"); err != nil {
-		return err
-	}
 	asset, err := s.readAsset(ctx, assetID)
 	if err != nil {
 		return err
@@ -320,8 +314,8 @@ func (s *SmartContract) UpdateHarvestDate(ctx contractapi.TransactionContextInte
 	if err := requireOwnership(ctx, asset); err != nil {
 		return err
 	}
-	if asset.Status != "AT_FARM" {
-		return fmt.Errorf("asset %s with status '%s' cannot be updated by the farm", assetID, asset.Status)
+	if err := requireAssetAction(ctx, asset, "UpdateHarvestDate"); err != nil {
+		return err
 	}
 	// Tìm sự kiện FARMING và cập nhật nó
 	updated := false
@@ -344,9 +338,6 @@ func (s *SmartContract) UpdateHarvestDate(ctx contractapi.TransactionContextInte
 // UpdateExpectedHarvestDate cập nhật ngày dự kiến thu hoạch.
 func (s *SmartContract) UpdateExpectedHarvestDate(ctx contractapi.TransactionContextInterface, assetID string, expectedHarvestDate string) error {
 	// ... (logic tương tự, chỉ cập nhật một trường) ...
-	if err := requireRole(ctx, "admin", "worker"); err != nil {
-		return err
-	}
 	asset, err := s.readAsset(ctx, assetID)
 	if err != nil {
 		return err
@@ -354,8 +345,8 @@ func (s *SmartContract) UpdateExpectedHarvestDate(ctx contractapi.TransactionCon
 	if err := requireOwnership(ctx, asset); err != nil {
 		return err
 	}
-	if asset.Status != "AT_FARM" {
-		return fmt.Errorf("asset %s with status '%s' cannot be updated by the farm", assetID, asset.Status)
+	if err := requireAssetAction(ctx, asset, "UpdateExpectedHarvestDate"); err != nil {
+		return err
 	}
 	// Tìm sự kiện FARMING và cập nhật nó
 	updated := false
@@ -380,14 +371,11 @@ func (s *SmartContract) AddCertificatesToFarmingBatch(ctx contractapi.Transactio
 	asset, err := s.readAsset(ctx, assetID)
 	if err != nil { return err }
 	// ... (kiểm tra quyền, trạng thái AT_FARM) ...
-	if err := requireRole(ctx, "admin", "worker"); err != nil {
-		return err
-	}
 	if err := requireOwnership(ctx, asset); err != nil {
 		return err
 	}
-	if asset.Status != "AT_FARM" {
-		return fmt.Errorf("asset %s with status '%s' cannot be updated by the farm", assetID, asset.Status)
+	if err := requireAssetAction(ctx, asset, "AddCertificatesToFarmingBatch"); err != nil {
+		return err
 	}
 	var newCertificates []Certificate
 	if err := json.Unmarshal([]byte(certificatesJSON), &newCertificates); err != nil {
@@ -422,7 +410,7 @@ func (s *SmartContract) GetAssetAtFarmByID(ctx contractapi.TransactionContextInt
 	if err != nil {
 		return nil, err
 	}
-	if asset.Status != "AT_FARM" {
+	if asset.Status != AssetStatusAtFarm {
 		return nil, fmt.Errorf("asset %s with status '%s' is not at farm", assetID, asset.Status)
 	}
 	return asset, nil
@@ -430,9 +418,6 @@ func (s *SmartContract) GetAssetAtFarmByID(ctx contractapi.TransactionContextInt
 
 // Cập nhật thông tin lưu kho cho một lô thịt, thêm sự kiện STORAGE_UPDATE vào lịch sử asset.
 func (s *SmartContract) UpdateStorageInfo(ctx contractapi.TransactionContextInterface, assetID string, storageDetailsJSON string) error {
-	if err := requireRole(ctx, "admin", "worker"); err != nil {
-		return err
-	}
 	asset, err := s.readAsset(ctx, assetID)
 	if err != nil {
 		return err
@@ -451,9 +436,6 @@ func (s *SmartContract) UpdateStorageInfo(ctx contractapi.TransactionContextInte
 
 // Tách một lô thịt tại nhà bán lẻ thành các đơn vị nhỏ hơn, tạo các asset mới cho từng đơn vị và cập nhật sự kiện SPLIT_INTO_UNITS.
 func (s *SmartContract) SplitBatchToUnits(ctx contractapi.TransactionContextInterface, parentAssetID string, unitCount int, unitIDPrefix string) error {
-	if err := requireRole(ctx, "admin", "worker"); err != nil {
-		return err
-	}
 	parentAsset, err := s.readAsset(ctx, parentAssetID)
 	if err != nil {
 		return err
@@ -461,13 +443,18 @@ func (s *SmartContract) SplitBatchToUnits(ctx contractapi.TransactionContextInte
 	if err := requireOwnership(ctx, parentAsset); err != nil {
 		return err
 	}
-	if parentAsset.Status != "AT_RETAILER" {
-		return fmt.Errorf("asset %s with status '%s' cannot be split into units", parentAssetID, parentAsset.Status)
+	if err := requireAssetAction(ctx, parentAsset, "SplitBatchToUnits"); err != nil {
+		return err
 	}
 	if float64(unitCount) > parentAsset.CurrentQuantity.Value {
 		return fmt.Errorf("unit count (%d) exceeds parent batch quantity (%f)", unitCount, parentAsset.CurrentQuantity.Value)
 	}
 
+	unitLot := parentAsset.LotNumber
+	if unitLot == "" {
+		unitLot = parentAsset.AssetID
+	}
+
 	for i := 1; i <= unitCount; i++ {
 		unitAssetID := fmt.Sprintf("%s%d", unitIDPrefix, i)
 		exists, err := s.assetExists(ctx, unitAssetID)
@@ -496,12 +483,20 @@ func (s *SmartContract) SplitBatchToUnits(ctx contractapi.TransactionContextInte
 			AverageWeight:    parentAsset.AverageWeight,
 			ParentAssetIDs:   []string{parentAssetID},
 			ProductName:      parentAsset.ProductName,
-			Status:           "ON_SHELF",
+			Status:           AssetStatusOnShelf,
 			OwnerOrg:         parentAsset.OwnerOrg,
+			OwnerMSP:         parentAsset.OwnerMSP,
 			OriginalQuantity: unitQuantity,
 			CurrentQuantity:  unitQuantity,
 			History:          []Event{*creationEvent},
 		}
+		// Đơn vị bán lẻ giữ số lô của lô cha và dùng mã asset làm số sê-ri
+		if err := s.assignGS1Identifiers(ctx, &newUnitAsset, unitLot, unitAssetID); err != nil {
+			return err
+		}
+		if err := setAssetEndorsementPolicy(ctx, &newUnitAsset); err != nil {
+			return err
+		}
 		err = s.updateAsset(ctx, &newUnitAsset)
 		if err != nil {
 			return err
@@ -513,14 +508,11 @@ func (s *SmartContract) SplitBatchToUnits(ctx contractapi.TransactionContextInte
 		"unitCount":    unitCount,
 		"unitIDPrefix": unitIDPrefix,
 	}
-	return s.addEvent(ctx, parentAsset, "SPLIT_INTO_UNITS", "SPLIT_INTO_UNITS_COMPLETED", splitEventDetails)
+	return s.addEvent(ctx, parentAsset, "SPLIT_INTO_UNITS", AssetStatusSplitIntoUnitsCompleted, splitEventDetails)
 }
 
 // Đánh dấu một đơn vị thịt đã được bán, thêm sự kiện SOLD vào lịch sử asset.
 func (s *SmartContract) MarkAsSold(ctx contractapi.TransactionContextInterface, assetID string, soldDetailsJSON string) error {
-	if err := requireRole(ctx, "admin", "worker"); err != nil {
-		return err
-	}
 	asset, err := s.readAsset(ctx, assetID)
 	if err != nil {
 		return err
@@ -528,8 +520,8 @@ func (s *SmartContract) MarkAsSold(ctx contractapi.TransactionContextInterface,
 	if err := requireOwnership(ctx, asset); err != nil {
 		return err
 	}
-	if asset.Status != "ON_SHELF" {
-		return fmt.Errorf("asset %s with status '%s' cannot be sold", assetID, asset.Status)
+	if err := requireAssetAction(ctx, asset, "MarkAsSold"); err != nil {
+		return err
 	}
 
 	var soldDetails map[string]interface{}
@@ -541,7 +533,32 @@ func (s *SmartContract) MarkAsSold(ctx contractapi.TransactionContextInterface,
 
 	soldDetails["saleTimestamp"] = txTimestamp
 
-	return s.addEvent(ctx, asset, "SOLD", "SOLD", soldDetails)
+	return s.addEvent(ctx, asset, "SOLD", AssetStatusSold, soldDetails)
+}
+
+// ReleaseAssetHold gỡ tạm giữ cho một asset sau khi đã kiểm tra, khôi phục trạng thái trước khi bị tạm giữ.
+func (s *SmartContract) ReleaseAssetHold(ctx contractapi.TransactionContextInterface, assetID string, resolution string) error {
+	asset, err := s.readAsset(ctx, assetID)
+	if err != nil {
+		return err
+	}
+	if err := requireOwnership(ctx, asset); err != nil {
+		return err
+	}
+	if err := requireAssetAction(ctx, asset, "ReleaseAssetHold"); err != nil {
+		return err
+	}
+	if resolution == "" {
+		return fmt.Errorf("a resolution is required to release the hold on asset %s", assetID)
+	}
+
+	restoredStatus := asset.StatusBeforeHold
+	asset.StatusBeforeHold = ""
+	releaseDetails := map[string]interface{}{
+		"resolution":     resolution,
+		"restoredStatus": restoredStatus,
+	}
+	return s.addEvent(ctx, asset, "HOLD_RELEASED", restoredStatus, releaseDetails)
 }
 
 // QueryAssetsByFacility thực hiện một truy vấn CouchDB để tìm tất cả các asset
@@ -605,9 +622,9 @@ func (s *SmartContract) QueryAssetsByFacility(ctx contractapi.TransactionContext
 // QueryAssetsAtProcessorByStatus thực hiện một truy vấn CouchDB để tìm tất cả các asset
 // thuộc sở hữu của một nhà máy chế biến và có một trạng thái cụ thể.
 func (s *SmartContract) QueryAssetsAtProcessorByStatus(ctx contractapi.TransactionContextInterface, facilityID string, status string) ([]*MeatAsset, error) {
-	// Chỉ những người dùng thuộc chính cơ sở đó mới có quyền truy vấn
-	callerFacilityID, found, err := ctx.GetClientIdentity().GetAttributeValue("facilityID")
-	if err != nil || !found || callerFacilityID != facilityID {
+	// Chỉ những người dùng thuộc chính cơ sở đó (hoặc cơ quan quản lý) mới có quyền truy vấn
+	callerFacilityID, found, err := getClientAttribute(ctx, "facilityID")
+	if !isRegulator(ctx) && (err != nil || !found || callerFacilityID != facilityID) {
 		return nil, fmt.Errorf("caller is not authorized to query assets for facility %s", facilityID)
 	}
 
@@ -656,9 +673,9 @@ func (s *SmartContract) QueryAssetsAtProcessorByStatus(ctx contractapi.Transacti
 //QueryAssetsAtRetailerByStatus thực hiện một truy vấn CouchDB để tìm tất cả các asset
 // thuộc sở hữu của một nhà bán lẻ và có một trạng thái cụ thể.
 func (s *SmartContract) QueryAssetsAtRetailerByStatus(ctx contractapi.TransactionContextInterface, facilityID string, status string) ([]*MeatAsset, error) {
-	// Chỉ những người dùng thuộc chính cơ sở đó mới có quyền truy vấn
-	callerFacilityID, found, err := ctx.GetClientIdentity().GetAttributeValue("facilityID")
-	if err != nil || !found || callerFacilityID != facilityID {
+	// Chỉ những người dùng thuộc chính cơ sở đó (hoặc cơ quan quản lý) mới có quyền truy vấn
+	callerFacilityID, found, err := getClientAttribute(ctx, "facilityID")
+	if !isRegulator(ctx) && (err != nil || !found || callerFacilityID != facilityID) {
 		return nil, fmt.Errorf("caller is not authorized to query assets for facility %s", facilityID)
 	}
 	// Xây dựng chuỗi truy vấn CouchDB.
@@ -701,6 +718,7 @@ func (s *SmartContract) QueryAssetsAtRetailerByStatus(ctx contractapi.Transactio
 
 //QueryAssetsByFacilityAndSKU thực hiện một truy vấn CouchDB để tìm tất cả các asset
 // được tạo ra bởi một facility cụ thể và có SKU cụ thể.
+// Chỉ trả về các asset còn số lượng khả dụng (available-to-promise) sau khi trừ phần đã giữ chỗ.
 func (s *SmartContract) QueryAssetsByFacilityAndSKU(ctx contractapi.TransactionContextInterface, facilityID string, sku string) ([]*MeatAsset, error) {
 	// Xây dựng chuỗi truy vấn CouchDB.
 	queryString := fmt.Sprintf(`{
@@ -728,6 +746,10 @@ func (s *SmartContract) QueryAssetsByFacilityAndSKU(ctx contractapi.TransactionC
 		if err != nil {
 			return nil, err
 		}
+		refreshAvailability(&asset)
+		if asset.AvailableQuantity.Value <= quantityTolerance {
+			continue
+		}
 		assets = append(assets, &asset)
 	}
 	return assets, nil
@@ -0,0 +1,364 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestCreateFarmingBatch(t *testing.T) {
+	quantity := Quantity{Unit: "head", Value: 20}
+	weight := Weight{Value: 90, Unit: "kg"}
+	runTransactionCases(t, func(c *testChannel) {
+		c.withCatalog()
+		c.createFarmBatch(farmAdmin, "FARM-BATCH-1", skuPorkCarcass, quantity)
+	}, []transactionCase{
+		{name: "farm worker creates batch", identity: farmWorker, function: "CreateFarmingBatch",
+			args: []interface{}{"FARM-BATCH-2", "Pork carcass", skuPorkCarcass, quantity, testFarmDetails("FARM-1"), weight}},
+		{name: "duplicate asset", identity: farmAdmin, function: "CreateFarmingBatch",
+			args:    []interface{}{"FARM-BATCH-1", "Pork carcass", skuPorkCarcass, quantity, testFarmDetails("FARM-1"), weight},
+			wantErr: "asset FARM-BATCH-1 already exists"},
+		{name: "invalid quantity", identity: farmAdmin, function: "CreateFarmingBatch",
+			args:    []interface{}{"FARM-BATCH-2", "Pork carcass", skuPorkCarcass, "[]", testFarmDetails("FARM-1"), weight},
+			wantErr: "failed to unmarshal quantityJSON"},
+		{name: "invalid farm details", identity: farmAdmin, function: "CreateFarmingBatch",
+			args:    []interface{}{"FARM-BATCH-2", "Pork carcass", skuPorkCarcass, quantity, "{", weight},
+			wantErr: "failed to unmarshal farmDetailsJSON"},
+		{name: "processor denied", identity: processorAdmin, function: "CreateFarmingBatch",
+			args:    []interface{}{"FARM-BATCH-2", "Pork carcass", skuPorkCarcass, quantity, testFarmDetails("FARM-1"), weight},
+			wantErr: "access to CreateFarmingBatch denied"},
+		{name: "driver denied", identity: driver, function: "CreateFarmingBatch",
+			args:    []interface{}{"FARM-BATCH-2", "Pork carcass", skuPorkCarcass, quantity, testFarmDetails("FARM-1"), weight},
+			wantErr: "access to CreateFarmingBatch denied"},
+		{name: "regulator denied", identity: regulator, function: "CreateFarmingBatch",
+			args:    []interface{}{"FARM-BATCH-2", "Pork carcass", skuPorkCarcass, quantity, testFarmDetails("FARM-1"), weight},
+			wantErr: "access to CreateFarmingBatch denied"},
+	})
+}
+
+func TestCreateFarmingBatchRecordsFarmingEvent(t *testing.T) {
+	c := newTestChannel(t).withCatalog()
+	c.createFarmBatch(farmWorker, "FARM-BATCH-1", skuPorkCarcass, Quantity{Unit: "head", Value: 20})
+
+	asset := c.mustEvaluate(regulator, "GetAssetAtFarmByID", "FARM-BATCH-1").(*MeatAsset)
+	if asset.Status != AssetStatusAtFarm || asset.OwnerOrg != "FARM-1" {
+		t.Fatalf("unexpected asset status %s / owner %s", asset.Status, asset.OwnerOrg)
+	}
+	if asset.CurrentQuantity != asset.OriginalQuantity || asset.CurrentQuantity.Value != 20 {
+		t.Fatalf("unexpected quantities %+v / %+v", asset.OriginalQuantity, asset.CurrentQuantity)
+	}
+	if len(asset.History) != 1 || asset.History[0].Type != "FARMING" || asset.History[0].ActorMSP != MeatSupplyOrgMSP {
+		t.Fatalf("unexpected history %+v", asset.History)
+	}
+	if details := asset.History[0].Details.(map[string]interface{}); details["facilityID"] != "FARM-1" {
+		t.Fatalf("farming event has facilityID %v, want FARM-1", details["facilityID"])
+	}
+}
+
+func TestFarmingBatchUpdates(t *testing.T) {
+	setup := func(c *testChannel) {
+		c.withCatalog()
+		c.createFarmBatch(farmAdmin, "FARM-BATCH-1", skuPorkCarcass, Quantity{Unit: "head", Value: 20})
+	}
+	feed := Feed{Name: "Corn mix", DosageKg: 500, StartDate: "2024-01-01"}
+	medication := Medication{Name: "Amoxicillin", Dose: "10mg/kg", DateApplied: "2024-01-15"}
+	certificates := []Certificate{{Name: "HACCP", Media: MediaPointer{URL: "https://example.com/haccp.pdf"}}}
+
+	runTransactionCases(t, setup, []transactionCase{
+		{name: "update farming details", identity: farmWorker, function: "UpdateFarmingDetails",
+			args: []interface{}{"FARM-BATCH-1", map[string]interface{}{"facilityName": "Green Valley Farm 2"}}},
+		{name: "update farming details invalid JSON", identity: farmWorker, function: "UpdateFarmingDetails",
+			args: []interface{}{"FARM-BATCH-1", "["}, wantErr: "failed to unmarshal updatedFarmDetailsJSON"},
+		{name: "update farming details by other farm", identity: otherFarmAdmin, function: "UpdateFarmingDetails",
+			args: []interface{}{"FARM-BATCH-1", map[string]interface{}{}}, wantErr: "is not the owner of asset FARM-BATCH-1"},
+		{name: "add feed", identity: farmWorker, function: "AddFeedToFarmingBatch",
+			args: []interface{}{"FARM-BATCH-1", feed}},
+		{name: "add feed to unknown asset", identity: farmWorker, function: "AddFeedToFarmingBatch",
+			args: []interface{}{"FARM-BATCH-9", feed}, wantErr: "does not exist"},
+		{name: "add feed by driver", identity: driver, function: "AddFeedToFarmingBatch",
+			args: []interface{}{"FARM-BATCH-1", feed}, wantErr: "access to AddFeedToFarmingBatch denied"},
+		{name: "add medication", identity: farmAdmin, function: "AddMedicationToFarmingBatch",
+			args: []interface{}{"FARM-BATCH-1", medication}},
+		{name: "add medication invalid JSON", identity: farmAdmin, function: "AddMedicationToFarmingBatch",
+			args: []interface{}{"FARM-BATCH-1", "{"}, wantErr: "failed to unmarshal medicationJSON"},
+		{name: "update average weight", identity: farmAdmin, function: "UpdateAverageWeight",
+			args: []interface{}{"FARM-BATCH-1", Weight{Value: 95, Unit: "kg"}}},
+		{name: "update average weight by other farm", identity: otherFarmAdmin, function: "UpdateAverageWeight",
+			args: []interface{}{"FARM-BATCH-1", Weight{Value: 95, Unit: "kg"}}, wantErr: "is not the owner"},
+		{name: "update harvest date", identity: farmWorker, function: "UpdateHarvestDate",
+			args: []interface{}{"FARM-BATCH-1", "2024-03-05"}},
+		{name: "update harvest date by regulator", identity: regulator, function: "UpdateHarvestDate",
+			args: []interface{}{"FARM-BATCH-1", "2024-03-05"}, wantErr: "access to UpdateHarvestDate denied"},
+		{name: "update expected harvest date", identity: farmWorker, function: "UpdateExpectedHarvestDate",
+			args: []interface{}{"FARM-BATCH-1", "2024-03-10"}},
+		{name: "update expected harvest date of unknown asset", identity: farmWorker, function: "UpdateExpectedHarvestDate",
+			args: []interface{}{"FARM-BATCH-9", "2024-03-10"}, wantErr: "does not exist"},
+		{name: "add certificates", identity: farmAdmin, function: "AddCertificatesToFarmingBatch",
+			args: []interface{}{"FARM-BATCH-1", certificates}},
+		{name: "add certificates invalid JSON", identity: farmAdmin, function: "AddCertificatesToFarmingBatch",
+			args: []interface{}{"FARM-BATCH-1", "{}"}, wantErr: "failed to unmarshal certificatesJSON"},
+	})
+}
+
+func TestFarmingBatchUpdatesMergeIntoFarmingEvent(t *testing.T) {
+	c := newTestChannel(t).withCatalog()
+	c.createFarmBatch(farmAdmin, "FARM-BATCH-1", skuPorkCarcass, Quantity{Unit: "head", Value: 20})
+
+	c.mustSubmit(farmWorker, "UpdateFarmingDetails", "FARM-BATCH-1", map[string]interface{}{"facilityName": "Green Valley Farm 2"})
+	c.mustSubmit(farmWorker, "AddFeedToFarmingBatch", "FARM-BATCH-1", Feed{Name: "Corn mix"})
+	c.mustSubmit(farmWorker, "AddFeedToFarmingBatch", "FARM-BATCH-1", Feed{Name: "Soybean meal"})
+	c.mustSubmit(farmWorker, "AddMedicationToFarmingBatch", "FARM-BATCH-1", Medication{Name: "Amoxicillin"})
+	c.mustSubmit(farmWorker, "AddCertificatesToFarmingBatch", "FARM-BATCH-1", []Certificate{{Name: "HACCP"}})
+	c.mustSubmit(farmWorker, "UpdateHarvestDate", "FARM-BATCH-1", "2024-03-05")
+	c.mustSubmit(farmWorker, "UpdateExpectedHarvestDate", "FARM-BATCH-1", "2024-03-10")
+	c.mustSubmit(farmWorker, "UpdateAverageWeight", "FARM-BATCH-1", Weight{Value: 95, Unit: "kg"})
+
+	asset := c.asset("FARM-BATCH-1")
+	if len(asset.History) != 1 {
+		t.Fatalf("farm updates must not add events, history has %d events", len(asset.History))
+	}
+	details := asset.History[0].Details.(map[string]interface{})
+	if details["facilityName"] != "Green Valley Farm 2" || details["harvestDate"] != "2024-03-05" || details["expectedHarvestDate"] != "2024-03-10" {
+		t.Fatalf("farming details not merged: %v", details)
+	}
+	if feeds := details["feeds"].([]interface{}); len(feeds) != 2 {
+		t.Fatalf("got %d feeds, want 2", len(feeds))
+	}
+	if medications := details["medications"].([]interface{}); len(medications) != 1 {
+		t.Fatalf("got %d medications, want 1", len(medications))
+	}
+	if certificates := details["certificates"].([]interface{}); len(certificates) != 2 {
+		t.Fatalf("got %d certificates, want 2", len(certificates))
+	}
+	if asset.AverageWeight.Value != 95 {
+		t.Fatalf("average weight is %v, want 95", asset.AverageWeight.Value)
+	}
+}
+
+func TestFarmingUpdatesRejectedAfterFarm(t *testing.T) {
+	c := newTestChannel(t).withCatalog()
+	c.createFarmBatch(farmAdmin, "FARM-BATCH-1", skuPorkCarcass, Quantity{Unit: "head", Value: 2})
+	c.ship("SHIP-1", farmAdmin, processorAdmin, "PROC-BATCH", testItem("FARM-BATCH-1", "head", 2))
+
+	_, err := c.submit(farmAdmin, "AddFeedToFarmingBatch", "FARM-BATCH-1", Feed{Name: "Corn mix"})
+	expectError(t, err, "AddFeedToFarmingBatch is not allowed for asset FARM-BATCH-1 with status 'SHIPPED_FULL'")
+	_, err = c.evaluate(farmAdmin, "GetAssetAtFarmByID", "FARM-BATCH-1")
+	expectError(t, err, "is not at farm")
+	_, err = c.evaluate(farmAdmin, "GetAssetAtFarmByID", "FARM-BATCH-9")
+	expectError(t, err, "does not exist")
+}
+
+// atProcessor dựng một lô thịt đã được giao tới nhà máy chế biến PROC-1 (asset PROC-BATCH-0, 10 con).
+func atProcessor(c *testChannel) {
+	c.withCatalog()
+	c.createFarmBatch(farmAdmin, "FARM-BATCH-1", skuPorkCarcass, Quantity{Unit: "head", Value: 10})
+	c.ship("SHIP-1", farmAdmin, processorAdmin, "PROC-BATCH", testItem("FARM-BATCH-1", "head", 10))
+}
+
+func TestProcessAndSplitBatch(t *testing.T) {
+	children := []ChildAssetInput{
+		{AssetID: "LOIN-1", ProductName: "Pork loin", SKU: skuPorkLoin, Quantity: Quantity{Unit: "kg", Value: 120}},
+		{AssetID: "TRAY-1", ProductName: "Pork belly tray", SKU: skuPorkTray, Quantity: Quantity{Unit: "tray", Value: 200}},
+	}
+	details := ProcessingDetails{ProcessorOrgName: "Song Than Foods", FacilityName: "Song Than Processing", Address: testFacilityAddresses["PROC-1"]}
+	runTransactionCases(t, atProcessor, []transactionCase{
+		{name: "processor splits batch", identity: processorAdmin, function: "ProcessAndSplitBatch",
+			args: []interface{}{"PROC-BATCH-0", children, details}},
+		{name: "unknown child SKU", identity: processorAdmin, function: "ProcessAndSplitBatch",
+			args:    []interface{}{"PROC-BATCH-0", []ChildAssetInput{{AssetID: "X-1", SKU: "BEEF"}}, details},
+			wantErr: "product with SKU BEEF does not exist"},
+		{name: "child already exists", identity: processorAdmin, function: "ProcessAndSplitBatch",
+			args:    []interface{}{"PROC-BATCH-0", []ChildAssetInput{{AssetID: "FARM-BATCH-1", SKU: skuPorkLoin}}, details},
+			wantErr: "child asset FARM-BATCH-1 already exists"},
+		{name: "invalid children JSON", identity: processorAdmin, function: "ProcessAndSplitBatch",
+			args: []interface{}{"PROC-BATCH-0", "{", details}, wantErr: "failed to unmarshal childAssetsJSON"},
+		{name: "farm cannot process", identity: farmAdmin, function: "ProcessAndSplitBatch",
+			args: []interface{}{"FARM-BATCH-1", children, details}, wantErr: "is not allowed for asset FARM-BATCH-1 with status 'SHIPPED_FULL'"},
+		{name: "not the owner", identity: retailerAdmin, function: "ProcessAndSplitBatch",
+			args: []interface{}{"PROC-BATCH-0", children, details}, wantErr: "is not allowed for facility type 'RETAILER'"},
+		{name: "regulator denied", identity: regulator, function: "ProcessAndSplitBatch",
+			args: []interface{}{"PROC-BATCH-0", children, details}, wantErr: "access to ProcessAndSplitBatch denied"},
+	})
+
+	t.Run("children inherit owner and product weight", func(t *testing.T) {
+		c := newTestChannel(t)
+		atProcessor(c)
+		c.mustSubmit(processorAdmin, "ProcessAndSplitBatch", "PROC-BATCH-0", children, details)
+
+		c.expectStatus("PROC-BATCH-0", AssetStatusProcessedAndSplit)
+		loin := c.asset("LOIN-1")
+		if loin.Status != AssetStatusPackaged || loin.OwnerOrg != "PROC-1" || loin.ParentAssetIDs[0] != "PROC-BATCH-0" {
+			t.Fatalf("unexpected child asset %+v", loin)
+		}
+		if loin.AverageWeight.Value != 1 {
+			t.Fatalf("child average weight is %v, want the product's 1 kg", loin.AverageWeight.Value)
+		}
+		if tray := c.asset("TRAY-1"); tray.GTIN == "" {
+			t.Fatalf("child of GTIN product %s has no GTIN", skuPorkTray)
+		}
+	})
+}
+
+// atRetailer dựng 24 khay thịt đã được giao tới cửa hàng RETAIL-1 (asset RETAIL-BATCH-0).
+func atRetailer(c *testChannel) {
+	atProcessor(c)
+	c.mustSubmit(processorAdmin, "ProcessAndSplitBatch", "PROC-BATCH-0", []ChildAssetInput{
+		{AssetID: "TRAY-1", ProductName: "Pork belly tray", SKU: skuPorkTray, Quantity: Quantity{Unit: "tray", Value: 24}},
+	}, ProcessingDetails{FacilityName: "Song Than Processing"})
+	c.ship("SHIP-2", processorAdmin, retailerAdmin, "RETAIL-BATCH", testItem("TRAY-1", "tray", 24))
+}
+
+func TestSplitBatchToUnits(t *testing.T) {
+	runTransactionCases(t, atRetailer, []transactionCase{
+		{name: "retailer splits batch", identity: retailerWorker, function: "SplitBatchToUnits",
+			args: []interface{}{"RETAIL-BATCH-0", 4, "UNIT-"}},
+		{name: "too many units", identity: retailerWorker, function: "SplitBatchToUnits",
+			args: []interface{}{"RETAIL-BATCH-0", 25, "UNIT-"}, wantErr: "unit count (25) exceeds parent batch quantity"},
+		{name: "unit already exists", identity: retailerWorker, function: "SplitBatchToUnits",
+			args: []interface{}{"RETAIL-BATCH-0", 2, "TRAY-"}, wantErr: "unit asset TRAY-1 already exists"},
+		{name: "processor cannot split", identity: processorAdmin, function: "SplitBatchToUnits",
+			args: []interface{}{"TRAY-1", 4, "UNIT-"}, wantErr: "SplitBatchToUnits is not allowed for asset TRAY-1"},
+		{name: "not the owner", identity: processorAdmin, function: "SplitBatchToUnits",
+			args: []interface{}{"RETAIL-BATCH-0", 4, "UNIT-"}, wantErr: "is not the owner of asset RETAIL-BATCH-0"},
+		{name: "driver denied", identity: driver, function: "SplitBatchToUnits",
+			args: []interface{}{"RETAIL-BATCH-0", 4, "UNIT-"}, wantErr: "access to SplitBatchToUnits denied"},
+	})
+
+	t.Run("units take quantity from parent", func(t *testing.T) {
+		c := newTestChannel(t)
+		atRetailer(c)
+		c.mustSubmit(retailerWorker, "SplitBatchToUnits", "RETAIL-BATCH-0", 4, "UNIT-")
+
+		c.expectStatus("RETAIL-BATCH-0", AssetStatusSplitIntoUnitsCompleted)
+		c.expectQuantity("RETAIL-BATCH-0", 20)
+		for _, unitID := range []string{"UNIT-1", "UNIT-2", "UNIT-3", "UNIT-4"} {
+			c.expectStatus(unitID, AssetStatusOnShelf)
+			c.expectQuantity(unitID, 1)
+			if unit := c.asset(unitID); unit.SerialNumber != unitID || unit.LotNumber != "TRAY-1" {
+				t.Fatalf("unit %s has serial %q and lot %q", unitID, unit.SerialNumber, unit.LotNumber)
+			}
+		}
+		if c.exists("UNIT-5") {
+			t.Fatal("unexpected unit UNIT-5")
+		}
+	})
+}
+
+func TestMarkAsSold(t *testing.T) {
+	setup := func(c *testChannel) {
+		atRetailer(c)
+		c.mustSubmit(retailerWorker, "SplitBatchToUnits", "RETAIL-BATCH-0", 2, "UNIT-")
+	}
+	sale := map[string]interface{}{"posID": "POS-3", "price": 89000}
+	runTransactionCases(t, setup, []transactionCase{
+		{name: "retailer sells unit", identity: retailerWorker, function: "MarkAsSold",
+			args: []interface{}{"UNIT-1", sale}},
+		{name: "batch cannot be sold", identity: retailerWorker, function: "MarkAsSold",
+			args: []interface{}{"RETAIL-BATCH-0", sale}, wantErr: "MarkAsSold is not allowed for asset RETAIL-BATCH-0"},
+		{name: "invalid sale details", identity: retailerWorker, function: "MarkAsSold",
+			args: []interface{}{"UNIT-1", "["}, wantErr: "failed to unmarshal soldDetailsJSON"},
+		{name: "not the owner", identity: processorAdmin, function: "MarkAsSold",
+			args: []interface{}{"UNIT-1", sale}, wantErr: "is not the owner of asset UNIT-1"},
+	})
+
+	t.Run("sold unit cannot be sold again", func(t *testing.T) {
+		c := newTestChannel(t)
+		setup(c)
+		c.mustSubmit(retailerWorker, "MarkAsSold", "UNIT-1", sale)
+		c.expectStatus("UNIT-1", AssetStatusSold)
+		if details := lastEvent(c.asset("UNIT-1")).Details.(map[string]interface{}); details["saleTimestamp"] == "" || details["posID"] != "POS-3" {
+			t.Fatalf("unexpected sale details %v", details)
+		}
+		_, err := c.submit(retailerWorker, "MarkAsSold", "UNIT-1", sale)
+		expectError(t, err, "MarkAsSold is not allowed for asset UNIT-1 with status 'SOLD'")
+	})
+}
+
+func TestUpdateStorageInfo(t *testing.T) {
+	storage := StorageDetails{FacilityName: "Song Than Processing", LocationInStore: "Cold room 2", Temperature: "-18C"}
+	runTransactionCases(t, atProcessor, []transactionCase{
+		{name: "owner records storage", identity: processorAdmin, function: "UpdateStorageInfo",
+			args: []interface{}{"PROC-BATCH-0", storage}},
+		{name: "invalid storage JSON", identity: processorAdmin, function: "UpdateStorageInfo",
+			args: []interface{}{"PROC-BATCH-0", "["}, wantErr: "failed to unmarshal storageDetailsJSON"},
+		{name: "shipped asset", identity: farmAdmin, function: "UpdateStorageInfo",
+			args: []interface{}{"FARM-BATCH-1", storage}, wantErr: "event STORAGE_UPDATE cannot move asset FARM-BATCH-1"},
+		{name: "not the owner", identity: retailerAdmin, function: "UpdateStorageInfo",
+			args: []interface{}{"PROC-BATCH-0", storage}, wantErr: "is not the owner"},
+	})
+}
+
+func TestReleaseAssetHold(t *testing.T) {
+	// Niêm phong khi đến không khớp nên hàng nhận được bị tạm giữ
+	setup := func(c *testChannel) {
+		c.withCatalog()
+		c.createFarmBatch(farmAdmin, "FARM-BATCH-1", skuPorkCarcass, Quantity{Unit: "head", Value: 10})
+		c.pickUpAndStart("SHIP-1", farmAdmin, "PROC-1", testItem("FARM-BATCH-1", "head", 10))
+		c.mustSubmit(driver, "AddDeliveryProof", "SHIP-1", "PROC-1", testProof("PROC-1"))
+		c.mustSubmit(processorAdmin, "ConfirmShipmentDelivery", "SHIP-1", "PROC-1", "PROC-BATCH", []string{"SEAL-OTHER"})
+	}
+	runTransactionCases(t, setup, []transactionCase{
+		{name: "owner releases hold", identity: processorAdmin, function: "ReleaseAssetHold",
+			args: []interface{}{"PROC-BATCH-0", "Inspected, goods intact"}},
+		{name: "resolution required", identity: processorAdmin, function: "ReleaseAssetHold",
+			args: []interface{}{"PROC-BATCH-0", ""}, wantErr: "a resolution is required"},
+		{name: "worker denied", identity: farmWorker, function: "ReleaseAssetHold",
+			args: []interface{}{"PROC-BATCH-0", "ok"}, wantErr: "access to ReleaseAssetHold denied"},
+		{name: "not the owner", identity: farmAdmin, function: "ReleaseAssetHold",
+			args: []interface{}{"PROC-BATCH-0", "ok"}, wantErr: "is not the owner"},
+		{name: "asset not on hold", identity: farmAdmin, function: "ReleaseAssetHold",
+			args: []interface{}{"FARM-BATCH-1", "ok"}, wantErr: "ReleaseAssetHold is not allowed for asset FARM-BATCH-1"},
+	})
+
+	t.Run("status before hold is restored", func(t *testing.T) {
+		c := newTestChannel(t)
+		setup(c)
+		held := c.asset("PROC-BATCH-0")
+		if held.Status != AssetStatusOnHold || held.StatusBeforeHold != AssetStatusAtProcessor {
+			t.Fatalf("asset status %s (before hold %s), want ON_HOLD (AT_PROCESSOR)", held.Status, held.StatusBeforeHold)
+		}
+		c.mustSubmit(processorAdmin, "ReleaseAssetHold", "PROC-BATCH-0", "Inspected, goods intact")
+		if released := c.asset("PROC-BATCH-0"); released.Status != AssetStatusAtProcessor || released.StatusBeforeHold != "" {
+			t.Fatalf("asset status %s (before hold %s) after release", released.Status, released.StatusBeforeHold)
+		}
+	})
+}
+
+func TestAssetQueries(t *testing.T) {
+	c := newTestChannel(t)
+	atProcessor(c)
+	c.createFarmBatch(farmAdmin, "FARM-BATCH-2", skuPorkCarcass, Quantity{Unit: "head", Value: 5})
+	c.createFarmBatch(otherFarmAdmin, "FARM2-BATCH-1", skuPorkCarcass, Quantity{Unit: "head", Value: 5})
+
+	byFarm := c.mustEvaluate(regulator, "QueryAssetsByFacility", "FARM-1").([]*MeatAsset)
+	if len(byFarm) != 2 || byFarm[0].AssetID != "FARM-BATCH-2" || byFarm[1].AssetID != "FARM-BATCH-1" {
+		t.Fatalf("QueryAssetsByFacility returned %v, want newest batch first", assetIDs(byFarm))
+	}
+
+	atProcessorAssets := c.mustEvaluate(processorAdmin, "QueryAssetsAtProcessorByStatus", "PROC-1", AssetStatusAtProcessor).([]*MeatAsset)
+	if len(atProcessorAssets) != 1 || atProcessorAssets[0].AssetID != "PROC-BATCH-0" {
+		t.Fatalf("QueryAssetsAtProcessorByStatus returned %v", assetIDs(atProcessorAssets))
+	}
+	_, err := c.evaluate(farmAdmin, "QueryAssetsAtProcessorByStatus", "PROC-1", AssetStatusAtProcessor)
+	expectError(t, err, "caller is not authorized to query assets for facility PROC-1")
+
+	if assets := c.mustEvaluate(regulator, "QueryAssetsAtRetailerByStatus", "RETAIL-1", AssetStatusAtRetailer).([]*MeatAsset); len(assets) != 0 {
+		t.Fatalf("QueryAssetsAtRetailerByStatus returned %v, want none", assetIDs(assets))
+	}
+	_, err = c.evaluate(processorAdmin, "QueryAssetsAtRetailerByStatus", "RETAIL-1", AssetStatusAtRetailer)
+	expectError(t, err, "caller is not authorized")
+
+	available := c.mustEvaluate(farmAdmin, "QueryAssetsByFacilityAndSKU", "FARM-1", skuPorkCarcass).([]*MeatAsset)
+	if len(available) != 1 || available[0].AssetID != "FARM-BATCH-2" {
+		t.Fatalf("QueryAssetsByFacilityAndSKU returned %v, want only the batch with stock", assetIDs(available))
+	}
+
+	_, err = c.evaluate(farmAdmin, "GetAsset", "MISSING")
+	expectError(t, err, "does not exist")
+}
+
+func assetIDs(assets []*MeatAsset) []string {
+	var ids []string
+	for _, asset := range assets {
+		ids = append(ids, asset.AssetID)
+	}
+	return ids
+}
@@ -5,9 +5,64 @@ import (
 	"github.com/hyperledger/fabric-contract-api-go/contractapi"
 )
 
+// Các tổ chức (MSP) trên kênh.
+const (
+	MeatSupplyOrgMSP = "MeatSupplyOrgMSP"
+	RegulatorOrgMSP  = "RegulatorOrgMSP"
+)
+
+// Vai trò của cơ quan quản lý: đọc được mọi asset, shipment nhưng chỉ ghi được sự kiện quản lý.
+const RegulatorRole = "regulator"
+
+// roleMSPs quy định tổ chức nào được cấp từng giá trị của thuộc tính 'role'.
+// Thuộc tính do CA của một tổ chức khác cấp sẽ không được tin cậy.
+var roleMSPs = map[string][]string{
+	"superadmin":  {MeatSupplyOrgMSP},
+	"admin":       {MeatSupplyOrgMSP},
+	"worker":      {MeatSupplyOrgMSP},
+	"driver":      {MeatSupplyOrgMSP},
+	RegulatorRole: {RegulatorOrgMSP},
+}
+
+// facilityAttributeMSPs là các tổ chức được cấp thuộc tính 'facilityID' và 'facilityType'.
+var facilityAttributeMSPs = []string{MeatSupplyOrgMSP}
+
+// Đọc một thuộc tính của client và kiểm tra thuộc tính đó được cấp bởi tổ chức có thẩm quyền.
+// Thuộc tính không hợp lệ với MSP của client được trả về như một lỗi.
+func getClientAttribute(ctx contractapi.TransactionContextInterface, name string) (string, bool, error) {
+	value, found, err := ctx.GetClientIdentity().GetAttributeValue(name)
+	if err != nil || !found {
+		return value, found, err
+	}
+	mspID, err := ctx.GetClientIdentity().GetMSPID()
+	if err != nil {
+		return "", false, fmt.Errorf("failed to get client MSP ID: %v", err)
+	}
+
+	var allowedMSPs []string
+	switch name {
+	case "role":
+		allowedMSPs = roleMSPs[value]
+	case "facilityID", "facilityType":
+		allowedMSPs = facilityAttributeMSPs
+	default:
+		return value, found, nil
+	}
+	if !containsString(allowedMSPs, mspID) {
+		return "", false, fmt.Errorf("attribute '%s=%s' is not valid for organization '%s'", name, value, mspID)
+	}
+	return value, found, nil
+}
+
+// Kiểm tra client có phải là cơ quan quản lý (vai trò regulator thuộc RegulatorOrgMSP) không.
+func isRegulator(ctx contractapi.TransactionContextInterface) bool {
+	role, _, err := getClientAttribute(ctx, "role")
+	return err == nil && role == RegulatorRole
+}
+
 // Kiểm tra vai trò của client có nằm trong danh sách cho phép không.
 func requireRole(ctx contractapi.TransactionContextInterface, allowedRoles ...string) error {
-	role, found, err := ctx.GetClientIdentity().GetAttributeValue("role")
+	role, found, err := getClientAttribute(ctx, "role")
 	if err != nil {
 		return fmt.Errorf("failed to get 'role' attribute: %v", err)
 	}
@@ -26,7 +81,7 @@ func requireRole(ctx contractapi.TransactionContextInterface, allowedRoles ...st
 
 // Kiểm tra client có phải là chủ sở hữu của asset không.
 func requireOwnership(ctx contractapi.TransactionContextInterface, asset *MeatAsset) error {
-	callerFacilityID, found, err := ctx.GetClientIdentity().GetAttributeValue("facilityID")
+	callerFacilityID, found, err := getClientAttribute(ctx, "facilityID")
 	if err != nil {
 		return fmt.Errorf("failed to get 'facilityID' attribute: %v", err)
 	}
@@ -43,7 +98,7 @@ func requireOwnership(ctx contractapi.TransactionContextInterface, asset *MeatAs
 
 // Kiểm tra loại cơ sở của client có nằm trong danh sách cho phép không.
 func requireFacilityType(ctx contractapi.TransactionContextInterface, allowedTypes ...string) error {
-	facilityType, found, err := ctx.GetClientIdentity().GetAttributeValue("facilityType")
+	facilityType, found, err := getClientAttribute(ctx, "facilityType")
 	if err != nil {
 		return fmt.Errorf("failed to get 'facilityType' attribute: %v", err)
 	}
@@ -81,4 +136,20 @@ func requireAssignedDriver(ctx contractapi.TransactionContextInterface, shipment
 	}
 
 	return nil
-}
\ No newline at end of file
+}
+
+// Kiểm tra tổ chức (MSP) của client có nằm trong danh sách cho phép không.
+func requireMSP(ctx contractapi.TransactionContextInterface, allowedMSPs ...string) error {
+	mspID, err := ctx.GetClientIdentity().GetMSPID()
+	if err != nil {
+		return fmt.Errorf("failed to get client MSP ID: %v", err)
+	}
+
+	for _, allowedMSP := range allowedMSPs {
+		if mspID == allowedMSP {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("caller from organization '%s' is not authorized", mspID)
+}
@@ -0,0 +1,370 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// Tên private data collection chứa điều khoản thương mại (xem collections_config.json).
+const commercialTermsCollection = "CommercialTermsCollection"
+
+// Khóa trong transient map chứa dữ liệu điều khoản thương mại do client gửi lên.
+const commercialTermsTransientKey = "commercialTerms"
+
+// Khóa trong transient map chứa salt ngẫu nhiên được ghi cùng điều khoản thương mại. Nếu không có salt,
+// đơn giá và số tiền ít giá trị khả dĩ có thể bị dò ngược từ hash SHA-256 công khai.
+const commercialTermsSaltTransientKey = "commercialTermsSalt"
+
+// Độ dài tối thiểu (byte) của salt.
+const minCommercialTermsSaltLength = 16
+
+// Các tổ chức là thành viên của CommercialTermsCollection; phải khớp với policy trong collections_config.json.
+var commercialTermsMSPs = []string{MeatSupplyOrgMSP}
+
+// Các loại tài liệu công khai có thể gắn điều khoản thương mại riêng tư.
+const (
+	TermsReferencePurchaseOrder = "PURCHASE_ORDER"
+	TermsReferenceAsset         = "ASSET"
+	TermsReferenceShipment      = "SHIPMENT"
+	TermsReferenceInvoice       = "INVOICE"
+)
+
+// SetPurchaseOrderTerms lưu đơn giá của đơn đặt hàng vào private data collection.
+// Dữ liệu được truyền qua transient map (khóa "commercialTerms" và "commercialTermsSalt") để không xuất hiện
+// trong proposal công khai; đơn đặt hàng công khai chỉ giữ hash của dữ liệu này.
+// Chỉ bên mua được đề xuất đơn giá khi đơn còn ở trạng thái CREATED; bên bán đồng ý bằng AcceptPurchaseOrder,
+// sau đó điều khoản bị khóa để không bên nào tự ý đổi giá đã được chấp nhận.
+func (s *SmartContract) SetPurchaseOrderTerms(ctx contractapi.TransactionContextInterface, poID string) error {
+	po, err := s.readPurchaseOrder(ctx, poID)
+	if err != nil {
+		return err
+	}
+	if err := requirePurchaseOrderParty(ctx, po.BuyerFacilityID); err != nil {
+		return err
+	}
+	if po.Status != POStatusCreated {
+		return fmt.Errorf("commercial terms of purchase order %s with status '%s' are frozen", poID, po.Status)
+	}
+
+	terms, err := readTransientCommercialTerms(ctx)
+	if err != nil {
+		return err
+	}
+	terms.BuyerFacilityID = po.BuyerFacilityID
+	terms.SellerFacilityID = po.SellerFacilityID
+	for i, price := range terms.LinePrices {
+		line := findPurchaseOrderLine(po, price.LineID)
+		if line == nil {
+			return fmt.Errorf("purchase order %s has no line %s", poID, price.LineID)
+		}
+		if price.UnitPrice < 0 {
+			return fmt.Errorf("unit price of line %s must not be negative", price.LineID)
+		}
+		terms.LinePrices[i].SKU = line.SKU
+	}
+
+	hash, err := s.putCommercialTerms(ctx, TermsReferencePurchaseOrder, poID, terms)
+	if err != nil {
+		return err
+	}
+	po.CommercialTermsHash = hash
+	return s.addPurchaseOrderEvent(ctx, po, "COMMERCIAL_TERMS_ATTACHED", po.Status, map[string]interface{}{"commercialTermsHash": hash})
+}
+
+// SetAssetSupplyContract lưu hợp đồng cung ứng của một asset vào private data collection.
+// Chỉ cơ sở sở hữu asset được gắn hợp đồng và phải là một bên của hợp đồng.
+func (s *SmartContract) SetAssetSupplyContract(ctx contractapi.TransactionContextInterface, assetID string) error {
+	asset, err := s.readAsset(ctx, assetID)
+	if err != nil {
+		return err
+	}
+	if err := requireOwnership(ctx, asset); err != nil {
+		return err
+	}
+	if err := requireAssetAction(ctx, asset, "SetAssetSupplyContract"); err != nil {
+		return err
+	}
+
+	terms, err := readTransientCommercialTerms(ctx)
+	if err != nil {
+		return err
+	}
+	if terms.SellerFacilityID != asset.OwnerOrg && terms.BuyerFacilityID != asset.OwnerOrg {
+		return fmt.Errorf("owner facility %s must be a party of the supply contract for asset %s", asset.OwnerOrg, assetID)
+	}
+
+	hash, err := s.putCommercialTerms(ctx, TermsReferenceAsset, assetID, terms)
+	if err != nil {
+		return err
+	}
+	asset.CommercialTermsHash = hash
+	return s.addEvent(ctx, asset, "COMMERCIAL_TERMS_ATTACHED", asset.Status, map[string]interface{}{"commercialTermsHash": hash})
+}
+
+// SetShipmentTerms lưu điều khoản vận chuyển (cước phí, bên mua) của lô hàng vào private data collection.
+// Cả hai bên của điều khoản phải là cơ sở có điểm dừng trên lô hàng và người gọi phải thuộc một trong hai bên.
+func (s *SmartContract) SetShipmentTerms(ctx contractapi.TransactionContextInterface, shipmentID string) error {
+	shipment, err := s.readShipmentAsset(ctx, shipmentID)
+	if err != nil {
+		return err
+	}
+	callerFacilityID, _, err := getClientAttribute(ctx, "facilityID")
+	if err != nil {
+		return fmt.Errorf("failed to get 'facilityID' attribute: %v", err)
+	}
+
+	terms, err := readTransientCommercialTerms(ctx)
+	if err != nil {
+		return err
+	}
+	stopFacilities := make([]string, 0, len(shipment.Stops))
+	for _, stop := range shipment.Stops {
+		stopFacilities = append(stopFacilities, stop.FacilityID)
+	}
+	if !containsString(stopFacilities, terms.SellerFacilityID) || !containsString(stopFacilities, terms.BuyerFacilityID) {
+		return fmt.Errorf("buyer and seller facilities must both be stops of shipment %s", shipmentID)
+	}
+	if callerFacilityID != terms.SellerFacilityID && callerFacilityID != terms.BuyerFacilityID {
+		return fmt.Errorf("caller from facility '%s' is not a party of the shipment terms", callerFacilityID)
+	}
+
+	hash, err := s.putCommercialTerms(ctx, TermsReferenceShipment, shipmentID, terms)
+	if err != nil {
+		return err
+	}
+	event, err := s.createEvent(ctx, "COMMERCIAL_TERMS_ATTACHED", map[string]interface{}{"commercialTermsHash": hash})
+	if err != nil {
+		return err
+	}
+	shipment.CommercialTermsHash = hash
+	shipment.History = append(shipment.History, *event)
+	return s.updateShipment(ctx, shipment)
+}
+
+// GetCommercialTerms trả về phần điều khoản thương mại riêng tư của một tài liệu.
+// Chỉ người gọi thuộc tổ chức thành viên của collection và thuộc bên mua/bên bán (hoặc superadmin) mới được đọc.
+func (s *SmartContract) GetCommercialTerms(ctx contractapi.TransactionContextInterface, referenceType string, referenceID string) (*CommercialTerms, error) {
+	if err := requireCommercialTermsMember(ctx); err != nil {
+		return nil, err
+	}
+	terms, err := s.readCommercialTerms(ctx, referenceType, referenceID)
+	if err != nil {
+		return nil, err
+	}
+	role, _, _ := getClientAttribute(ctx, "role")
+	if role == "superadmin" {
+		return terms, nil
+	}
+	callerFacilityID, _, _ := getClientAttribute(ctx, "facilityID")
+	if callerFacilityID == "" || (callerFacilityID != terms.BuyerFacilityID && callerFacilityID != terms.SellerFacilityID) {
+		return nil, fmt.Errorf("caller from facility '%s' is not authorized to read the commercial terms of %s %s", callerFacilityID, referenceType, referenceID)
+	}
+	return terms, nil
+}
+
+// VerifyCommercialTerms so sánh hash của dữ liệu riêng tư trên ledger với hash lưu trên tài liệu công khai.
+// Mọi tổ chức trên kênh đều có thể gọi hàm này mà không cần đọc được dữ liệu riêng tư.
+func (s *SmartContract) VerifyCommercialTerms(ctx contractapi.TransactionContextInterface, referenceType string, referenceID string) (bool, error) {
+	publicHash, err := s.publicCommercialTermsHash(ctx, referenceType, referenceID)
+	if err != nil {
+		return false, err
+	}
+	if publicHash == "" {
+		return false, fmt.Errorf("%s %s has no commercial terms attached", referenceType, referenceID)
+	}
+	key, err := commercialTermsKey(ctx, referenceType, referenceID)
+	if err != nil {
+		return false, err
+	}
+	ledgerHash, err := ctx.GetStub().GetPrivateDataHash(commercialTermsCollection, key)
+	if err != nil {
+		return false, fmt.Errorf("failed to read private data hash: %v", err)
+	}
+	if ledgerHash == nil {
+		return false, nil
+	}
+	return hex.EncodeToString(ledgerHash) == publicHash, nil
+}
+
+// --- Các hàm hỗ trợ cho private data ---
+
+// readTransientCommercialTerms đọc và kiểm tra điều khoản thương mại từ transient map của proposal.
+func readTransientCommercialTerms(ctx contractapi.TransactionContextInterface) (*CommercialTerms, error) {
+	if err := requireCommercialTermsMember(ctx); err != nil {
+		return nil, err
+	}
+	transientMap, err := ctx.GetStub().GetTransient()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read transient map: %v", err)
+	}
+	termsJSON, ok := transientMap[commercialTermsTransientKey]
+	if !ok || len(termsJSON) == 0 {
+		return nil, fmt.Errorf("commercial terms must be passed in the transient map under key '%s'", commercialTermsTransientKey)
+	}
+
+	var terms CommercialTerms
+	decoder := json.NewDecoder(bytes.NewReader(termsJSON))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&terms); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal commercial terms: %v", err)
+	}
+	if terms.Currency == "" {
+		return nil, fmt.Errorf("currency of the commercial terms is required")
+	}
+	if terms.TotalAmount < 0 {
+		return nil, fmt.Errorf("total amount must not be negative")
+	}
+	salt, err := readTransientSalt(ctx)
+	if err != nil {
+		return nil, err
+	}
+	terms.Salt = salt
+	return &terms, nil
+}
+
+// readVerifiedTransientTerms đọc bản ghi điều khoản thương mại mà client gửi lại qua transient map (khóa "commercialTerms")
+// và đối chiếu với hash trên ledger. Transaction chỉ dùng GetPrivateDataHash nên peer của tổ chức không phải
+// thành viên collection (ví dụ RegulatorOrg) vẫn endorse được.
+func (s *SmartContract) readVerifiedTransientTerms(ctx contractapi.TransactionContextInterface, referenceType string, referenceID string) (*CommercialTerms, error) {
+	key, err := commercialTermsKey(ctx, referenceType, referenceID)
+	if err != nil {
+		return nil, err
+	}
+	ledgerHash, err := ctx.GetStub().GetPrivateDataHash(commercialTermsCollection, key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read private data hash: %v", err)
+	}
+	if ledgerHash == nil {
+		return nil, fmt.Errorf("no commercial terms found for %s %s", referenceType, referenceID)
+	}
+	transientMap, err := ctx.GetStub().GetTransient()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read transient map: %v", err)
+	}
+	termsJSON, ok := transientMap[commercialTermsTransientKey]
+	if !ok || len(termsJSON) == 0 {
+		return nil, fmt.Errorf("commercial terms of %s %s must be passed in the transient map under key '%s'", referenceType, referenceID, commercialTermsTransientKey)
+	}
+	hash := sha256.Sum256(termsJSON)
+	if !bytes.Equal(hash[:], ledgerHash) {
+		return nil, fmt.Errorf("commercial terms passed for %s %s do not match the ledger", referenceType, referenceID)
+	}
+	var terms CommercialTerms
+	if err := json.Unmarshal(termsJSON, &terms); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal commercial terms: %v", err)
+	}
+	return &terms, nil
+}
+
+// readTransientSalt đọc salt của điều khoản thương mại từ transient map của proposal.
+func readTransientSalt(ctx contractapi.TransactionContextInterface) (string, error) {
+	transientMap, err := ctx.GetStub().GetTransient()
+	if err != nil {
+		return "", fmt.Errorf("failed to read transient map: %v", err)
+	}
+	salt := transientMap[commercialTermsSaltTransientKey]
+	if len(salt) < minCommercialTermsSaltLength {
+		return "", fmt.Errorf("a salt of at least %d bytes must be passed in the transient map under key '%s'", minCommercialTermsSaltLength, commercialTermsSaltTransientKey)
+	}
+	return hex.EncodeToString(salt), nil
+}
+
+// putCommercialTerms ghi điều khoản thương mại vào collection và trả về hash (hex SHA-256) của bản ghi,
+// trùng với giá trị GetPrivateDataHash mà các tổ chức không phải thành viên nhìn thấy.
+func (s *SmartContract) putCommercialTerms(ctx contractapi.TransactionContextInterface, referenceType string, referenceID string, terms *CommercialTerms) (string, error) {
+	terms.ObjectType = "CommercialTerms"
+	terms.ReferenceType = referenceType
+	terms.ReferenceID = referenceID
+	terms.UpdatedAt = s.getTxTimestamp(ctx)
+
+	termsBytes, err := json.Marshal(terms)
+	if err != nil {
+		return "", err
+	}
+	key, err := commercialTermsKey(ctx, referenceType, referenceID)
+	if err != nil {
+		return "", err
+	}
+	if err := ctx.GetStub().PutPrivateData(commercialTermsCollection, key, termsBytes); err != nil {
+		return "", fmt.Errorf("failed to put commercial terms into private data: %v", err)
+	}
+	hash := sha256.Sum256(termsBytes)
+	return hex.EncodeToString(hash[:]), nil
+}
+
+// readCommercialTerms đọc điều khoản thương mại từ collection.
+func (s *SmartContract) readCommercialTerms(ctx contractapi.TransactionContextInterface, referenceType string, referenceID string) (*CommercialTerms, error) {
+	key, err := commercialTermsKey(ctx, referenceType, referenceID)
+	if err != nil {
+		return nil, err
+	}
+	termsJSON, err := ctx.GetStub().GetPrivateData(commercialTermsCollection, key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read commercial terms from private data: %v", err)
+	}
+	if termsJSON == nil {
+		return nil, fmt.Errorf("no commercial terms found for %s %s", referenceType, referenceID)
+	}
+	var terms CommercialTerms
+	if err := json.Unmarshal(termsJSON, &terms); err != nil {
+		return nil, err
+	}
+	return &terms, nil
+}
+
+// publicCommercialTermsHash trả về hash điều khoản thương mại được lưu trên tài liệu công khai tương ứng.
+func (s *SmartContract) publicCommercialTermsHash(ctx contractapi.TransactionContextInterface, referenceType string, referenceID string) (string, error) {
+	switch referenceType {
+	case TermsReferencePurchaseOrder:
+		po, err := s.readPurchaseOrder(ctx, referenceID)
+		if err != nil {
+			return "", err
+		}
+		return po.CommercialTermsHash, nil
+	case TermsReferenceAsset:
+		asset, err := s.readAsset(ctx, referenceID)
+		if err != nil {
+			return "", err
+		}
+		return asset.CommercialTermsHash, nil
+	case TermsReferenceShipment:
+		shipment, err := s.readShipmentAsset(ctx, referenceID)
+		if err != nil {
+			return "", err
+		}
+		return shipment.CommercialTermsHash, nil
+	case TermsReferenceInvoice:
+		invoice, err := s.readInvoice(ctx, referenceID)
+		if err != nil {
+			return "", err
+		}
+		return invoice.CommercialTermsHash, nil
+	}
+	return "", fmt.Errorf("unsupported commercial terms reference type '%s'", referenceType)
+}
+
+// commercialTermsKey tạo composite key của điều khoản thương mại trong collection.
+func commercialTermsKey(ctx contractapi.TransactionContextInterface, referenceType string, referenceID string) (string, error) {
+	key, err := ctx.GetStub().CreateCompositeKey("CommercialTerms", []string{referenceType, referenceID})
+	if err != nil {
+		return "", fmt.Errorf("failed to create composite key: %v", err)
+	}
+	return key, nil
+}
+
+// requireCommercialTermsMember kiểm tra người gọi thuộc tổ chức thành viên của CommercialTermsCollection.
+func requireCommercialTermsMember(ctx contractapi.TransactionContextInterface) error {
+	mspID, err := ctx.GetClientIdentity().GetMSPID()
+	if err != nil {
+		return fmt.Errorf("failed to get client MSP ID: %v", err)
+	}
+	if !containsString(commercialTermsMSPs, mspID) {
+		return fmt.Errorf("organization %s is not a member of collection %s", mspID, commercialTermsCollection)
+	}
+	return nil
+}
@@ -0,0 +1,227 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+// testSalt là salt 16 byte mà các test gửi kèm điều khoản thương mại.
+var testSalt = []byte("0123456789abcdef")
+
+// saltTransient trả về transient map chỉ chứa salt, dùng cho các transaction tự tính số tiền (IssueInvoice).
+func saltTransient() map[string][]byte {
+	return map[string][]byte{commercialTermsSaltTransientKey: testSalt}
+}
+
+// termsTransient đóng gói điều khoản thương mại và salt vào transient map như client gửi lên.
+func termsTransient(t *testing.T, terms interface{}) map[string][]byte {
+	termsJSON, err := json.Marshal(terms)
+	if err != nil {
+		t.Fatalf("failed to marshal commercial terms: %v", err)
+	}
+	transient := saltTransient()
+	transient[commercialTermsTransientKey] = termsJSON
+	return transient
+}
+
+// orderTerms là đơn giá của PO-1: 4.500.000 VND mỗi con.
+var orderTerms = CommercialTerms{Currency: "VND", BuyerName: "Song Than Foods", LinePrices: []LinePrice{{LineID: "L1", UnitPrice: 4500000}}}
+
+func TestSetPurchaseOrderTerms(t *testing.T) {
+	cases := []struct {
+		name      string
+		identity  *mockIdentity
+		transient interface{}
+		wantErr   string
+	}{
+		{name: "buyer attaches prices", identity: processorAdmin, transient: orderTerms},
+		{name: "seller cannot set prices", identity: farmAdmin, transient: orderTerms,
+			wantErr: "caller from facility 'FARM-1' is not authorized for this purchase order (expected 'PROC-1')"},
+		{name: "unknown line", identity: processorAdmin, transient: CommercialTerms{Currency: "VND", LinePrices: []LinePrice{{LineID: "L9", UnitPrice: 1}}},
+			wantErr: "purchase order PO-1 has no line L9"},
+		{name: "negative price", identity: processorAdmin, transient: CommercialTerms{Currency: "VND", LinePrices: []LinePrice{{LineID: "L1", UnitPrice: -1}}},
+			wantErr: "unit price of line L1 must not be negative"},
+		{name: "currency required", identity: processorAdmin, transient: CommercialTerms{LinePrices: orderTerms.LinePrices},
+			wantErr: "currency of the commercial terms is required"},
+		{name: "unknown fields rejected", identity: processorAdmin, transient: map[string]interface{}{"currency": "VND", "discount": 5},
+			wantErr: "unknown field \"discount\""},
+		{name: "missing transient data", identity: processorAdmin, transient: nil,
+			wantErr: "commercial terms must be passed in the transient map under key 'commercialTerms'"},
+		{name: "third party denied", identity: retailerAdmin, transient: orderTerms, wantErr: "is not authorized for this purchase order"},
+		{name: "regulator denied", identity: regulator, transient: orderTerms, wantErr: "access to SetPurchaseOrderTerms denied"},
+	}
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			c := newTestChannel(t)
+			withOrder(c)
+			transient := map[string][]byte{}
+			if tc.transient != nil {
+				transient = termsTransient(t, tc.transient)
+			}
+			_, err := c.submitWithTransient(tc.identity, transient, "SetPurchaseOrderTerms", "PO-1")
+			expectError(t, err, tc.wantErr)
+		})
+	}
+
+	t.Run("rejected order cannot carry terms", func(t *testing.T) {
+		c := newTestChannel(t)
+		withOrder(c)
+		c.mustSubmit(farmAdmin, "RejectPurchaseOrder", "PO-1", "Out of stock")
+		_, err := c.submitWithTransient(processorAdmin, termsTransient(t, orderTerms), "SetPurchaseOrderTerms", "PO-1")
+		expectError(t, err, "commercial terms of purchase order PO-1 with status 'REJECTED' are frozen")
+	})
+
+	t.Run("terms are frozen once the seller accepts", func(t *testing.T) {
+		c := newTestChannel(t)
+		withOrder(c)
+		c.mustSubmitWithTransient(processorAdmin, termsTransient(t, orderTerms), "SetPurchaseOrderTerms", "PO-1")
+		c.mustSubmit(farmAdmin, "AcceptPurchaseOrder", "PO-1")
+		raised := CommercialTerms{Currency: "VND", LinePrices: []LinePrice{{LineID: "L1", UnitPrice: 9000000}}}
+		_, err := c.submitWithTransient(processorAdmin, termsTransient(t, raised), "SetPurchaseOrderTerms", "PO-1")
+		expectError(t, err, "commercial terms of purchase order PO-1 with status 'ACCEPTED' are frozen")
+		terms := c.mustEvaluate(farmAdmin, "GetCommercialTerms", TermsReferencePurchaseOrder, "PO-1").(*CommercialTerms)
+		if terms.LinePrices[0].UnitPrice != 4500000 {
+			t.Fatalf("accepted unit price changed to %v", terms.LinePrices[0].UnitPrice)
+		}
+	})
+
+	t.Run("salt is required", func(t *testing.T) {
+		for _, salt := range [][]byte{nil, []byte("short")} {
+			c := newTestChannel(t)
+			withOrder(c)
+			transient := termsTransient(t, orderTerms)
+			transient[commercialTermsSaltTransientKey] = salt
+			_, err := c.submitWithTransient(processorAdmin, transient, "SetPurchaseOrderTerms", "PO-1")
+			expectError(t, err, "a salt of at least 16 bytes must be passed in the transient map under key 'commercialTermsSalt'")
+		}
+	})
+
+	t.Run("salt changes the public hash", func(t *testing.T) {
+		hashes := make(map[string]bool)
+		for _, salt := range [][]byte{testSalt, []byte("fedcba9876543210")} {
+			c := newTestChannel(t)
+			withOrder(c)
+			transient := termsTransient(t, orderTerms)
+			transient[commercialTermsSaltTransientKey] = salt
+			c.mustSubmitWithTransient(processorAdmin, transient, "SetPurchaseOrderTerms", "PO-1")
+			hashes[c.mustEvaluate(regulator, "GetPurchaseOrder", "PO-1").(*PurchaseOrder).CommercialTermsHash] = true
+		}
+		if len(hashes) != 2 {
+			t.Fatal("identical terms with different salts must not share a public hash")
+		}
+	})
+}
+
+func TestCommercialTermsPrivacy(t *testing.T) {
+	c := newTestChannel(t)
+	withOrder(c)
+	c.mustSubmitWithTransient(processorAdmin, termsTransient(t, orderTerms), "SetPurchaseOrderTerms", "PO-1")
+
+	po := c.mustEvaluate(regulator, "GetPurchaseOrder", "PO-1").(*PurchaseOrder)
+	if po.CommercialTermsHash == "" {
+		t.Fatal("purchase order has no commercial terms hash")
+	}
+	poJSON, _ := json.Marshal(po)
+	for _, secret := range []string{"4500000", "Song Than Foods"} {
+		if strings.Contains(string(poJSON), secret) {
+			t.Fatalf("public purchase order leaks %q", secret)
+		}
+	}
+
+	terms := c.mustEvaluate(farmAdmin, "GetCommercialTerms", TermsReferencePurchaseOrder, "PO-1").(*CommercialTerms)
+	if terms.LinePrices[0].UnitPrice != 4500000 || terms.LinePrices[0].SKU != skuPorkCarcass || terms.BuyerFacilityID != "PROC-1" || terms.SellerFacilityID != "FARM-1" {
+		t.Fatalf("unexpected terms %+v", terms)
+	}
+	c.mustEvaluate(superadmin, "GetCommercialTerms", TermsReferencePurchaseOrder, "PO-1")
+	_, err := c.evaluate(retailerAdmin, "GetCommercialTerms", TermsReferencePurchaseOrder, "PO-1")
+	expectError(t, err, "caller from facility 'RETAIL-1' is not authorized to read the commercial terms of PURCHASE_ORDER PO-1")
+	_, err = c.evaluate(regulator, "GetCommercialTerms", TermsReferencePurchaseOrder, "PO-1")
+	expectError(t, err, "access to GetCommercialTerms denied")
+	_, err = c.evaluate(farmAdmin, "GetCommercialTerms", TermsReferencePurchaseOrder, "PO-9")
+	expectError(t, err, "no commercial terms found for PURCHASE_ORDER PO-9")
+
+	// Mọi tổ chức, kể cả không phải thành viên collection, đều đối chiếu được hash
+	if ok := c.mustEvaluate(regulator, "VerifyCommercialTerms", TermsReferencePurchaseOrder, "PO-1").(bool); !ok {
+		t.Fatal("commercial terms hash does not match the private data")
+	}
+	_, err = c.evaluate(regulator, "VerifyCommercialTerms", TermsReferenceShipment, "SHIP-9")
+	expectError(t, err, "does not exist")
+	_, err = c.evaluate(regulator, "VerifyCommercialTerms", TermsReferenceAsset, "FARM-BATCH-1")
+	expectError(t, err, "ASSET FARM-BATCH-1 has no commercial terms attached")
+	_, err = c.evaluate(regulator, "VerifyCommercialTerms", "CONTRACT", "PO-1")
+	expectError(t, err, "unsupported commercial terms reference type 'CONTRACT'")
+
+	// Hash công khai không còn khớp nếu dữ liệu riêng tư bị thay đổi ngoài chaincode
+	key, _ := c.stub.CreateCompositeKey("CommercialTerms", []string{TermsReferencePurchaseOrder, "PO-1"})
+	c.stub.PvtState[commercialTermsCollection][key] = []byte(`{"currency":"USD"}`)
+	if ok := c.mustEvaluate(regulator, "VerifyCommercialTerms", TermsReferencePurchaseOrder, "PO-1").(bool); ok {
+		t.Fatal("tampered private data must not verify")
+	}
+}
+
+func TestSetAssetSupplyContract(t *testing.T) {
+	contract := CommercialTerms{Currency: "VND", SellerFacilityID: "FARM-1", BuyerFacilityID: "PROC-1", TotalAmount: 45000000, ContractTerms: "Net 30"}
+	cases := []struct {
+		name     string
+		identity *mockIdentity
+		terms    CommercialTerms
+		wantErr  string
+	}{
+		{name: "owner attaches contract", identity: farmAdmin, terms: contract},
+		{name: "owner must be a party", identity: farmAdmin, terms: CommercialTerms{Currency: "VND", SellerFacilityID: "FARM-2", BuyerFacilityID: "PROC-1"},
+			wantErr: "owner facility FARM-1 must be a party of the supply contract for asset FARM-BATCH-1"},
+		{name: "negative amount", identity: farmAdmin, terms: CommercialTerms{Currency: "VND", SellerFacilityID: "FARM-1", TotalAmount: -1},
+			wantErr: "total amount must not be negative"},
+		{name: "not the owner", identity: otherFarmAdmin, terms: contract, wantErr: "is not the owner"},
+		{name: "worker denied", identity: farmWorker, terms: contract, wantErr: "access to SetAssetSupplyContract denied"},
+	}
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			c := newTestChannel(t)
+			withFarmBatch(c)
+			_, err := c.submitWithTransient(tc.identity, termsTransient(t, tc.terms), "SetAssetSupplyContract", "FARM-BATCH-1")
+			expectError(t, err, tc.wantErr)
+			if err == nil {
+				asset := c.asset("FARM-BATCH-1")
+				if asset.CommercialTermsHash == "" || lastEvent(asset).Type != "COMMERCIAL_TERMS_ATTACHED" {
+					t.Fatalf("asset has no commercial terms attached")
+				}
+				if !c.mustEvaluate(regulator, "VerifyCommercialTerms", TermsReferenceAsset, "FARM-BATCH-1").(bool) {
+					t.Fatal("asset supply contract does not verify")
+				}
+			}
+		})
+	}
+}
+
+func TestSetShipmentTerms(t *testing.T) {
+	freight := CommercialTerms{Currency: "VND", SellerFacilityID: "FARM-1", BuyerFacilityID: "PROC-1", TotalAmount: 3500000}
+	cases := []struct {
+		name     string
+		identity *mockIdentity
+		terms    CommercialTerms
+		wantErr  string
+	}{
+		{name: "seller attaches freight terms", identity: farmAdmin, terms: freight},
+		{name: "buyer attaches freight terms", identity: processorAdmin, terms: freight},
+		{name: "parties must be stops", identity: farmAdmin, terms: CommercialTerms{Currency: "VND", SellerFacilityID: "FARM-1", BuyerFacilityID: "RETAIL-1"},
+			wantErr: "buyer and seller facilities must both be stops of shipment SHIP-1"},
+		{name: "caller must be a party", identity: retailerAdmin, terms: freight, wantErr: "caller from facility 'RETAIL-1' is not a party of the shipment terms"},
+		{name: "regulator denied", identity: regulator, terms: freight, wantErr: "access to SetShipmentTerms denied"},
+	}
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			c := newTestChannel(t)
+			withPendingShipment(c)
+			_, err := c.submitWithTransient(tc.identity, termsTransient(t, tc.terms), "SetShipmentTerms", "SHIP-1")
+			expectError(t, err, tc.wantErr)
+			if err == nil && !c.mustEvaluate(driver, "VerifyCommercialTerms", TermsReferenceShipment, "SHIP-1").(bool) {
+				t.Fatal("shipment terms do not verify")
+			}
+		})
+	}
+}
@@ -0,0 +1,181 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// Nhiệt độ bảo quản tối đa (°C) của thịt tươi; vượt quá ngưỡng này chuỗi lạnh bị coi là gián đoạn.
+const coldChainMaxCelsius = 4.0
+
+// consumerRedactionRule quy định một loại sự kiện được hiển thị cho người tiêu dùng như thế nào:
+// chặng hiển thị và danh sách trường chi tiết được giữ lại. Mọi trường khác (người thực hiện,
+// txID, tài xế, biển số xe, địa chỉ chi tiết, ghi chú tự do...) đều bị loại bỏ.
+type consumerRedactionRule struct {
+	Stage  string
+	Fields []string
+	Region bool // Hiển thị khu vực (tỉnh/thành, quốc gia) thay cho địa chỉ đầy đủ
+}
+
+// consumerRedactionRules là quy tắc lược bớt theo từng loại sự kiện. Sự kiện không có trong bảng
+// (giữ chỗ, điều khoản thương mại, sự kiện nội bộ của lô hàng...) không được hiển thị.
+var consumerRedactionRules = map[string]consumerRedactionRule{
+	"FARMING":                 {Stage: "FARM", Fields: []string{"facilityName", "harvestDate"}, Region: true},
+	"PROCESSING":              {Stage: "PROCESSING", Fields: []string{"processorOrgName", "facilityName"}, Region: true},
+	"CREATED_FROM_PROCESSING": {Stage: "PACKAGED"},
+	"STORAGE_UPDATE":          {Stage: "STORAGE", Fields: []string{"facilityName", "temperature"}, Region: true},
+	"SHIPPING_STARTED":        {Stage: "IN_TRANSIT"},
+	"RECEIVING":               {Stage: "RECEIVED"},
+	"SEAL_BROKEN":             {Stage: "SEAL_CHECK_FAILED"},
+	"HOLD_RELEASED":           {Stage: "QUALITY_RELEASED"},
+	"CREATED_AS_UNIT":         {Stage: "RETAIL_PACKED"},
+	"SOLD":                    {Stage: "SOLD", Fields: []string{"retailerOrgName", "facilityName"}, Region: true},
+}
+
+// GetConsumerTrace trả về hành trình đã được lược bớt của một đơn vị bán lẻ (vùng nuôi, ngày thu hoạch,
+// cơ sở chế biến, chứng nhận, tình trạng chuỗi lạnh, ngày bán) để hiển thị trên trang quét mã QR.
+func (s *SmartContract) GetConsumerTrace(ctx contractapi.TransactionContextInterface, unitAssetID string) (*ConsumerTrace, error) {
+	asset, err := s.readAsset(ctx, unitAssetID)
+	if err != nil {
+		return nil, err
+	}
+	fullHistory, err := s.getAssetHistoryRecursive(ctx, unitAssetID)
+	if err != nil {
+		return nil, err
+	}
+
+	trace := ConsumerTrace{
+		AssetID:         asset.AssetID,
+		ProductName:     asset.ProductName,
+		SKU:             asset.SKU,
+		ColdChainStatus: "UNKNOWN",
+		Journey:         []ConsumerTraceStep{},
+	}
+	temperatureReadings := 0
+	for _, event := range fullHistory {
+		rule, ok := consumerRedactionRules[event.Type]
+		if !ok {
+			continue
+		}
+		details, _ := event.Details.(map[string]interface{})
+		step := ConsumerTraceStep{
+			Stage: rule.Stage,
+			Date:  dateOnly(event.Timestamp),
+		}
+		for _, field := range rule.Fields {
+			if value, ok := details[field]; ok && value != "" {
+				if step.Details == nil {
+					step.Details = make(map[string]interface{})
+				}
+				step.Details[field] = value
+			}
+		}
+		if rule.Region {
+			step.Region = regionFromAddress(details["address"])
+		}
+		trace.Journey = append(trace.Journey, step)
+
+		switch event.Type {
+		case "FARMING":
+			trace.FarmName, _ = details["facilityName"].(string)
+			trace.FarmRegion = step.Region
+			if harvestDate, _ := details["harvestDate"].(string); harvestDate != "" {
+				trace.HarvestDate = harvestDate
+			}
+			trace.Certificates = appendCertificateNames(trace.Certificates, details["certificates"])
+		case "PROCESSING":
+			if name, _ := details["processorOrgName"].(string); name != "" && !containsString(trace.Processors, name) {
+				trace.Processors = append(trace.Processors, name)
+			}
+			trace.Certificates = appendCertificateNames(trace.Certificates, details["certificates"])
+		case "STORAGE_UPDATE":
+			if celsius, ok := parseCelsius(details["temperature"]); ok {
+				temperatureReadings++
+				if celsius > coldChainMaxCelsius {
+					trace.ColdChainStatus = "EXCURSION"
+				}
+			}
+		case "SOLD":
+			trace.SaleDate = dateOnly(event.Timestamp)
+			if saleTimestamp, _ := details["saleTimestamp"].(string); saleTimestamp != "" {
+				trace.SaleDate = dateOnly(saleTimestamp)
+			}
+		}
+	}
+	if temperatureReadings > 0 && trace.ColdChainStatus == "UNKNOWN" {
+		trace.ColdChainStatus = "OK"
+	}
+
+	status, err := readRegulatoryStatus(ctx, unitAssetID)
+	if err != nil {
+		return nil, err
+	}
+	if status.Status == RegulatoryStatusRecalled {
+		trace.RecallNotice = fmt.Sprintf("This product is subject to recall %s: %s", status.RecallID, status.Reason)
+	}
+
+	return &trace, nil
+}
+
+// --- Các hàm hỗ trợ cho hành trình người tiêu dùng ---
+
+// dateOnly cắt timestamp RFC3339 về dạng ngày YYYY-MM-DD.
+func dateOnly(timestamp string) string {
+	if len(timestamp) >= 10 {
+		return timestamp[:10]
+	}
+	return timestamp
+}
+
+// regionFromAddress lấy hai thành phần cuối (tỉnh/thành, quốc gia) của địa chỉ đầy đủ, bỏ số nhà và đường phố.
+func regionFromAddress(address interface{}) string {
+	addressMap, _ := address.(map[string]interface{})
+	fullText, _ := addressMap["fullText"].(string)
+	var parts []string
+	for _, part := range strings.Split(fullText, ",") {
+		if part = strings.TrimSpace(part); part != "" {
+			parts = append(parts, part)
+		}
+	}
+	if len(parts) > 2 {
+		parts = parts[len(parts)-2:]
+	}
+	return strings.Join(parts, ", ")
+}
+
+// appendCertificateNames thêm tên các chứng nhận (không kèm đường dẫn file) vào danh sách, bỏ trùng lặp.
+func appendCertificateNames(names []string, certificates interface{}) []string {
+	certificateList, _ := certificates.([]interface{})
+	for _, certificate := range certificateList {
+		certificateMap, _ := certificate.(map[string]interface{})
+		if name, _ := certificateMap["name"].(string); name != "" && !containsString(names, name) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+var celsiusPattern = regexp.MustCompile(`-?\d+(\.\d+)?`)
+
+// parseCelsius đọc nhiệt độ dạng "2°C", "-18 C" hoặc "3.5"; giá trị °F được đổi sang °C.
+func parseCelsius(temperature interface{}) (float64, bool) {
+	text, _ := temperature.(string)
+	match := celsiusPattern.FindString(text)
+	if match == "" {
+		return 0, false
+	}
+	value, err := strconv.ParseFloat(match, 64)
+	if err != nil {
+		return 0, false
+	}
+	if strings.Contains(strings.ToUpper(text), "F") {
+		value = (value - 32) * 5 / 9
+	}
+	return value, true
+}
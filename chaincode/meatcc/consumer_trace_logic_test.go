@@ -0,0 +1,81 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+// soldUnit đưa khay thịt tới cửa hàng, lưu kho ở nhiệt độ temperature (bỏ qua nếu rỗng) rồi bán UNIT-1.
+func soldUnit(c *testChannel, temperature string) {
+	atRetailer(c)
+	if temperature != "" {
+		c.mustSubmit(retailerWorker, "UpdateStorageInfo", "RETAIL-BATCH-0",
+			StorageDetails{FacilityName: "District 1 Store", LocationInStore: "Chiller 3", Temperature: temperature})
+	}
+	c.mustSubmit(retailerWorker, "SplitBatchToUnits", "RETAIL-BATCH-0", 2, "UNIT-")
+	c.mustSubmit(retailerWorker, "MarkAsSold", "UNIT-1", map[string]interface{}{
+		"retailerOrgName": "Fresh Mart",
+		"facilityName":    "District 1 Store",
+		"address":         testFacilityAddresses["RETAIL-1"],
+		"cashier":         "Tran Thi B",
+	})
+}
+
+func TestGetConsumerTrace(t *testing.T) {
+	c := newTestChannel(t)
+	soldUnit(c, "2°C")
+
+	trace := c.mustEvaluate(regulator, "GetConsumerTrace", "UNIT-1").(*ConsumerTrace)
+	if trace.FarmName != "Green Valley Farm" || trace.FarmRegion != "Binh Duong, Vietnam" || trace.HarvestDate != "" {
+		t.Fatalf("unexpected farm origin %+v", trace)
+	}
+	if len(trace.Certificates) != 1 || trace.Certificates[0] != "VietGAP" {
+		t.Fatalf("expected VietGAP certificate, got %v", trace.Certificates)
+	}
+	if trace.ColdChainStatus != "OK" || trace.SaleDate != "2024-03-01" || trace.RecallNotice != "" {
+		t.Fatalf("unexpected trace %+v", trace)
+	}
+	var stages []string
+	for _, step := range trace.Journey {
+		stages = append(stages, step.Stage)
+	}
+	for _, stage := range []string{"FARM", "IN_TRANSIT", "RECEIVED", "PROCESSING", "PACKAGED", "STORAGE", "RETAIL_PACKED", "SOLD"} {
+		if !containsString(stages, stage) {
+			t.Fatalf("journey %v has no %s stage", stages, stage)
+		}
+	}
+	sold := trace.Journey[len(trace.Journey)-1]
+	if sold.Stage != "SOLD" || sold.Region != "Ho Chi Minh City, Vietnam" || sold.Details["retailerOrgName"] != "Fresh Mart" {
+		t.Fatalf("unexpected sale step %+v", sold)
+	}
+
+	// Người thực hiện, tài xế, biển số, địa chỉ chi tiết và ghi chú tự do không được lộ ra
+	traceJSON, _ := json.Marshal(trace)
+	for _, secret := range []string{"driver-1", "51C-123.45", "Nguyen Van A", "Tran Thi B", "Km 5", "12 Nguyen Hue", "vietgap.pdf", MeatSupplyOrgMSP, "tx0"} {
+		if strings.Contains(string(traceJSON), secret) {
+			t.Errorf("consumer trace leaks %q", secret)
+		}
+	}
+
+	_, err := c.evaluate(regulator, "GetConsumerTrace", "UNIT-9")
+	expectError(t, err, "does not exist")
+}
+
+func TestConsumerTraceColdChainAndRecall(t *testing.T) {
+	for temperature, want := range map[string]string{"": "UNKNOWN", "3.5": "OK", "8°C": "EXCURSION", "41°F": "EXCURSION"} {
+		c := newTestChannel(t)
+		soldUnit(c, temperature)
+		if trace := c.mustEvaluate(regulator, "GetConsumerTrace", "UNIT-1").(*ConsumerTrace); trace.ColdChainStatus != want {
+			t.Errorf("temperature %q: expected cold chain %s, got %s", temperature, want, trace.ColdChainStatus)
+		}
+	}
+
+	c := newTestChannel(t)
+	soldUnit(c, "2°C")
+	c.mustSubmit(regulator, "RecallAsset", "FARM-BATCH-1", "RC-2024-01", "Salmonella")
+	trace := c.mustEvaluate(retailerWorker, "GetConsumerTrace", "UNIT-1").(*ConsumerTrace)
+	if trace.RecallNotice != "This product is subject to recall RC-2024-01: Salmonella" {
+		t.Fatalf("unexpected recall notice %q", trace.RecallNotice)
+	}
+}
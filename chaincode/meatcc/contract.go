@@ -43,7 +43,11 @@ func (s *SmartContract) GetAssetWithFullHistory(ctx contractapi.TransactionConte
 // --- Các hàm hỗ trợ nội bộ ---
 
 // Thêm một sự kiện vào asset, cập nhật trạng thái mới và lưu lại asset.
+// Chuyển trạng thái phải hợp lệ theo vòng đời asset (assetLifecycle).
 func (s *SmartContract) addEvent(ctx contractapi.TransactionContextInterface, asset *MeatAsset, eventType string, newStatus string, details interface{}) error {
+	if err := requireAssetTransition(ctx, asset, eventType, newStatus); err != nil {
+		return err
+	}
 	event, err := s.createEvent(ctx, eventType, details)
 	if err != nil {
 		return err
@@ -80,8 +84,9 @@ func (s *SmartContract) createEvent(ctx contractapi.TransactionContextInterface,
 	return &event, nil
 }
 
-// Lưu asset vào world state.
+// Lưu asset vào world state, đồng thời cập nhật số lượng đã giữ chỗ và số lượng khả dụng.
 func (s *SmartContract) updateAsset(ctx contractapi.TransactionContextInterface, asset *MeatAsset) error {
+	refreshAvailability(asset)
 	assetJSON, err := json.Marshal(asset)
 	if err != nil {
 		return err
@@ -103,6 +108,7 @@ func (s *SmartContract) readAsset(ctx contractapi.TransactionContextInterface, a
 	if err != nil {
 		return nil, err
 	}
+	refreshAvailability(&asset)
 	return &asset, nil
 }
 
@@ -0,0 +1,34 @@
+package main
+
+import (
+	"sort"
+	"testing"
+)
+
+func TestGetAssetWithFullHistory(t *testing.T) {
+	c := newTestChannel(t)
+	atRetailer(c)
+
+	trace := c.mustEvaluate(regulator, "GetAssetWithFullHistory", "RETAIL-BATCH-0").(*FullAssetTrace)
+	if trace.AssetID != "RETAIL-BATCH-0" || trace.Status != AssetStatusAtRetailer || len(trace.ParentAssetIDs) != 1 || trace.ParentAssetIDs[0] != "TRAY-1" {
+		t.Fatalf("unexpected trace header %+v", trace)
+	}
+	if !sort.SliceIsSorted(trace.FullHistory, func(i, j int) bool { return trace.FullHistory[i].Timestamp < trace.FullHistory[j].Timestamp }) {
+		t.Fatal("full history is not ordered by timestamp")
+	}
+
+	// Lịch sử gồm sự kiện của asset và mọi asset tổ tiên, mỗi sự kiện đúng một lần
+	want := map[string]int{"FARMING": 1, "PROCESSING": 1, "CREATED_FROM_PROCESSING": 1, "RECEIVING": 2, "SHIPPING_STARTED": 2, "PICKED_UP_FOR_SHIPMENT": 2}
+	got := make(map[string]int)
+	for _, event := range trace.FullHistory {
+		got[event.Type]++
+	}
+	for eventType, count := range want {
+		if got[eventType] != count {
+			t.Errorf("expected %d %s events, got %d", count, eventType, got[eventType])
+		}
+	}
+
+	_, err := c.evaluate(regulator, "GetAssetWithFullHistory", "RETAIL-BATCH-9")
+	expectError(t, err, "does not exist")
+}
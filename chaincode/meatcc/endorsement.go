@@ -0,0 +1,42 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/hyperledger/fabric-chaincode-go/pkg/statebased"
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// setAssetEndorsementPolicy gắn chính sách xác nhận theo khóa (state-based endorsement) cho asset:
+// mọi thay đổi tiếp theo của asset phải được cả peer của tổ chức sở hữu và peer của cơ quan quản lý xác nhận.
+// Cơ quan quản lý vẫn phải xác nhận như với chính sách MAJORITY Endorsement chung của kênh, nên chính sách theo khóa
+// chỉ chặt hơn chính sách của kênh. Nếu asset chưa có OwnerMSP, tổ chức của người gọi là chủ sở hữu.
+func setAssetEndorsementPolicy(ctx contractapi.TransactionContextInterface, asset *MeatAsset) error {
+	if asset.OwnerMSP == "" {
+		mspID, err := ctx.GetClientIdentity().GetMSPID()
+		if err != nil {
+			return fmt.Errorf("failed to get client MSP ID: %v", err)
+		}
+		asset.OwnerMSP = mspID
+	}
+	return setKeyEndorsementPolicy(ctx, asset.AssetID, asset.OwnerMSP, RegulatorOrgMSP)
+}
+
+// setKeyEndorsementPolicy yêu cầu peer của tất cả các tổ chức được liệt kê xác nhận mọi thay đổi của khóa.
+func setKeyEndorsementPolicy(ctx contractapi.TransactionContextInterface, key string, mspIDs ...string) error {
+	endorsementPolicy, err := statebased.NewStateEP(nil)
+	if err != nil {
+		return fmt.Errorf("failed to create endorsement policy: %v", err)
+	}
+	if err := endorsementPolicy.AddOrgs(statebased.RoleTypePeer, mspIDs...); err != nil {
+		return fmt.Errorf("failed to add orgs to endorsement policy: %v", err)
+	}
+	policy, err := endorsementPolicy.Policy()
+	if err != nil {
+		return fmt.Errorf("failed to marshal endorsement policy: %v", err)
+	}
+	if err := ctx.GetStub().SetStateValidationParameter(key, policy); err != nil {
+		return fmt.Errorf("failed to set endorsement policy for key %s: %v", key, err)
+	}
+	return nil
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/hyperledger/fabric-chaincode-go/pkg/statebased"
+)
+
+// endorsingOrgs trả về các tổ chức phải xác nhận thay đổi của khóa theo chính sách xác nhận theo khóa.
+func (c *testChannel) endorsingOrgs(key string) []string {
+	c.t.Helper()
+	policy, err := c.stub.GetStateValidationParameter(key)
+	if err != nil || policy == nil {
+		c.t.Fatalf("key %s has no endorsement policy (err %v)", key, err)
+	}
+	endorsementPolicy, err := statebased.NewStateEP(policy)
+	if err != nil {
+		c.t.Fatalf("failed to parse endorsement policy of key %s: %v", key, err)
+	}
+	orgs := endorsementPolicy.ListOrgs()
+	sort.Strings(orgs)
+	return orgs
+}
+
+func TestAssetEndorsementPolicy(t *testing.T) {
+	c := newTestChannel(t)
+	withShipmentInTransit(c)
+	c.mustSubmit(driver, "AddDeliveryProof", "SHIP-1", "PROC-1", testProof("PROC-1"))
+	c.mustSubmit(processorAdmin, "ConfirmShipmentDelivery", "SHIP-1", "PROC-1", "PROC-BATCH", []string{"SEAL-SHIP-1"})
+
+	// Chủ sở hữu không thay thế được cơ quan quản lý: cả hai tổ chức đều phải xác nhận
+	want := []string{MeatSupplyOrgMSP, RegulatorOrgMSP}
+	for _, assetID := range []string{"FARM-BATCH-1", "PROC-BATCH-0"} {
+		if got := c.endorsingOrgs(assetID); !reflect.DeepEqual(got, want) {
+			t.Fatalf("asset %s must be endorsed by %v, got %v", assetID, want, got)
+		}
+	}
+}
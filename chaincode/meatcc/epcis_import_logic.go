@@ -0,0 +1,609 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// Đơn vị đo của chaincode tương ứng với mã UN/CEFACT trong quantityList.
+var epcisUnitsByUOM = map[string]string{"KGM": "kg", "GRM": "g", "LBR": "lb"}
+
+// Hệ số quy đổi các đơn vị khối lượng về kg, dùng khi cân đối đầu vào và đầu ra của sự kiện chế biến.
+var kilogramsPerUnit = map[string]float64{"kg": 1, "g": 0.001, "lb": 0.45359237}
+
+// ImportEPCIS nhập một tài liệu EPCIS 2.0 JSON-LD do nhà cung cấp gửi và áp dụng các sự kiện theo đúng nghiệp vụ
+// của chaincode:
+//   - ObjectEvent ADD với bizStep commissioning -> CreateFarmingBatch (mỗi phần tử quantityList là một lô nuôi)
+//   - TransformationEvent -> ProcessAndSplitBatch (một đầu vào được dùng hết, các đầu ra là lô con mới)
+//   - ObjectEvent với bizStep shipping -> ConfirmPickup của lô vận chuyển trong bizTransactionList
+//   - ObjectEvent với bizStep receiving -> ConfirmShipmentDelivery
+//
+// Mỗi sự kiện được kiểm tra theo access policy của nghiệp vụ tương ứng và các quy tắc sở hữu, số lượng của nghiệp vụ đó;
+// một sự kiện vi phạm làm toàn bộ tài liệu bị từ chối. Lô mới phải được định danh bằng GS1 Digital Link (GTIN + số lô,
+// số lô trở thành mã asset); asset đã có được tham chiếu bằng Digital Link hoặc urn:meatcc:lot:<assetID>.
+// Các sự kiện khác (lưu kho, bốc/dỡ hàng...) và các sự kiện đã được nhập trước đó (theo eventID) được bỏ qua.
+func (s *SmartContract) ImportEPCIS(ctx contractapi.TransactionContextInterface, documentJSON string) (*EPCISImportResult, error) {
+	var document EPCISDocument
+	if err := json.Unmarshal([]byte(documentJSON), &document); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal EPCIS document: %v", err)
+	}
+	if err := validateEPCISDocument(&document); err != nil {
+		return nil, err
+	}
+	callerFacilityID, _, err := getClientAttribute(ctx, "facilityID")
+	if err != nil {
+		return nil, fmt.Errorf("failed to get 'facilityID' attribute: %v", err)
+	}
+
+	result := EPCISImportResult{Applied: []EPCISImportedEvent{}, Skipped: []EPCISImportedEvent{}}
+	batch := &epcisImportBatch{changed: make(map[string]int)}
+	for index, event := range document.EPCISBody.EventList {
+		batch.index = index
+		imported := EPCISImportedEvent{Index: index, EventID: event.EventID}
+		if event.EventID != "" {
+			key, err := ctx.GetStub().CreateCompositeKey("EPCISEvent", []string{event.EventID})
+			if err != nil {
+				return nil, fmt.Errorf("failed to create composite key: %v", err)
+			}
+			importedTxID, err := ctx.GetStub().GetState(key)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read from world state: %v", err)
+			}
+			if importedTxID != nil {
+				imported.Reason = fmt.Sprintf("already imported in transaction %s", string(importedTxID))
+				result.Skipped = append(result.Skipped, imported)
+				continue
+			}
+		}
+
+		facilityID := epcisEventFacility(&event, callerFacilityID)
+		bizStep := cbvValue(event.BizStep)
+		switch {
+		case event.Type == "ObjectEvent" && event.Action == "ADD" && bizStep == "commissioning":
+			imported.Transaction = "CreateFarmingBatch"
+			imported.AssetIDs, err = s.importCommissioning(ctx, batch, &event, facilityID, callerFacilityID)
+		case event.Type == "TransformationEvent":
+			imported.Transaction = "ProcessAndSplitBatch"
+			imported.AssetIDs, err = s.importTransformation(ctx, batch, &event, callerFacilityID)
+		case event.Type == "ObjectEvent" && bizStep == "shipping":
+			imported.Transaction = "ConfirmPickup"
+			imported.AssetIDs, err = s.importShipping(ctx, batch, &event, facilityID, callerFacilityID)
+		case event.Type == "ObjectEvent" && bizStep == "receiving":
+			imported.Transaction = "ConfirmShipmentDelivery"
+			imported.AssetIDs, err = s.importReceiving(ctx, batch, &event, facilityID)
+		default:
+			imported.Reason = fmt.Sprintf("%s with bizStep '%s' is not imported", event.Type, bizStep)
+			result.Skipped = append(result.Skipped, imported)
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("event %d (%s): %v", index, imported.Transaction, err)
+		}
+
+		if event.EventID != "" {
+			key, _ := ctx.GetStub().CreateCompositeKey("EPCISEvent", []string{event.EventID})
+			if err := ctx.GetStub().PutState(key, []byte(ctx.GetStub().GetTxID())); err != nil {
+				return nil, fmt.Errorf("failed to record imported event %s: %v", event.EventID, err)
+			}
+		}
+		result.Applied = append(result.Applied, imported)
+	}
+	return &result, nil
+}
+
+// --- Các hàm hỗ trợ cho nhập EPCIS ---
+
+// epcisImportBatch theo dõi các đối tượng đã bị thay đổi bởi các sự kiện trước trong cùng tài liệu.
+// Chaincode không đọc được dữ liệu do chính transaction ghi, nên mỗi asset, lô vận chuyển và đơn đặt hàng
+// chỉ được thay đổi bởi một sự kiện trong một lần nhập.
+type epcisImportBatch struct {
+	index   int
+	changed map[string]int
+}
+
+// change ghi nhận sự kiện hiện tại thay đổi các đối tượng, từ chối nếu một sự kiện trước đã thay đổi chúng.
+func (b *epcisImportBatch) change(kind string, ids ...string) error {
+	for _, id := range ids {
+		key := kind + " " + id
+		if previous, ok := b.changed[key]; ok && previous != b.index {
+			return fmt.Errorf("%s was already changed by event %d of this document; import the events in separate documents", key, previous)
+		}
+		b.changed[key] = b.index
+	}
+	return nil
+}
+
+// importCommissioning tạo các lô nuôi mới theo CreateFarmingBatch, một lô cho mỗi phần tử quantityList.
+func (s *SmartContract) importCommissioning(ctx contractapi.TransactionContextInterface, batch *epcisImportBatch, event *EPCISEvent, facilityID string, callerFacilityID string) ([]string, error) {
+	if err := requireTransactionAccess(ctx, "CreateFarmingBatch"); err != nil {
+		return nil, err
+	}
+	if facilityID != callerFacilityID {
+		return nil, fmt.Errorf("batches commissioned at facility %s cannot be imported by facility %s", facilityID, callerFacilityID)
+	}
+	if len(event.EPCList) > 0 || len(event.QuantityList) == 0 {
+		return nil, fmt.Errorf("commissioning events must identify farm batches by class (GTIN + lot) in quantityList")
+	}
+
+	farmDetails := FarmDetails{FacilityID: facilityID}
+	if event.FarmDetails != nil {
+		farmDetails = *event.FarmDetails
+		if farmDetails.FacilityID == "" {
+			farmDetails.FacilityID = facilityID
+		}
+		if farmDetails.FacilityID != facilityID {
+			return nil, fmt.Errorf("farm details of facility %s do not match business location %s", farmDetails.FacilityID, facilityID)
+		}
+	}
+	farmDetailsJSON, _ := json.Marshal(farmDetails)
+
+	var assetIDs []string
+	for _, element := range event.QuantityList {
+		assetID, product, err := s.newEPCISObject(ctx, element.EPCClass)
+		if err != nil {
+			return nil, err
+		}
+		if err := batch.change("asset", assetID); err != nil {
+			return nil, err
+		}
+		quantity, err := epcisQuantityValue(element, product.Unit)
+		if err != nil {
+			return nil, err
+		}
+		quantityJSON, _ := json.Marshal(quantity)
+		averageWeightJSON, _ := json.Marshal(product.AverageWeight)
+		if err := s.CreateFarmingBatch(ctx, assetID, product.Name, product.SKU, string(quantityJSON), string(farmDetailsJSON), string(averageWeightJSON)); err != nil {
+			return nil, err
+		}
+		assetIDs = append(assetIDs, assetID)
+	}
+	return assetIDs, nil
+}
+
+// importTransformation chế biến và tách một asset thành các lô con mới theo ProcessAndSplitBatch.
+// Số lượng đầu vào không được vượt quá số lượng hiện có, tổng đầu ra (cùng đơn vị) không được vượt quá đầu vào.
+func (s *SmartContract) importTransformation(ctx contractapi.TransactionContextInterface, batch *epcisImportBatch, event *EPCISEvent, callerFacilityID string) ([]string, error) {
+	if err := requireTransactionAccess(ctx, "ProcessAndSplitBatch"); err != nil {
+		return nil, err
+	}
+	if len(event.InputEPCList)+len(event.InputQuantityList) != 1 {
+		return nil, fmt.Errorf("transformation events must have exactly one input")
+	}
+	if len(event.OutputEPCList) > 0 || len(event.OutputQuantityList) == 0 {
+		return nil, fmt.Errorf("transformation outputs must be new lots identified by class (GTIN + lot) in outputQuantityList")
+	}
+
+	inputID := ""
+	if len(event.InputEPCList) == 1 {
+		inputID = event.InputEPCList[0]
+	} else {
+		inputID = event.InputQuantityList[0].EPCClass
+	}
+	parentAssetID, err := s.resolveEPCISObject(ctx, inputID, callerFacilityID)
+	if err != nil {
+		return nil, err
+	}
+	if err := batch.change("asset", parentAssetID); err != nil {
+		return nil, err
+	}
+	parentAsset, err := s.readAsset(ctx, parentAssetID)
+	if err != nil {
+		return nil, err
+	}
+	inputQuantity := parentAsset.CurrentQuantity
+	if len(event.InputQuantityList) == 1 {
+		if inputQuantity, err = epcisQuantityValue(event.InputQuantityList[0], parentAsset.CurrentQuantity.Unit); err != nil {
+			return nil, err
+		}
+	}
+	if inputQuantity.Unit != parentAsset.CurrentQuantity.Unit {
+		return nil, fmt.Errorf("input unit '%s' does not match unit '%s' of asset %s", inputQuantity.Unit, parentAsset.CurrentQuantity.Unit, parentAssetID)
+	}
+	// ProcessAndSplitBatch tiêu thụ toàn bộ asset đầu vào nên đầu vào phải là toàn bộ số lượng hiện có.
+	if math.Abs(inputQuantity.Value-parentAsset.CurrentQuantity.Value) > quantityTolerance {
+		return nil, fmt.Errorf("input quantity %f must be the whole current quantity %f of asset %s", inputQuantity.Value, parentAsset.CurrentQuantity.Value, parentAssetID)
+	}
+
+	var children []ChildAssetInput
+	outputTotal := 0.0
+	assetIDs := []string{parentAssetID}
+	for _, element := range event.OutputQuantityList {
+		childAssetID, product, err := s.newEPCISObject(ctx, element.EPCClass)
+		if err != nil {
+			return nil, err
+		}
+		if err := batch.change("asset", childAssetID); err != nil {
+			return nil, err
+		}
+		quantity, err := epcisQuantityValue(element, product.Unit)
+		if err != nil {
+			return nil, err
+		}
+		outputValue := quantity.Value
+		if quantity.Unit != inputQuantity.Unit {
+			// Đơn vị khác nhau được cân đối qua khối lượng: đơn vị đếm dùng khối lượng trung bình của sản phẩm.
+			inputKgPerUnit, inputOK, err := s.kilogramsPerAssetUnit(ctx, parentAsset)
+			if err != nil {
+				return nil, err
+			}
+			outputKgPerUnit, outputOK := kilogramsPer(quantity.Unit, product.AverageWeight)
+			if !inputOK || !outputOK {
+				return nil, fmt.Errorf("output unit '%s' of %s cannot be compared with input unit '%s'", quantity.Unit, element.EPCClass, inputQuantity.Unit)
+			}
+			outputValue = quantity.Value * outputKgPerUnit / inputKgPerUnit
+		}
+		outputTotal += outputValue
+		children = append(children, ChildAssetInput{AssetID: childAssetID, ProductName: product.Name, SKU: product.SKU, Quantity: quantity})
+		assetIDs = append(assetIDs, childAssetID)
+	}
+	if outputTotal > inputQuantity.Value+quantityTolerance {
+		return nil, fmt.Errorf("outputs (%f %s) exceed the input quantity (%f %s)", outputTotal, inputQuantity.Unit, inputQuantity.Value, inputQuantity.Unit)
+	}
+
+	processingDetails := ProcessingDetails{FacilityName: callerFacilityID}
+	if event.ProcessingDetails != nil {
+		processingDetails = *event.ProcessingDetails
+	}
+	childAssetsJSON, _ := json.Marshal(children)
+	processingDetailsJSON, _ := json.Marshal(processingDetails)
+	if err := s.ProcessAndSplitBatch(ctx, parentAssetID, string(childAssetsJSON), string(processingDetailsJSON)); err != nil {
+		return nil, err
+	}
+	return assetIDs, nil
+}
+
+// importShipping xác nhận lấy hàng tại một điểm dừng của lô vận chuyển theo ConfirmPickup.
+func (s *SmartContract) importShipping(ctx contractapi.TransactionContextInterface, batch *epcisImportBatch, event *EPCISEvent, facilityID string, callerFacilityID string) ([]string, error) {
+	if err := requireTransactionAccess(ctx, "ConfirmPickup"); err != nil {
+		return nil, err
+	}
+	shipmentID := epcisShipmentID(event)
+	if shipmentID == "" {
+		return nil, fmt.Errorf("shipping events must reference a shipment (%sshipment:<id>) in bizTransactionList", epcisURNPrefix)
+	}
+	if err := batch.change("shipment", shipmentID); err != nil {
+		return nil, err
+	}
+
+	var items []ItemInShipment
+	var assetIDs []string
+	addItem := func(id string, element *EPCISQuantity) error {
+		assetID, err := s.resolveEPCISObject(ctx, id, callerFacilityID)
+		if err != nil {
+			return err
+		}
+		if err := batch.change("asset", assetID); err != nil {
+			return err
+		}
+		asset, err := s.readAsset(ctx, assetID)
+		if err != nil {
+			return err
+		}
+		quantity := Quantity{Unit: asset.CurrentQuantity.Unit, Value: 1}
+		if element != nil {
+			if quantity, err = epcisQuantityValue(*element, asset.CurrentQuantity.Unit); err != nil {
+				return err
+			}
+		}
+		if quantity.Unit != asset.CurrentQuantity.Unit {
+			return fmt.Errorf("shipped unit '%s' does not match unit '%s' of asset %s", quantity.Unit, asset.CurrentQuantity.Unit, assetID)
+		}
+		for i := range items {
+			if items[i].AssetID == assetID {
+				items[i].Quantity.Value += quantity.Value
+				return nil
+			}
+		}
+		items = append(items, ItemInShipment{AssetID: assetID, Quantity: quantity})
+		assetIDs = append(assetIDs, assetID)
+		return nil
+	}
+	for _, epc := range event.EPCList {
+		if err := addItem(epc, nil); err != nil {
+			return nil, err
+		}
+	}
+	for i := range event.QuantityList {
+		if err := addItem(event.QuantityList[i].EPCClass, &event.QuantityList[i]); err != nil {
+			return nil, err
+		}
+	}
+	if len(items) == 0 {
+		return nil, fmt.Errorf("shipping events must list the shipped objects")
+	}
+
+	itemsJSON, _ := json.Marshal(items)
+	if err := s.ConfirmPickup(ctx, shipmentID, facilityID, string(itemsJSON)); err != nil {
+		return nil, err
+	}
+	return assetIDs, nil
+}
+
+// importReceiving xác nhận giao hàng tại một điểm dừng theo ConfirmShipmentDelivery. Hàng khai báo trong sự kiện
+// phải khớp với phần hàng được phân bổ từ manifest của lô vận chuyển cho điểm giao đó.
+func (s *SmartContract) importReceiving(ctx contractapi.TransactionContextInterface, batch *epcisImportBatch, event *EPCISEvent, facilityID string) ([]string, error) {
+	if err := requireTransactionAccess(ctx, "ConfirmShipmentDelivery"); err != nil {
+		return nil, err
+	}
+	shipmentID := epcisShipmentID(event)
+	if shipmentID == "" {
+		return nil, fmt.Errorf("receiving events must reference a shipment (%sshipment:<id>) in bizTransactionList", epcisURNPrefix)
+	}
+	if err := batch.change("shipment", shipmentID); err != nil {
+		return nil, err
+	}
+	shipment, err := s.readShipmentAsset(ctx, shipmentID)
+	if err != nil {
+		return nil, err
+	}
+	var deliveredItems []ItemInShipment
+	stopFound := false
+	for _, stop := range shipment.Stops {
+		if stop.FacilityID == facilityID && stop.Action == "DELIVERY" && stop.Status == "PENDING" {
+			if deliveredItems, err = allocateFromManifest(shipment, stop.Items); err != nil {
+				return nil, fmt.Errorf("invalid delivery at facility %s: %v", facilityID, err)
+			}
+			stopFound = true
+			break
+		}
+	}
+	if !stopFound {
+		return nil, fmt.Errorf("no pending delivery stop found for facility %s", facilityID)
+	}
+
+	// Đối chiếu theo định danh EPCIS của hàng giao: từng cá thể (epcList) hoặc từng lớp đối tượng (quantityList)
+	expected := make(map[string]float64)
+	for _, item := range deliveredItems {
+		if item.POID != "" {
+			if err := batch.change("purchase order", item.POID); err != nil {
+				return nil, err
+			}
+		}
+		asset, err := s.readAsset(ctx, item.AssetID)
+		if err != nil {
+			return nil, err
+		}
+		epcs, quantities := epcisObjects(asset, item.Quantity)
+		for _, epc := range epcs {
+			expected[epc]++
+		}
+		for _, quantity := range quantities {
+			expected[quantity.EPCClass] += quantity.Quantity
+		}
+	}
+	declared := make(map[string]float64)
+	for _, epc := range event.EPCList {
+		declared[epc]++
+	}
+	for _, element := range event.QuantityList {
+		declared[element.EPCClass] += element.Quantity
+	}
+	for id, quantity := range declared {
+		if math.Abs(expected[id]-quantity) > quantityTolerance {
+			return nil, fmt.Errorf("received %f of %s but shipment %s delivers %f to facility %s", quantity, id, shipmentID, expected[id], facilityID)
+		}
+	}
+	for id, quantity := range expected {
+		if _, ok := declared[id]; !ok {
+			return nil, fmt.Errorf("shipment %s delivers %f of %s to facility %s but the event does not list it", shipmentID, quantity, id, facilityID)
+		}
+	}
+
+	sealIDsJSON, _ := json.Marshal(event.SealIDs)
+	newAssetIDPrefix := fmt.Sprintf("%s-%s", shipmentID, facilityID)
+	if err := s.ConfirmShipmentDelivery(ctx, shipmentID, facilityID, newAssetIDPrefix, string(sealIDsJSON)); err != nil {
+		return nil, err
+	}
+	var assetIDs []string
+	for j := range deliveredItems {
+		assetIDs = append(assetIDs, fmt.Sprintf("%s-%d", newAssetIDPrefix, j))
+	}
+	if err := batch.change("asset", assetIDs...); err != nil {
+		return nil, err
+	}
+	return assetIDs, nil
+}
+
+// newEPCISObject đọc mã asset (số lô) và sản phẩm (theo GTIN) của một lô mới được định danh bằng GS1 Digital Link.
+func (s *SmartContract) newEPCISObject(ctx contractapi.TransactionContextInterface, epcClass string) (string, *Product, error) {
+	gtin, lot, serial, err := parseDigitalLink(epcClass)
+	if err != nil {
+		return "", nil, fmt.Errorf("new lots must be identified by a GS1 Digital Link with GTIN and lot: %v", err)
+	}
+	if lot == "" || serial != "" {
+		return "", nil, fmt.Errorf("new lot %s must have a lot number (AI 10) and no serial number", epcClass)
+	}
+	product, err := s.readProductByGTIN(ctx, gtin)
+	if err != nil {
+		return "", nil, err
+	}
+	if !product.Active {
+		return "", nil, fmt.Errorf("product %s is not active", product.SKU)
+	}
+	return lot, product, nil
+}
+
+// resolveEPCISObject tìm asset đã có ứng với một định danh EPCIS: urn:meatcc:lot:<assetID>, Digital Link của
+// một đơn vị có số sê-ri, hoặc Digital Link của một lô (ưu tiên phần của lô đang thuộc cơ sở của người gọi).
+func (s *SmartContract) resolveEPCISObject(ctx contractapi.TransactionContextInterface, id string, facilityID string) (string, error) {
+	if strings.HasPrefix(id, epcisURNPrefix+"lot:") {
+		return strings.TrimPrefix(id, epcisURNPrefix+"lot:"), nil
+	}
+	gtin, lot, serial, err := parseDigitalLink(id)
+	if err != nil {
+		return "", fmt.Errorf("unsupported EPCIS identifier '%s': %v", id, err)
+	}
+
+	key, err := digitalLinkKey(ctx, gtin, lot, serial)
+	if err != nil {
+		return "", err
+	}
+	indexedAssetID, err := ctx.GetStub().GetState(key)
+	if err != nil {
+		return "", fmt.Errorf("failed to read from world state: %v", err)
+	}
+	if indexedAssetID != nil && serial != "" {
+		return string(indexedAssetID), nil
+	}
+	if serial != "" {
+		return "", fmt.Errorf("no asset is registered for %s", id)
+	}
+
+	// Hàng đã qua vận chuyển giữ nguyên GTIN và số lô nhưng là asset mới của bên nhận
+	query := map[string]interface{}{
+		"selector": map[string]interface{}{
+			"docType":               "MeatAsset",
+			"gtin":                  gtin,
+			"lotNumber":             lot,
+			"ownerOrg":              facilityID,
+			"currentQuantity.value": map[string]interface{}{"$gt": 0},
+		},
+		"fields": []string{"assetID"},
+	}
+	queryBytes, err := json.Marshal(query)
+	if err != nil {
+		return "", fmt.Errorf("failed to build query: %v", err)
+	}
+	resultsIterator, err := ctx.GetStub().GetQueryResult(string(queryBytes))
+	if err != nil {
+		return "", fmt.Errorf("failed to execute rich query: %v", err)
+	}
+	defer resultsIterator.Close()
+	var assetIDs []string
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return "", err
+		}
+		assetIDs = append(assetIDs, queryResponse.Key)
+	}
+	switch {
+	case len(assetIDs) == 1:
+		return assetIDs[0], nil
+	case len(assetIDs) > 1:
+		return "", fmt.Errorf("lot %s is held in several assets at facility %s (%s); reference one with %slot:<assetID>", id, facilityID, strings.Join(assetIDs, ", "), epcisURNPrefix)
+	case indexedAssetID != nil:
+		return string(indexedAssetID), nil
+	}
+	return "", fmt.Errorf("no asset is registered for %s", id)
+}
+
+// validateEPCISDocument kiểm tra cấu trúc tài liệu: loại tài liệu, phiên bản 2.x, loại sự kiện, thời gian,
+// action và số lượng dương.
+func validateEPCISDocument(document *EPCISDocument) error {
+	if document.Type != "EPCISDocument" {
+		return fmt.Errorf("invalid EPCIS document type '%s'", document.Type)
+	}
+	if !strings.HasPrefix(document.SchemaVersion, "2.") {
+		return fmt.Errorf("unsupported EPCIS schema version '%s', expected 2.x", document.SchemaVersion)
+	}
+	if len(document.EPCISBody.EventList) == 0 {
+		return fmt.Errorf("the EPCIS document has no events")
+	}
+	eventIDs := make(map[string]bool)
+	for index, event := range document.EPCISBody.EventList {
+		switch event.Type {
+		case "ObjectEvent", "AggregationEvent", "TransactionEvent":
+			if event.Action != "ADD" && event.Action != "OBSERVE" && event.Action != "DELETE" {
+				return fmt.Errorf("event %d: invalid action '%s'", index, event.Action)
+			}
+		case "TransformationEvent", "AssociationEvent":
+		default:
+			return fmt.Errorf("event %d: unknown event type '%s'", index, event.Type)
+		}
+		if _, err := time.Parse(time.RFC3339, event.EventTime); err != nil {
+			return fmt.Errorf("event %d: invalid eventTime '%s'", index, event.EventTime)
+		}
+		if event.EventID != "" {
+			if eventIDs[event.EventID] {
+				return fmt.Errorf("event %d: duplicate eventID %s", index, event.EventID)
+			}
+			eventIDs[event.EventID] = true
+		}
+		lists := [][]EPCISQuantity{event.QuantityList, event.ChildQuantityList, event.InputQuantityList, event.OutputQuantityList}
+		for _, list := range lists {
+			for _, element := range list {
+				if element.EPCClass == "" || element.Quantity <= 0 {
+					return fmt.Errorf("event %d: quantity elements need an epcClass and a positive quantity", index)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// epcisQuantityValue đổi một phần tử quantityList sang Quantity; không có uom nghĩa là đơn vị đếm mặc định.
+func epcisQuantityValue(element EPCISQuantity, defaultUnit string) (Quantity, error) {
+	if element.UOM == "" {
+		return Quantity{Unit: defaultUnit, Value: element.Quantity}, nil
+	}
+	unit, ok := epcisUnitsByUOM[element.UOM]
+	if !ok {
+		return Quantity{}, fmt.Errorf("unsupported unit of measure '%s'", element.UOM)
+	}
+	return Quantity{Unit: unit, Value: element.Quantity}, nil
+}
+
+// kilogramsPer trả về số kg của một đơn vị unit: đơn vị khối lượng được quy đổi trực tiếp,
+// đơn vị đếm dùng khối lượng trung bình averageWeight của sản phẩm.
+func kilogramsPer(unit string, averageWeight Weight) (float64, bool) {
+	if factor, ok := kilogramsPerUnit[unit]; ok {
+		return factor, true
+	}
+	if factor, ok := kilogramsPerUnit[averageWeight.Unit]; ok && averageWeight.Value > 0 {
+		return averageWeight.Value * factor, true
+	}
+	return 0, false
+}
+
+// kilogramsPerAssetUnit trả về số kg của một đơn vị số lượng của asset.
+func (s *SmartContract) kilogramsPerAssetUnit(ctx contractapi.TransactionContextInterface, asset *MeatAsset) (float64, bool, error) {
+	averageWeight := Weight{}
+	if _, isMass := kilogramsPerUnit[asset.CurrentQuantity.Unit]; !isMass {
+		product, err := s.GetProduct(ctx, asset.SKU)
+		if err != nil {
+			return 0, false, err
+		}
+		averageWeight = product.AverageWeight
+	}
+	factor, ok := kilogramsPer(asset.CurrentQuantity.Unit, averageWeight)
+	return factor, ok, nil
+}
+
+// epcisEventFacility trả về cơ sở của sự kiện (bizLocation, hoặc readPoint) nếu là urn:meatcc:facility:<id>,
+// nếu không thì dùng cơ sở của người gọi.
+func epcisEventFacility(event *EPCISEvent, defaultFacilityID string) string {
+	for _, location := range []*EPCISLocation{event.BizLocation, event.ReadPoint} {
+		if location != nil && strings.HasPrefix(location.ID, epcisURNPrefix+"facility:") {
+			return strings.TrimPrefix(location.ID, epcisURNPrefix+"facility:")
+		}
+	}
+	return defaultFacilityID
+}
+
+// epcisShipmentID trả về mã lô vận chuyển được tham chiếu trong bizTransactionList.
+func epcisShipmentID(event *EPCISEvent) string {
+	for _, transaction := range event.BizTransactionList {
+		if strings.HasPrefix(transaction.BizTransaction, epcisURNPrefix+"shipment:") {
+			return strings.TrimPrefix(transaction.BizTransaction, epcisURNPrefix+"shipment:")
+		}
+	}
+	return ""
+}
+
+// cbvValue rút gọn giá trị CBV về dạng ngắn: "https://ref.gs1.org/cbv/BizStep-shipping" và
+// "urn:epcglobal:cbv:bizstep:shipping" đều trở thành "shipping".
+func cbvValue(value string) string {
+	if i := strings.LastIndex(value, "-"); strings.HasPrefix(value, "http") && i >= 0 {
+		return value[i+1:]
+	}
+	if i := strings.LastIndex(value, ":"); strings.HasPrefix(value, "urn:") && i >= 0 {
+		return value[i+1:]
+	}
+	return value
+}
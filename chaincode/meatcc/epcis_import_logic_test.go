@@ -0,0 +1,246 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+// GTIN-14 gán cho sản phẩm thịt heo nguyên con để các lô nuôi được định danh bằng Digital Link.
+const carcassGTIN = "04006381333931"
+
+// epcisDocumentJSON đóng gói các sự kiện thành một tài liệu EPCIS 2.0.
+func epcisDocumentJSON(t *testing.T, events ...EPCISEvent) string {
+	documentJSON, err := json.Marshal(EPCISDocument{
+		Context:       epcisContextURL,
+		Type:          "EPCISDocument",
+		SchemaVersion: "2.0",
+		CreationDate:  "2024-03-01T07:00:00Z",
+		EPCISBody:     EPCISBody{EventList: events},
+	})
+	if err != nil {
+		t.Fatalf("failed to marshal EPCIS document: %v", err)
+	}
+	return string(documentJSON)
+}
+
+// commissioningEvent là sự kiện khởi tạo lô nuôi lot nặng quantity kg tại FARM-1.
+func commissioningEvent(eventID string, lot string, quantity float64) EPCISEvent {
+	farmDetails := testFarmDetails("FARM-1")
+	return EPCISEvent{
+		Type:         "ObjectEvent",
+		EventID:      eventID,
+		EventTime:    "2024-02-28T06:00:00+07:00",
+		Action:       "ADD",
+		BizStep:      "https://ref.gs1.org/cbv/BizStep-commissioning",
+		BizLocation:  epcisFacility("FARM-1"),
+		QuantityList: []EPCISQuantity{{EPCClass: digitalLinkURI(carcassGTIN, lot, ""), Quantity: quantity, UOM: "KGM"}},
+		FarmDetails:  &farmDetails,
+	}
+}
+
+// shipmentEvent là sự kiện vận chuyển (shipping hoặc receiving) của lô vận chuyển tại cơ sở facilityID.
+func shipmentEvent(bizStep string, shipmentID string, facilityID string, quantities ...EPCISQuantity) EPCISEvent {
+	return EPCISEvent{
+		Type:               "ObjectEvent",
+		EventTime:          "2024-03-01T09:00:00Z",
+		Action:             "OBSERVE",
+		BizStep:            "urn:epcglobal:cbv:bizstep:" + bizStep,
+		BizLocation:        epcisFacility(facilityID),
+		BizTransactionList: []EPCISBizTransaction{{Type: "bol", BizTransaction: epcisURN("shipment", shipmentID)}},
+		QuantityList:       quantities,
+	}
+}
+
+// withImportedBatch gán GTIN cho sản phẩm thịt heo nguyên con và nhập lô nuôi LOT-A (900 kg) tại FARM-1.
+func withImportedBatch(c *testChannel) {
+	c.withCatalog()
+	c.mustSubmit(superadmin, "SetProductGTIN", skuPorkCarcass, carcassGTIN)
+	c.mustSubmit(farmAdmin, "ImportEPCIS", epcisDocumentJSON(c.t, commissioningEvent("urn:uuid:farm-1", "LOT-A", 900)))
+}
+
+func TestImportEPCISCommissioning(t *testing.T) {
+	c := newTestChannel(t)
+	c.withCatalog()
+	c.mustSubmit(superadmin, "SetProductGTIN", skuPorkCarcass, carcassGTIN)
+
+	storage := EPCISEvent{Type: "ObjectEvent", EventTime: "2024-02-28T07:00:00Z", Action: "OBSERVE", BizStep: "storing"}
+	document := epcisDocumentJSON(t, commissioningEvent("urn:uuid:farm-1", "LOT-A", 900), storage)
+	result := c.mustSubmit(farmAdmin, "ImportEPCIS", document).(*EPCISImportResult)
+	if len(result.Applied) != 1 || result.Applied[0].Transaction != "CreateFarmingBatch" || result.Applied[0].AssetIDs[0] != "LOT-A" {
+		t.Fatalf("unexpected applied events %+v", result.Applied)
+	}
+	if len(result.Skipped) != 1 || result.Skipped[0].Reason != "ObjectEvent with bizStep 'storing' is not imported" {
+		t.Fatalf("unexpected skipped events %+v", result.Skipped)
+	}
+	c.expectStatus("LOT-A", AssetStatusAtFarm)
+	c.expectQuantity("LOT-A", 900)
+	asset := c.asset("LOT-A")
+	if asset.GTIN != carcassGTIN || asset.LotNumber != "LOT-A" {
+		t.Fatalf("unexpected imported asset %+v", asset)
+	}
+	if details, _ := asset.History[0].Details.(map[string]interface{}); details["facilityName"] != "Green Valley Farm" {
+		t.Fatalf("farm details were not imported: %v", asset.History[0].Details)
+	}
+
+	// Nhập lại cùng tài liệu không tạo lô mới
+	result = c.mustSubmit(farmAdmin, "ImportEPCIS", document).(*EPCISImportResult)
+	if len(result.Applied) != 0 || len(result.Skipped) != 2 || result.Skipped[0].Reason != "already imported in transaction tx00005" {
+		t.Fatalf("expected re-import to be skipped, got %+v", result)
+	}
+}
+
+func TestImportEPCISSupplyChain(t *testing.T) {
+	c := newTestChannel(t)
+	withImportedBatch(c)
+	c.mustSubmit(superadmin, "SetProductGTIN", skuPorkLoin, "4006381333948")
+
+	lot := EPCISQuantity{EPCClass: digitalLinkURI(carcassGTIN, "LOT-A", ""), Quantity: 900, UOM: "KGM"}
+	c.mustSubmit(farmAdmin, "CreateShipment", "SHIP-1", "REFRIGERATED", "driver-1", "Nguyen Van A", "51C-123.45",
+		[]StopInJourney{testStop("FARM-1", "PICKUP", testItem("LOT-A", "kg", 900)), testStop("PROC-1", "DELIVERY")})
+	c.mustSubmit(driver, "AddPickupProof", "SHIP-1", "FARM-1", testProof("FARM-1"))
+
+	result := c.mustSubmit(farmWorker, "ImportEPCIS", epcisDocumentJSON(t, shipmentEvent("shipping", "SHIP-1", "FARM-1", lot))).(*EPCISImportResult)
+	if result.Applied[0].Transaction != "ConfirmPickup" {
+		t.Fatalf("unexpected applied events %+v", result.Applied)
+	}
+	c.mustSubmit(driver, "StartShipment", "SHIP-1", []string{"SEAL-SHIP-1"})
+	c.mustSubmit(driver, "AddDeliveryProof", "SHIP-1", "PROC-1", testProof("PROC-1"))
+
+	short := shipmentEvent("receiving", "SHIP-1", "PROC-1", EPCISQuantity{EPCClass: lot.EPCClass, Quantity: 850, UOM: "KGM"})
+	_, err := c.submit(processorAdmin, "ImportEPCIS", epcisDocumentJSON(t, short))
+	expectError(t, err, "event 0 (ConfirmShipmentDelivery): received 850.000000 of "+lot.EPCClass+" but shipment SHIP-1 delivers 900.000000 to facility PROC-1")
+
+	receiving := shipmentEvent("receiving", "SHIP-1", "PROC-1", lot)
+	receiving.SealIDs = []string{"SEAL-SHIP-1"}
+	result = c.mustSubmit(processorAdmin, "ImportEPCIS", epcisDocumentJSON(t, receiving)).(*EPCISImportResult)
+	if got := result.Applied[0].AssetIDs; len(got) != 1 || got[0] != "SHIP-1-PROC-1-0" {
+		t.Fatalf("unexpected received assets %v", got)
+	}
+	c.expectStatus("SHIP-1-PROC-1-0", AssetStatusAtProcessor)
+
+	// Lô nhận được giữ GTIN và số lô nên được tham chiếu bằng cùng Digital Link
+	transformation := EPCISEvent{
+		Type:               "TransformationEvent",
+		EventTime:          "2024-03-01T13:00:00Z",
+		BizStep:            "commissioning",
+		InputQuantityList:  []EPCISQuantity{lot},
+		OutputQuantityList: []EPCISQuantity{{EPCClass: digitalLinkURI("04006381333948", "LOIN-7", ""), Quantity: 95, UOM: "KGM"}},
+		ProcessingDetails:  &ProcessingDetails{ProcessorOrgName: "Song Than Foods", FacilityName: "Song Than Processing"},
+	}
+	result = c.mustSubmit(processorAdmin, "ImportEPCIS", epcisDocumentJSON(t, transformation)).(*EPCISImportResult)
+	if got := result.Applied[0].AssetIDs; len(got) != 2 || got[0] != "SHIP-1-PROC-1-0" || got[1] != "LOIN-7" {
+		t.Fatalf("unexpected transformation assets %v", got)
+	}
+	c.expectStatus("SHIP-1-PROC-1-0", AssetStatusProcessedAndSplit)
+	if loin := c.asset("LOIN-7"); loin.SKU != skuPorkLoin || loin.CurrentQuantity.Unit != "kg" || loin.CurrentQuantity.Value != 95 {
+		t.Fatalf("unexpected output asset %+v", loin)
+	}
+}
+
+func TestImportEPCISRejections(t *testing.T) {
+	validEvent := commissioningEvent("urn:uuid:farm-2", "LOT-B", 360)
+	badTime := validEvent
+	badTime.EventTime = "28/02/2024"
+	zeroQuantity := commissioningEvent("urn:uuid:farm-2", "LOT-B", 0)
+	otherFarm := validEvent
+	otherFarm.BizLocation = epcisFacility("FARM-2")
+	unknownGTIN := validEvent
+	unknownGTIN.QuantityList = []EPCISQuantity{{EPCClass: digitalLinkURI("09506000134369", "LOT-B", ""), Quantity: 360, UOM: "KGM"}}
+	serialized := validEvent
+	serialized.QuantityList = []EPCISQuantity{{EPCClass: digitalLinkURI(carcassGTIN, "LOT-B", "1"), Quantity: 360, UOM: "KGM"}}
+	processing := EPCISEvent{
+		Type:               "TransformationEvent",
+		EventTime:          "2024-03-01T13:00:00Z",
+		InputEPCList:       []string{"urn:meatcc:lot:LOT-A"},
+		OutputQuantityList: []EPCISQuantity{{EPCClass: digitalLinkURI(carcassGTIN, "LOT-C", ""), Quantity: 1, UOM: "KGM"}},
+	}
+	pickup := shipmentEvent("shipping", "SHIP-9", "FARM-1", EPCISQuantity{EPCClass: "urn:meatcc:lot:LOT-A", Quantity: 1, UOM: "KGM"})
+
+	cases := []struct {
+		name     string
+		identity *mockIdentity
+		document string
+		wantErr  string
+	}{
+		{name: "valid document", identity: farmAdmin, document: epcisDocumentJSON(t, validEvent)},
+		{name: "malformed JSON", identity: farmAdmin, document: "{", wantErr: "failed to unmarshal EPCIS document"},
+		{name: "EPCIS 1.2", identity: farmAdmin, document: `{"type":"EPCISDocument","schemaVersion":"1.2","epcisBody":{"eventList":[]}}`,
+			wantErr: "unsupported EPCIS schema version '1.2', expected 2.x"},
+		{name: "no events", identity: farmAdmin, document: epcisDocumentJSON(t), wantErr: "the EPCIS document has no events"},
+		{name: "invalid event time", identity: farmAdmin, document: epcisDocumentJSON(t, badTime), wantErr: "event 0: invalid eventTime '28/02/2024'"},
+		{name: "duplicate event ID", identity: farmAdmin, document: epcisDocumentJSON(t, validEvent, validEvent),
+			wantErr: "event 1: duplicate eventID urn:uuid:farm-2"},
+		{name: "zero quantity", identity: farmAdmin, document: epcisDocumentJSON(t, zeroQuantity),
+			wantErr: "quantity elements need an epcClass and a positive quantity"},
+		{name: "another farm's batch", identity: farmAdmin, document: epcisDocumentJSON(t, otherFarm),
+			wantErr: "batches commissioned at facility FARM-2 cannot be imported by facility FARM-1"},
+		{name: "unknown GTIN", identity: farmAdmin, document: epcisDocumentJSON(t, unknownGTIN),
+			wantErr: "no product is registered with GTIN 09506000134369"},
+		{name: "new lot with serial number", identity: farmAdmin, document: epcisDocumentJSON(t, serialized),
+			wantErr: "must have a lot number (AI 10) and no serial number"},
+		{name: "existing lot", identity: farmAdmin, document: epcisDocumentJSON(t, commissioningEvent("urn:uuid:farm-3", "LOT-A", 360)),
+			wantErr: "asset LOT-A already exists"},
+		{name: "processor cannot commission", identity: processorAdmin, document: epcisDocumentJSON(t, validEvent),
+			wantErr: "event 0 (CreateFarmingBatch): access to CreateFarmingBatch denied"},
+		{name: "farm cannot process", identity: farmAdmin, document: epcisDocumentJSON(t, processing),
+			wantErr: "event 0 (ProcessAndSplitBatch): ProcessAndSplitBatch is not allowed for asset LOT-A with status 'AT_FARM'"},
+		{name: "unknown shipment", identity: farmAdmin, document: epcisDocumentJSON(t, pickup), wantErr: "event 0 (ConfirmPickup)"},
+		{name: "asset changed twice", identity: farmAdmin,
+			document: epcisDocumentJSON(t, validEvent, commissioningEvent("urn:uuid:farm-3", "LOT-B", 360)),
+			wantErr:  "asset LOT-B was already changed by event 0 of this document"},
+		{name: "regulator denied", identity: regulator, document: epcisDocumentJSON(t, validEvent), wantErr: "access to ImportEPCIS denied"},
+	}
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			c := newTestChannel(t)
+			withImportedBatch(c)
+			_, err := c.submit(tc.identity, "ImportEPCIS", tc.document)
+			expectError(t, err, tc.wantErr)
+		})
+	}
+}
+
+func TestImportEPCISTransformationQuantities(t *testing.T) {
+	loinGTIN, trayGTIN := "04006381333948", "0"+skuPorkTray
+	transformation := func(input EPCISQuantity, outputs ...EPCISQuantity) string {
+		return epcisDocumentJSON(t, EPCISEvent{
+			Type:               "TransformationEvent",
+			EventTime:          "2024-03-01T13:00:00Z",
+			InputQuantityList:  []EPCISQuantity{input},
+			OutputQuantityList: outputs,
+		})
+	}
+	whole := EPCISQuantity{EPCClass: "urn:meatcc:lot:RCV-0", Quantity: 900, UOM: "KGM"}
+	half := EPCISQuantity{EPCClass: "urn:meatcc:lot:RCV-0", Quantity: 450, UOM: "KGM"}
+	loinGrams := EPCISQuantity{EPCClass: digitalLinkURI(loinGTIN, "LOIN-1", ""), Quantity: 95000, UOM: "GRM"}
+	loinPounds := EPCISQuantity{EPCClass: digitalLinkURI(loinGTIN, "LOIN-2", ""), Quantity: 100, UOM: "LBR"}
+	trays := func(count float64) EPCISQuantity {
+		return EPCISQuantity{EPCClass: digitalLinkURI(trayGTIN, "TRAY-1", ""), Quantity: count}
+	}
+
+	cases := []struct {
+		name     string
+		document string
+		wantErr  string
+	}{
+		{name: "outputs in other units within the input", document: transformation(whole, loinGrams, loinPounds, trays(1000))},
+		{name: "outputs in grams exceed the input", document: transformation(whole, EPCISQuantity{EPCClass: loinGrams.EPCClass, Quantity: 1e9, UOM: "GRM"}),
+			wantErr: "outputs (1000000.000000 kg) exceed the input quantity (900.000000 kg)"},
+		{name: "trays exceed the input by average weight", document: transformation(whole, trays(2000)),
+			wantErr: "outputs (1000.000000 kg) exceed the input quantity (900.000000 kg)"},
+		{name: "partial input", document: transformation(half, loinGrams),
+			wantErr: "input quantity 450.000000 must be the whole current quantity 900.000000 of asset RCV-0"},
+	}
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			c := newTestChannel(t)
+			withImportedBatch(c)
+			c.mustSubmit(superadmin, "SetProductGTIN", skuPorkLoin, loinGTIN)
+			c.ship("SHIP-1", farmAdmin, processorAdmin, "RCV", testItem("LOT-A", "kg", 900))
+			_, err := c.submit(processorAdmin, "ImportEPCIS", tc.document)
+			expectError(t, err, tc.wantErr)
+		})
+	}
+}
@@ -0,0 +1,401 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// Ngữ cảnh JSON-LD chuẩn của EPCIS 2.0.
+const epcisContextURL = "https://ref.gs1.org/standards/epcis/2.0.0/epcis-context.jsonld"
+
+// Tiền tố URI cho các định danh nội bộ của chaincode (cơ sở, lô hàng, đơn đặt hàng, lô không có GTIN).
+const epcisURNPrefix = "urn:meatcc:"
+
+// Mã đơn vị UN/CEFACT cho các đơn vị khối lượng; các đơn vị đếm (hộp, khay...) không có uom.
+var epcisUOMs = map[string]string{"kg": "KGM", "g": "GRM", "lb": "LBR"}
+
+// epcisStep là bước nghiệp vụ (bizStep) và trạng thái (disposition) theo từ vựng CBV.
+type epcisStep struct {
+	BizStep     string
+	Disposition string
+}
+
+// epcisObjectEventSteps ánh xạ các sự kiện của asset và của hồ sơ quản lý sang ObjectEvent (OBSERVE).
+// Các sự kiện khởi tạo, chế biến, chia đơn vị, vận chuyển và nhận hàng được xử lý riêng.
+var epcisObjectEventSteps = map[string]epcisStep{
+	"STORAGE_UPDATE":           {BizStep: "storing", Disposition: "in_progress"},
+	"SEAL_BROKEN":              {BizStep: "holding", Disposition: "non_sellable_other"},
+	"HOLD_RELEASED":            {BizStep: "inspecting", Disposition: "active"},
+	"SOLD":                     {BizStep: "retail_selling", Disposition: "retail_sold"},
+	"INSPECTION":               {BizStep: "inspecting"},
+	"SAMPLING":                 {BizStep: "sampling"},
+	"REGULATORY_HOLD_PLACED":   {BizStep: "holding", Disposition: "non_sellable_other"},
+	"REGULATORY_HOLD_RELEASED": {BizStep: "inspecting", Disposition: "active"},
+	"RECALLED":                 {BizStep: "holding", Disposition: "recalled"},
+}
+
+// ExportEPCIS xuất toàn bộ nguồn gốc của một asset (asset và các asset tổ tiên cùng các lô vận chuyển liên quan)
+// thành tài liệu EPCIS 2.0 JSON-LD: ObjectEvent cho khởi tạo, lưu kho, vận chuyển, nhận hàng và bán,
+// TransformationEvent cho ProcessAndSplitBatch/SplitBatchToUnits, AggregationEvent cho việc bốc/dỡ hàng lên xe.
+func (s *SmartContract) ExportEPCIS(ctx contractapi.TransactionContextInterface, assetID string) (string, error) {
+	lineage, err := s.readAssetLineage(ctx, assetID)
+	if err != nil {
+		return "", err
+	}
+	lineageByID := make(map[string]*MeatAsset)
+	for _, asset := range lineage {
+		lineageByID[asset.AssetID] = asset
+	}
+
+	var events []EPCISEvent
+	var shipmentIDs []string
+	shipments := make(map[string]*ShipmentAsset)
+	readShipment := func(shipmentID string) (*ShipmentAsset, error) {
+		if shipment, ok := shipments[shipmentID]; ok {
+			return shipment, nil
+		}
+		shipment, err := s.readShipmentAsset(ctx, shipmentID)
+		if err != nil {
+			return nil, err
+		}
+		shipments[shipmentID] = shipment
+		shipmentIDs = append(shipmentIDs, shipmentID)
+		return shipment, nil
+	}
+
+	for _, asset := range lineage {
+		facility := epcisFacility(asset.OwnerOrg)
+		for _, event := range asset.History {
+			details, _ := event.Details.(map[string]interface{})
+			switch event.Type {
+			case "FARMING":
+				farm := facility
+				if facilityID, _ := details["facilityID"].(string); facilityID != "" {
+					farm = epcisFacility(facilityID)
+				}
+				epcisEvent := newEPCISEvent("ObjectEvent", event, asset.AssetID)
+				epcisEvent.Action = "ADD"
+				epcisEvent.EPCList, epcisEvent.QuantityList = epcisObjects(asset, asset.OriginalQuantity)
+				epcisEvent.BizStep = "commissioning"
+				epcisEvent.Disposition = "active"
+				epcisEvent.ReadPoint, epcisEvent.BizLocation = farm, farm
+				events = append(events, epcisEvent)
+
+			case "PROCESSING", "SPLIT_INTO_UNITS":
+				inputQuantity := asset.CurrentQuantity
+				bizStep := "commissioning"
+				if event.Type == "SPLIT_INTO_UNITS" {
+					unitCount, _ := details["unitCount"].(float64)
+					inputQuantity = Quantity{Unit: asset.OriginalQuantity.Unit, Value: unitCount}
+					bizStep = "repackaging"
+				}
+				outputs, err := s.readTransformationOutputs(ctx, asset.AssetID, event.TxID)
+				if err != nil {
+					return "", err
+				}
+				epcisEvent := newEPCISEvent("TransformationEvent", event, asset.AssetID)
+				epcisEvent.InputEPCList, epcisEvent.InputQuantityList = epcisObjects(asset, inputQuantity)
+				for _, output := range outputs {
+					epcs, quantities := epcisObjects(output, output.OriginalQuantity)
+					epcisEvent.OutputEPCList = append(epcisEvent.OutputEPCList, epcs...)
+					epcisEvent.OutputQuantityList = append(epcisEvent.OutputQuantityList, quantities...)
+				}
+				epcisEvent.BizStep = bizStep
+				epcisEvent.Disposition = "active"
+				epcisEvent.ReadPoint, epcisEvent.BizLocation = facility, facility
+				events = append(events, epcisEvent)
+
+			case "PICKED_UP_FOR_SHIPMENT":
+				if shipmentID, _ := details["shipmentID"].(string); shipmentID != "" {
+					if _, err := readShipment(shipmentID); err != nil {
+						return "", err
+					}
+				}
+
+			case "SHIPPING_STARTED":
+				shipmentID, _ := details["shipmentID"].(string)
+				shipment, err := readShipment(shipmentID)
+				if err != nil {
+					return "", err
+				}
+				shippedQuantity := asset.OriginalQuantity
+				for _, item := range shipment.Manifest {
+					if item.AssetID == asset.AssetID {
+						shippedQuantity = item.LoadedQuantity
+					}
+				}
+				epcisEvent := newEPCISEvent("ObjectEvent", event, asset.AssetID)
+				epcisEvent.Action = "OBSERVE"
+				epcisEvent.EPCList, epcisEvent.QuantityList = epcisObjects(asset, shippedQuantity)
+				epcisEvent.BizStep = "shipping"
+				epcisEvent.Disposition = "in_transit"
+				epcisEvent.ReadPoint = facility
+				epcisEvent.BizTransactionList = epcisShipmentTransactions(shipment, asset.AssetID)
+				epcisEvent.SourceList = []EPCISSource{{Type: "owning_party", Source: facility.ID}}
+				for _, stop := range shipment.Stops {
+					if stop.Action == "DELIVERY" && epcisStopHasItem(stop, asset.AssetID) {
+						epcisEvent.DestinationList = append(epcisEvent.DestinationList, EPCISDestination{Type: "owning_party", Destination: epcisURN("facility", stop.FacilityID)})
+					}
+				}
+				events = append(events, epcisEvent)
+
+			case "RECEIVING":
+				shipmentID, _ := details["shipmentID"].(string)
+				shipment, err := readShipment(shipmentID)
+				if err != nil {
+					return "", err
+				}
+				epcisEvent := newEPCISEvent("ObjectEvent", event, asset.AssetID)
+				epcisEvent.Action = "OBSERVE"
+				epcisEvent.EPCList, epcisEvent.QuantityList = epcisObjects(asset, asset.OriginalQuantity)
+				epcisEvent.BizStep = "receiving"
+				epcisEvent.Disposition = "in_progress"
+				epcisEvent.ReadPoint, epcisEvent.BizLocation = facility, facility
+				epcisEvent.BizTransactionList = []EPCISBizTransaction{{Type: "bol", BizTransaction: epcisURN("shipment", shipment.ShipmentID)}}
+				if poID, _ := details["poID"].(string); poID != "" {
+					epcisEvent.BizTransactionList = append(epcisEvent.BizTransactionList, EPCISBizTransaction{Type: "po", BizTransaction: epcisURN("po", poID)})
+				}
+				for _, parentID := range asset.ParentAssetIDs {
+					if parent, ok := lineageByID[parentID]; ok {
+						epcisEvent.SourceList = append(epcisEvent.SourceList, EPCISSource{Type: "owning_party", Source: epcisURN("facility", parent.OwnerOrg)})
+					}
+				}
+				epcisEvent.DestinationList = []EPCISDestination{{Type: "owning_party", Destination: facility.ID}}
+				events = append(events, epcisEvent)
+
+			default:
+				if step, ok := epcisObjectEventSteps[event.Type]; ok {
+					events = append(events, newEPCISObservation(event, asset, facility, step))
+				}
+			}
+		}
+
+		status, err := readRegulatoryStatus(ctx, asset.AssetID)
+		if err != nil {
+			return "", err
+		}
+		for _, event := range status.Events {
+			if step, ok := epcisObjectEventSteps[event.Type]; ok {
+				events = append(events, newEPCISObservation(event, asset, facility, step))
+			}
+		}
+	}
+
+	// Bốc hàng lên xe tại điểm lấy hàng và dỡ hàng tại điểm giao: chỉ gồm các asset thuộc nguồn gốc được xuất
+	for _, shipmentID := range shipmentIDs {
+		events = append(events, epcisShipmentAggregations(shipments[shipmentID], lineageByID)...)
+	}
+
+	sort.SliceStable(events, func(i, j int) bool {
+		return events[i].EventTime < events[j].EventTime
+	})
+	if events == nil {
+		events = []EPCISEvent{}
+	}
+
+	document := EPCISDocument{
+		Context:       []interface{}{epcisContextURL, map[string]string{"meatcc": epcisURNPrefix}},
+		Type:          "EPCISDocument",
+		SchemaVersion: "2.0",
+		CreationDate:  s.getTxTimestamp(ctx),
+		EPCISBody:     EPCISBody{EventList: events},
+	}
+	documentJSON, err := json.Marshal(document)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal EPCIS document: %v", err)
+	}
+	return string(documentJSON), nil
+}
+
+// --- Các hàm hỗ trợ cho EPCIS ---
+
+// readAssetLineage đọc asset và toàn bộ các asset tổ tiên (theo ParentAssetIDs).
+func (s *SmartContract) readAssetLineage(ctx contractapi.TransactionContextInterface, assetID string) ([]*MeatAsset, error) {
+	var lineage []*MeatAsset
+	queue := []string{assetID}
+	processedIDs := map[string]bool{assetID: true}
+	for len(queue) > 0 {
+		currentID := queue[0]
+		queue = queue[1:]
+		asset, err := s.readAsset(ctx, currentID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read asset %s: %v", currentID, err)
+		}
+		lineage = append(lineage, asset)
+		for _, parentID := range asset.ParentAssetIDs {
+			if !processedIDs[parentID] {
+				processedIDs[parentID] = true
+				queue = append(queue, parentID)
+			}
+		}
+	}
+	return lineage, nil
+}
+
+// readTransformationOutputs đọc các asset con được tạo ra trong cùng giao dịch chế biến/chia đơn vị của asset cha.
+func (s *SmartContract) readTransformationOutputs(ctx contractapi.TransactionContextInterface, parentAssetID string, txID string) ([]*MeatAsset, error) {
+	childIDs, err := queryChildAssetIDs(ctx, parentAssetID)
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(childIDs)
+	var outputs []*MeatAsset
+	for _, childID := range childIDs {
+		child, err := s.readAsset(ctx, childID)
+		if err != nil {
+			return nil, err
+		}
+		if len(child.History) > 0 && child.History[0].TxID == txID {
+			outputs = append(outputs, child)
+		}
+	}
+	return outputs, nil
+}
+
+// newEPCISEvent tạo sự kiện EPCIS với thời gian, mã sự kiện và tham chiếu tới sự kiện gốc trong chaincode.
+func newEPCISEvent(eventType string, event Event, objectID string) EPCISEvent {
+	eventTime, offset := epcisTime(event.Timestamp)
+	return EPCISEvent{
+		Type:                eventType,
+		EventID:             fmt.Sprintf("%sevent:%s:%s:%s", epcisURNPrefix, event.TxID, event.Type, objectID),
+		EventTime:           eventTime,
+		EventTimeZoneOffset: offset,
+		ChaincodeEventType:  event.Type,
+		ChaincodeTxID:       event.TxID,
+	}
+}
+
+// newEPCISObservation tạo ObjectEvent (OBSERVE) cho một sự kiện của asset tại cơ sở đang sở hữu asset.
+func newEPCISObservation(event Event, asset *MeatAsset, facility *EPCISLocation, step epcisStep) EPCISEvent {
+	epcisEvent := newEPCISEvent("ObjectEvent", event, asset.AssetID)
+	epcisEvent.Action = "OBSERVE"
+	epcisEvent.EPCList, epcisEvent.QuantityList = epcisObjects(asset, asset.CurrentQuantity)
+	epcisEvent.BizStep = step.BizStep
+	epcisEvent.Disposition = step.Disposition
+	epcisEvent.ReadPoint, epcisEvent.BizLocation = facility, facility
+	if event.Type == "STORAGE_UPDATE" {
+		details, _ := event.Details.(map[string]interface{})
+		if celsius, ok := parseCelsius(details["temperature"]); ok {
+			epcisEvent.SensorElementList = []EPCISSensorElement{{
+				SensorReport: []EPCISSensorReport{{Type: "Temperature", Value: celsius, UOM: "CEL"}},
+			}}
+		}
+	}
+	return epcisEvent
+}
+
+// epcisShipmentAggregations tạo AggregationEvent ADD khi bốc hàng tại điểm lấy hàng và DELETE khi dỡ hàng tại điểm giao.
+func epcisShipmentAggregations(shipment *ShipmentAsset, lineageByID map[string]*MeatAsset) []EPCISEvent {
+	parentID := epcisURN("shipment", shipment.ShipmentID)
+	var events []EPCISEvent
+	for _, stop := range shipment.Stops {
+		if stop.Status != "COMPLETED" {
+			continue
+		}
+		action, bizStep, timelineType := "ADD", "loading", "pickup_confirmed"
+		if stop.Action == "DELIVERY" {
+			action, bizStep, timelineType = "DELETE", "unloading", "arrival"
+		}
+
+		var children []EPCISQuantity
+		var childEPCs []string
+		for _, item := range stop.Items {
+			asset, ok := lineageByID[item.AssetID]
+			if !ok {
+				continue
+			}
+			epcs, quantities := epcisObjects(asset, item.Quantity)
+			childEPCs = append(childEPCs, epcs...)
+			children = append(children, quantities...)
+		}
+		if len(children) == 0 && len(childEPCs) == 0 {
+			continue
+		}
+
+		timestamp := stop.ActualTime
+		for _, entry := range shipment.Timeline {
+			if entry.Type == timelineType && entry.FacilityID == stop.FacilityID {
+				timestamp = entry.Timestamp
+			}
+		}
+		eventTime, offset := epcisTime(timestamp)
+		facility := epcisFacility(stop.FacilityID)
+		events = append(events, EPCISEvent{
+			Type:                "AggregationEvent",
+			EventID:             fmt.Sprintf("%sevent:%s:%s:%s", epcisURNPrefix, shipment.ShipmentID, stop.Action, stop.FacilityID),
+			EventTime:           eventTime,
+			EventTimeZoneOffset: offset,
+			ParentID:            parentID,
+			ChildEPCs:           childEPCs,
+			ChildQuantityList:   children,
+			Action:              action,
+			BizStep:             bizStep,
+			Disposition:         "in_progress",
+			ReadPoint:           facility,
+			BizLocation:         facility,
+			BizTransactionList:  []EPCISBizTransaction{{Type: "bol", BizTransaction: parentID}},
+		})
+	}
+	return events
+}
+
+// epcisShipmentTransactions trả về vận đơn (lô vận chuyển) và các đơn đặt hàng mà asset được giao theo.
+func epcisShipmentTransactions(shipment *ShipmentAsset, assetID string) []EPCISBizTransaction {
+	transactions := []EPCISBizTransaction{{Type: "bol", BizTransaction: epcisURN("shipment", shipment.ShipmentID)}}
+	var poIDs []string
+	for _, stop := range shipment.Stops {
+		for _, item := range stop.Items {
+			if item.AssetID == assetID && item.POID != "" && !containsString(poIDs, item.POID) {
+				poIDs = append(poIDs, item.POID)
+				transactions = append(transactions, EPCISBizTransaction{Type: "po", BizTransaction: epcisURN("po", item.POID)})
+			}
+		}
+	}
+	return transactions
+}
+
+// epcisStopHasItem kiểm tra điểm dừng có giao/lấy asset không.
+func epcisStopHasItem(stop StopInJourney, assetID string) bool {
+	for _, item := range stop.Items {
+		if item.AssetID == assetID {
+			return true
+		}
+	}
+	return false
+}
+
+// epcisObjects trả về định danh EPCIS của asset: đơn vị có số sê-ri GS1 được liệt kê theo từng cá thể (epcList),
+// các lô còn lại được biểu diễn bằng lớp đối tượng (GTIN + số lô, hoặc mã lô nội bộ) kèm số lượng.
+func epcisObjects(asset *MeatAsset, quantity Quantity) ([]string, []EPCISQuantity) {
+	if asset.GTIN != "" && asset.SerialNumber != "" {
+		return []string{digitalLinkURI(asset.GTIN, asset.LotNumber, asset.SerialNumber)}, nil
+	}
+	epcClass := epcisURN("lot", asset.AssetID)
+	if asset.GTIN != "" {
+		epcClass = digitalLinkURI(asset.GTIN, asset.LotNumber, "")
+	}
+	return nil, []EPCISQuantity{{EPCClass: epcClass, Quantity: quantity.Value, UOM: epcisUOMs[quantity.Unit]}}
+}
+
+// epcisURN tạo định danh nội bộ dạng urn:meatcc:<loại>:<mã>.
+func epcisURN(kind string, id string) string {
+	return fmt.Sprintf("%s%s:%s", epcisURNPrefix, kind, id)
+}
+
+// epcisFacility tạo địa điểm EPCIS (readPoint/bizLocation) cho một cơ sở.
+func epcisFacility(facilityID string) *EPCISLocation {
+	return &EPCISLocation{ID: epcisURN("facility", facilityID)}
+}
+
+// epcisTime trả về thời gian sự kiện và độ lệch múi giờ (±hh:mm) theo yêu cầu của EPCIS.
+func epcisTime(timestamp string) (string, string) {
+	parsed, err := time.Parse(time.RFC3339, timestamp)
+	if err != nil {
+		return timestamp, "+00:00"
+	}
+	return parsed.Format(time.RFC3339), parsed.Format("-07:00")
+}
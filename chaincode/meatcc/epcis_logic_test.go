@@ -0,0 +1,120 @@
+package main
+
+import (
+	"encoding/json"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// exportEPCIS gọi ExportEPCIS và giải mã tài liệu trả về.
+func (c *testChannel) exportEPCIS(assetID string) *EPCISDocument {
+	c.t.Helper()
+	documentJSON := c.mustEvaluate(regulator, "ExportEPCIS", assetID).(string)
+	var document EPCISDocument
+	if err := json.Unmarshal([]byte(documentJSON), &document); err != nil {
+		c.t.Fatalf("ExportEPCIS returned invalid JSON: %v", err)
+	}
+	return &document
+}
+
+// epcisEventsByStep nhóm các sự kiện EPCIS theo "loại/bizStep".
+func epcisEventsByStep(document *EPCISDocument) map[string][]EPCISEvent {
+	events := make(map[string][]EPCISEvent)
+	for _, event := range document.EPCISBody.EventList {
+		key := event.Type + "/" + event.BizStep
+		events[key] = append(events[key], event)
+	}
+	return events
+}
+
+func TestExportEPCIS(t *testing.T) {
+	c := newTestChannel(t)
+	soldUnit(c, "2°C")
+	c.mustSubmit(regulator, "RecordRegulatoryEvent", "UNIT-1", "INSPECTION", `{"result":"PASS"}`)
+
+	document := c.exportEPCIS("UNIT-1")
+	if document.Type != "EPCISDocument" || document.SchemaVersion != "2.0" || document.CreationDate == "" {
+		t.Fatalf("unexpected document header %+v", document)
+	}
+	events := document.EPCISBody.EventList
+	if !sort.SliceIsSorted(events, func(i, j int) bool { return events[i].EventTime < events[j].EventTime }) {
+		t.Fatal("events are not ordered by eventTime")
+	}
+
+	byStep := epcisEventsByStep(document)
+	want := map[string]int{
+		"ObjectEvent/commissioning":         1, // FARM-BATCH-1
+		"TransformationEvent/commissioning": 1, // PROC-BATCH-0 -> TRAY-1
+		"TransformationEvent/repackaging":   1, // RETAIL-BATCH-0 -> UNIT-1, UNIT-2
+		"ObjectEvent/shipping":              2,
+		"ObjectEvent/receiving":             2,
+		"AggregationEvent/loading":          2,
+		"AggregationEvent/unloading":        2,
+		"ObjectEvent/storing":               1,
+		"ObjectEvent/retail_selling":        1,
+		"ObjectEvent/inspecting":            1,
+	}
+	for key, count := range want {
+		if len(byStep[key]) != count {
+			t.Errorf("expected %d %s events, got %d", count, key, len(byStep[key]))
+		}
+	}
+	if len(events) != 14 {
+		t.Errorf("expected 14 events, got %d", len(events))
+	}
+
+	farm := byStep["ObjectEvent/commissioning"][0]
+	if farm.Action != "ADD" || farm.BizLocation.ID != "urn:meatcc:facility:FARM-1" ||
+		len(farm.QuantityList) != 1 || farm.QuantityList[0].EPCClass != "urn:meatcc:lot:FARM-BATCH-1" || farm.QuantityList[0].Quantity != 10 {
+		t.Fatalf("unexpected commissioning event %+v", farm)
+	}
+	processing := byStep["TransformationEvent/commissioning"][0]
+	if processing.InputQuantityList[0].EPCClass != "urn:meatcc:lot:PROC-BATCH-0" ||
+		processing.OutputQuantityList[0].EPCClass != "https://id.gs1.org/01/09506000134352/10/TRAY-1" || processing.OutputQuantityList[0].Quantity != 24 {
+		t.Fatalf("unexpected transformation event %+v", processing)
+	}
+	repackaging := byStep["TransformationEvent/repackaging"][0]
+	if strings.Join(repackaging.OutputEPCList, " ") != "https://id.gs1.org/01/09506000134352/10/TRAY-1/21/UNIT-1 https://id.gs1.org/01/09506000134352/10/TRAY-1/21/UNIT-2" ||
+		repackaging.InputQuantityList[0].Quantity != 2 {
+		t.Fatalf("unexpected repackaging event %+v", repackaging)
+	}
+	storing := byStep["ObjectEvent/storing"][0]
+	if len(storing.SensorElementList) != 1 || storing.SensorElementList[0].SensorReport[0].Value != 2 || storing.SensorElementList[0].SensorReport[0].UOM != "CEL" {
+		t.Fatalf("unexpected storage event %+v", storing)
+	}
+	sold := byStep["ObjectEvent/retail_selling"][0]
+	if sold.Disposition != "retail_sold" || len(sold.EPCList) != 1 || sold.EPCList[0] != "https://id.gs1.org/01/09506000134352/10/TRAY-1/21/UNIT-1" {
+		t.Fatalf("unexpected sale event %+v", sold)
+	}
+	for _, shipping := range byStep["ObjectEvent/shipping"] {
+		if len(shipping.DestinationList) != 1 || len(shipping.SourceList) != 1 || shipping.BizTransactionList[0].Type != "bol" {
+			t.Fatalf("unexpected shipping event %+v", shipping)
+		}
+	}
+	for _, loading := range append(byStep["AggregationEvent/loading"], byStep["AggregationEvent/unloading"]...) {
+		if !strings.HasPrefix(loading.ParentID, "urn:meatcc:shipment:SHIP-") || len(loading.ChildQuantityList) != 1 {
+			t.Fatalf("unexpected aggregation event %+v", loading)
+		}
+	}
+
+	_, err := c.evaluate(regulator, "ExportEPCIS", "UNIT-9")
+	expectError(t, err, "failed to read asset UNIT-9")
+}
+
+func TestExportEPCISOrderReferences(t *testing.T) {
+	c := newTestChannel(t)
+	withAcceptedOrder(c)
+	c.deliverOrder("SHIP-1", 5)
+
+	byStep := epcisEventsByStep(c.exportEPCIS("RCV-SHIP-1-0"))
+	for _, key := range []string{"ObjectEvent/shipping", "ObjectEvent/receiving"} {
+		event := byStep[key][0]
+		if len(event.BizTransactionList) != 2 || event.BizTransactionList[1].BizTransaction != "urn:meatcc:po:PO-1" {
+			t.Fatalf("%s event does not reference PO-1: %+v", key, event.BizTransactionList)
+		}
+	}
+	if shipped := byStep["ObjectEvent/shipping"][0]; shipped.QuantityList[0].Quantity != 5 {
+		t.Fatalf("expected 5 head shipped, got %v", shipped.QuantityList)
+	}
+}
@@ -0,0 +1,419 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// Các danh tính dùng chung cho test. Thuộc tính role, facilityID, facilityType được cấp như trong registerEnroll.sh.
+var (
+	superadmin     = newTestIdentity(MeatSupplyOrgMSP, "superadmin", "superadmin", "", "")
+	farmAdmin      = newTestIdentity(MeatSupplyOrgMSP, "farm-admin", "admin", "FARM-1", FacilityTypeFarm)
+	farmWorker     = newTestIdentity(MeatSupplyOrgMSP, "farm-worker", "worker", "FARM-1", FacilityTypeFarm)
+	otherFarmAdmin = newTestIdentity(MeatSupplyOrgMSP, "farm2-admin", "admin", "FARM-2", FacilityTypeFarm)
+	processorAdmin = newTestIdentity(MeatSupplyOrgMSP, "processor-admin", "admin", "PROC-1", FacilityTypeProcessor)
+	warehouseAdmin = newTestIdentity(MeatSupplyOrgMSP, "warehouse-admin", "admin", "WH-1", FacilityTypeWarehouse)
+	retailerAdmin  = newTestIdentity(MeatSupplyOrgMSP, "retailer-admin", "admin", "RETAIL-1", FacilityTypeRetailer)
+	retailerWorker = newTestIdentity(MeatSupplyOrgMSP, "retailer-worker", "worker", "RETAIL-1", FacilityTypeRetailer)
+	driver         = newTestIdentity(MeatSupplyOrgMSP, "driver-1", "driver", "", "")
+	otherDriver    = newTestIdentity(MeatSupplyOrgMSP, "driver-2", "driver", "", "")
+	regulator      = newTestIdentity(RegulatorOrgMSP, "inspector-1", RegulatorRole, "", "")
+)
+
+// Địa chỉ của các cơ sở trong test.
+var testFacilityAddresses = map[string]Address{
+	"FARM-1":   {FullText: "Km 5, Tan Uyen, Binh Duong, Vietnam", Latitude: 11.0500, Longitude: 106.7900},
+	"FARM-2":   {FullText: "Xuan Loc, Dong Nai, Vietnam", Latitude: 10.9300, Longitude: 107.4100},
+	"PROC-1":   {FullText: "Lot B2, Song Than IZ, Di An, Binh Duong, Vietnam", Latitude: 10.9000, Longitude: 106.7600},
+	"WH-1":     {FullText: "Cat Lai, Thu Duc, Ho Chi Minh City, Vietnam", Latitude: 10.7600, Longitude: 106.7900},
+	"RETAIL-1": {FullText: "12 Nguyen Hue, District 1, Ho Chi Minh City, Vietnam", Latitude: 10.7740, Longitude: 106.7030},
+}
+
+// Các sản phẩm trong danh mục test. SKU của khay thịt là một GTIN-13 hợp lệ nên được cấp mã GS1.
+const (
+	skuPorkCarcass = "PORK-CARCASS"
+	skuPorkLoin    = "PORK-LOIN"
+	skuPorkTray    = "9506000134352"
+)
+
+func newTestIdentity(mspID string, commonName string, role string, facilityID string, facilityType string) *mockIdentity {
+	attributes := map[string]string{"role": role}
+	if facilityID != "" {
+		attributes["facilityID"] = facilityID
+	}
+	if facilityType != "" {
+		attributes["facilityType"] = facilityType
+	}
+	return &mockIdentity{mspID: mspID, commonName: commonName, attributes: attributes}
+}
+
+// invokedTransactions ghi lại các transaction đã được test gọi, để TestMain kiểm tra mọi hàm exported đều có test.
+var invokedTransactions = make(map[string]bool)
+
+func TestMain(m *testing.M) {
+	code := m.Run()
+	// Chỉ kiểm tra độ phủ khi chạy toàn bộ test (không lọc bằng -run)
+	if runFlag := flag.Lookup("test.run"); code == 0 && (runFlag == nil || runFlag.Value.String() == "") {
+		var missing []string
+		for _, name := range contractTransactions() {
+			if !invokedTransactions[name] {
+				missing = append(missing, name)
+			}
+		}
+		if len(missing) > 0 {
+			fmt.Printf("FAIL: transactions without tests: %s\n", strings.Join(missing, ", "))
+			code = 1
+		}
+	}
+	os.Exit(code)
+}
+
+// contractTransactions liệt kê các transaction của SmartContract (method exported nhận transaction context).
+func contractTransactions() []string {
+	contextType := reflect.TypeOf((*contractapi.TransactionContextInterface)(nil)).Elem()
+	contractType := reflect.TypeOf(&SmartContract{})
+	var names []string
+	for i := 0; i < contractType.NumMethod(); i++ {
+		method := contractType.Method(i)
+		if method.Type.NumIn() > 1 && method.Type.In(1) == contextType {
+			names = append(names, method.Name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// testChannel chạy các transaction của SmartContract trên một mockStub giống như peer: mỗi lần gọi là một transaction
+// mới (txID và thời điểm tăng dần), đi qua access policy (BeforeTransaction) rồi mới tới hàm nghiệp vụ,
+// và chỉ được commit khi thành công.
+type testChannel struct {
+	t        *testing.T
+	contract *SmartContract
+	stub     *mockStub
+	clock    time.Time
+	txCount  int
+}
+
+func newTestChannel(t *testing.T) *testChannel {
+	c := newUninitializedChannel(t)
+	// Access policy mặc định như sau khi superadmin gọi InitAccessPolicy lúc triển khai
+	key, err := c.stub.CreateCompositeKey("Config", []string{"accessPolicy"})
+	if err != nil {
+		t.Fatalf("failed to create composite key: %v", err)
+	}
+	c.seed(key, AccessPolicy{ObjectType: "AccessPolicy", Rules: defaultAccessRules})
+	return c
+}
+
+// newUninitializedChannel tạo kênh mới vừa triển khai chaincode, chưa có access policy trên ledger.
+func newUninitializedChannel(t *testing.T) *testChannel {
+	return &testChannel{
+		t:        t,
+		contract: &SmartContract{},
+		stub:     newMockStub(),
+		clock:    time.Date(2024, 3, 1, 8, 0, 0, 0, time.UTC),
+	}
+}
+
+// advance dời đồng hồ của kênh; mỗi transaction tự tăng thêm một phút.
+func (c *testChannel) advance(d time.Duration) {
+	c.clock = c.clock.Add(d)
+}
+
+// submit gọi một transaction và commit kết quả nếu thành công. Tham số không phải chuỗi của các hàm nhận chuỗi
+// (quantityJSON, stopsJSON...) được marshal sang JSON.
+func (c *testChannel) submit(identity *mockIdentity, function string, args ...interface{}) (interface{}, error) {
+	return c.invoke(identity, nil, true, function, args...)
+}
+
+// submitWithTransient giống submit nhưng gửi kèm transient map (dữ liệu riêng tư).
+func (c *testChannel) submitWithTransient(identity *mockIdentity, transient map[string][]byte, function string, args ...interface{}) (interface{}, error) {
+	return c.invoke(identity, transient, true, function, args...)
+}
+
+// evaluate gọi một transaction truy vấn; kết quả ghi (nếu có) không bao giờ được commit.
+func (c *testChannel) evaluate(identity *mockIdentity, function string, args ...interface{}) (interface{}, error) {
+	return c.invoke(identity, nil, false, function, args...)
+}
+
+// mustSubmit gọi submit và dừng test nếu transaction lỗi.
+func (c *testChannel) mustSubmit(identity *mockIdentity, function string, args ...interface{}) interface{} {
+	c.t.Helper()
+	result, err := c.submit(identity, function, args...)
+	if err != nil {
+		c.t.Fatalf("%s failed: %v", function, err)
+	}
+	return result
+}
+
+// mustSubmitWithTransient gọi submitWithTransient và dừng test nếu transaction lỗi.
+func (c *testChannel) mustSubmitWithTransient(identity *mockIdentity, transient map[string][]byte, function string, args ...interface{}) interface{} {
+	c.t.Helper()
+	result, err := c.submitWithTransient(identity, transient, function, args...)
+	if err != nil {
+		c.t.Fatalf("%s failed: %v", function, err)
+	}
+	return result
+}
+
+// mustEvaluate gọi evaluate và dừng test nếu truy vấn lỗi.
+func (c *testChannel) mustEvaluate(identity *mockIdentity, function string, args ...interface{}) interface{} {
+	c.t.Helper()
+	result, err := c.evaluate(identity, function, args...)
+	if err != nil {
+		c.t.Fatalf("%s failed: %v", function, err)
+	}
+	return result
+}
+
+func (c *testChannel) invoke(identity *mockIdentity, transient map[string][]byte, commit bool, function string, args ...interface{}) (interface{}, error) {
+	c.t.Helper()
+	method := reflect.ValueOf(c.contract).MethodByName(function)
+	if !method.IsValid() {
+		c.t.Fatalf("SmartContract has no transaction %s", function)
+	}
+	methodType := method.Type()
+	if methodType.NumIn() != len(args)+1 {
+		c.t.Fatalf("%s expects %d arguments, got %d", function, methodType.NumIn()-1, len(args))
+	}
+
+	c.txCount++
+	c.clock = c.clock.Add(time.Minute)
+	ctx := newMockContext(c.stub, identity)
+	in := []reflect.Value{reflect.ValueOf(ctx)}
+	var parameters []string
+	for i, arg := range args {
+		value, parameter, err := transactionArgument(methodType.In(i+1), arg)
+		if err != nil {
+			c.t.Fatalf("%s argument %d: %v", function, i+1, err)
+		}
+		in = append(in, value)
+		parameters = append(parameters, parameter)
+	}
+	invokedTransactions[function] = true
+
+	c.stub.startTransaction(fmt.Sprintf("tx%05d", c.txCount), c.clock, function, parameters, transient)
+	var result interface{}
+	err := enforceAccessPolicy(ctx)
+	if err == nil {
+		out := method.Call(in)
+		if last := out[len(out)-1]; !last.IsNil() {
+			err = last.Interface().(error)
+		}
+		if len(out) == 2 && err == nil {
+			result = out[0].Interface()
+		}
+	}
+	c.stub.endTransaction(commit && err == nil)
+	return result, err
+}
+
+// transactionArgument đổi tham số của test sang kiểu tham số của transaction.
+func transactionArgument(parameterType reflect.Type, arg interface{}) (reflect.Value, string, error) {
+	switch parameterType.Kind() {
+	case reflect.String:
+		if text, ok := arg.(string); ok {
+			return reflect.ValueOf(text), text, nil
+		}
+		argJSON, err := json.Marshal(arg)
+		if err != nil {
+			return reflect.Value{}, "", err
+		}
+		return reflect.ValueOf(string(argJSON)), string(argJSON), nil
+	case reflect.Int, reflect.Float64:
+		value := reflect.ValueOf(arg)
+		if !value.IsValid() || !value.Type().ConvertibleTo(parameterType) {
+			return reflect.Value{}, "", fmt.Errorf("cannot use %v as %s", arg, parameterType)
+		}
+		return value.Convert(parameterType), fmt.Sprint(arg), nil
+	}
+	return reflect.Value{}, "", fmt.Errorf("unsupported parameter type %s", parameterType)
+}
+
+// seed ghi trực tiếp một document vào world state (bỏ qua chaincode), dùng để dựng trạng thái khó đạt được bằng transaction.
+func (c *testChannel) seed(key string, value interface{}) {
+	c.t.Helper()
+	valueJSON, err := json.Marshal(value)
+	if err != nil {
+		c.t.Fatalf("failed to marshal %s: %v", key, err)
+	}
+	c.stub.startTransaction("seed", c.clock, "", nil, nil)
+	if err := c.stub.PutState(key, valueJSON); err != nil {
+		c.t.Fatalf("failed to seed %s: %v", key, err)
+	}
+	c.stub.endTransaction(true)
+}
+
+// asset đọc một asset đã commit.
+func (c *testChannel) asset(assetID string) *MeatAsset {
+	c.t.Helper()
+	return c.mustEvaluate(superadmin, "GetAsset", assetID).(*MeatAsset)
+}
+
+// shipment đọc một lô vận chuyển đã commit.
+func (c *testChannel) shipment(shipmentID string) *ShipmentAsset {
+	c.t.Helper()
+	return c.mustEvaluate(superadmin, "GetShipment", shipmentID).(*ShipmentAsset)
+}
+
+// exists kiểm tra một khóa đã được commit vào world state.
+func (c *testChannel) exists(key string) bool {
+	return c.stub.State[key] != nil
+}
+
+// --- Dựng dữ liệu dùng chung ---
+
+// withCatalog tạo các sản phẩm dùng chung cho test.
+func (c *testChannel) withCatalog() *testChannel {
+	c.t.Helper()
+	c.mustSubmit(superadmin, "CreateProduct", skuPorkCarcass, "Pork carcass", "Whole pig carcass", "kg", "PORK", "RAW_MATERIAL", Weight{Value: 90, Unit: "kg"})
+	c.mustSubmit(superadmin, "CreateProduct", skuPorkLoin, "Pork loin", "Boneless pork loin", "kg", "PORK", "FINISHED_GOOD", Weight{Value: 1, Unit: "kg"})
+	c.mustSubmit(superadmin, "CreateProduct", skuPorkTray, "Pork belly tray", "500g pork belly tray", "tray", "PORK", "FINISHED_GOOD", Weight{Value: 0.5, Unit: "kg"})
+	return c
+}
+
+// testFarmDetails trả về chi tiết trang trại của một cơ sở.
+func testFarmDetails(facilityID string) FarmDetails {
+	return FarmDetails{
+		FacilityID:          facilityID,
+		FacilityName:        "Green Valley Farm",
+		Address:             testFacilityAddresses[facilityID],
+		StartDate:           "2023-09-01",
+		ExpectedHarvestDate: "2024-03-01",
+		Certificates:        []Certificate{{Name: "VietGAP", Media: MediaPointer{URL: "https://example.com/vietgap.pdf", MimeType: "application/pdf"}}},
+	}
+}
+
+// createFarmBatch tạo một lô nuôi của trang trại thuộc danh tính farm.
+func (c *testChannel) createFarmBatch(farm *mockIdentity, assetID string, sku string, quantity Quantity) {
+	c.t.Helper()
+	c.mustSubmit(farm, "CreateFarmingBatch", assetID, "Pork carcass", sku, quantity, testFarmDetails(farm.attributes["facilityID"]), Weight{Value: 90, Unit: "kg"})
+}
+
+// testStop dựng một điểm dừng tại cơ sở với địa chỉ của cơ sở đó.
+func testStop(facilityID string, action string, items ...ItemInShipment) StopInJourney {
+	return StopInJourney{
+		FacilityID:      facilityID,
+		FacilityName:    facilityID,
+		FacilityAddress: testFacilityAddresses[facilityID],
+		Action:          action,
+		Items:           items,
+	}
+}
+
+// testItem dựng một dòng hàng của lô vận chuyển.
+func testItem(assetID string, unit string, value float64) ItemInShipment {
+	return ItemInShipment{AssetID: assetID, Quantity: Quantity{Unit: unit, Value: value}}
+}
+
+// testProof dựng bằng chứng của tài xế tại một cơ sở, với tọa độ đúng vị trí cơ sở.
+func testProof(facilityID string) map[string]interface{} {
+	address := testFacilityAddresses[facilityID]
+	return map[string]interface{}{
+		"facilityID": facilityID,
+		"photo":      "https://example.com/proof/" + facilityID + ".jpg",
+		"latitude":   address.Latitude,
+		"longitude":  address.Longitude,
+	}
+}
+
+// pickUpAndStart tạo lô vận chuyển từ một điểm lấy hàng tới một điểm giao, bốc hàng và khởi hành.
+func (c *testChannel) pickUpAndStart(shipmentID string, sender *mockIdentity, receiverFacilityID string, items ...ItemInShipment) {
+	c.t.Helper()
+	senderFacilityID := sender.attributes["facilityID"]
+	stops := []StopInJourney{testStop(senderFacilityID, "PICKUP", items...), testStop(receiverFacilityID, "DELIVERY")}
+	c.mustSubmit(sender, "CreateShipment", shipmentID, "REFRIGERATED", "driver-1", "Nguyen Van A", "51C-123.45", stops)
+	c.mustSubmit(driver, "AddPickupProof", shipmentID, senderFacilityID, testProof(senderFacilityID))
+	c.mustSubmit(sender, "ConfirmPickup", shipmentID, senderFacilityID, items)
+	c.mustSubmit(driver, "StartShipment", shipmentID, []string{"SEAL-" + shipmentID})
+}
+
+// ship chở hàng từ cơ sở của sender tới cơ sở của receiver; asset nhận được có mã receivedPrefix-0, receivedPrefix-1...
+func (c *testChannel) ship(shipmentID string, sender *mockIdentity, receiver *mockIdentity, receivedPrefix string, items ...ItemInShipment) {
+	c.t.Helper()
+	receiverFacilityID := receiver.attributes["facilityID"]
+	c.pickUpAndStart(shipmentID, sender, receiverFacilityID, items...)
+	c.mustSubmit(driver, "AddDeliveryProof", shipmentID, receiverFacilityID, testProof(receiverFacilityID))
+	c.mustSubmit(receiver, "ConfirmShipmentDelivery", shipmentID, receiverFacilityID, receivedPrefix, []string{"SEAL-" + shipmentID})
+}
+
+// --- Kiểm tra kết quả ---
+
+// transactionCase là một dòng của bảng test: gọi transaction với danh tính và tham số, mong đợi lỗi chứa wantErr
+// (rỗng = thành công).
+type transactionCase struct {
+	name     string
+	identity *mockIdentity
+	function string
+	args     []interface{}
+	wantErr  string
+}
+
+// runTransactionCases chạy từng dòng của bảng test trên một kênh mới do setup dựng.
+func runTransactionCases(t *testing.T, setup func(c *testChannel), cases []transactionCase) {
+	t.Helper()
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			c := newTestChannel(t)
+			setup(c)
+			_, err := c.submit(tc.identity, tc.function, tc.args...)
+			expectError(t, err, tc.wantErr)
+		})
+	}
+}
+
+// expectError kiểm tra err chứa wantErr, hoặc err = nil nếu wantErr rỗng.
+func expectError(t *testing.T, err error, wantErr string) {
+	t.Helper()
+	switch {
+	case wantErr == "" && err != nil:
+		t.Fatalf("unexpected error: %v", err)
+	case wantErr != "" && err == nil:
+		t.Fatalf("expected error containing %q, got success", wantErr)
+	case wantErr != "" && !strings.Contains(err.Error(), wantErr):
+		t.Fatalf("expected error containing %q, got %q", wantErr, err.Error())
+	}
+}
+
+// expectStatus kiểm tra trạng thái của một asset.
+func (c *testChannel) expectStatus(assetID string, status string) {
+	c.t.Helper()
+	if asset := c.asset(assetID); asset.Status != status {
+		c.t.Fatalf("asset %s has status %s, want %s", assetID, asset.Status, status)
+	}
+}
+
+// expectQuantity kiểm tra số lượng hiện có của một asset.
+func (c *testChannel) expectQuantity(assetID string, value float64) {
+	c.t.Helper()
+	if asset := c.asset(assetID); !approxEqual(asset.CurrentQuantity.Value, value) {
+		c.t.Fatalf("asset %s has current quantity %s, want %s", assetID, formatFloat(asset.CurrentQuantity.Value), formatFloat(value))
+	}
+}
+
+func approxEqual(a float64, b float64) bool {
+	return a-b <= quantityTolerance && b-a <= quantityTolerance
+}
+
+func formatFloat(value float64) string {
+	return strconv.FormatFloat(value, 'f', -1, 64)
+}
+
+// lastEvent trả về sự kiện cuối cùng trong lịch sử của asset.
+func lastEvent(asset *MeatAsset) Event {
+	if len(asset.History) == 0 {
+		return Event{}
+	}
+	return asset.History[len(asset.History)-1]
+}
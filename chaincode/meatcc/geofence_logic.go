@@ -0,0 +1,135 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// Giá trị mặc định khi kênh chưa có cấu hình geofence.
+const (
+	defaultGeofenceRadiusMeters = 500.0
+	defaultGeofenceMode         = "FLAG"
+)
+
+// SetGeofenceConfig cập nhật bán kính và chế độ xử lý bằng chứng nằm ngoài geofence.
+// Chỉ Super Admin mới có quyền gọi.
+func (s *SmartContract) SetGeofenceConfig(ctx contractapi.TransactionContextInterface, radiusMeters float64, mode string) error {
+	if radiusMeters <= 0 {
+		return fmt.Errorf("geofence radius must be positive")
+	}
+	if mode != "REJECT" && mode != "FLAG" {
+		return fmt.Errorf("invalid geofence mode '%s', expected REJECT or FLAG", mode)
+	}
+
+	config := GeofenceConfig{
+		ObjectType:   "GeofenceConfig",
+		RadiusMeters: radiusMeters,
+		Mode:         mode,
+	}
+	configJSON, err := json.Marshal(config)
+	if err != nil {
+		return err
+	}
+	key, err := ctx.GetStub().CreateCompositeKey("Config", []string{"geofence"})
+	if err != nil {
+		return fmt.Errorf("failed to create composite key: %v", err)
+	}
+	return ctx.GetStub().PutState(key, configJSON)
+}
+
+// GetGeofenceConfig trả về cấu hình geofence hiện hành (hoặc giá trị mặc định).
+func (s *SmartContract) GetGeofenceConfig(ctx contractapi.TransactionContextInterface) (*GeofenceConfig, error) {
+	key, err := ctx.GetStub().CreateCompositeKey("Config", []string{"geofence"})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create composite key: %v", err)
+	}
+	configJSON, err := ctx.GetStub().GetState(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read from world state: %v", err)
+	}
+	if configJSON == nil {
+		return &GeofenceConfig{
+			ObjectType:   "GeofenceConfig",
+			RadiusMeters: defaultGeofenceRadiusMeters,
+			Mode:         defaultGeofenceMode,
+		}, nil
+	}
+	var config GeofenceConfig
+	if err := json.Unmarshal(configJSON, &config); err != nil {
+		return nil, err
+	}
+	return &config, nil
+}
+
+// checkGeofence so sánh tọa độ "latitude"/"longitude" trong bằng chứng với địa chỉ của điểm dừng.
+// Ở chế độ REJECT, bằng chứng thiếu tọa độ, tại cơ sở chưa có tọa độ hoặc nằm ngoài bán kính sẽ bị từ chối;
+// ở chế độ FLAG, kết quả chỉ được ghi lại để đối soát.
+func (s *SmartContract) checkGeofence(ctx contractapi.TransactionContextInterface, stop *StopInJourney, proof map[string]interface{}) (*GeofenceCheck, error) {
+	config, err := s.GetGeofenceConfig(ctx)
+	if err != nil {
+		return nil, err
+	}
+	// Bán kính của điểm dừng do người tạo lô hàng khai báo nên chỉ được thu hẹp bán kính của kênh.
+	radius := config.RadiusMeters
+	if stop.GeofenceRadiusMeters > 0 && stop.GeofenceRadiusMeters < radius {
+		radius = stop.GeofenceRadiusMeters
+	}
+	check := &GeofenceCheck{RadiusMeters: radius}
+
+	latitude, latOK := proof["latitude"].(float64)
+	longitude, lonOK := proof["longitude"].(float64)
+	if !latOK || !lonOK {
+		if config.Mode == "REJECT" {
+			return nil, fmt.Errorf("proof for facility %s must include numeric latitude and longitude", stop.FacilityID)
+		}
+		check.Status = "NO_COORDINATES"
+		return check, nil
+	}
+	check.Latitude = latitude
+	check.Longitude = longitude
+
+	// Cơ sở chưa khai báo tọa độ thì không thể kiểm tra: bị từ chối ở chế độ REJECT, chỉ đánh dấu ở chế độ FLAG.
+	if stop.FacilityAddress.Latitude == 0 && stop.FacilityAddress.Longitude == 0 {
+		if config.Mode == "REJECT" {
+			return nil, fmt.Errorf("facility %s has no coordinates, the geofence cannot be checked", stop.FacilityID)
+		}
+		check.Status = "FACILITY_LOCATION_UNKNOWN"
+		return check, nil
+	}
+
+	check.DistanceMeters = haversineKm(latitude, longitude, stop.FacilityAddress.Latitude, stop.FacilityAddress.Longitude) * 1000
+	if check.DistanceMeters <= radius {
+		check.Status = "INSIDE"
+		return check, nil
+	}
+	if config.Mode == "REJECT" {
+		return nil, fmt.Errorf("proof location is %.0fm from facility %s, outside the %.0fm geofence", check.DistanceMeters, stop.FacilityID, radius)
+	}
+	check.Status = "OUTSIDE"
+	return check, nil
+}
+
+// requireStopProof kiểm tra tài xế đã thêm bằng chứng proofType ("pickup" hoặc "delivery") tại cơ sở facilityID.
+// Ở chế độ REJECT chỉ bằng chứng có kết quả geofence INSIDE mới được chấp nhận, kể cả bằng chứng ghi từ trước khi đổi chế độ.
+func (s *SmartContract) requireStopProof(ctx contractapi.TransactionContextInterface, shipment *ShipmentAsset, proofType string, facilityID string) error {
+	config, err := s.GetGeofenceConfig(ctx)
+	if err != nil {
+		return err
+	}
+	proofExists := false
+	for _, event := range shipment.Timeline {
+		if event.Type != proofType+"_proof_added" || event.FacilityID != facilityID {
+			continue
+		}
+		if config.Mode != "REJECT" || (event.Geofence != nil && event.Geofence.Status == "INSIDE") {
+			return nil
+		}
+		proofExists = true
+	}
+	if proofExists {
+		return fmt.Errorf("no %s proof for facility %s lies inside the geofence", proofType, facilityID)
+	}
+	return fmt.Errorf("%s proof for facility %s has not been added by the driver yet", proofType, facilityID)
+}
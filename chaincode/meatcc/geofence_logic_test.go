@@ -0,0 +1,127 @@
+package main
+
+import "testing"
+
+func TestSetGeofenceConfig(t *testing.T) {
+	runTransactionCases(t, func(c *testChannel) {}, []transactionCase{
+		{name: "superadmin sets config", identity: superadmin, function: "SetGeofenceConfig", args: []interface{}{250.0, "REJECT"}},
+		{name: "radius must be positive", identity: superadmin, function: "SetGeofenceConfig", args: []interface{}{0, "FLAG"}, wantErr: "geofence radius must be positive"},
+		{name: "invalid mode", identity: superadmin, function: "SetGeofenceConfig", args: []interface{}{250, "WARN"}, wantErr: "invalid geofence mode 'WARN'"},
+		{name: "admin denied", identity: farmAdmin, function: "SetGeofenceConfig", args: []interface{}{250, "FLAG"}, wantErr: "access to SetGeofenceConfig denied"},
+	})
+
+	c := newTestChannel(t)
+	if config := c.mustEvaluate(driver, "GetGeofenceConfig").(*GeofenceConfig); config.RadiusMeters != defaultGeofenceRadiusMeters || config.Mode != defaultGeofenceMode {
+		t.Fatalf("default config is %+v", config)
+	}
+	c.mustSubmit(superadmin, "SetGeofenceConfig", 250.0, "REJECT")
+	if config := c.mustEvaluate(driver, "GetGeofenceConfig").(*GeofenceConfig); config.RadiusMeters != 250 || config.Mode != "REJECT" {
+		t.Fatalf("config is %+v after update", config)
+	}
+}
+
+func TestGeofenceProofChecks(t *testing.T) {
+	farm := testFacilityAddresses["FARM-1"]
+	// Khoảng 1,1 km về phía bắc của trang trại
+	farAway := map[string]interface{}{"facilityID": "FARM-1", "latitude": farm.Latitude + 0.01, "longitude": farm.Longitude}
+	noCoordinates := map[string]interface{}{"facilityID": "FARM-1", "photo": "https://example.com/p.jpg"}
+
+	geofenceStatus := func(c *testChannel) *GeofenceCheck {
+		timeline := c.shipment("SHIP-1").Timeline
+		return timeline[len(timeline)-1].Geofence
+	}
+
+	t.Run("flag mode records the result", func(t *testing.T) {
+		c := newTestChannel(t)
+		withPendingShipment(c)
+		c.mustSubmit(driver, "AddPickupProof", "SHIP-1", "FARM-1", testProof("FARM-1"))
+		if check := geofenceStatus(c); check.Status != "INSIDE" || check.DistanceMeters > 1 {
+			t.Fatalf("unexpected geofence check %+v", check)
+		}
+		c.mustSubmit(driver, "AddPickupProof", "SHIP-1", "FARM-1", farAway)
+		if check := geofenceStatus(c); check.Status != "OUTSIDE" || check.DistanceMeters < 1000 || check.RadiusMeters != 500 {
+			t.Fatalf("unexpected geofence check %+v", check)
+		}
+		c.mustSubmit(driver, "AddPickupProof", "SHIP-1", "FARM-1", noCoordinates)
+		if check := geofenceStatus(c); check.Status != "NO_COORDINATES" {
+			t.Fatalf("unexpected geofence check %+v", check)
+		}
+	})
+
+	t.Run("reject mode refuses proofs outside the geofence", func(t *testing.T) {
+		c := newTestChannel(t)
+		withPendingShipment(c)
+		c.mustSubmit(superadmin, "SetGeofenceConfig", 500.0, "REJECT")
+		_, err := c.submit(driver, "AddPickupProof", "SHIP-1", "FARM-1", farAway)
+		expectError(t, err, "outside the 500m geofence")
+		_, err = c.submit(driver, "AddPickupProof", "SHIP-1", "FARM-1", noCoordinates)
+		expectError(t, err, "proof for facility FARM-1 must include numeric latitude and longitude")
+		c.mustSubmit(driver, "AddPickupProof", "SHIP-1", "FARM-1", testProof("FARM-1"))
+	})
+
+	t.Run("stop radius can only narrow the channel radius", func(t *testing.T) {
+		for _, tc := range []struct {
+			stopRadius float64
+			wantRadius float64
+		}{{2000, 500}, {100, 100}} {
+			c := newTestChannel(t)
+			withFarmBatch(c)
+			pickup := testStop("FARM-1", "PICKUP", testItem("FARM-BATCH-1", "head", 1))
+			pickup.GeofenceRadiusMeters = tc.stopRadius
+			c.mustSubmit(farmAdmin, "CreateShipment", "SHIP-1", "REFRIGERATED", "driver-1", "Nguyen Van A", "51C-123.45", []StopInJourney{pickup, testStop("PROC-1", "DELIVERY")})
+			c.mustSubmit(driver, "AddPickupProof", "SHIP-1", "FARM-1", farAway)
+			if check := geofenceStatus(c); check.Status != "OUTSIDE" || check.RadiusMeters != tc.wantRadius {
+				t.Fatalf("stop radius %.0f: unexpected geofence check %+v", tc.stopRadius, check)
+			}
+		}
+	})
+
+	t.Run("facility without coordinates", func(t *testing.T) {
+		c := newTestChannel(t)
+		withFarmBatch(c)
+		pickup := testStop("FARM-1", "PICKUP", testItem("FARM-BATCH-1", "head", 1))
+		pickup.FacilityAddress = Address{FullText: testFacilityAddresses["FARM-1"].FullText}
+		c.mustSubmit(farmAdmin, "CreateShipment", "SHIP-1", "REFRIGERATED", "driver-1", "Nguyen Van A", "51C-123.45", []StopInJourney{pickup, testStop("PROC-1", "DELIVERY")})
+		c.mustSubmit(driver, "AddPickupProof", "SHIP-1", "FARM-1", testProof("FARM-1"))
+		if check := geofenceStatus(c); check.Status != "FACILITY_LOCATION_UNKNOWN" {
+			t.Fatalf("unexpected geofence check %+v", check)
+		}
+		c.mustSubmit(superadmin, "SetGeofenceConfig", 500.0, "REJECT")
+		_, err := c.submit(driver, "AddPickupProof", "SHIP-1", "FARM-1", testProof("FARM-1"))
+		expectError(t, err, "facility FARM-1 has no coordinates, the geofence cannot be checked")
+	})
+}
+
+func TestStopProofConfirmation(t *testing.T) {
+	farm := testFacilityAddresses["FARM-1"]
+	farAway := map[string]interface{}{"facilityID": "FARM-1", "latitude": farm.Latitude + 0.01, "longitude": farm.Longitude}
+	items := []ItemInShipment{testItem("FARM-BATCH-1", "head", 6)}
+
+	t.Run("proof recorded at another stop does not count", func(t *testing.T) {
+		c := newTestChannel(t)
+		withPendingShipment(c)
+		mislabelled := testProof("PROC-1")
+		mislabelled["facilityID"] = "FARM-1"
+		c.mustSubmit(driver, "AddPickupProof", "SHIP-1", "PROC-1", mislabelled)
+		_, err := c.submit(farmWorker, "ConfirmPickup", "SHIP-1", "FARM-1", items)
+		expectError(t, err, "pickup proof for facility FARM-1 has not been added by the driver yet")
+	})
+
+	t.Run("reject mode only accepts proofs inside the geofence", func(t *testing.T) {
+		c := newTestChannel(t)
+		withPendingShipment(c)
+		c.mustSubmit(driver, "AddPickupProof", "SHIP-1", "FARM-1", farAway)
+		c.mustSubmit(superadmin, "SetGeofenceConfig", 500.0, "REJECT")
+		_, err := c.submit(farmWorker, "ConfirmPickup", "SHIP-1", "FARM-1", items)
+		expectError(t, err, "no pickup proof for facility FARM-1 lies inside the geofence")
+		c.mustSubmit(driver, "AddPickupProof", "SHIP-1", "FARM-1", testProof("FARM-1"))
+		c.mustSubmit(farmWorker, "ConfirmPickup", "SHIP-1", "FARM-1", items)
+	})
+
+	t.Run("flag mode accepts proofs outside the geofence", func(t *testing.T) {
+		c := newTestChannel(t)
+		withPendingShipment(c)
+		c.mustSubmit(driver, "AddPickupProof", "SHIP-1", "FARM-1", farAway)
+		c.mustSubmit(farmWorker, "ConfirmPickup", "SHIP-1", "FARM-1", items)
+	})
+}
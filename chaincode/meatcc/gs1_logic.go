@@ -0,0 +1,270 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// Tên miền mặc định của GS1 Digital Link (resolver của GS1).
+const digitalLinkBaseURL = "https://id.gs1.org"
+
+// Các Application Identifier (AI) của GS1 được sử dụng.
+const (
+	gs1AIGTIN   = "01"
+	gs1AILot    = "10"
+	gs1AISerial = "21"
+)
+
+// Số lô (AI 10) và số sê-ri (AI 21) chỉ được chứa tối đa 20 ký tự thuộc bộ ký tự GS1 AI 82.
+var gs1ComponentPattern = regexp.MustCompile(`^[A-Za-z0-9!"%&'()*+,\-./:;<=>?_]{1,20}$`)
+
+// SetProductGTIN gán GTIN cho một sản phẩm có SKU không phải là GTIN, để các đơn vị của sản phẩm
+// được cấp mã GS1. Mỗi GTIN chỉ thuộc về một sản phẩm. Chỉ Super Admin mới có quyền gọi.
+func (s *SmartContract) SetProductGTIN(ctx contractapi.TransactionContextInterface, sku string, gtin string) error {
+	product, err := s.GetProduct(ctx, sku)
+	if err != nil {
+		return err
+	}
+	normalized, err := normalizeGTIN(gtin)
+	if err != nil {
+		return err
+	}
+	if owner, err := s.readProductByGTIN(ctx, normalized); err == nil && owner.SKU != sku {
+		return fmt.Errorf("GTIN %s is already used by product %s", normalized, owner.SKU)
+	}
+	product.GTIN = normalized
+	productJSON, _ := json.Marshal(product)
+	return ctx.GetStub().PutState(sku, productJSON)
+}
+
+// GetDigitalLink trả về URI GS1 Digital Link của một asset đã được cấp mã GS1,
+// ví dụ https://id.gs1.org/01/09506000134352/10/LOT-1/21/UNIT-1.
+func (s *SmartContract) GetDigitalLink(ctx contractapi.TransactionContextInterface, assetID string) (string, error) {
+	asset, err := s.readAsset(ctx, assetID)
+	if err != nil {
+		return "", err
+	}
+	if asset.GTIN == "" {
+		return "", fmt.Errorf("asset %s has no GS1 identifier", assetID)
+	}
+	return digitalLinkURI(asset.GTIN, asset.LotNumber, asset.SerialNumber), nil
+}
+
+// ResolveDigitalLink tìm asset tương ứng với một URI GS1 Digital Link được quét từ mã QR.
+// Chấp nhận cả URI đầy đủ lẫn phần đường dẫn (/01/{gtin}/10/{lot}/21/{serial}); các AI khác bị bỏ qua.
+// Khi hàng được giao, asset nhận được giữ nguyên mã GS1 và chỉ mục được trỏ sang asset đó, nên kết quả là
+// bên giữ hàng hiện tại; nếu một lô được giao tách cho nhiều bên, kết quả là lần nhận hàng được xác nhận sau cùng.
+func (s *SmartContract) ResolveDigitalLink(ctx contractapi.TransactionContextInterface, digitalLink string) (*MeatAsset, error) {
+	gtin, lot, serial, err := parseDigitalLink(digitalLink)
+	if err != nil {
+		return nil, err
+	}
+	key, err := digitalLinkKey(ctx, gtin, lot, serial)
+	if err != nil {
+		return nil, err
+	}
+	assetID, err := ctx.GetStub().GetState(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read from world state: %v", err)
+	}
+	if assetID == nil {
+		return nil, fmt.Errorf("no asset is registered for digital link %s", digitalLinkURI(gtin, lot, serial))
+	}
+	return s.readAsset(ctx, string(assetID))
+}
+
+// --- Các hàm hỗ trợ cho mã GS1 ---
+
+// assignGS1Identifiers cấp GTIN, số lô và số sê-ri cho asset mới và ghi chỉ mục Digital Link.
+// Không làm gì nếu sản phẩm của asset chưa có GTIN, hoặc nếu số lô/số sê-ri (thường là mã asset) không hợp lệ theo GS1
+// (quá 20 ký tự hoặc có ký tự ngoài bộ AI 82, ví dụ dấu cách): asset vẫn được tạo nhưng không có Digital Link.
+func (s *SmartContract) assignGS1Identifiers(ctx contractapi.TransactionContextInterface, asset *MeatAsset, lot string, serial string) error {
+	gtin, err := s.productGTIN(ctx, asset.SKU)
+	if err != nil || gtin == "" {
+		return err
+	}
+	if !gs1ComponentPattern.MatchString(lot) || (serial != "" && !gs1ComponentPattern.MatchString(serial)) {
+		return nil
+	}
+
+	key, err := digitalLinkKey(ctx, gtin, lot, serial)
+	if err != nil {
+		return err
+	}
+	existingAssetID, err := ctx.GetStub().GetState(key)
+	if err != nil {
+		return fmt.Errorf("failed to read from world state: %v", err)
+	}
+	if existingAssetID != nil {
+		return fmt.Errorf("digital link %s is already assigned to asset %s", digitalLinkURI(gtin, lot, serial), string(existingAssetID))
+	}
+	if err := ctx.GetStub().PutState(key, []byte(asset.AssetID)); err != nil {
+		return fmt.Errorf("failed to put digital link index: %v", err)
+	}
+
+	asset.GTIN = gtin
+	asset.LotNumber = lot
+	asset.SerialNumber = serial
+	return nil
+}
+
+// moveDigitalLinkToHolder trỏ chỉ mục Digital Link theo mã GS1 của asset sang asset đó, dùng khi asset nhận được
+// từ lô hàng tiếp nhận mã GS1 của asset nguồn.
+func moveDigitalLinkToHolder(ctx contractapi.TransactionContextInterface, asset *MeatAsset) error {
+	key, err := digitalLinkKey(ctx, asset.GTIN, asset.LotNumber, asset.SerialNumber)
+	if err != nil {
+		return err
+	}
+	if err := ctx.GetStub().PutState(key, []byte(asset.AssetID)); err != nil {
+		return fmt.Errorf("failed to put digital link index: %v", err)
+	}
+	return nil
+}
+
+// productGTIN trả về GTIN-14 của sản phẩm: SKU nếu SKU là một GTIN hợp lệ, nếu không thì GTIN được gán riêng.
+// Trả về chuỗi rỗng nếu sản phẩm không tồn tại hoặc chưa có GTIN.
+func (s *SmartContract) productGTIN(ctx contractapi.TransactionContextInterface, sku string) (string, error) {
+	productJSON, err := ctx.GetStub().GetState(sku)
+	if err != nil {
+		return "", fmt.Errorf("failed to read product for SKU %s: %v", sku, err)
+	}
+	if productJSON == nil {
+		return "", nil
+	}
+	var product Product
+	if err := json.Unmarshal(productJSON, &product); err != nil {
+		return "", fmt.Errorf("failed to unmarshal product data for SKU %s: %v", sku, err)
+	}
+	if gtin, err := normalizeGTIN(product.SKU); err == nil {
+		return gtin, nil
+	}
+	return product.GTIN, nil
+}
+
+// readProductByGTIN tìm sản phẩm có GTIN-14 cho trước: sản phẩm có SKU là GTIN đó (ở dạng GTIN-8/12/13/14)
+// hoặc sản phẩm đã được gán GTIN bằng SetProductGTIN.
+func (s *SmartContract) readProductByGTIN(ctx contractapi.TransactionContextInterface, gtin string) (*Product, error) {
+	for _, length := range []int{14, 13, 12, 8} {
+		if strings.TrimLeft(gtin[:14-length], "0") != "" {
+			continue
+		}
+		productJSON, err := ctx.GetStub().GetState(gtin[14-length:])
+		if err != nil {
+			return nil, fmt.Errorf("failed to read from world state: %v", err)
+		}
+		var product Product
+		if productJSON != nil && json.Unmarshal(productJSON, &product) == nil && product.ObjectType == "Product" {
+			return &product, nil
+		}
+	}
+
+	queryString := fmt.Sprintf(`{"selector":{"docType":"Product","gtin":"%s"}}`, gtin)
+	resultsIterator, err := ctx.GetStub().GetQueryResult(queryString)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute rich query: %v", err)
+	}
+	defer resultsIterator.Close()
+	if !resultsIterator.HasNext() {
+		return nil, fmt.Errorf("no product is registered with GTIN %s", gtin)
+	}
+	queryResponse, err := resultsIterator.Next()
+	if err != nil {
+		return nil, err
+	}
+	var product Product
+	if err := json.Unmarshal(queryResponse.Value, &product); err != nil {
+		return nil, err
+	}
+	return &product, nil
+}
+
+// normalizeGTIN kiểm tra một GTIN-8/12/13/14 (độ dài và chữ số kiểm tra) và chuẩn hóa về GTIN-14.
+func normalizeGTIN(code string) (string, error) {
+	switch len(code) {
+	case 8, 12, 13, 14:
+	default:
+		return "", fmt.Errorf("invalid GTIN '%s': expected 8, 12, 13 or 14 digits", code)
+	}
+	for _, c := range code {
+		if c < '0' || c > '9' {
+			return "", fmt.Errorf("invalid GTIN '%s': only digits are allowed", code)
+		}
+	}
+	gtin := strings.Repeat("0", 14-len(code)) + code
+
+	// Chữ số kiểm tra GS1: trọng số 3 và 1 xen kẽ tính từ chữ số ngay trước chữ số kiểm tra
+	sum := 0
+	for i := 0; i < 13; i++ {
+		digit := int(gtin[i] - '0')
+		if i%2 == 0 {
+			digit *= 3
+		}
+		sum += digit
+	}
+	checkDigit := (10 - sum%10) % 10
+	if int(gtin[13]-'0') != checkDigit {
+		return "", fmt.Errorf("invalid GTIN '%s': check digit should be %d", code, checkDigit)
+	}
+	return gtin, nil
+}
+
+// digitalLinkURI dựng URI GS1 Digital Link từ GTIN, số lô và số sê-ri (có thể rỗng).
+func digitalLinkURI(gtin string, lot string, serial string) string {
+	uri := fmt.Sprintf("%s/%s/%s", digitalLinkBaseURL, gs1AIGTIN, gtin)
+	if lot != "" {
+		uri += fmt.Sprintf("/%s/%s", gs1AILot, url.PathEscape(lot))
+	}
+	if serial != "" {
+		uri += fmt.Sprintf("/%s/%s", gs1AISerial, url.PathEscape(serial))
+	}
+	return uri
+}
+
+// parseDigitalLink tách GTIN (chuẩn hóa về GTIN-14), số lô và số sê-ri từ một URI GS1 Digital Link.
+func parseDigitalLink(digitalLink string) (string, string, string, error) {
+	parsed, err := url.Parse(strings.TrimSpace(digitalLink))
+	if err != nil {
+		return "", "", "", fmt.Errorf("invalid digital link '%s': %v", digitalLink, err)
+	}
+	segments := strings.Split(strings.Trim(parsed.EscapedPath(), "/"), "/")
+
+	// Các AI bắt đầu từ phân đoạn "01"; phần đường dẫn phía trước (nếu có) thuộc về resolver
+	start := -1
+	for i, segment := range segments {
+		if segment == gs1AIGTIN {
+			start = i
+			break
+		}
+	}
+	if start < 0 || (len(segments)-start)%2 != 0 {
+		return "", "", "", fmt.Errorf("invalid digital link '%s': expected /01/{gtin}[/10/{lot}][/21/{serial}]", digitalLink)
+	}
+
+	values := make(map[string]string)
+	for i := start; i < len(segments); i += 2 {
+		value, err := url.PathUnescape(segments[i+1])
+		if err != nil {
+			return "", "", "", fmt.Errorf("invalid digital link '%s': %v", digitalLink, err)
+		}
+		values[segments[i]] = value
+	}
+	gtin, err := normalizeGTIN(values[gs1AIGTIN])
+	if err != nil {
+		return "", "", "", err
+	}
+	return gtin, values[gs1AILot], values[gs1AISerial], nil
+}
+
+// digitalLinkKey tạo composite key của chỉ mục Digital Link (GTIN, số lô, số sê-ri) -> assetID.
+func digitalLinkKey(ctx contractapi.TransactionContextInterface, gtin string, lot string, serial string) (string, error) {
+	key, err := ctx.GetStub().CreateCompositeKey("DigitalLink", []string{gtin, lot, serial})
+	if err != nil {
+		return "", fmt.Errorf("failed to create composite key: %v", err)
+	}
+	return key, nil
+}
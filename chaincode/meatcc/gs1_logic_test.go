@@ -0,0 +1,119 @@
+package main
+
+import "testing"
+
+func TestSetProductGTIN(t *testing.T) {
+	runTransactionCases(t, func(c *testChannel) { c.withCatalog() }, []transactionCase{
+		{name: "superadmin assigns GTIN-13", identity: superadmin, function: "SetProductGTIN", args: []interface{}{skuPorkLoin, "4006381333931"}},
+		{name: "wrong check digit", identity: superadmin, function: "SetProductGTIN", args: []interface{}{skuPorkLoin, "4006381333932"},
+			wantErr: "invalid GTIN '4006381333932': check digit should be 1"},
+		{name: "non-digit GTIN", identity: superadmin, function: "SetProductGTIN", args: []interface{}{skuPorkLoin, "40063813339AB"},
+			wantErr: "only digits are allowed"},
+		{name: "wrong length", identity: superadmin, function: "SetProductGTIN", args: []interface{}{skuPorkLoin, "12345"},
+			wantErr: "expected 8, 12, 13 or 14 digits"},
+		{name: "unknown product", identity: superadmin, function: "SetProductGTIN", args: []interface{}{"BEEF", "4006381333931"},
+			wantErr: "BEEF does not exist"},
+		{name: "admin denied", identity: processorAdmin, function: "SetProductGTIN", args: []interface{}{skuPorkLoin, "4006381333931"},
+			wantErr: "access to SetProductGTIN denied"},
+		{name: "GTIN is another product's SKU", identity: superadmin, function: "SetProductGTIN", args: []interface{}{skuPorkLoin, "9506000134352"},
+			wantErr: "GTIN 09506000134352 is already used by product 9506000134352"},
+	})
+
+	t.Run("GTIN is unique across products", func(t *testing.T) {
+		c := newTestChannel(t)
+		c.withCatalog()
+		c.mustSubmit(superadmin, "SetProductGTIN", skuPorkLoin, "4006381333931")
+		c.mustSubmit(superadmin, "SetProductGTIN", skuPorkLoin, "04006381333931")
+		_, err := c.submit(superadmin, "SetProductGTIN", skuPorkCarcass, "4006381333931")
+		expectError(t, err, "GTIN 04006381333931 is already used by product PORK-LOIN")
+	})
+
+	t.Run("new assets of the product get GS1 identifiers", func(t *testing.T) {
+		c := newTestChannel(t)
+		atProcessor(c)
+		c.mustSubmit(superadmin, "SetProductGTIN", skuPorkLoin, "4006381333931")
+		c.mustSubmit(processorAdmin, "ProcessAndSplitBatch", "PROC-BATCH-0", []ChildAssetInput{
+			{AssetID: "LOIN-1", ProductName: "Pork loin", SKU: skuPorkLoin, Quantity: Quantity{Unit: "kg", Value: 120}},
+		}, ProcessingDetails{FacilityName: "Song Than Processing"})
+
+		link := c.mustEvaluate(regulator, "GetDigitalLink", "LOIN-1").(string)
+		if link != "https://id.gs1.org/01/04006381333931/10/LOIN-1" {
+			t.Fatalf("unexpected digital link %s", link)
+		}
+		if asset := c.mustEvaluate(regulator, "ResolveDigitalLink", link).(*MeatAsset); asset.AssetID != "LOIN-1" {
+			t.Fatalf("digital link resolved to %s", asset.AssetID)
+		}
+	})
+}
+
+func TestNonGS1IdentifiersAreSkipped(t *testing.T) {
+	c := newTestChannel(t)
+	c.withCatalog()
+	c.mustSubmit(superadmin, "SetProductGTIN", skuPorkCarcass, "4006381333931")
+
+	// Mã asset dài hơn 20 ký tự hoặc có dấu cách không dùng được làm số lô GS1, nhưng lô nuôi vẫn được tạo
+	for _, assetID := range []string{"FARM-BATCH-2024-10-000123", "FARM BATCH 7"} {
+		c.createFarmBatch(farmAdmin, assetID, skuPorkCarcass, Quantity{Unit: "head", Value: 10})
+		if asset := c.asset(assetID); asset.GTIN != "" || asset.LotNumber != "" {
+			t.Fatalf("asset %s got GS1 identifiers %s/%s", assetID, asset.GTIN, asset.LotNumber)
+		}
+		_, err := c.evaluate(regulator, "GetDigitalLink", assetID)
+		expectError(t, err, "has no GS1 identifier")
+	}
+	c.createFarmBatch(farmAdmin, "FARM-BATCH-8", skuPorkCarcass, Quantity{Unit: "head", Value: 10})
+	if link := c.mustEvaluate(regulator, "GetDigitalLink", "FARM-BATCH-8").(string); link != "https://id.gs1.org/01/04006381333931/10/FARM-BATCH-8" {
+		t.Fatalf("unexpected digital link %s", link)
+	}
+
+	// Số sê-ri quá dài: đơn vị bán lẻ vẫn được tạo, không có Digital Link
+	c = newTestChannel(t)
+	atRetailer(c)
+	c.mustSubmit(retailerWorker, "SplitBatchToUnits", "RETAIL-BATCH-0", 1, "RETAIL-UNIT-2024-10-")
+	if unit := c.asset("RETAIL-UNIT-2024-10-1"); unit.GTIN != "" || unit.SerialNumber != "" {
+		t.Fatalf("unit got GS1 identifiers %s/%s", unit.GTIN, unit.SerialNumber)
+	}
+}
+
+func TestDigitalLink(t *testing.T) {
+	c := newTestChannel(t)
+	atRetailer(c)
+	c.mustSubmit(retailerWorker, "SplitBatchToUnits", "RETAIL-BATCH-0", 2, "UNIT-")
+
+	link := c.mustEvaluate(retailerWorker, "GetDigitalLink", "UNIT-1").(string)
+	if link != "https://id.gs1.org/01/09506000134352/10/TRAY-1/21/UNIT-1" {
+		t.Fatalf("unexpected digital link %s", link)
+	}
+	_, err := c.evaluate(retailerWorker, "GetDigitalLink", "FARM-BATCH-1")
+	expectError(t, err, "asset FARM-BATCH-1 has no GS1 identifier")
+
+	// Chấp nhận URI đầy đủ, phần đường dẫn, resolver khác, GTIN-13 và các AI không dùng tới
+	resolved := map[string]string{
+		link:                                     "UNIT-1",
+		"/01/09506000134352/10/TRAY-1/21/UNIT-2": "UNIT-2",
+		"https://scan.example.vn/dl/01/9506000134352/10/TRAY-1/21/UNIT-2?linkType=gs1:traceability": "UNIT-2",
+		"https://id.gs1.org/01/09506000134352/10/TRAY-1/21/UNIT-2/17/240331":                        "UNIT-2",
+	}
+	for scanned, wantID := range resolved {
+		if asset := c.mustEvaluate(driver, "ResolveDigitalLink", scanned).(*MeatAsset); asset.AssetID != wantID {
+			t.Fatalf("%s resolved to %s, want %s", scanned, asset.AssetID, wantID)
+		}
+	}
+
+	// Lô TRAY-1 đã được giao tới RETAIL-1: Digital Link của lô trỏ tới bên đang giữ hàng
+	if asset := c.mustEvaluate(driver, "ResolveDigitalLink", "https://id.gs1.org/01/09506000134352/10/TRAY-1").(*MeatAsset); asset.AssetID != "RETAIL-BATCH-0" {
+		t.Fatalf("lot TRAY-1 resolved to %s, want the current holder RETAIL-BATCH-0", asset.AssetID)
+	}
+	if received := c.mustEvaluate(driver, "GetDigitalLink", "RETAIL-BATCH-0").(string); received != c.mustEvaluate(driver, "GetDigitalLink", "TRAY-1").(string) {
+		t.Fatalf("received lot has digital link %s, want the same link as TRAY-1", received)
+	}
+
+	cases := map[string]string{
+		"https://id.gs1.org/01/09506000134352/10/TRAY-1/21/UNIT-9": "no asset is registered for digital link https://id.gs1.org/01/09506000134352/10/TRAY-1/21/UNIT-9",
+		"https://id.gs1.org/10/TRAY-1":                             "expected /01/{gtin}[/10/{lot}][/21/{serial}]",
+		"https://id.gs1.org/01/09506000134353":                     "check digit should be 2",
+	}
+	for scanned, wantErr := range cases {
+		_, err := c.evaluate(driver, "ResolveDigitalLink", scanned)
+		expectError(t, err, wantErr)
+	}
+}
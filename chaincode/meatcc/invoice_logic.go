@@ -0,0 +1,311 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// Các trạng thái của hóa đơn.
+const (
+	InvoiceStatusIssued   = "ISSUED"
+	InvoiceStatusDisputed = "DISPUTED"
+	InvoiceStatusAccepted = "ACCEPTED"
+	InvoiceStatusPaid     = "PAID"
+)
+
+// IssueInvoice cho phép bên bán lập hóa đơn cho số lượng đã giao của lô hàng theo một đơn đặt hàng.
+// Số lượng được lấy từ các điểm giao đã hoàn thành và chưa có hóa đơn; lô hàng đang vận chuyển chưa được lập hóa đơn.
+// Đơn giá lấy từ điều khoản riêng tư của đơn đặt hàng mà client gửi qua transient map (khóa "commercialTerms") và được
+// đối chiếu với hash trên ledger, nên transaction không đọc private data và mọi peer trên kênh đều endorse được.
+// Số tiền được lưu trong private data collection cùng salt do client gửi qua transient map (khóa "commercialTermsSalt").
+func (s *SmartContract) IssueInvoice(ctx contractapi.TransactionContextInterface, invoiceID string, shipmentID string, poID string, dueDate string) error {
+	exists, err := s.assetExists(ctx, invoiceID)
+	if err != nil {
+		return err
+	}
+	if exists {
+		return fmt.Errorf("invoice %s already exists", invoiceID)
+	}
+	if _, err := time.Parse("2006-01-02", dueDate); err != nil {
+		return fmt.Errorf("invalid dueDate '%s', expected YYYY-MM-DD", dueDate)
+	}
+
+	po, err := s.readPurchaseOrder(ctx, poID)
+	if err != nil {
+		return err
+	}
+	if err := requirePurchaseOrderParty(ctx, po.SellerFacilityID); err != nil {
+		return err
+	}
+	if !containsString(deliverablePOStatuses, po.Status) {
+		return fmt.Errorf("purchase order %s with status '%s' cannot be invoiced", poID, po.Status)
+	}
+	shipment, err := s.readShipmentAsset(ctx, shipmentID)
+	if err != nil {
+		return err
+	}
+	if shipment.Status == ShipmentStatusInTransit {
+		return fmt.Errorf("shipment %s is still in transit and cannot be invoiced yet", shipmentID)
+	}
+
+	// Gộp số lượng đã giao theo dòng đơn đặt hàng. Mỗi điểm giao chỉ được lập hóa đơn một lần cho mỗi đơn đặt hàng;
+	// các điểm đã có hóa đơn được bỏ qua.
+	var lineOrder []string
+	var indexKeys []string
+	existingInvoiceID := ""
+	deliveredByLine := make(map[string]*InvoiceLine)
+	for stopIndex, stop := range shipment.Stops {
+		if stop.Action != "DELIVERY" || stop.Status != "COMPLETED" || stop.FacilityID != po.BuyerFacilityID || !stopHasPurchaseOrder(stop, poID) {
+			continue
+		}
+		indexKey, err := ctx.GetStub().CreateCompositeKey("InvoiceByShipmentStop", []string{shipmentID, strconv.Itoa(stopIndex), poID})
+		if err != nil {
+			return fmt.Errorf("failed to create composite key: %v", err)
+		}
+		invoicedBy, err := ctx.GetStub().GetState(indexKey)
+		if err != nil {
+			return fmt.Errorf("failed to read from world state: %v", err)
+		}
+		if invoicedBy != nil {
+			existingInvoiceID = string(invoicedBy)
+			continue
+		}
+		indexKeys = append(indexKeys, indexKey)
+		for _, item := range stop.Items {
+			if item.POID != poID {
+				continue
+			}
+			line := findPurchaseOrderLine(po, item.POLineID)
+			if line == nil {
+				return fmt.Errorf("purchase order %s has no line %s", poID, item.POLineID)
+			}
+			invoiceLine, ok := deliveredByLine[line.LineID]
+			if !ok {
+				invoiceLine = &InvoiceLine{LineID: line.LineID, SKU: line.SKU, Quantity: Quantity{Unit: line.Quantity.Unit}}
+				deliveredByLine[line.LineID] = invoiceLine
+				lineOrder = append(lineOrder, line.LineID)
+			}
+			invoiceLine.Quantity.Value += item.Quantity.Value
+		}
+	}
+	if len(lineOrder) == 0 && existingInvoiceID != "" {
+		return fmt.Errorf("shipment %s has already been invoiced for purchase order %s in invoice %s", shipmentID, poID, existingInvoiceID)
+	}
+	if len(lineOrder) == 0 {
+		return fmt.Errorf("shipment %s has no completed deliveries for purchase order %s", shipmentID, poID)
+	}
+
+	salt, err := readTransientSalt(ctx)
+	if err != nil {
+		return err
+	}
+
+	// Tính số tiền từ đơn giá riêng tư của đơn đặt hàng do client gửi lại và được đối chiếu với hash trên ledger
+	poTerms, err := s.readVerifiedTransientTerms(ctx, TermsReferencePurchaseOrder, poID)
+	if err != nil {
+		return fmt.Errorf("cannot price invoice %s: %v", invoiceID, err)
+	}
+	unitPrices := make(map[string]float64)
+	for _, price := range poTerms.LinePrices {
+		unitPrices[price.LineID] = price.UnitPrice
+	}
+	invoiceTerms := CommercialTerms{
+		SellerFacilityID: po.SellerFacilityID,
+		BuyerFacilityID:  po.BuyerFacilityID,
+		BuyerName:        poTerms.BuyerName,
+		Currency:         poTerms.Currency,
+		Salt:             salt,
+	}
+	lines := make([]InvoiceLine, 0, len(lineOrder))
+	total := 0.0
+	for _, lineID := range lineOrder {
+		unitPrice, ok := unitPrices[lineID]
+		if !ok {
+			return fmt.Errorf("purchase order %s has no unit price for line %s", poID, lineID)
+		}
+		invoiceLine := deliveredByLine[lineID]
+		lines = append(lines, *invoiceLine)
+		invoiceTerms.LinePrices = append(invoiceTerms.LinePrices, LinePrice{LineID: lineID, SKU: invoiceLine.SKU, UnitPrice: unitPrice})
+		total += invoiceLine.Quantity.Value * unitPrice
+	}
+	invoiceTerms.TotalAmount = math.Round(total*100) / 100
+
+	hash, err := s.putCommercialTerms(ctx, TermsReferenceInvoice, invoiceID, &invoiceTerms)
+	if err != nil {
+		return err
+	}
+	event, err := s.createEvent(ctx, "INVOICE_ISSUED", map[string]interface{}{"shipmentID": shipmentID, "poID": poID})
+	if err != nil {
+		return err
+	}
+
+	invoice := Invoice{
+		ObjectType:          "Invoice",
+		InvoiceID:           invoiceID,
+		POID:                poID,
+		ShipmentID:          shipmentID,
+		SellerFacilityID:    po.SellerFacilityID,
+		BuyerFacilityID:     po.BuyerFacilityID,
+		Lines:               lines,
+		DueDate:             dueDate,
+		Status:              InvoiceStatusIssued,
+		CommercialTermsHash: hash,
+		History:             []Event{*event},
+	}
+	for _, indexKey := range indexKeys {
+		if err := ctx.GetStub().PutState(indexKey, []byte(invoiceID)); err != nil {
+			return fmt.Errorf("failed to put invoice index: %v", err)
+		}
+	}
+	return s.updateInvoice(ctx, &invoice)
+}
+
+// DisputeInvoice cho phép bên mua khiếu nại một hóa đơn đã phát hành kèm lý do.
+func (s *SmartContract) DisputeInvoice(ctx contractapi.TransactionContextInterface, invoiceID string, reason string) error {
+	if reason == "" {
+		return fmt.Errorf("a reason is required to dispute invoice %s", invoiceID)
+	}
+	invoice, err := s.readInvoice(ctx, invoiceID)
+	if err != nil {
+		return err
+	}
+	if err := requirePurchaseOrderParty(ctx, invoice.BuyerFacilityID); err != nil {
+		return err
+	}
+	if invoice.Status != InvoiceStatusIssued {
+		return fmt.Errorf("invoice %s with status '%s' cannot be disputed", invoiceID, invoice.Status)
+	}
+	return s.addInvoiceEvent(ctx, invoice, "INVOICE_DISPUTED", InvoiceStatusDisputed, map[string]interface{}{"reason": reason})
+}
+
+// AcceptInvoice cho phép bên mua chấp nhận hóa đơn (kể cả sau khi khiếu nại đã được giải quyết).
+func (s *SmartContract) AcceptInvoice(ctx contractapi.TransactionContextInterface, invoiceID string) error {
+	invoice, err := s.readInvoice(ctx, invoiceID)
+	if err != nil {
+		return err
+	}
+	if err := requirePurchaseOrderParty(ctx, invoice.BuyerFacilityID); err != nil {
+		return err
+	}
+	if invoice.Status != InvoiceStatusIssued && invoice.Status != InvoiceStatusDisputed {
+		return fmt.Errorf("invoice %s with status '%s' cannot be accepted", invoiceID, invoice.Status)
+	}
+	return s.addInvoiceEvent(ctx, invoice, "INVOICE_ACCEPTED", InvoiceStatusAccepted, nil)
+}
+
+// MarkInvoicePaid cho phép bên bán xác nhận đã nhận thanh toán cho một hóa đơn đã được chấp nhận.
+func (s *SmartContract) MarkInvoicePaid(ctx contractapi.TransactionContextInterface, invoiceID string, paymentReference string) error {
+	if paymentReference == "" {
+		return fmt.Errorf("a payment reference is required to mark invoice %s as paid", invoiceID)
+	}
+	invoice, err := s.readInvoice(ctx, invoiceID)
+	if err != nil {
+		return err
+	}
+	if err := requirePurchaseOrderParty(ctx, invoice.SellerFacilityID); err != nil {
+		return err
+	}
+	if invoice.Status != InvoiceStatusAccepted {
+		return fmt.Errorf("invoice %s with status '%s' cannot be marked as paid", invoiceID, invoice.Status)
+	}
+	invoice.PaymentReference = paymentReference
+	return s.addInvoiceEvent(ctx, invoice, "INVOICE_PAID", InvoiceStatusPaid, map[string]interface{}{"paymentReference": paymentReference})
+}
+
+// GetInvoice lấy thông tin công khai của một hóa đơn; số tiền được đọc qua GetCommercialTerms("INVOICE", invoiceID).
+func (s *SmartContract) GetInvoice(ctx contractapi.TransactionContextInterface, invoiceID string) (*Invoice, error) {
+	return s.readInvoice(ctx, invoiceID)
+}
+
+// QueryInvoicesByFacility thực hiện một truy vấn CouchDB để tìm các hóa đơn mà một cơ sở là bên mua hoặc bên bán.
+func (s *SmartContract) QueryInvoicesByFacility(ctx contractapi.TransactionContextInterface, facilityID string) ([]*Invoice, error) {
+	query := map[string]interface{}{
+		"selector": map[string]interface{}{
+			"docType": "Invoice",
+			"$or": []interface{}{
+				map[string]interface{}{"buyerFacilityID": facilityID},
+				map[string]interface{}{"sellerFacilityID": facilityID},
+			},
+		},
+	}
+	queryBytes, err := json.Marshal(query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build query: %v", err)
+	}
+
+	resultsIterator, err := ctx.GetStub().GetQueryResult(string(queryBytes))
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute rich query: %v", err)
+	}
+	defer resultsIterator.Close()
+
+	var invoices []*Invoice
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+		var invoice Invoice
+		if err := json.Unmarshal(queryResponse.Value, &invoice); err != nil {
+			return nil, err
+		}
+		invoices = append(invoices, &invoice)
+	}
+	return invoices, nil
+}
+
+// --- Các hàm hỗ trợ cho hóa đơn ---
+
+// Thêm một sự kiện vào hóa đơn, cập nhật trạng thái mới và lưu lại.
+func (s *SmartContract) addInvoiceEvent(ctx contractapi.TransactionContextInterface, invoice *Invoice, eventType string, newStatus string, details interface{}) error {
+	event, err := s.createEvent(ctx, eventType, details)
+	if err != nil {
+		return err
+	}
+	invoice.History = append(invoice.History, *event)
+	invoice.Status = newStatus
+	return s.updateInvoice(ctx, invoice)
+}
+
+// stopHasPurchaseOrder kiểm tra điểm dừng có giao hàng cho đơn đặt hàng poID hay không.
+func stopHasPurchaseOrder(stop StopInJourney, poID string) bool {
+	for _, item := range stop.Items {
+		if item.POID == poID {
+			return true
+		}
+	}
+	return false
+}
+
+// Lưu hóa đơn vào world state.
+func (s *SmartContract) updateInvoice(ctx contractapi.TransactionContextInterface, invoice *Invoice) error {
+	invoiceJSON, err := json.Marshal(invoice)
+	if err != nil {
+		return err
+	}
+	return ctx.GetStub().PutState(invoice.InvoiceID, invoiceJSON)
+}
+
+// Đọc thông tin hóa đơn từ world state.
+func (s *SmartContract) readInvoice(ctx contractapi.TransactionContextInterface, invoiceID string) (*Invoice, error) {
+	invoiceJSON, err := ctx.GetStub().GetState(invoiceID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read from world state: %v", err)
+	}
+	if invoiceJSON == nil {
+		return nil, fmt.Errorf("the invoice %s does not exist", invoiceID)
+	}
+	var invoice Invoice
+	if err := json.Unmarshal(invoiceJSON, &invoice); err != nil {
+		return nil, err
+	}
+	if invoice.ObjectType != "Invoice" {
+		return nil, fmt.Errorf("%s is not an invoice", invoiceID)
+	}
+	return &invoice, nil
+}
@@ -0,0 +1,237 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+// withDeliveredOrder dựng PO-1 có đơn giá riêng tư và lô hàng SHIP-1 đã giao 5 con theo dòng L1.
+func withDeliveredOrder(c *testChannel) {
+	withOrder(c)
+	c.mustSubmitWithTransient(processorAdmin, termsTransient(c.t, orderTerms), "SetPurchaseOrderTerms", "PO-1")
+	c.mustSubmit(farmAdmin, "AcceptPurchaseOrder", "PO-1")
+	c.deliverOrder("SHIP-1", 5)
+}
+
+// invoiceTransient dựng transient map cho IssueInvoice như client gửi lên: điều khoản của PO-1 đọc từ
+// GetCommercialTerms (nếu có) và salt cho số tiền của hóa đơn.
+func invoiceTransient(c *testChannel) map[string][]byte {
+	c.t.Helper()
+	transient := saltTransient()
+	if terms, err := c.evaluate(farmAdmin, "GetCommercialTerms", TermsReferencePurchaseOrder, "PO-1"); err == nil {
+		termsJSON, err := json.Marshal(terms)
+		if err != nil {
+			c.t.Fatalf("failed to marshal commercial terms: %v", err)
+		}
+		transient[commercialTermsTransientKey] = termsJSON
+	}
+	return transient
+}
+
+// withIssuedInvoice dựng hóa đơn INV-1 do FARM-1 lập cho SHIP-1 theo PO-1.
+func withIssuedInvoice(c *testChannel) {
+	withDeliveredOrder(c)
+	c.mustSubmitWithTransient(farmAdmin, invoiceTransient(c), "IssueInvoice", "INV-1", "SHIP-1", "PO-1", "2024-04-01")
+}
+
+func TestIssueInvoice(t *testing.T) {
+	cases := []struct {
+		name     string
+		identity *mockIdentity
+		args     []interface{}
+		wantErr  string
+	}{
+		{name: "seller issues invoice", identity: farmAdmin, args: []interface{}{"INV-1", "SHIP-1", "PO-1", "2024-04-01"}},
+		{name: "buyer cannot issue", identity: processorAdmin, args: []interface{}{"INV-1", "SHIP-1", "PO-1", "2024-04-01"},
+			wantErr: "caller from facility 'PROC-1' is not authorized for this purchase order (expected 'FARM-1')"},
+		{name: "invalid due date", identity: farmAdmin, args: []interface{}{"INV-1", "SHIP-1", "PO-1", "01/04/2024"},
+			wantErr: "invalid dueDate '01/04/2024', expected YYYY-MM-DD"},
+		{name: "unknown purchase order", identity: farmAdmin, args: []interface{}{"INV-1", "SHIP-1", "PO-9", "2024-04-01"},
+			wantErr: "does not exist"},
+		{name: "unknown shipment", identity: farmAdmin, args: []interface{}{"INV-1", "SHIP-9", "PO-1", "2024-04-01"},
+			wantErr: "does not exist"},
+		{name: "invoice ID taken", identity: farmAdmin, args: []interface{}{"FARM-BATCH-1", "SHIP-1", "PO-1", "2024-04-01"},
+			wantErr: "invoice FARM-BATCH-1 already exists"},
+		{name: "worker denied", identity: farmWorker, args: []interface{}{"INV-1", "SHIP-1", "PO-1", "2024-04-01"},
+			wantErr: "access to IssueInvoice denied"},
+	}
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			c := newTestChannel(t)
+			withDeliveredOrder(c)
+			_, err := c.submitWithTransient(tc.identity, invoiceTransient(c), "IssueInvoice", tc.args...)
+			expectError(t, err, tc.wantErr)
+		})
+	}
+
+	t.Run("amount is priced from private terms", func(t *testing.T) {
+		c := newTestChannel(t)
+		withIssuedInvoice(c)
+		invoice := c.mustEvaluate(regulator, "GetInvoice", "INV-1").(*Invoice)
+		if invoice.Status != InvoiceStatusIssued || len(invoice.Lines) != 1 || invoice.Lines[0].Quantity.Value != 5 || invoice.CommercialTermsHash == "" {
+			t.Fatalf("unexpected invoice %+v", invoice)
+		}
+		terms := c.mustEvaluate(processorAdmin, "GetCommercialTerms", TermsReferenceInvoice, "INV-1").(*CommercialTerms)
+		if terms.TotalAmount != 22500000 || terms.Currency != "VND" {
+			t.Fatalf("expected 22500000 VND, got %v %s", terms.TotalAmount, terms.Currency)
+		}
+		if !c.mustEvaluate(regulator, "VerifyCommercialTerms", TermsReferenceInvoice, "INV-1").(bool) {
+			t.Fatal("invoice amount does not verify")
+		}
+	})
+
+	t.Run("peer outside the collection can endorse", func(t *testing.T) {
+		c := newTestChannel(t)
+		withDeliveredOrder(c)
+		transient := invoiceTransient(c)
+		c.stub.nonMemberPeer = true
+		c.mustSubmitWithTransient(farmAdmin, transient, "IssueInvoice", "INV-1", "SHIP-1", "PO-1", "2024-04-01")
+	})
+
+	t.Run("prices must match the ledger", func(t *testing.T) {
+		c := newTestChannel(t)
+		withDeliveredOrder(c)
+		transient := invoiceTransient(c)
+		var terms CommercialTerms
+		if err := json.Unmarshal(transient[commercialTermsTransientKey], &terms); err != nil {
+			t.Fatal(err)
+		}
+		terms.LinePrices[0].UnitPrice = 9000000
+		transient[commercialTermsTransientKey], _ = json.Marshal(terms)
+		_, err := c.submitWithTransient(farmAdmin, transient, "IssueInvoice", "INV-1", "SHIP-1", "PO-1", "2024-04-01")
+		expectError(t, err, "commercial terms passed for PURCHASE_ORDER PO-1 do not match the ledger")
+
+		delete(transient, commercialTermsTransientKey)
+		_, err = c.submitWithTransient(farmAdmin, transient, "IssueInvoice", "INV-1", "SHIP-1", "PO-1", "2024-04-01")
+		expectError(t, err, "commercial terms of PURCHASE_ORDER PO-1 must be passed in the transient map under key 'commercialTerms'")
+	})
+
+	t.Run("salt required", func(t *testing.T) {
+		c := newTestChannel(t)
+		withDeliveredOrder(c)
+		transient := invoiceTransient(c)
+		delete(transient, commercialTermsSaltTransientKey)
+		_, err := c.submitWithTransient(farmAdmin, transient, "IssueInvoice", "INV-1", "SHIP-1", "PO-1", "2024-04-01")
+		expectError(t, err, "a salt of at least 16 bytes must be passed in the transient map under key 'commercialTermsSalt'")
+	})
+
+	t.Run("one invoice per delivery stop and order", func(t *testing.T) {
+		c := newTestChannel(t)
+		withIssuedInvoice(c)
+		_, err := c.submitWithTransient(farmAdmin, invoiceTransient(c), "IssueInvoice", "INV-2", "SHIP-1", "PO-1", "2024-04-01")
+		expectError(t, err, "shipment SHIP-1 has already been invoiced for purchase order PO-1 in invoice INV-1")
+	})
+
+	t.Run("every delivery stop of the order is invoiced", func(t *testing.T) {
+		c := newTestChannel(t)
+		withOrder(c)
+		c.mustSubmitWithTransient(processorAdmin, termsTransient(t, orderTerms), "SetPurchaseOrderTerms", "PO-1")
+		c.mustSubmit(farmAdmin, "AcceptPurchaseOrder", "PO-1")
+		item := testItem("FARM-BATCH-1", "head", 5)
+		orderItem := func(quantity float64) ItemInShipment {
+			return ItemInShipment{AssetID: "FARM-BATCH-1", Quantity: Quantity{Unit: "head", Value: quantity}, POID: "PO-1", POLineID: "L1"}
+		}
+		c.mustSubmit(farmAdmin, "CreateShipment", "SHIP-1", "REFRIGERATED", "driver-1", "Nguyen Van A", "51C-123.45",
+			[]StopInJourney{testStop("FARM-1", "PICKUP", item), testStop("PROC-1", "DELIVERY", orderItem(3)), testStop("PROC-1", "DELIVERY", orderItem(2))})
+		c.mustSubmit(driver, "AddPickupProof", "SHIP-1", "FARM-1", testProof("FARM-1"))
+		c.mustSubmit(farmAdmin, "ConfirmPickup", "SHIP-1", "FARM-1", []ItemInShipment{item})
+		c.mustSubmit(driver, "StartShipment", "SHIP-1", []string{"SEAL-1"})
+		c.mustSubmit(driver, "AddDeliveryProof", "SHIP-1", "PROC-1", testProof("PROC-1"))
+		c.mustSubmit(processorAdmin, "ConfirmShipmentDelivery", "SHIP-1", "PROC-1", "RCV-A", []string{"SEAL-1"})
+
+		_, err := c.submitWithTransient(farmAdmin, invoiceTransient(c), "IssueInvoice", "INV-1", "SHIP-1", "PO-1", "2024-04-01")
+		expectError(t, err, "shipment SHIP-1 is still in transit and cannot be invoiced yet")
+
+		c.mustSubmit(processorAdmin, "ConfirmShipmentDelivery", "SHIP-1", "PROC-1", "RCV-B", []string{"SEAL-1"})
+		c.mustSubmitWithTransient(farmAdmin, invoiceTransient(c), "IssueInvoice", "INV-1", "SHIP-1", "PO-1", "2024-04-01")
+		invoice := c.mustEvaluate(regulator, "GetInvoice", "INV-1").(*Invoice)
+		if len(invoice.Lines) != 1 || invoice.Lines[0].Quantity.Value != 5 {
+			t.Fatalf("expected both stops (5 head) on the invoice, got %+v", invoice.Lines)
+		}
+	})
+
+	t.Run("order without prices cannot be invoiced", func(t *testing.T) {
+		c := newTestChannel(t)
+		withAcceptedOrder(c)
+		c.deliverOrder("SHIP-1", 5)
+		_, err := c.submitWithTransient(farmAdmin, invoiceTransient(c), "IssueInvoice", "INV-1", "SHIP-1", "PO-1", "2024-04-01")
+		expectError(t, err, "cannot price invoice INV-1: no commercial terms found for PURCHASE_ORDER PO-1")
+	})
+
+	t.Run("shipment without deliveries for the order", func(t *testing.T) {
+		c := newTestChannel(t)
+		withDeliveredOrder(c)
+		c.pickUpAndStart("SHIP-2", farmAdmin, "PROC-1", testItem("FARM-BATCH-1", "head", 2))
+		_, err := c.submitWithTransient(farmAdmin, invoiceTransient(c), "IssueInvoice", "INV-1", "SHIP-2", "PO-1", "2024-04-01")
+		expectError(t, err, "shipment SHIP-2 is still in transit and cannot be invoiced yet")
+
+		c.mustSubmit(farmAdmin, "ReturnShipment", "SHIP-2", "Refused at gate")
+		_, err = c.submitWithTransient(farmAdmin, invoiceTransient(c), "IssueInvoice", "INV-1", "SHIP-2", "PO-1", "2024-04-01")
+		expectError(t, err, "shipment SHIP-2 has no completed deliveries for purchase order PO-1")
+	})
+}
+
+func TestInvoiceSettlement(t *testing.T) {
+	runTransactionCases(t, withIssuedInvoice, []transactionCase{
+		{name: "buyer disputes", identity: processorAdmin, function: "DisputeInvoice", args: []interface{}{"INV-1", "Two carcasses underweight"}},
+		{name: "dispute needs a reason", identity: processorAdmin, function: "DisputeInvoice", args: []interface{}{"INV-1", ""},
+			wantErr: "a reason is required to dispute invoice INV-1"},
+		{name: "seller cannot dispute", identity: farmAdmin, function: "DisputeInvoice", args: []interface{}{"INV-1", "No reason"},
+			wantErr: "is not authorized for this purchase order"},
+		{name: "buyer accepts", identity: processorAdmin, function: "AcceptInvoice", args: []interface{}{"INV-1"}},
+		{name: "seller cannot accept", identity: farmAdmin, function: "AcceptInvoice", args: []interface{}{"INV-1"},
+			wantErr: "is not authorized for this purchase order"},
+		{name: "issued invoice cannot be paid", identity: farmAdmin, function: "MarkInvoicePaid", args: []interface{}{"INV-1", "VCB-0001"},
+			wantErr: "invoice INV-1 with status 'ISSUED' cannot be marked as paid"},
+		{name: "payment needs a reference", identity: farmAdmin, function: "MarkInvoicePaid", args: []interface{}{"INV-1", ""},
+			wantErr: "a payment reference is required to mark invoice INV-1 as paid"},
+		{name: "unknown invoice", identity: processorAdmin, function: "AcceptInvoice", args: []interface{}{"INV-9"},
+			wantErr: "the invoice INV-9 does not exist"},
+		{name: "asset is not an invoice", identity: processorAdmin, function: "AcceptInvoice", args: []interface{}{"FARM-BATCH-1"},
+			wantErr: "FARM-BATCH-1 is not an invoice"},
+	})
+
+	t.Run("dispute, accept and pay", func(t *testing.T) {
+		c := newTestChannel(t)
+		withIssuedInvoice(c)
+		c.mustSubmit(processorAdmin, "DisputeInvoice", "INV-1", "Two carcasses underweight")
+		_, err := c.submit(processorAdmin, "DisputeInvoice", "INV-1", "Again")
+		expectError(t, err, "invoice INV-1 with status 'DISPUTED' cannot be disputed")
+		c.mustSubmit(processorAdmin, "AcceptInvoice", "INV-1")
+		_, err = c.submit(processorAdmin, "AcceptInvoice", "INV-1")
+		expectError(t, err, "invoice INV-1 with status 'ACCEPTED' cannot be accepted")
+		_, err = c.submit(processorAdmin, "MarkInvoicePaid", "INV-1", "VCB-0001")
+		expectError(t, err, "is not authorized for this purchase order")
+		c.mustSubmit(farmAdmin, "MarkInvoicePaid", "INV-1", "VCB-0001")
+
+		invoice := c.mustEvaluate(regulator, "GetInvoice", "INV-1").(*Invoice)
+		if invoice.Status != InvoiceStatusPaid || invoice.PaymentReference != "VCB-0001" {
+			t.Fatalf("unexpected invoice %+v", invoice)
+		}
+		var types []string
+		for _, event := range invoice.History {
+			types = append(types, event.Type)
+		}
+		want := []string{"INVOICE_ISSUED", "INVOICE_DISPUTED", "INVOICE_ACCEPTED", "INVOICE_PAID"}
+		if len(types) != len(want) {
+			t.Fatalf("expected history %v, got %v", want, types)
+		}
+		for i := range want {
+			if types[i] != want[i] {
+				t.Fatalf("expected history %v, got %v", want, types)
+			}
+		}
+	})
+}
+
+func TestQueryInvoicesByFacility(t *testing.T) {
+	c := newTestChannel(t)
+	withIssuedInvoice(c)
+	for facilityID, want := range map[string]int{"FARM-1": 1, "PROC-1": 1, "RETAIL-1": 0} {
+		invoices := c.mustEvaluate(regulator, "QueryInvoicesByFacility", facilityID).([]*Invoice)
+		if len(invoices) != want {
+			t.Fatalf("expected %d invoices for %s, got %d", want, facilityID, len(invoices))
+		}
+	}
+}
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"encoding/json"
+	"sort"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// Loại quan hệ giữa asset cha và asset con theo sự kiện tạo ra asset con.
+var lineageEdgeTypes = map[string]string{
+	"CREATED_FROM_PROCESSING": "PROCESSED_INTO",
+	"CREATED_AS_UNIT":         "SPLIT_INTO",
+}
+
+// GetLineageGraph trả về đồ thị nguồn gốc đầy đủ của một asset dưới dạng danh sách nút và cạnh: các asset tổ tiên
+// (theo ParentAssetIDs), các asset hậu duệ, và các lô vận chuyển đã chở hàng giữa chúng.
+// Dùng cho điều tra sự cố; công cụ tools/lineage2dot chuyển kết quả sang Graphviz DOT.
+func (s *SmartContract) GetLineageGraph(ctx contractapi.TransactionContextInterface, assetID string) (*LineageGraph, error) {
+	ancestors, err := s.readAssetLineage(ctx, assetID)
+	if err != nil {
+		return nil, err
+	}
+	assets := make(map[string]*MeatAsset)
+	var assetIDs []string
+	for _, asset := range ancestors {
+		assets[asset.AssetID] = asset
+		assetIDs = append(assetIDs, asset.AssetID)
+	}
+
+	// Các asset hậu duệ (kể cả asset gốc) được coi là phía hạ nguồn
+	downstream := map[string]bool{assetID: true}
+	queue := []string{assetID}
+	for len(queue) > 0 {
+		currentID := queue[0]
+		queue = queue[1:]
+		childIDs, err := queryChildAssetIDs(ctx, currentID)
+		if err != nil {
+			return nil, err
+		}
+		sort.Strings(childIDs)
+		for _, childID := range childIDs {
+			if downstream[childID] {
+				continue
+			}
+			child, err := s.readAsset(ctx, childID)
+			if err != nil {
+				return nil, err
+			}
+			downstream[childID] = true
+			assets[childID] = child
+			assetIDs = append(assetIDs, childID)
+			queue = append(queue, childID)
+		}
+	}
+
+	graph := LineageGraph{RootAssetID: assetID, Nodes: []LineageNode{}, Edges: []LineageEdge{}}
+	shipments := make(map[string]*ShipmentAsset)
+	readShipment := func(shipmentID string) (*ShipmentAsset, error) {
+		if shipment, ok := shipments[shipmentID]; ok {
+			return shipment, nil
+		}
+		shipment, err := s.readShipmentAsset(ctx, shipmentID)
+		if err != nil {
+			return nil, err
+		}
+		shipments[shipmentID] = shipment
+		return shipment, nil
+	}
+
+	var pickups []LineageEdge
+	deliveringShipments := make(map[string]bool)
+	for _, id := range assetIDs {
+		asset := assets[id]
+		originalQuantity, currentQuantity := asset.OriginalQuantity, asset.CurrentQuantity
+		graph.Nodes = append(graph.Nodes, LineageNode{
+			ID:               asset.AssetID,
+			Type:             "ASSET",
+			ProductName:      asset.ProductName,
+			SKU:              asset.SKU,
+			Status:           asset.Status,
+			FacilityID:       asset.OwnerOrg,
+			OriginalQuantity: &originalQuantity,
+			CurrentQuantity:  &currentQuantity,
+		})
+
+		// Cạnh từ asset cha: hàng nhận từ lô vận chuyển được nối qua nút lô vận chuyển
+		if len(asset.History) > 0 {
+			creation := asset.History[0]
+			details, _ := creation.Details.(map[string]interface{})
+			shipmentID, _ := details["shipmentID"].(string)
+			if creation.Type == "RECEIVING" && shipmentID != "" {
+				if _, err := readShipment(shipmentID); err != nil {
+					return nil, err
+				}
+				deliveringShipments[shipmentID] = true
+				graph.Edges = append(graph.Edges, LineageEdge{From: shipmentID, To: asset.AssetID, Type: "DELIVERED", Quantity: &originalQuantity})
+			} else {
+				edgeType, ok := lineageEdgeTypes[creation.Type]
+				if !ok {
+					edgeType = "DERIVED"
+				}
+				for _, parentID := range asset.ParentAssetIDs {
+					if _, ok := assets[parentID]; ok {
+						graph.Edges = append(graph.Edges, LineageEdge{From: parentID, To: asset.AssetID, Type: edgeType, Quantity: &originalQuantity})
+					}
+				}
+			}
+		}
+
+		for _, event := range asset.History {
+			if event.Type != "PICKED_UP_FOR_SHIPMENT" {
+				continue
+			}
+			details, _ := event.Details.(map[string]interface{})
+			shipmentID, _ := details["shipmentID"].(string)
+			if shipmentID == "" {
+				continue
+			}
+			pickups = append(pickups, LineageEdge{From: asset.AssetID, To: shipmentID, Type: "PICKED_UP", Quantity: quantityFromDetails(details["quantity"])})
+		}
+	}
+
+	// Lô vận chuyển của asset tổ tiên chỉ được giữ lại nếu đã giao hàng vào đồ thị;
+	// lô vận chuyển của asset hạ nguồn luôn được giữ lại (kể cả đang trên đường).
+	for _, pickup := range pickups {
+		if !deliveringShipments[pickup.To] && !downstream[pickup.From] {
+			continue
+		}
+		if _, err := readShipment(pickup.To); err != nil {
+			return nil, err
+		}
+		deliveringShipments[pickup.To] = true
+		graph.Edges = append(graph.Edges, pickup)
+	}
+	var shipmentIDs []string
+	for shipmentID := range deliveringShipments {
+		shipmentIDs = append(shipmentIDs, shipmentID)
+	}
+	sort.Strings(shipmentIDs)
+	for _, shipmentID := range shipmentIDs {
+		shipment := shipments[shipmentID]
+		graph.Nodes = append(graph.Nodes, LineageNode{ID: shipment.ShipmentID, Type: "SHIPMENT", Status: shipment.Status})
+	}
+
+	return &graph, nil
+}
+
+// --- Các hàm hỗ trợ cho đồ thị nguồn gốc ---
+
+// quantityFromDetails đọc một Quantity lưu trong chi tiết sự kiện (đã qua JSON), trả về nil nếu không hợp lệ.
+func quantityFromDetails(value interface{}) *Quantity {
+	valueJSON, err := json.Marshal(value)
+	if err != nil {
+		return nil
+	}
+	var quantity Quantity
+	if err := json.Unmarshal(valueJSON, &quantity); err != nil || quantity.Unit == "" {
+		return nil
+	}
+	return &quantity
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"sort"
+	"strings"
+	"testing"
+)
+
+// edgeStrings trả về các cạnh của đồ thị dạng "from -TYPE-> to", đã sắp xếp.
+func edgeStrings(graph *LineageGraph) []string {
+	var edges []string
+	for _, edge := range graph.Edges {
+		edges = append(edges, edge.From+" -"+edge.Type+"-> "+edge.To)
+	}
+	sort.Strings(edges)
+	return edges
+}
+
+// findNode tìm nút theo ID, trả về nil nếu đồ thị không có nút đó.
+func findNode(graph *LineageGraph, id string) *LineageNode {
+	for i := range graph.Nodes {
+		if graph.Nodes[i].ID == id {
+			return &graph.Nodes[i]
+		}
+	}
+	return nil
+}
+
+func TestGetLineageGraph(t *testing.T) {
+	c := newTestChannel(t)
+	atRetailer(c)
+	c.mustSubmit(retailerWorker, "SplitBatchToUnits", "RETAIL-BATCH-0", 2, "UNIT-")
+
+	graph := c.mustEvaluate(regulator, "GetLineageGraph", "TRAY-1").(*LineageGraph)
+	want := []string{
+		"FARM-BATCH-1 -PICKED_UP-> SHIP-1",
+		"PROC-BATCH-0 -PROCESSED_INTO-> TRAY-1",
+		"RETAIL-BATCH-0 -SPLIT_INTO-> UNIT-1",
+		"RETAIL-BATCH-0 -SPLIT_INTO-> UNIT-2",
+		"SHIP-1 -DELIVERED-> PROC-BATCH-0",
+		"SHIP-2 -DELIVERED-> RETAIL-BATCH-0",
+		"TRAY-1 -PICKED_UP-> SHIP-2",
+	}
+	if got := edgeStrings(graph); strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("unexpected edges:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+	if graph.RootAssetID != "TRAY-1" || len(graph.Nodes) != 8 {
+		t.Fatalf("expected 8 nodes rooted at TRAY-1, got %d rooted at %s", len(graph.Nodes), graph.RootAssetID)
+	}
+	if node := findNode(graph, "SHIP-2"); node == nil || node.Type != "SHIPMENT" || node.Status != "COMPLETED" {
+		t.Fatalf("unexpected shipment node %+v", node)
+	}
+	if node := findNode(graph, "RETAIL-BATCH-0"); node.FacilityID != "RETAIL-1" || node.CurrentQuantity.Value != 22 || node.OriginalQuantity.Value != 24 {
+		t.Fatalf("unexpected asset node %+v", node)
+	}
+	for _, edge := range graph.Edges {
+		if edge.Quantity == nil {
+			t.Fatalf("edge %s -> %s has no quantity", edge.From, edge.To)
+		}
+	}
+
+	_, err := c.evaluate(regulator, "GetLineageGraph", "TRAY-9")
+	expectError(t, err, "does not exist")
+}
+
+func TestLineageGraphShipments(t *testing.T) {
+	c := newTestChannel(t)
+	atProcessor(c)
+	c.mustSubmit(processorAdmin, "ProcessAndSplitBatch", "PROC-BATCH-0", []ChildAssetInput{
+		{AssetID: "TRAY-1", ProductName: "Pork belly tray", SKU: skuPorkTray, Quantity: Quantity{Unit: "tray", Value: 24}},
+	}, ProcessingDetails{FacilityName: "Song Than Processing"})
+	c.pickUpAndStart("SHIP-2", processorAdmin, "RETAIL-1", testItem("TRAY-1", "tray", 12))
+
+	// Lô hàng hạ nguồn đang trên đường vẫn được giữ lại
+	graph := c.mustEvaluate(regulator, "GetLineageGraph", "PROC-BATCH-0").(*LineageGraph)
+	if node := findNode(graph, "SHIP-2"); node == nil || node.Status != "IN_TRANSIT" {
+		t.Fatalf("expected in-transit shipment SHIP-2, got %+v", node)
+	}
+
+	// Lô hàng chở asset hạ nguồn của một nhánh khác không thuộc nguồn gốc của FARM-BATCH-1
+	c.createFarmBatch(farmAdmin, "FARM-BATCH-2", skuPorkCarcass, Quantity{Unit: "head", Value: 4})
+	c.pickUpAndStart("SHIP-3", farmAdmin, "PROC-1", testItem("FARM-BATCH-2", "head", 4))
+	graph = c.mustEvaluate(regulator, "GetLineageGraph", "TRAY-1").(*LineageGraph)
+	if findNode(graph, "SHIP-3") != nil || findNode(graph, "FARM-BATCH-2") != nil {
+		t.Fatalf("lineage of TRAY-1 contains an unrelated branch: %v", edgeStrings(graph))
+	}
+}
@@ -6,7 +6,11 @@ import (
 )
 
 func main() {
-	assetChaincode, err := contractapi.NewChaincode(&SmartContract{})
+	contract := &SmartContract{}
+	// Mọi transaction đều đi qua access policy trước khi được thực thi
+	contract.BeforeTransaction = enforceAccessPolicy
+
+	assetChaincode, err := contractapi.NewChaincode(contract)
 	if err != nil {
 		fmt.Printf("Error creating meatcc chaincode: %v", err)
 		return
@@ -0,0 +1,465 @@
+package main
+
+import (
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/hyperledger/fabric-chaincode-go/shim"
+	"github.com/hyperledger/fabric-chaincode-go/shimtest"
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/hyperledger/fabric-protos-go/ledger/queryresult"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// mockStub là world state trong bộ nhớ dùng cho test, dựa trên shimtest.MockStub và bổ sung các phần
+// MockStub chưa hỗ trợ: truy vấn CouchDB theo selector JSON, hash của private data, tên hàm đang được gọi.
+// Ghi dữ liệu theo ngữ nghĩa của peer Fabric: PutState/PutPrivateData chỉ vào write set của transaction,
+// transaction không đọc được dữ liệu chính nó vừa ghi, và write set chỉ được commit khi transaction thành công.
+type mockStub struct {
+	*shimtest.MockStub
+	function   string
+	parameters []string
+	writes     map[string][]byte            // nil = xóa khóa
+	pvtWrites  map[string]map[string][]byte // collection -> khóa -> giá trị
+	// nonMemberPeer giả lập peer của tổ chức không thuộc private data collection: chỉ đọc được hash, không đọc được giá trị.
+	nonMemberPeer bool
+}
+
+func newMockStub() *mockStub {
+	return &mockStub{MockStub: shimtest.NewMockStub("meatcc", nil)}
+}
+
+// startTransaction bắt đầu một transaction mới với txID, thời điểm và hàm được gọi cho trước.
+func (stub *mockStub) startTransaction(txID string, timestamp time.Time, function string, parameters []string, transient map[string][]byte) {
+	stub.MockTransactionStart(txID)
+	stub.TxTimestamp = timestamppb.New(timestamp)
+	stub.TransientMap = transient
+	stub.function = function
+	stub.parameters = parameters
+	stub.writes = make(map[string][]byte)
+	stub.pvtWrites = make(map[string]map[string][]byte)
+}
+
+// endTransaction kết thúc transaction, commit write set nếu commit = true và bỏ đi nếu ngược lại.
+func (stub *mockStub) endTransaction(commit bool) {
+	if commit {
+		keys := make([]string, 0, len(stub.writes))
+		for key := range stub.writes {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			if value := stub.writes[key]; value == nil {
+				_ = stub.MockStub.DelState(key)
+			} else {
+				_ = stub.MockStub.PutState(key, value)
+			}
+		}
+		for collection, writes := range stub.pvtWrites {
+			for key, value := range writes {
+				_ = stub.MockStub.PutPrivateData(collection, key, value)
+			}
+		}
+	}
+	stub.MockTransactionEnd(stub.TxID)
+	stub.TransientMap = nil
+	stub.writes = nil
+	stub.pvtWrites = nil
+}
+
+func (stub *mockStub) GetFunctionAndParameters() (string, []string) {
+	return stub.function, stub.parameters
+}
+
+func (stub *mockStub) PutState(key string, value []byte) error {
+	if stub.writes == nil {
+		return fmt.Errorf("cannot PutState outside of a transaction")
+	}
+	if key == "" {
+		return fmt.Errorf("key must not be an empty string")
+	}
+	// Giá trị rỗng được peer coi là xóa khóa
+	stub.writes[key] = append([]byte(nil), value...)
+	return nil
+}
+
+func (stub *mockStub) DelState(key string) error {
+	if stub.writes == nil {
+		return fmt.Errorf("cannot DelState outside of a transaction")
+	}
+	stub.writes[key] = nil
+	return nil
+}
+
+func (stub *mockStub) PutPrivateData(collection string, key string, value []byte) error {
+	if stub.pvtWrites == nil {
+		return fmt.Errorf("cannot PutPrivateData outside of a transaction")
+	}
+	if stub.pvtWrites[collection] == nil {
+		stub.pvtWrites[collection] = make(map[string][]byte)
+	}
+	stub.pvtWrites[collection][key] = append([]byte(nil), value...)
+	return nil
+}
+
+func (stub *mockStub) GetPrivateData(collection string, key string) ([]byte, error) {
+	if stub.nonMemberPeer {
+		return nil, fmt.Errorf("peer is not a member of collection %s", collection)
+	}
+	return stub.MockStub.GetPrivateData(collection, key)
+}
+
+func (stub *mockStub) GetPrivateDataHash(collection string, key string) ([]byte, error) {
+	value, err := stub.MockStub.GetPrivateData(collection, key)
+	if err != nil || value == nil {
+		return nil, err
+	}
+	hash := sha256.Sum256(value)
+	return hash[:], nil
+}
+
+// GetQueryResult thực thi một truy vấn CouchDB (Mango) trên world state đã commit.
+// Hỗ trợ selector với đường dẫn có dấu chấm, đối tượng lồng nhau và các toán tử $eq, $ne, $gt, $gte, $lt, $lte,
+// $in, $nin, $exists, $regex, $size, $elemMatch, $allMatch, $and, $or, $nor, $not; cùng với fields, limit, skip.
+// Kết quả được sắp theo khóa, giống thứ tự _id mặc định của CouchDB.
+func (stub *mockStub) GetQueryResult(query string) (shim.StateQueryIteratorInterface, error) {
+	var parsed struct {
+		Selector map[string]interface{} `json:"selector"`
+		Fields   []string               `json:"fields"`
+		Limit    int                    `json:"limit"`
+		Skip     int                    `json:"skip"`
+		Sort     []interface{}          `json:"sort"`
+		UseIndex interface{}            `json:"use_index"`
+	}
+	decoder := json.NewDecoder(strings.NewReader(query))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&parsed); err != nil {
+		return nil, fmt.Errorf("invalid query: %v", err)
+	}
+	if parsed.Selector == nil {
+		return nil, fmt.Errorf("invalid query: selector is required")
+	}
+	if len(parsed.Sort) > 0 {
+		return nil, fmt.Errorf("sort is not supported by the mock query engine")
+	}
+
+	var results []*queryresult.KV
+	for element := stub.Keys.Front(); element != nil; element = element.Next() {
+		key := element.Value.(string)
+		var document map[string]interface{}
+		if err := json.Unmarshal(stub.State[key], &document); err != nil {
+			continue // CouchDB chỉ lập chỉ mục các giá trị JSON
+		}
+		matched, err := matchCondition(document, parsed.Selector)
+		if err != nil {
+			return nil, err
+		}
+		if !matched {
+			continue
+		}
+		value := stub.State[key]
+		if len(parsed.Fields) > 0 {
+			value, _ = json.Marshal(projectFields(document, parsed.Fields))
+		}
+		results = append(results, &queryresult.KV{Key: key, Value: value})
+	}
+	if parsed.Skip > 0 {
+		if parsed.Skip >= len(results) {
+			results = nil
+		} else {
+			results = results[parsed.Skip:]
+		}
+	}
+	if parsed.Limit > 0 && parsed.Limit < len(results) {
+		results = results[:parsed.Limit]
+	}
+	return &mockQueryIterator{results: results}, nil
+}
+
+// mockQueryIterator duyệt kết quả của GetQueryResult.
+type mockQueryIterator struct {
+	results []*queryresult.KV
+	next    int
+}
+
+func (it *mockQueryIterator) HasNext() bool {
+	return it.next < len(it.results)
+}
+
+func (it *mockQueryIterator) Next() (*queryresult.KV, error) {
+	if !it.HasNext() {
+		return nil, fmt.Errorf("no more results")
+	}
+	it.next++
+	return it.results[it.next-1], nil
+}
+
+func (it *mockQueryIterator) Close() error {
+	return nil
+}
+
+// matchCondition kiểm tra một giá trị JSON thỏa mãn một điều kiện Mango: toán tử ($...) áp dụng cho chính giá trị,
+// tên trường (có thể có dấu chấm) áp dụng cho trường tương ứng, giá trị không phải đối tượng là phép so sánh bằng.
+func matchCondition(value interface{}, condition interface{}) (bool, error) {
+	conditionMap, ok := condition.(map[string]interface{})
+	if !ok {
+		return value != nil && reflect.DeepEqual(value, condition), nil
+	}
+	for key, operand := range conditionMap {
+		var matched bool
+		var err error
+		if strings.HasPrefix(key, "$") {
+			matched, err = matchOperator(value, key, operand)
+		} else {
+			fieldValue, found := lookupField(value, key)
+			if !found {
+				// Trường không tồn tại chỉ thỏa mãn {"$exists": false}
+				fieldValue = nil
+			}
+			matched, err = matchCondition(fieldValue, operand)
+		}
+		if err != nil || !matched {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+// matchOperator áp dụng một toán tử Mango cho giá trị.
+func matchOperator(value interface{}, operator string, operand interface{}) (bool, error) {
+	switch operator {
+	case "$eq":
+		return value != nil && reflect.DeepEqual(value, operand), nil
+	case "$ne":
+		return value != nil && !reflect.DeepEqual(value, operand), nil
+	case "$gt", "$gte", "$lt", "$lte":
+		comparison, ok := compareValues(value, operand)
+		if !ok {
+			return false, nil
+		}
+		switch operator {
+		case "$gt":
+			return comparison > 0, nil
+		case "$gte":
+			return comparison >= 0, nil
+		case "$lt":
+			return comparison < 0, nil
+		default:
+			return comparison <= 0, nil
+		}
+	case "$in", "$nin":
+		candidates, ok := operand.([]interface{})
+		if !ok {
+			return false, fmt.Errorf("%s requires an array", operator)
+		}
+		found := false
+		for _, candidate := range candidates {
+			if value != nil && reflect.DeepEqual(value, candidate) {
+				found = true
+				break
+			}
+		}
+		if operator == "$in" {
+			return found, nil
+		}
+		return value != nil && !found, nil
+	case "$exists":
+		exists, ok := operand.(bool)
+		if !ok {
+			return false, fmt.Errorf("$exists requires a boolean")
+		}
+		return (value != nil) == exists, nil
+	case "$regex":
+		pattern, ok := operand.(string)
+		if !ok {
+			return false, fmt.Errorf("$regex requires a string")
+		}
+		text, ok := value.(string)
+		if !ok {
+			return false, nil
+		}
+		return regexp.MatchString(pattern, text)
+	case "$size":
+		size, ok := operand.(float64)
+		array, isArray := value.([]interface{})
+		if !ok {
+			return false, fmt.Errorf("$size requires a number")
+		}
+		return isArray && float64(len(array)) == size, nil
+	case "$elemMatch", "$allMatch":
+		array, ok := value.([]interface{})
+		if !ok {
+			return false, nil
+		}
+		if operator == "$allMatch" && len(array) == 0 {
+			return false, nil
+		}
+		for _, element := range array {
+			matched, err := matchCondition(element, operand)
+			if err != nil {
+				return false, err
+			}
+			if operator == "$elemMatch" && matched {
+				return true, nil
+			}
+			if operator == "$allMatch" && !matched {
+				return false, nil
+			}
+		}
+		return operator == "$allMatch", nil
+	case "$and", "$or", "$nor":
+		conditions, ok := operand.([]interface{})
+		if !ok {
+			return false, fmt.Errorf("%s requires an array", operator)
+		}
+		for _, condition := range conditions {
+			matched, err := matchCondition(value, condition)
+			if err != nil {
+				return false, err
+			}
+			if operator == "$and" && !matched {
+				return false, nil
+			}
+			if operator != "$and" && matched {
+				return operator == "$or", nil
+			}
+		}
+		return operator != "$or", nil
+	case "$not":
+		matched, err := matchCondition(value, operand)
+		return !matched, err
+	}
+	return false, fmt.Errorf("unsupported query operator %s", operator)
+}
+
+// compareValues so sánh hai số hoặc hai chuỗi; ok = false nếu hai giá trị không cùng kiểu.
+func compareValues(a interface{}, b interface{}) (int, bool) {
+	switch a := a.(type) {
+	case float64:
+		b, ok := b.(float64)
+		if !ok {
+			return 0, false
+		}
+		switch {
+		case a < b:
+			return -1, true
+		case a > b:
+			return 1, true
+		}
+		return 0, true
+	case string:
+		b, ok := b.(string)
+		if !ok {
+			return 0, false
+		}
+		return strings.Compare(a, b), true
+	}
+	return 0, false
+}
+
+// lookupField đọc một trường theo đường dẫn có dấu chấm, ví dụ "details.facilityID".
+func lookupField(value interface{}, path string) (interface{}, bool) {
+	for _, name := range strings.Split(path, ".") {
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		if value, ok = object[name]; !ok {
+			return nil, false
+		}
+	}
+	return value, true
+}
+
+// projectFields chỉ giữ lại các trường được liệt kê trong "fields" của truy vấn.
+func projectFields(document map[string]interface{}, fields []string) map[string]interface{} {
+	projected := make(map[string]interface{})
+	for _, field := range fields {
+		value, found := lookupField(document, field)
+		if !found {
+			continue
+		}
+		target := projected
+		names := strings.Split(field, ".")
+		for _, name := range names[:len(names)-1] {
+			next, ok := target[name].(map[string]interface{})
+			if !ok {
+				next = make(map[string]interface{})
+				target[name] = next
+			}
+			target = next
+		}
+		target[names[len(names)-1]] = value
+	}
+	return projected
+}
+
+// mockIdentity là danh tính client giả lập: MSP, các thuộc tính trong chứng chỉ và CN (enrollment ID).
+type mockIdentity struct {
+	mspID      string
+	commonName string
+	attributes map[string]string
+}
+
+func (id *mockIdentity) GetID() (string, error) {
+	dn := fmt.Sprintf("x509::CN=%s::CN=ca.%s", id.commonName, strings.ToLower(id.mspID))
+	return base64.StdEncoding.EncodeToString([]byte(dn)), nil
+}
+
+func (id *mockIdentity) GetMSPID() (string, error) {
+	return id.mspID, nil
+}
+
+func (id *mockIdentity) GetAttributeValue(name string) (string, bool, error) {
+	value, found := id.attributes[name]
+	return value, found, nil
+}
+
+func (id *mockIdentity) AssertAttributeValue(name string, value string) error {
+	actual, found := id.attributes[name]
+	if !found {
+		return fmt.Errorf("attribute '%s' was not found", name)
+	}
+	if actual != value {
+		return fmt.Errorf("attribute '%s' equals '%s', not '%s'", name, actual, value)
+	}
+	return nil
+}
+
+func (id *mockIdentity) GetX509Certificate() (*x509.Certificate, error) {
+	return &x509.Certificate{
+		Subject: pkix.Name{CommonName: id.commonName},
+		Issuer:  pkix.Name{CommonName: "ca." + strings.ToLower(id.mspID)},
+	}, nil
+}
+
+// with trả về bản sao của danh tính với một thuộc tính được thay đổi (giá trị rỗng = xóa thuộc tính).
+func (id *mockIdentity) with(name string, value string) *mockIdentity {
+	clone := &mockIdentity{mspID: id.mspID, commonName: id.commonName, attributes: make(map[string]string)}
+	for key, attribute := range id.attributes {
+		clone.attributes[key] = attribute
+	}
+	if value == "" {
+		delete(clone.attributes, name)
+	} else {
+		clone.attributes[name] = value
+	}
+	return clone
+}
+
+// newMockContext tạo transaction context dùng stub và danh tính giả lập.
+func newMockContext(stub *mockStub, identity *mockIdentity) *contractapi.TransactionContext {
+	ctx := new(contractapi.TransactionContext)
+	ctx.SetStub(stub)
+	ctx.SetClientIdentity(identity)
+	return ctx
+}
@@ -0,0 +1,3 @@
+module github.com/your-repo/meatcc/models
+
+go 1.19
@@ -0,0 +1,527 @@
+// Package models chứa các kiểu dữ liệu dùng chung giữa chaincode và Go client.
+// Package nằm trong module riêng để client chỉ phụ thuộc vào các kiểu dữ liệu, không kéo theo module chaincode;
+// module chưa được phát hành nên client và chaincode dùng bản trong repo qua chỉ thị replace.
+package models
+
+// Quantity định nghĩa đơn vị và giá trị số lượng.
+type Quantity struct {
+	Unit  string  `json:"unit"`
+	Value float64 `json:"value"`
+}
+
+// MediaPointer là tham chiếu đến file media lưu ngoài blockchain.
+type MediaPointer struct {
+	URL      string `json:"url"`
+	MimeType string `json:"mimeType"`
+}
+
+// Certificate lưu trữ thông tin về chứng nhận.
+type Certificate struct {
+	Name  string       `json:"name"`
+	Media MediaPointer `json:"media"`
+}
+
+// Address lưu trữ thông tin địa chỉ.
+type Address struct {
+	FullText  string  `json:"fullText"`
+	Latitude  float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
+}
+
+// Feed lưu trữ thông tin về thức ăn.
+type Feed struct {
+    Name         string  `json:"name"`          // Tên loại thức ăn (vd: "Green Feed tập ăn")
+    DosageKg     float64 `json:"dosageKg"`      // Liều lượng mỗi ngày (kg/con hoặc kg/tổng đàn)
+    StartDate    string  `json:"startDate"`     // Ngày bắt đầu sử dụng (YYYY-MM-DD)
+    EndDate      string  `json:"endDate"`       // (Tùy chọn) Ngày kết thúc sử dụng
+    Notes        string  `json:"notes"`         // (Tùy chọn) Ghi chú thêm như "giai đoạn tập ăn", "tăng trọng"
+}
+
+// Medication lưu trữ thông tin về thuốc và chất bổ sung.
+type Medication struct {
+    Name         string  `json:"name"` 		// Tên thuốc/chất bổ sung (vd: "Vitamin C", "Thuốc giảm đau")
+    Dose         string  `json:"dose"` 	   // Liều dùng (vd: "500mg", "2ml/con")
+    DateApplied  string  `json:"dateApplied"` // Ngày áp dụng (YYYY-MM-DD)
+    NextDueDate  string  `json:"nextDueDate"` // (Tùy chọn) Ngày cần áp dụng tiếp theo
+}
+
+// FarmDetails lưu thông tin giai đoạn nuôi/trồng tại trang trại.
+type FarmDetails struct {
+	FacilityID   string         `json:"facilityID"`
+	FacilityName string         `json:"facilityName"`
+	Address      Address        `json:"address"`
+	SowingDate   string         `json:"sowingDate"`
+	StartDate    string         `json:"startDate"`
+	ExpectedHarvestDate string         `json:"expectedHarvestDate"`
+	HarvestDate  string         `json:"harvestDate"`
+	Feeds        []Feed         `json:"feeds"` 
+	Medications   []Medication  `json:"medications"` 
+	Certificates []Certificate `json:"certificates"`
+}
+
+// ProcessingStep mô tả một bước trong quá trình chế biến.
+type ProcessingStep struct {
+	Name      string `json:"name"`
+	Technique string `json:"technique"`
+	Timestamp string `json:"timestamp"`
+}
+
+// ProcessingDetails lưu thông tin về quá trình chế biến.
+type ProcessingDetails struct {
+	ProcessorOrgName string           `json:"processorOrgName"`
+	FacilityName     string           `json:"facilityName"`
+	Address          Address          `json:"address"`
+	Steps            []ProcessingStep `json:"steps"`
+	Certificates     []Certificate    `json:"certificates"`
+}
+
+// ShipmentTimeline lưu mốc thời gian trong quá trình vận chuyển.
+type ShipmentTimeline struct {
+	Type      string `json:"type"`
+	Timestamp string `json:"timestamp"`
+	Location  string `json:"location,omitempty"`
+	FacilityID string `json:"facilityID"`
+	Proof     map[string]interface{} `json:"proof"`
+	Geofence  *GeofenceCheck         `json:"geofence,omitempty"`
+}
+
+// GeofenceCheck lưu kết quả đối chiếu tọa độ của bằng chứng với vị trí cơ sở.
+type GeofenceCheck struct {
+	Status         string  `json:"status"` // INSIDE, OUTSIDE, NO_COORDINATES, FACILITY_LOCATION_UNKNOWN
+	DistanceMeters float64 `json:"distanceMeters"`
+	RadiusMeters   float64 `json:"radiusMeters"`
+	Latitude       float64 `json:"latitude"`
+	Longitude      float64 `json:"longitude"`
+}
+
+// GeofenceConfig là cấu hình kiểm tra vị trí dùng chung cho toàn kênh.
+type GeofenceConfig struct {
+	ObjectType   string  `json:"docType"`
+	RadiusMeters float64 `json:"radiusMeters"`
+	Mode         string  `json:"mode"` // REJECT: từ chối bằng chứng ngoài vùng, FLAG: chỉ đánh dấu
+}
+
+// AccessRule quy định danh tính nào được gọi một transaction. Danh sách rỗng nghĩa là không giới hạn theo tiêu chí đó.
+type AccessRule struct {
+	Transaction   string   `json:"transaction"`
+	Roles         []string `json:"roles,omitempty"`
+	FacilityTypes []string `json:"facilityTypes,omitempty"`
+	MSPs          []string `json:"msps,omitempty"`
+}
+
+// AccessPolicy là access policy đầy đủ được lưu trên ledger, gồm một AccessRule cho mỗi transaction.
+type AccessPolicy struct {
+	ObjectType string       `json:"docType"`
+	Rules      []AccessRule `json:"rules"`
+}
+
+// StorageDetails lưu thông tin về quá trình lưu kho.
+type StorageDetails struct {
+	OwnerOrgName    string `json:"ownerOrgName"`
+	FacilityName    string `json:"facilityName"`
+	Address         Address `json:"address"`
+	LocationInStore string `json:"locationInStore,omitempty"`
+	Temperature     string `json:"temperature,omitempty"`
+	Note            string `json:"note"`
+}
+
+// SoldDetails lưu thông tin về việc bán hàng cuối cùng.
+type SoldDetails struct {
+	RetailerOrgName string `json:"retailerOrgName"`
+	FacilityName    string `json:"facilityName"`
+	Address         Address `json:"address"`
+	SaleTimestamp   string `json:"saleTimestamp"`
+}
+
+// Event lưu lại sự kiện quan trọng trong vòng đời của asset.
+type Event struct {
+	Type      string      `json:"type"`
+	ActorMSP  string      `json:"actorMSP"`
+	ActorID   string      `json:"actorID"`
+	Timestamp string      `json:"timestamp"`
+	TxID      string      `json:"txID"`
+	Details   interface{} `json:"details"`
+}
+
+// MeatAsset là đối tượng chính để truy xuất nguồn gốc sản phẩm.
+type MeatAsset struct {
+	ObjectType          string        `json:"docType"`
+	AssetID             string        `json:"assetID"`
+	SKU                 string        `json:"sku"`
+	GTIN                string        `json:"gtin,omitempty"`                // GTIN-14 của sản phẩm (GS1 AI 01)
+	LotNumber           string        `json:"lotNumber,omitempty"`           // Số lô (GS1 AI 10)
+	SerialNumber        string        `json:"serialNumber,omitempty"`        // Số sê-ri của đơn vị bán lẻ (GS1 AI 21)
+	AverageWeight       Weight        `json:"averageWeight"`
+	ParentAssetIDs      []string      `json:"parentAssetIDs"`
+	ProductName         string        `json:"productName"`
+	Status              string        `json:"status"`
+	StatusBeforeHold    string        `json:"statusBeforeHold,omitempty"`    // Trạng thái sẽ được khôi phục khi gỡ tạm giữ
+	OwnerOrg            string        `json:"ownerOrg"`
+	OwnerMSP            string        `json:"ownerMSP,omitempty"`            // Tổ chức sở hữu, cùng với cơ quan quản lý, phải xác nhận (endorse) mọi thay đổi của asset
+	OriginalQuantity    Quantity      `json:"originalQuantity"`
+	CurrentQuantity     Quantity      `json:"currentQuantity"`
+	ReservedQuantity    Quantity      `json:"reservedQuantity"`              // Tổng số lượng đang được giữ chỗ
+	AvailableQuantity   Quantity      `json:"availableQuantity"`             // Số lượng còn có thể cam kết (available-to-promise)
+	Reservations        []Reservation `json:"reservations,omitempty"`        // Số lượng đã được giữ chỗ cho các đơn hàng và lô hàng
+	CommercialTermsHash string        `json:"commercialTermsHash,omitempty"` // Hash SHA-256 của hợp đồng cung ứng lưu trong private data
+	History             []Event       `json:"history"`
+}
+
+// Reservation mô tả một phần số lượng của asset đã được giữ chỗ cho một tham chiếu (ví dụ một lô hàng).
+type Reservation struct {
+	ReferenceType string   `json:"referenceType"` // ORDER hoặc SHIPMENT
+	ReferenceID   string   `json:"referenceID"`
+	Quantity      Quantity `json:"quantity"`
+	Timestamp     string   `json:"timestamp"`
+}
+
+// ItemInShipment mô tả một sản phẩm nằm trong lô vận chuyển.
+type ItemInShipment struct {
+	AssetID  string   `json:"assetID"`
+	Quantity Quantity `json:"quantity"`
+	POID     string   `json:"poID,omitempty"`     // (Tùy chọn) Đơn đặt hàng mà hàng giao này đáp ứng
+	POLineID string   `json:"poLineID,omitempty"` // (Tùy chọn) Dòng của đơn đặt hàng
+}
+
+// StopInJourney mô tả một điểm dừng trong hành trình vận chuyển.
+type StopInJourney struct {
+	FacilityID           string           `json:"facilityID"`
+	FacilityName         string           `json:"facilityName"`    // <-- THÊM MỚI
+	FacilityAddress      Address          `json:"facilityAddress"` // <-- THÊM MỚI
+	Action               string           `json:"action"`
+	Status               string           `json:"status"`
+	Items                []ItemInShipment `json:"items"`
+	OutOfOrderAllowed    bool             `json:"outOfOrderAllowed"`              // Cho phép hoàn tất điểm dừng không theo thứ tự lộ trình
+	GeofenceRadiusMeters float64          `json:"geofenceRadiusMeters,omitempty"` // (Tùy chọn) Thu hẹp bán kính geofence của kênh
+	ArrivalSealIDs       []string         `json:"arrivalSealIDs,omitempty"`       // Số niêm phong bên nhận ghi nhận khi hàng đến
+	SealStatus           string           `json:"sealStatus,omitempty"`           // SEAL_INTACT hoặc SEAL_BROKEN
+	ResealedSealIDs      []string         `json:"resealedSealIDs,omitempty"`      // Niêm phong mới do cơ sở nhận hàng ghi sau khi mở container
+	PlannedWindowStart   string           `json:"plannedWindowStart,omitempty"`   // (Tùy chọn) Đầu khung giờ dự kiến (RFC3339)
+	PlannedWindowEnd     string           `json:"plannedWindowEnd,omitempty"`     // (Tùy chọn) Cuối khung giờ dự kiến (RFC3339)
+	ActualTime           string           `json:"actualTime,omitempty"`           // Thời điểm xác nhận thực tế (UTC, RFC3339)
+	Late                 bool             `json:"late"`                           // Xác nhận sau cuối khung giờ dự kiến
+	LatenessMinutes      float64          `json:"latenessMinutes"`                // Số phút trễ so với cuối khung giờ
+}
+
+// ManifestItem mô tả hàng thực tế đã được bốc lên xe tại các điểm lấy hàng.
+type ManifestItem struct {
+	AssetID           string   `json:"assetID"`
+	SourceFacilityID  string   `json:"sourceFacilityID"`
+	LoadedQuantity    Quantity `json:"loadedQuantity"`
+	DeliveredQuantity Quantity `json:"deliveredQuantity"`
+}
+
+// ShipmentAsset mô tả một lô vận chuyển.
+type ShipmentAsset struct {
+	ObjectType          string             `json:"docType"`
+	ShipmentID          string             `json:"shipmentID"`
+	ShipmentType        string             `json:"shipmentType"`
+	DriverEnrollmentID  string             `json:"driverEnrollmentID"`
+	DriverName          string             `json:"driverName"`
+	VehiclePlate        string             `json:"vehiclePlate"`
+	SealIDs             []string           `json:"sealIDs,omitempty"`
+	Status              string             `json:"status"`
+	Stops               []StopInJourney    `json:"stops"`
+	Manifest            []ManifestItem     `json:"manifest,omitempty"`
+	Timeline            []ShipmentTimeline `json:"timeline"`
+	CommercialTermsHash string             `json:"commercialTermsHash,omitempty"` // Hash SHA-256 của điều khoản vận chuyển lưu trong private data
+	History             []Event            `json:"history"`
+}
+
+// ChildAssetInput dùng cho các hàm tách lô sản phẩm.
+type ChildAssetInput struct {
+	AssetID     string   `json:"assetID"`
+	ProductName string   `json:"productName"`
+	SKU         string   `json:"sku"`
+	Quantity    Quantity `json:"quantity"`
+}
+
+// FullAssetTrace là cấu trúc trả về khi truy xuất nguồn gốc asset.
+type FullAssetTrace struct {
+	AssetID          string   `json:"assetID"`
+	ParentAssetIDs   []string `json:"parentAssetIDs"`
+	ProductName      string   `json:"productName"`
+	Status           string   `json:"status"`
+	OriginalQuantity Quantity `json:"originalQuantity"`
+	CurrentQuantity  Quantity `json:"currentQuantity"`
+	FullHistory      []Event  `json:"fullHistory"`
+}
+
+// Weight lưu thông tin cân nặng.
+type Weight struct {
+	Value float64 `json:"value"`
+	Unit  string  `json:"unit"` // e.g., "kg", "g", "lb"
+}
+
+// Product defines a product in the catalog.
+type Product struct {
+	ObjectType    string  `json:"docType"`
+	SKU           string  `json:"sku"`
+	GTIN          string  `json:"gtin,omitempty"` // GTIN-14 dùng cho mã GS1 khi SKU không phải là một GTIN
+	Name          string  `json:"name"`
+	Description   string  `json:"description"`
+	Unit          string  `json:"unit"` // e.g., "box", "tray", "piece"
+	AverageWeight Weight  `json:"averageWeight"` 
+	SourceType    string  `json:"sourceType"` //BEEF, PORK, CHICKEN
+	Category      string  `json:"category"`   //RAW_MATERIAL, FINISHED_GOOD
+	Active        bool    `json:"active"`
+}
+// LocationPing là một điểm GPS do ứng dụng của tài xế gửi lên trong quá trình vận chuyển.
+type LocationPing struct {
+	ObjectType string  `json:"docType,omitempty"`
+	ShipmentID string  `json:"shipmentID,omitempty"`
+	Latitude   float64 `json:"latitude"`
+	Longitude  float64 `json:"longitude"`
+	SpeedKmh   float64 `json:"speedKmh"`
+	Timestamp  string  `json:"timestamp"`
+}
+
+// ShipmentTrack là lộ trình thực tế của lô vận chuyển dựng lại từ các điểm GPS.
+type ShipmentTrack struct {
+	ShipmentID      string         `json:"shipmentID"`
+	Points          []LocationPing `json:"points"`
+	TotalDistanceKm float64        `json:"totalDistanceKm"`
+}
+
+// PurchaseOrderLine là một dòng hàng (SKU, số lượng) trong đơn đặt hàng.
+type PurchaseOrderLine struct {
+	LineID            string   `json:"lineID"`
+	SKU               string   `json:"sku"`
+	Quantity          Quantity `json:"quantity"`
+	DeliveredQuantity Quantity `json:"deliveredQuantity"`
+	Variance          float64  `json:"variance"` // Đã giao trừ đã đặt: âm là giao thiếu, dương là giao thừa
+	Status            string   `json:"status"`   // OPEN, PARTIALLY_DELIVERED, DELIVERED, OVER_DELIVERED
+}
+
+// PurchaseOrder là đơn đặt hàng giữa cơ sở mua và cơ sở bán.
+type PurchaseOrder struct {
+	ObjectType            string              `json:"docType"`
+	POID                  string              `json:"poID"`
+	BuyerFacilityID       string              `json:"buyerFacilityID"`
+	SellerFacilityID      string              `json:"sellerFacilityID"`
+	RequestedDeliveryDate string              `json:"requestedDeliveryDate"`
+	Status                string              `json:"status"` // CREATED, ACCEPTED, REJECTED, PARTIALLY_FULFILLED, FULFILLED
+	Lines                 []PurchaseOrderLine `json:"lines"`
+	CommercialTermsHash   string              `json:"commercialTermsHash,omitempty"` // Hash SHA-256 của giá đơn hàng lưu trong private data
+	History               []Event             `json:"history"`
+}
+
+// CommercialTerms là phần dữ liệu thương mại (giá, số tiền, điều khoản hợp đồng, danh tính bên mua)
+// được lưu trong private data collection; ledger công khai chỉ giữ hash SHA-256 của nó.
+type CommercialTerms struct {
+	ObjectType       string      `json:"docType"`
+	ReferenceType    string      `json:"referenceType"` // PURCHASE_ORDER, ASSET, SHIPMENT, INVOICE
+	ReferenceID      string      `json:"referenceID"`
+	SellerFacilityID string      `json:"sellerFacilityID"`
+	BuyerFacilityID  string      `json:"buyerFacilityID"`
+	BuyerName        string      `json:"buyerName,omitempty"`
+	Currency         string      `json:"currency"`
+	LinePrices       []LinePrice `json:"linePrices,omitempty"`
+	TotalAmount      float64     `json:"totalAmount,omitempty"`
+	ContractTerms    string      `json:"contractTerms,omitempty"`
+	Salt             string      `json:"salt"` // Chuỗi ngẫu nhiên do client gửi qua transient map để hash công khai không dò ngược được
+	UpdatedAt        string      `json:"updatedAt"`
+}
+
+// LinePrice là đơn giá áp dụng cho một dòng đơn đặt hàng hoặc một SKU.
+type LinePrice struct {
+	LineID    string  `json:"lineID,omitempty"`
+	SKU       string  `json:"sku"`
+	UnitPrice float64 `json:"unitPrice"`
+}
+
+// InvoiceLine là số lượng đã giao được lập hóa đơn cho một dòng đơn đặt hàng.
+type InvoiceLine struct {
+	LineID   string   `json:"lineID"`
+	SKU      string   `json:"sku"`
+	Quantity Quantity `json:"quantity"`
+}
+
+// Invoice là hóa đơn thanh toán được lập từ số lượng đã giao của một lô hàng theo một đơn đặt hàng.
+// Số tiền được lưu trong private data collection; tài liệu công khai chỉ giữ hash.
+type Invoice struct {
+	ObjectType          string        `json:"docType"`
+	InvoiceID           string        `json:"invoiceID"`
+	POID                string        `json:"poID"`
+	ShipmentID          string        `json:"shipmentID"`
+	SellerFacilityID    string        `json:"sellerFacilityID"`
+	BuyerFacilityID     string        `json:"buyerFacilityID"`
+	Lines               []InvoiceLine `json:"lines"`
+	DueDate             string        `json:"dueDate"`
+	Status              string        `json:"status"` // ISSUED, DISPUTED, ACCEPTED, PAID
+	PaymentReference    string        `json:"paymentReference,omitempty"`
+	CommercialTermsHash string        `json:"commercialTermsHash"` // Hash SHA-256 của số tiền hóa đơn lưu trong private data
+	History             []Event       `json:"history"`
+}
+
+// RegulatoryStatus là hồ sơ quản lý của một asset do cơ quan quản lý ghi, lưu dưới khóa riêng
+// để tách khỏi dữ liệu do chuỗi cung ứng ghi.
+type RegulatoryStatus struct {
+	ObjectType string  `json:"docType"`
+	AssetID    string  `json:"assetID"`
+	Status     string  `json:"status"` // CLEAR, HOLD, RECALLED
+	Reason     string  `json:"reason,omitempty"`
+	RecallID   string  `json:"recallID,omitempty"`
+	Events     []Event `json:"events"`
+}
+
+// ConsumerTrace là hành trình đã được lược bớt thông tin nội bộ của một sản phẩm,
+// dùng cho trang thông tin người tiêu dùng (quét mã QR).
+type ConsumerTrace struct {
+	AssetID         string              `json:"assetID"`
+	ProductName     string              `json:"productName"`
+	SKU             string              `json:"sku"`
+	FarmName        string              `json:"farmName,omitempty"`
+	FarmRegion      string              `json:"farmRegion,omitempty"`
+	HarvestDate     string              `json:"harvestDate,omitempty"`
+	Processors      []string            `json:"processors,omitempty"`
+	Certificates    []string            `json:"certificates,omitempty"`
+	ColdChainStatus string              `json:"coldChainStatus"` // OK, EXCURSION, UNKNOWN
+	SaleDate        string              `json:"saleDate,omitempty"`
+	RecallNotice    string              `json:"recallNotice,omitempty"`
+	Journey         []ConsumerTraceStep `json:"journey"`
+}
+
+// ConsumerTraceStep là một chặng trong hành trình hiển thị cho người tiêu dùng.
+type ConsumerTraceStep struct {
+	Stage   string                 `json:"stage"`
+	Date    string                 `json:"date"`
+	Region  string                 `json:"region,omitempty"`
+	Details map[string]interface{} `json:"details,omitempty"`
+}
+
+// EPCISDocument là tài liệu GS1 EPCIS 2.0 (JSON-LD) dùng để trao đổi sự kiện truy xuất nguồn gốc với đối tác.
+type EPCISDocument struct {
+	Context       interface{}   `json:"@context"` // URL hoặc danh sách ngữ cảnh JSON-LD
+	Type          string        `json:"type"` // EPCISDocument
+	SchemaVersion string        `json:"schemaVersion"`
+	CreationDate  string        `json:"creationDate"`
+	EPCISBody     EPCISBody     `json:"epcisBody"`
+}
+
+// EPCISBody chứa danh sách sự kiện của tài liệu EPCIS.
+type EPCISBody struct {
+	EventList []EPCISEvent `json:"eventList"`
+}
+
+// EPCISEvent là một sự kiện EPCIS 2.0; chỉ các trường phù hợp với loại sự kiện được điền
+// (ObjectEvent, AggregationEvent, TransformationEvent).
+type EPCISEvent struct {
+	Type                string                `json:"type"`
+	EventID             string                `json:"eventID,omitempty"`
+	EventTime           string                `json:"eventTime"`
+	EventTimeZoneOffset string                `json:"eventTimeZoneOffset"`
+	ParentID            string                `json:"parentID,omitempty"`
+	EPCList             []string              `json:"epcList,omitempty"`
+	ChildEPCs           []string              `json:"childEPCs,omitempty"`
+	QuantityList        []EPCISQuantity       `json:"quantityList,omitempty"`
+	ChildQuantityList   []EPCISQuantity       `json:"childQuantityList,omitempty"`
+	InputEPCList        []string              `json:"inputEPCList,omitempty"`
+	InputQuantityList   []EPCISQuantity       `json:"inputQuantityList,omitempty"`
+	OutputEPCList       []string              `json:"outputEPCList,omitempty"`
+	OutputQuantityList  []EPCISQuantity       `json:"outputQuantityList,omitempty"`
+	Action              string                `json:"action,omitempty"` // ADD, OBSERVE, DELETE
+	BizStep             string                `json:"bizStep,omitempty"`
+	Disposition         string                `json:"disposition,omitempty"`
+	ReadPoint           *EPCISLocation        `json:"readPoint,omitempty"`
+	BizLocation         *EPCISLocation        `json:"bizLocation,omitempty"`
+	BizTransactionList  []EPCISBizTransaction `json:"bizTransactionList,omitempty"`
+	SourceList          []EPCISSource         `json:"sourceList,omitempty"`
+	DestinationList     []EPCISDestination    `json:"destinationList,omitempty"`
+	SensorElementList   []EPCISSensorElement  `json:"sensorElementList,omitempty"`
+	ChaincodeEventType  string                `json:"meatcc:eventType,omitempty"` // Loại sự kiện gốc trong chaincode
+	ChaincodeTxID       string                `json:"meatcc:txID,omitempty"`      // Giao dịch Fabric đã ghi sự kiện
+	FarmDetails         *FarmDetails          `json:"meatcc:farmDetails,omitempty"`       // (Nhập) Chi tiết trang trại của sự kiện commissioning
+	ProcessingDetails   *ProcessingDetails    `json:"meatcc:processingDetails,omitempty"` // (Nhập) Chi tiết chế biến của TransformationEvent
+	SealIDs             []string              `json:"meatcc:sealIDs,omitempty"`           // (Nhập) Số niêm phong bên nhận ghi nhận khi hàng đến
+}
+
+// EPCISQuantity là số lượng của một lớp đối tượng (ví dụ GTIN + số lô).
+type EPCISQuantity struct {
+	EPCClass string  `json:"epcClass"`
+	Quantity float64 `json:"quantity"`
+	UOM      string  `json:"uom,omitempty"` // Mã đơn vị UN/CEFACT, ví dụ KGM
+}
+
+// EPCISLocation là một địa điểm (readPoint hoặc bizLocation).
+type EPCISLocation struct {
+	ID string `json:"id"`
+}
+
+// EPCISBizTransaction là một giao dịch kinh doanh liên quan (đơn đặt hàng, vận đơn...).
+type EPCISBizTransaction struct {
+	Type           string `json:"type,omitempty"` // po, bol, inv...
+	BizTransaction string `json:"bizTransaction"`
+}
+
+// EPCISSource là bên gửi của một sự kiện chuyển giao.
+type EPCISSource struct {
+	Type   string `json:"type"` // owning_party, possessing_party, location
+	Source string `json:"source"`
+}
+
+// EPCISDestination là bên nhận của một sự kiện chuyển giao.
+type EPCISDestination struct {
+	Type        string `json:"type"`
+	Destination string `json:"destination"`
+}
+
+// EPCISSensorElement chứa các phép đo cảm biến gắn với sự kiện (ví dụ nhiệt độ kho lạnh).
+type EPCISSensorElement struct {
+	SensorReport []EPCISSensorReport `json:"sensorReport"`
+}
+
+// EPCISSensorReport là một phép đo cảm biến.
+type EPCISSensorReport struct {
+	Type  string  `json:"type"` // Temperature
+	Value float64 `json:"value"`
+	UOM   string  `json:"uom"` // CEL
+}
+
+// EPCISImportResult là kết quả của ImportEPCIS: các sự kiện đã được áp dụng và các sự kiện bị bỏ qua.
+type EPCISImportResult struct {
+	Applied []EPCISImportedEvent `json:"applied"`
+	Skipped []EPCISImportedEvent `json:"skipped"`
+}
+
+// EPCISImportedEvent mô tả cách một sự kiện trong tài liệu EPCIS được xử lý.
+type EPCISImportedEvent struct {
+	Index       int      `json:"index"` // Vị trí của sự kiện trong eventList
+	EventID     string   `json:"eventID,omitempty"`
+	Transaction string   `json:"transaction,omitempty"` // Nghiệp vụ tương ứng, ví dụ CreateFarmingBatch
+	AssetIDs    []string `json:"assetIDs,omitempty"`
+	Reason      string   `json:"reason,omitempty"` // Lý do bỏ qua
+}
+
+// LineageGraph là đồ thị nguồn gốc của một asset: các asset tổ tiên, hậu duệ và các lô vận chuyển nối giữa chúng.
+type LineageGraph struct {
+	RootAssetID string        `json:"rootAssetID"`
+	Nodes       []LineageNode `json:"nodes"`
+	Edges       []LineageEdge `json:"edges"`
+}
+
+// LineageNode là một asset hoặc một lô vận chuyển trong đồ thị nguồn gốc.
+type LineageNode struct {
+	ID               string    `json:"id"`
+	Type             string    `json:"type"` // ASSET hoặc SHIPMENT
+	ProductName      string    `json:"productName,omitempty"`
+	SKU              string    `json:"sku,omitempty"`
+	Status           string    `json:"status"`
+	FacilityID       string    `json:"facilityID,omitempty"` // Cơ sở sở hữu asset
+	OriginalQuantity *Quantity `json:"originalQuantity,omitempty"`
+	CurrentQuantity  *Quantity `json:"currentQuantity,omitempty"`
+}
+
+// LineageEdge là một quan hệ có hướng giữa hai nút của đồ thị nguồn gốc.
+type LineageEdge struct {
+	From     string    `json:"from"`
+	To       string    `json:"to"`
+	Type     string    `json:"type"` // PROCESSED_INTO, SPLIT_INTO, PICKED_UP, DELIVERED, DERIVED
+	Quantity *Quantity `json:"quantity,omitempty"`
+}
+
+// AllowedAction mô tả một transaction mà người gọi có thể thực hiện trên asset tại thời điểm hiện tại.
+type AllowedAction struct {
+	Transaction  string   `json:"transaction"`
+	NextStatuses []string `json:"nextStatuses"`
+}
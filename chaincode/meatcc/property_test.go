@@ -0,0 +1,649 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// Test thuộc tính (property-based) cho số lượng hàng hóa: sinh ngẫu nhiên các chuỗi thao tác hợp lệ
+// (nuôi, vận chuyển, hủy/trả về/đóng cưỡng bức lô hàng, chế biến, chia đơn vị, bán, giữ chỗ) trên một kênh
+// và kiểm tra các bất biến về số lượng sau từng bước.
+//
+// Mỗi lần chạy được xác định bởi seed. Khi một bất biến bị vi phạm, test in seed và nhật ký thao tác;
+// chạy lại đúng chuỗi đó bằng:
+//
+//	go test -run TestPropertyQuantityConservation -property.seed=<seed>
+var (
+	propertySeed  = flag.Int64("property.seed", 0, "chỉ chạy test thuộc tính với seed này (0 = chạy các seed mặc định)")
+	propertyRuns  = flag.Int("property.runs", 30, "số chuỗi thao tác ngẫu nhiên của test thuộc tính")
+	propertySteps = flag.Int("property.steps", 80, "số thao tác trong mỗi chuỗi của test thuộc tính")
+)
+
+// Sai số khi cộng dồn số lượng và khối lượng của nhiều asset.
+const propertyTolerance = 1e-6
+
+// Các cơ sở tham gia test thuộc tính và danh tính admin của từng cơ sở.
+var (
+	propertyFacilityIDs = []string{"FARM-1", "FARM-2", "PROC-1", "WH-1", "RETAIL-1"}
+	propertyAdmins      = map[string]*mockIdentity{
+		"FARM-1":   farmAdmin,
+		"FARM-2":   otherFarmAdmin,
+		"PROC-1":   processorAdmin,
+		"WH-1":     warehouseAdmin,
+		"RETAIL-1": retailerAdmin,
+	}
+)
+
+func TestPropertyQuantityConservation(t *testing.T) {
+	seeds := []int64{*propertySeed}
+	if *propertySeed == 0 {
+		runs := *propertyRuns
+		if testing.Short() {
+			runs = 5
+		}
+		seeds = nil
+		for i := 1; i <= runs; i++ {
+			seeds = append(seeds, int64(i))
+		}
+	}
+	for _, seed := range seeds {
+		seed := seed
+		t.Run(fmt.Sprintf("seed=%d", seed), func(t *testing.T) {
+			r := newPropertyRun(t, seed)
+			for r.step = 1; r.step <= *propertySteps; r.step++ {
+				commissioned := r.randomOperation()
+				r.checkInvariants(commissioned)
+			}
+		})
+	}
+}
+
+// propertyRun là trạng thái của một chuỗi thao tác ngẫu nhiên.
+type propertyRun struct {
+	t            *testing.T
+	c            *testChannel
+	rng          *rand.Rand
+	seed         int64
+	step         int
+	log          []string
+	seals        map[string]string // Niêm phong hiện tại của từng lô hàng
+	resealed     map[string]string // Cơ sở vừa nhận hàng, phải niêm phong lại trước điểm giao tiếp theo
+	liveMass     float64           // Khối lượng hàng còn tồn tại (kg) sau bước trước
+	commissioned float64           // Khối lượng hàng mới được nuôi trong bước hiện tại (kg)
+}
+
+func newPropertyRun(t *testing.T, seed int64) *propertyRun {
+	c := newTestChannel(t)
+	c.withCatalog()
+	return &propertyRun{
+		t:        t,
+		c:        c,
+		rng:      rand.New(rand.NewSource(seed)),
+		seed:     seed,
+		seals:    make(map[string]string),
+		resealed: make(map[string]string),
+	}
+}
+
+// propertyOperation là một loại thao tác; run trả về false nếu trạng thái hiện tại không cho phép thao tác này.
+type propertyOperation struct {
+	name   string
+	weight int
+	run    func(r *propertyRun) bool
+}
+
+var propertyOperations = []propertyOperation{
+	{"commission", 3, (*propertyRun).commission},
+	{"create shipment", 5, (*propertyRun).createShipment},
+	{"advance shipment", 10, (*propertyRun).advanceShipment},
+	{"abort shipment", 2, (*propertyRun).abortShipment},
+	{"process", 3, (*propertyRun).process},
+	{"split into units", 3, (*propertyRun).splitIntoUnits},
+	{"sell unit", 3, (*propertyRun).sellUnit},
+	{"release hold", 2, (*propertyRun).releaseHold},
+	{"reserve", 2, (*propertyRun).reserve},
+	{"unreserve", 2, (*propertyRun).unreserve},
+}
+
+// randomOperation chọn ngẫu nhiên (theo trọng số) và thực hiện một thao tác áp dụng được,
+// trả về khối lượng hàng mới được đưa vào chuỗi cung ứng (kg).
+func (r *propertyRun) randomOperation() float64 {
+	total := 0
+	for _, operation := range propertyOperations {
+		total += operation.weight
+	}
+	for attempt := 0; attempt < 100; attempt++ {
+		pick := r.rng.Intn(total)
+		for _, operation := range propertyOperations {
+			if pick >= operation.weight {
+				pick -= operation.weight
+				continue
+			}
+			r.commissioned = 0
+			if operation.run(r) {
+				return r.commissioned
+			}
+			break
+		}
+	}
+	r.fail("no applicable operation")
+	return 0
+}
+
+// --- Các thao tác ---
+
+// commission tạo một lô nuôi mới tại một trang trại.
+func (r *propertyRun) commission() bool {
+	farmID := []string{"FARM-1", "FARM-2"}[r.rng.Intn(2)]
+	assetID := fmt.Sprintf("FB-%03d", r.step)
+	head := float64(1 + r.rng.Intn(30))
+	r.record("%s commissions %s with %s head", farmID, assetID, formatFloat(head))
+	r.c.createFarmBatch(propertyAdmins[farmID], assetID, skuPorkCarcass, Quantity{Unit: "head", Value: head})
+	asset := r.c.asset(assetID)
+	r.commissioned = massKg(asset, asset.OriginalQuantity)
+	return true
+}
+
+// createShipment đặt chỗ hàng của một hoặc hai asset cùng cơ sở lên một lô hàng mới, tới một hoặc hai điểm giao.
+// Thỉnh thoảng thử đặt chỗ vượt quá số lượng khả dụng và yêu cầu contract từ chối.
+func (r *propertyRun) createShipment() bool {
+	var candidates []*MeatAsset
+	for _, asset := range r.assets() {
+		if containsString(shippableAssetStatuses, asset.Status) && propertyAdmins[asset.OwnerOrg] != nil && r.amount(asset, availableQuantity(asset)) > 0 {
+			candidates = append(candidates, asset)
+		}
+	}
+	if len(candidates) == 0 {
+		return false
+	}
+	source := candidates[r.rng.Intn(len(candidates))]
+	senderID := source.OwnerOrg
+	sender := propertyAdmins[senderID]
+	shipmentID := fmt.Sprintf("SH-%03d", r.step)
+
+	if r.rng.Intn(5) == 0 {
+		excess := availableQuantity(source) + r.minimumAmount(source)
+		stops := []StopInJourney{
+			testStop(senderID, "PICKUP", testItem(source.AssetID, source.CurrentQuantity.Unit, excess)),
+			testStop(r.otherFacility(senderID), "DELIVERY"),
+		}
+		r.record("%s tries to book %s of %s (only %s available)", senderID, formatFloat(excess), source.AssetID, formatFloat(availableQuantity(source)))
+		r.expectRejected(sender, "CreateShipment", shipmentID, "REFRIGERATED", "driver-1", "Nguyen Van A", "51C-123.45", stops)
+	}
+
+	items := []ItemInShipment{testItem(source.AssetID, source.CurrentQuantity.Unit, r.amount(source, availableQuantity(source)))}
+	for _, other := range candidates {
+		if other.AssetID != source.AssetID && other.OwnerOrg == senderID && r.rng.Intn(4) == 0 {
+			items = append(items, testItem(other.AssetID, other.CurrentQuantity.Unit, r.amount(other, availableQuantity(other))))
+			break
+		}
+	}
+
+	receiverID := r.otherFacility(senderID)
+	stops := []StopInJourney{testStop(senderID, "PICKUP", items...)}
+	if first := items[0]; len(items) == 1 && first.Quantity.Value >= 2*r.minimumAmount(source) && r.rng.Intn(3) == 0 {
+		// Hai điểm giao: điểm đầu nhận một nửa, điểm sau nhận phần còn lại của manifest
+		half := r.amount(source, first.Quantity.Value/2)
+		secondID := r.otherFacility(senderID, receiverID)
+		stops = append(stops, testStop(receiverID, "DELIVERY", testItem(first.AssetID, first.Quantity.Unit, half)), testStop(secondID, "DELIVERY"))
+		r.record("%s books %s on %s to %s (%s) and %s", senderID, describeItems(items), shipmentID, receiverID, formatFloat(half), secondID)
+	} else {
+		stops = append(stops, testStop(receiverID, "DELIVERY"))
+		r.record("%s books %s on %s to %s", senderID, describeItems(items), shipmentID, receiverID)
+	}
+	r.submit(sender, "CreateShipment", shipmentID, "REFRIGERATED", "driver-1", "Nguyen Van A", "51C-123.45", stops)
+	return true
+}
+
+// advanceShipment thực hiện bước tiếp theo của một lô hàng đang mở: lấy hàng, khởi hành hoặc giao hàng.
+func (r *propertyRun) advanceShipment() bool {
+	shipments := r.openShipments()
+	if len(shipments) == 0 {
+		return false
+	}
+	shipment := shipments[r.rng.Intn(len(shipments))]
+	shipmentID := shipment.ShipmentID
+
+	var next *StopInJourney
+	for i := range shipment.Stops {
+		if shipment.Stops[i].Status == "PENDING" {
+			next = &shipment.Stops[i]
+			break
+		}
+	}
+
+	switch {
+	case shipment.Status == ShipmentStatusPending && next != nil && next.Action == "PICKUP":
+		items := next.Items
+		if len(shipment.Stops) == 2 && r.rng.Intn(4) == 0 {
+			// Lấy ít hơn số đã đặt chỗ; phần giữ chỗ còn lại được giải phóng
+			items = nil
+			for _, item := range next.Items {
+				asset := r.c.asset(item.AssetID)
+				items = append(items, testItem(item.AssetID, item.Quantity.Unit, r.amount(asset, item.Quantity.Value)))
+			}
+		}
+		r.record("%s picks up %s at %s", shipmentID, describeItems(items), next.FacilityID)
+		r.submit(driver, "AddPickupProof", shipmentID, next.FacilityID, testProof(next.FacilityID))
+		r.submit(propertyAdmins[next.FacilityID], "ConfirmPickup", shipmentID, next.FacilityID, items)
+	case shipment.Status == ShipmentStatusPending:
+		r.seals[shipmentID] = fmt.Sprintf("SEAL-%s-%03d", shipmentID, r.step)
+		r.record("%s departs with seal %s", shipmentID, r.seals[shipmentID])
+		r.submit(driver, "StartShipment", shipmentID, []string{r.seals[shipmentID]})
+	case next != nil && next.Action == "DELIVERY":
+		if facilityID := r.resealed[shipmentID]; facilityID != "" {
+			r.seals[shipmentID] = fmt.Sprintf("SEAL-%s-%03d", shipmentID, r.step)
+			r.record("%s is resealed with %s at %s", shipmentID, r.seals[shipmentID], facilityID)
+			r.submit(propertyAdmins[facilityID], "ResealShipment", shipmentID, facilityID, []string{r.seals[shipmentID]})
+		}
+		arrivalSeal := r.seals[shipmentID]
+		if r.rng.Intn(8) == 0 {
+			arrivalSeal = "SEAL-TAMPERED"
+		}
+		prefix := fmt.Sprintf("RCV-%03d", r.step)
+		r.record("%s delivers to %s as %s-* (arrival seal %s)", shipmentID, next.FacilityID, prefix, arrivalSeal)
+		r.submit(driver, "AddDeliveryProof", shipmentID, next.FacilityID, testProof(next.FacilityID))
+		r.submit(propertyAdmins[next.FacilityID], "ConfirmShipmentDelivery", shipmentID, next.FacilityID, prefix, []string{arrivalSeal})
+		r.resealed[shipmentID] = next.FacilityID
+	default:
+		r.fail("shipment %s in status %s has no next step", shipmentID, shipment.Status)
+	}
+	return true
+}
+
+// abortShipment hủy (chưa khởi hành), trả về (đang vận chuyển) hoặc đóng cưỡng bức một lô hàng đang mở.
+func (r *propertyRun) abortShipment() bool {
+	shipments := r.openShipments()
+	if len(shipments) == 0 {
+		return false
+	}
+	shipment := shipments[r.rng.Intn(len(shipments))]
+	shipmentID := shipment.ShipmentID
+	admin := propertyAdmins[shipment.Stops[0].FacilityID]
+
+	switch {
+	case r.rng.Intn(4) == 0:
+		targets := shipmentTransitions["ForceCloseShipment"].To
+		target := targets[r.rng.Intn(len(targets))]
+		r.record("%s is force-closed as %s", shipmentID, target)
+		r.submit(admin, "ForceCloseShipment", shipmentID, target, "Reconciled offline")
+	case shipment.Status == ShipmentStatusPending:
+		r.record("%s is cancelled", shipmentID)
+		r.submit(admin, "CancelShipment", shipmentID, "Customer cancelled")
+	default:
+		r.record("%s is returned", shipmentID)
+		r.submit(admin, "ReturnShipment", shipmentID, "Refused at gate")
+	}
+	return true
+}
+
+// process chế biến một lô tại nhà máy thành thăn (kg) và khay, với tổng khối lượng không vượt quá lô cha.
+func (r *propertyRun) process() bool {
+	var candidates []*MeatAsset
+	for _, asset := range r.idleAssets() {
+		if asset.Status == AssetStatusAtProcessor && massKg(asset, asset.CurrentQuantity) >= 1 {
+			candidates = append(candidates, asset)
+		}
+	}
+	if len(candidates) == 0 {
+		return false
+	}
+	parent := candidates[r.rng.Intn(len(candidates))]
+	mass := massKg(parent, parent.CurrentQuantity)
+
+	// Thăn chiếm tối đa một nửa khối lượng, khay (0,5 kg) tối đa phần còn lại
+	loin := math.Floor(mass*r.rng.Float64()) / 2
+	trays := math.Floor((mass - loin) * 2 * r.rng.Float64())
+	var children []ChildAssetInput
+	if loin > 0 {
+		children = append(children, ChildAssetInput{AssetID: fmt.Sprintf("LOIN-%03d", r.step), ProductName: "Pork loin", SKU: skuPorkLoin, Quantity: Quantity{Unit: "kg", Value: loin}})
+	}
+	if trays > 0 {
+		children = append(children, ChildAssetInput{AssetID: fmt.Sprintf("TRAY-%03d", r.step), ProductName: "Pork belly tray", SKU: skuPorkTray, Quantity: Quantity{Unit: "tray", Value: trays}})
+	}
+	r.record("PROC-1 processes %s (%s kg) into %s kg loin and %s trays", parent.AssetID, formatFloat(mass), formatFloat(loin), formatFloat(trays))
+	r.submit(processorAdmin, "ProcessAndSplitBatch", parent.AssetID, children, map[string]interface{}{"line": "LINE-1"})
+	return true
+}
+
+// splitIntoUnits chia một lô tại nhà bán lẻ thành các đơn vị bán lẻ. Thỉnh thoảng thử chia nhiều hơn số lượng
+// hiện có và yêu cầu contract từ chối.
+func (r *propertyRun) splitIntoUnits() bool {
+	var candidates []*MeatAsset
+	for _, asset := range r.idleAssets() {
+		if asset.Status == AssetStatusAtRetailer && availableQuantity(asset) >= 1 {
+			candidates = append(candidates, asset)
+		}
+	}
+	if len(candidates) == 0 {
+		return false
+	}
+	parent := candidates[r.rng.Intn(len(candidates))]
+	prefix := fmt.Sprintf("U-%03d-", r.step)
+
+	if r.rng.Intn(5) == 0 {
+		excess := int(math.Floor(parent.CurrentQuantity.Value)) + 1
+		r.record("RETAIL-1 tries to split %s into %d units (only %s)", parent.AssetID, excess, formatFloat(parent.CurrentQuantity.Value))
+		r.expectRejected(retailerAdmin, "SplitBatchToUnits", parent.AssetID, excess, prefix)
+	}
+
+	count := 1 + r.rng.Intn(int(math.Min(math.Floor(availableQuantity(parent)), 5)))
+	r.record("RETAIL-1 splits %d units off %s as %s*", count, parent.AssetID, prefix)
+	r.submit(retailerAdmin, "SplitBatchToUnits", parent.AssetID, count, prefix)
+	return true
+}
+
+// sellUnit bán một đơn vị đang trên kệ.
+func (r *propertyRun) sellUnit() bool {
+	var candidates []*MeatAsset
+	for _, asset := range r.assets() {
+		if asset.Status == AssetStatusOnShelf {
+			candidates = append(candidates, asset)
+		}
+	}
+	if len(candidates) == 0 {
+		return false
+	}
+	unit := candidates[r.rng.Intn(len(candidates))]
+	r.record("RETAIL-1 sells %s", unit.AssetID)
+	r.submit(retailerAdmin, "MarkAsSold", unit.AssetID, map[string]interface{}{"channel": "POS"})
+	return true
+}
+
+// releaseHold gỡ tạm giữ cho hàng nhận được với niêm phong không khớp.
+func (r *propertyRun) releaseHold() bool {
+	var candidates []*MeatAsset
+	for _, asset := range r.assets() {
+		if asset.Status == AssetStatusOnHold {
+			candidates = append(candidates, asset)
+		}
+	}
+	if len(candidates) == 0 {
+		return false
+	}
+	asset := candidates[r.rng.Intn(len(candidates))]
+	r.record("%s releases the hold on %s", asset.OwnerOrg, asset.AssetID)
+	r.submit(propertyAdmins[asset.OwnerOrg], "ReleaseAssetHold", asset.AssetID, "Inspected, contents match manifest")
+	return true
+}
+
+// reserve giữ chỗ một phần số lượng khả dụng của asset cho một đơn hàng.
+func (r *propertyRun) reserve() bool {
+	var candidates []*MeatAsset
+	for _, asset := range r.assets() {
+		if containsString(shippableAssetStatuses, asset.Status) && propertyAdmins[asset.OwnerOrg] != nil && r.amount(asset, availableQuantity(asset)) > 0 {
+			candidates = append(candidates, asset)
+		}
+	}
+	if len(candidates) == 0 {
+		return false
+	}
+	asset := candidates[r.rng.Intn(len(candidates))]
+	quantity := Quantity{Unit: asset.CurrentQuantity.Unit, Value: r.amount(asset, availableQuantity(asset))}
+	orderID := fmt.Sprintf("PO-%03d", r.step)
+	r.record("%s reserves %s of %s for %s", asset.OwnerOrg, formatFloat(quantity.Value), asset.AssetID, orderID)
+	r.submit(propertyAdmins[asset.OwnerOrg], "ReserveAsset", asset.AssetID, "ORDER", orderID, quantity)
+	return true
+}
+
+// unreserve giải phóng phần giữ chỗ của một đơn hàng.
+func (r *propertyRun) unreserve() bool {
+	type orderReservation struct {
+		asset   *MeatAsset
+		orderID string
+	}
+	var candidates []orderReservation
+	for _, asset := range r.assets() {
+		for _, reservation := range asset.Reservations {
+			if reservation.ReferenceType == "ORDER" && propertyAdmins[asset.OwnerOrg] != nil {
+				candidates = append(candidates, orderReservation{asset, reservation.ReferenceID})
+			}
+		}
+	}
+	if len(candidates) == 0 {
+		return false
+	}
+	pick := candidates[r.rng.Intn(len(candidates))]
+	r.record("%s releases %s on %s", pick.asset.OwnerOrg, pick.orderID, pick.asset.AssetID)
+	r.submit(propertyAdmins[pick.asset.OwnerOrg], "UnreserveAsset", pick.asset.AssetID, "ORDER", pick.orderID)
+	return true
+}
+
+// --- Các bất biến ---
+
+// checkInvariants kiểm tra các bất biến về số lượng trên toàn bộ world state đã commit:
+//   - số lượng hiện có nằm trong [0, số lượng ban đầu], phần giữ chỗ không vượt quá số lượng hiện có;
+//   - mọi asset cha đều tồn tại;
+//   - bảo toàn: số lượng ban đầu = hiện có + hàng đã chuyển sang asset con (nhận hàng, chia đơn vị)
+//   - hàng còn trên xe của lô hàng đang mở hoặc đã bị đóng cưỡng bức sang COMPLETED (hủy và trả về, kể cả đóng cưỡng bức,
+//     đều hoàn lại hàng cho asset nguồn);
+//   - tổng khối lượng lô con chế biến không vượt quá khối lượng lô cha;
+//   - tổng khối lượng hàng còn tồn tại không tăng, ngoại trừ hàng mới được nuôi (commissioned, kg).
+func (r *propertyRun) checkInvariants(commissioned float64) {
+	r.t.Helper()
+	assets := make(map[string]*MeatAsset)
+	for _, asset := range r.assets() {
+		assets[asset.AssetID] = asset
+	}
+
+	transferred := make(map[string]float64)   // Số lượng đã chuyển khỏi asset (theo đơn vị của asset)
+	processedMass := make(map[string]float64) // Khối lượng lô con chế biến của từng lô cha (kg)
+	liveMass := 0.0
+	for _, shipment := range r.shipments() {
+		open := shipment.Status == ShipmentStatusPending || shipment.Status == ShipmentStatusInTransit
+		forceCompleted := false
+		for _, event := range shipment.History {
+			forceCompleted = forceCompleted || (event.Type == "SHIPMENT_FORCE_CLOSED" && shipment.Status == ShipmentStatusCompleted)
+		}
+		if !open && !forceCompleted {
+			continue
+		}
+		for _, entry := range shipment.Manifest {
+			onBoard := entry.LoadedQuantity.Value - entry.DeliveredQuantity.Value
+			transferred[entry.AssetID] += onBoard
+			if source := assets[entry.AssetID]; open && source != nil {
+				liveMass += massKg(source, Quantity{Unit: entry.LoadedQuantity.Unit, Value: onBoard})
+			}
+		}
+	}
+
+	for _, asset := range assets {
+		for _, parentID := range asset.ParentAssetIDs {
+			parent := assets[parentID]
+			if parent == nil {
+				r.fail("asset %s has missing parent %s", asset.AssetID, parentID)
+			}
+			switch asset.History[0].Type {
+			case "RECEIVING", "CREATED_AS_UNIT":
+				transferred[parentID] += asset.OriginalQuantity.Value
+			case "CREATED_FROM_PROCESSING":
+				processedMass[parentID] += massKg(asset, asset.OriginalQuantity)
+			default:
+				r.fail("asset %s with parents was created by %s", asset.AssetID, asset.History[0].Type)
+			}
+		}
+	}
+
+	for _, asset := range r.assets() {
+		current := asset.CurrentQuantity.Value
+		if current < -propertyTolerance {
+			r.fail("asset %s has negative quantity %s", asset.AssetID, formatFloat(current))
+		}
+		if current > asset.OriginalQuantity.Value+propertyTolerance {
+			r.fail("asset %s has %s, more than its original %s", asset.AssetID, formatFloat(current), formatFloat(asset.OriginalQuantity.Value))
+		}
+		if reserved := reservedQuantity(asset); reserved > current+propertyTolerance {
+			r.fail("asset %s has %s reserved but only %s on hand", asset.AssetID, formatFloat(reserved), formatFloat(current))
+		}
+		if accounted := current + transferred[asset.AssetID]; math.Abs(accounted-asset.OriginalQuantity.Value) > propertyTolerance {
+			r.fail("asset %s is not conserved: original %s, on hand %s, transferred %s", asset.AssetID,
+				formatFloat(asset.OriginalQuantity.Value), formatFloat(current), formatFloat(transferred[asset.AssetID]))
+		}
+		if asset.Status == AssetStatusProcessedAndSplit {
+			if mass := massKg(asset, asset.CurrentQuantity); processedMass[asset.AssetID] > mass+propertyTolerance {
+				r.fail("processing %s (%s kg) produced %s kg", asset.AssetID, formatFloat(mass), formatFloat(processedMass[asset.AssetID]))
+			}
+			continue
+		}
+		liveMass += massKg(asset, asset.CurrentQuantity)
+	}
+
+	if liveMass > r.liveMass+commissioned+propertyTolerance {
+		r.fail("total mass grew from %s kg to %s kg (%s kg commissioned)", formatFloat(r.liveMass), formatFloat(liveMass), formatFloat(commissioned))
+	}
+	r.liveMass = liveMass
+}
+
+// --- Các hàm hỗ trợ ---
+
+// assets đọc mọi MeatAsset đã commit, sắp xếp theo mã để chuỗi thao tác tái lập được theo seed.
+func (r *propertyRun) assets() []*MeatAsset {
+	var assets []*MeatAsset
+	for _, value := range r.documents("MeatAsset") {
+		var asset MeatAsset
+		if err := json.Unmarshal(value, &asset); err != nil {
+			r.fail("failed to unmarshal asset: %v", err)
+		}
+		assets = append(assets, &asset)
+	}
+	return assets
+}
+
+// shipments đọc mọi lô hàng đã commit, sắp xếp theo mã.
+func (r *propertyRun) shipments() []*ShipmentAsset {
+	var shipments []*ShipmentAsset
+	for _, value := range r.documents("ShipmentAsset") {
+		var shipment ShipmentAsset
+		if err := json.Unmarshal(value, &shipment); err != nil {
+			r.fail("failed to unmarshal shipment: %v", err)
+		}
+		shipments = append(shipments, &shipment)
+	}
+	return shipments
+}
+
+// documents trả về các document có docType trong world state, theo thứ tự khóa.
+func (r *propertyRun) documents(docType string) [][]byte {
+	var keys []string
+	for key := range r.c.stub.State {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	var documents [][]byte
+	for _, key := range keys {
+		var document struct {
+			ObjectType string `json:"docType"`
+		}
+		value := r.c.stub.State[key]
+		if json.Unmarshal(value, &document) == nil && document.ObjectType == docType {
+			documents = append(documents, value)
+		}
+	}
+	return documents
+}
+
+// openShipments trả về các lô hàng chưa khởi hành hoặc đang vận chuyển.
+func (r *propertyRun) openShipments() []*ShipmentAsset {
+	var open []*ShipmentAsset
+	for _, shipment := range r.shipments() {
+		if shipment.Status == ShipmentStatusPending || shipment.Status == ShipmentStatusInTransit {
+			open = append(open, shipment)
+		}
+	}
+	return open
+}
+
+// idleAssets trả về các asset không nằm trên lô hàng đang mở nào, để việc chế biến hay chia đơn vị
+// không làm kẹt lô hàng đã đặt chỗ hoặc đã bốc hàng của asset đó.
+func (r *propertyRun) idleAssets() []*MeatAsset {
+	busy := make(map[string]bool)
+	for _, shipment := range r.openShipments() {
+		for _, stop := range shipment.Stops {
+			for _, item := range stop.Items {
+				busy[item.AssetID] = true
+			}
+		}
+		for _, entry := range shipment.Manifest {
+			busy[entry.AssetID] = true
+		}
+	}
+	var idle []*MeatAsset
+	for _, asset := range r.assets() {
+		if !busy[asset.AssetID] {
+			idle = append(idle, asset)
+		}
+	}
+	return idle
+}
+
+// otherFacility chọn ngẫu nhiên một cơ sở khác các cơ sở trong excluded.
+func (r *propertyRun) otherFacility(excluded ...string) string {
+	var choices []string
+	for _, facilityID := range propertyFacilityIDs {
+		if !containsString(excluded, facilityID) {
+			choices = append(choices, facilityID)
+		}
+	}
+	return choices[r.rng.Intn(len(choices))]
+}
+
+// minimumAmount là lượng nhỏ nhất được sinh cho asset: 0,5 với hàng tính theo kg, 1 với hàng tính theo đơn vị.
+func (r *propertyRun) minimumAmount(asset *MeatAsset) float64 {
+	if asset.CurrentQuantity.Unit == "kg" {
+		return 0.5
+	}
+	return 1
+}
+
+// amount sinh ngẫu nhiên một lượng dương không vượt quá max, là bội của minimumAmount; trả về 0 nếu max quá nhỏ.
+func (r *propertyRun) amount(asset *MeatAsset, max float64) float64 {
+	step := r.minimumAmount(asset)
+	steps := int(math.Floor(max/step + propertyTolerance))
+	if steps < 1 {
+		return 0
+	}
+	return float64(1+r.rng.Intn(steps)) * step
+}
+
+// submit gọi một transaction mà bộ sinh thao tác coi là hợp lệ; lỗi là vi phạm thuộc tính.
+func (r *propertyRun) submit(identity *mockIdentity, function string, args ...interface{}) {
+	r.t.Helper()
+	if _, err := r.c.submit(identity, function, args...); err != nil {
+		r.fail("%s failed: %v", function, err)
+	}
+}
+
+// expectRejected gọi một transaction vượt quá số lượng cho phép và yêu cầu contract từ chối.
+func (r *propertyRun) expectRejected(identity *mockIdentity, function string, args ...interface{}) {
+	r.t.Helper()
+	if _, err := r.c.submit(identity, function, args...); err == nil {
+		r.fail("%s accepted a quantity it should have rejected", function)
+	}
+}
+
+// record ghi một thao tác vào nhật ký của chuỗi.
+func (r *propertyRun) record(format string, args ...interface{}) {
+	r.log = append(r.log, fmt.Sprintf("%3d. ", r.step)+fmt.Sprintf(format, args...))
+}
+
+// fail dừng chuỗi, in seed và nhật ký thao tác để tái lập.
+func (r *propertyRun) fail(format string, args ...interface{}) {
+	r.t.Helper()
+	r.t.Fatalf("seed %d, step %d: %s\noperations:\n%s\nre-run with: go test -run TestPropertyQuantityConservation -property.seed=%d",
+		r.seed, r.step, fmt.Sprintf(format, args...), strings.Join(r.log, "\n"), r.seed)
+}
+
+// describeItems mô tả các dòng hàng dạng "2 head of FB-001, 1.5 kg of LOIN-004".
+func describeItems(items []ItemInShipment) string {
+	var parts []string
+	for _, item := range items {
+		parts = append(parts, fmt.Sprintf("%s %s of %s", formatFloat(item.Quantity.Value), item.Quantity.Unit, item.AssetID))
+	}
+	return strings.Join(parts, ", ")
+}
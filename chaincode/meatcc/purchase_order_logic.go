@@ -0,0 +1,403 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// Các trạng thái của đơn đặt hàng.
+const (
+	POStatusCreated            = "CREATED"
+	POStatusAccepted           = "ACCEPTED"
+	POStatusRejected           = "REJECTED"
+	POStatusPartiallyFulfilled = "PARTIALLY_FULFILLED"
+	POStatusFulfilled          = "FULFILLED"
+)
+
+// Các trạng thái đơn đặt hàng còn nhận hàng giao.
+var deliverablePOStatuses = []string{POStatusAccepted, POStatusPartiallyFulfilled, POStatusFulfilled}
+
+// CreatePurchaseOrder tạo một đơn đặt hàng mới; cơ sở của người gọi là bên mua.
+func (s *SmartContract) CreatePurchaseOrder(ctx contractapi.TransactionContextInterface, poID string, sellerFacilityID string, linesJSON string, requestedDeliveryDate string) error {
+	buyerFacilityID, found, err := getClientAttribute(ctx, "facilityID")
+	if err != nil || !found {
+		return fmt.Errorf("the client identity does not have a 'facilityID' attribute")
+	}
+	if sellerFacilityID == "" || sellerFacilityID == buyerFacilityID {
+		return fmt.Errorf("seller facility must be set and different from the buyer facility")
+	}
+	exists, err := s.assetExists(ctx, poID)
+	if err != nil {
+		return err
+	}
+	if exists {
+		return fmt.Errorf("purchase order %s already exists", poID)
+	}
+	if _, err := time.Parse("2006-01-02", requestedDeliveryDate); err != nil {
+		if _, err := time.Parse(time.RFC3339, requestedDeliveryDate); err != nil {
+			return fmt.Errorf("invalid requestedDeliveryDate '%s', expected YYYY-MM-DD or RFC3339", requestedDeliveryDate)
+		}
+	}
+
+	var lines []PurchaseOrderLine
+	if err := json.Unmarshal([]byte(linesJSON), &lines); err != nil {
+		return fmt.Errorf("failed to unmarshal linesJSON: %v", err)
+	}
+	if len(lines) == 0 {
+		return fmt.Errorf("purchase order %s must have at least one line", poID)
+	}
+	seenLines := make(map[string]bool)
+	for i, line := range lines {
+		if line.LineID == "" || seenLines[line.LineID] {
+			return fmt.Errorf("line %d of purchase order %s must have a unique lineID", i+1, poID)
+		}
+		seenLines[line.LineID] = true
+		if line.Quantity.Value <= 0 {
+			return fmt.Errorf("quantity of line %s must be positive", line.LineID)
+		}
+		productJSON, err := ctx.GetStub().GetState(line.SKU)
+		if err != nil {
+			return fmt.Errorf("failed to read product for SKU %s: %v", line.SKU, err)
+		}
+		if productJSON == nil {
+			return fmt.Errorf("product with SKU %s does not exist", line.SKU)
+		}
+		lines[i].DeliveredQuantity = Quantity{Unit: line.Quantity.Unit, Value: 0}
+		lines[i].Variance = -line.Quantity.Value
+		lines[i].Status = "OPEN"
+	}
+
+	event, err := s.createEvent(ctx, "PO_CREATED", map[string]interface{}{"buyerFacilityID": buyerFacilityID, "sellerFacilityID": sellerFacilityID})
+	if err != nil {
+		return err
+	}
+
+	po := PurchaseOrder{
+		ObjectType:            "PurchaseOrder",
+		POID:                  poID,
+		BuyerFacilityID:       buyerFacilityID,
+		SellerFacilityID:      sellerFacilityID,
+		RequestedDeliveryDate: requestedDeliveryDate,
+		Status:                POStatusCreated,
+		Lines:                 lines,
+		History:               []Event{*event},
+	}
+	return s.updatePurchaseOrder(ctx, &po)
+}
+
+// AcceptPurchaseOrder cho phép bên bán chấp nhận đơn đặt hàng.
+func (s *SmartContract) AcceptPurchaseOrder(ctx contractapi.TransactionContextInterface, poID string) error {
+	po, err := s.readPurchaseOrder(ctx, poID)
+	if err != nil {
+		return err
+	}
+	if err := requirePurchaseOrderParty(ctx, po.SellerFacilityID); err != nil {
+		return err
+	}
+	if po.Status != POStatusCreated {
+		return fmt.Errorf("purchase order %s with status '%s' cannot be accepted", poID, po.Status)
+	}
+	return s.addPurchaseOrderEvent(ctx, po, "PO_ACCEPTED", POStatusAccepted, nil)
+}
+
+// RejectPurchaseOrder cho phép bên bán từ chối đơn đặt hàng kèm lý do.
+func (s *SmartContract) RejectPurchaseOrder(ctx contractapi.TransactionContextInterface, poID string, reason string) error {
+	if reason == "" {
+		return fmt.Errorf("a reason is required to reject purchase order %s", poID)
+	}
+	po, err := s.readPurchaseOrder(ctx, poID)
+	if err != nil {
+		return err
+	}
+	if err := requirePurchaseOrderParty(ctx, po.SellerFacilityID); err != nil {
+		return err
+	}
+	if po.Status != POStatusCreated {
+		return fmt.Errorf("purchase order %s with status '%s' cannot be rejected", poID, po.Status)
+	}
+	return s.addPurchaseOrderEvent(ctx, po, "PO_REJECTED", POStatusRejected, map[string]interface{}{"reason": reason})
+}
+
+// FulfilPurchaseOrder cho phép bên mua đóng đơn đặt hàng là đã hoàn tất, kể cả khi còn dòng giao thiếu.
+func (s *SmartContract) FulfilPurchaseOrder(ctx contractapi.TransactionContextInterface, poID string, note string) error {
+	po, err := s.readPurchaseOrder(ctx, poID)
+	if err != nil {
+		return err
+	}
+	if err := requirePurchaseOrderParty(ctx, po.BuyerFacilityID); err != nil {
+		return err
+	}
+	if po.Status != POStatusAccepted && po.Status != POStatusPartiallyFulfilled {
+		return fmt.Errorf("purchase order %s with status '%s' cannot be fulfilled", poID, po.Status)
+	}
+
+	var shortLines []string
+	for _, line := range po.Lines {
+		if line.Variance < -quantityTolerance {
+			shortLines = append(shortLines, line.LineID)
+		}
+	}
+	details := map[string]interface{}{
+		"note":       note,
+		"shortLines": shortLines,
+	}
+	return s.addPurchaseOrderEvent(ctx, po, "PO_FULFILLED", POStatusFulfilled, details)
+}
+
+// GetPurchaseOrder lấy thông tin chi tiết của một đơn đặt hàng.
+func (s *SmartContract) GetPurchaseOrder(ctx contractapi.TransactionContextInterface, poID string) (*PurchaseOrder, error) {
+	return s.readPurchaseOrder(ctx, poID)
+}
+
+// QueryPurchaseOrdersByFacility thực hiện một truy vấn CouchDB để tìm các đơn đặt hàng
+// mà một cơ sở là bên mua hoặc bên bán.
+func (s *SmartContract) QueryPurchaseOrdersByFacility(ctx contractapi.TransactionContextInterface, facilityID string) ([]*PurchaseOrder, error) {
+	query := map[string]interface{}{
+		"selector": map[string]interface{}{
+			"docType": "PurchaseOrder",
+			"$or": []interface{}{
+				map[string]interface{}{"buyerFacilityID": facilityID},
+				map[string]interface{}{"sellerFacilityID": facilityID},
+			},
+		},
+	}
+	queryBytes, err := json.Marshal(query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build query: %v", err)
+	}
+
+	resultsIterator, err := ctx.GetStub().GetQueryResult(string(queryBytes))
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute rich query: %v", err)
+	}
+	defer resultsIterator.Close()
+
+	var orders []*PurchaseOrder
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+		var po PurchaseOrder
+		if err := json.Unmarshal(queryResponse.Value, &po); err != nil {
+			return nil, err
+		}
+		orders = append(orders, &po)
+	}
+
+	// Sắp xếp theo thời gian tạo đơn, mới nhất trước
+	sort.Slice(orders, func(i, j int) bool {
+		if len(orders[i].History) == 0 || len(orders[j].History) == 0 {
+			return len(orders[j].History) == 0 && len(orders[i].History) > 0
+		}
+		return orders[i].History[0].Timestamp > orders[j].History[0].Timestamp
+	})
+
+	return orders, nil
+}
+
+// --- Các hàm hỗ trợ cho đơn đặt hàng ---
+
+// validatePurchaseOrderReferences kiểm tra các hàng tại điểm giao có tham chiếu dòng đơn đặt hàng:
+// đơn phải tồn tại, còn nhận hàng, có dòng được tham chiếu, bên mua chính là cơ sở nhận hàng
+// và hàng thuộc về bên bán.
+func (s *SmartContract) validatePurchaseOrderReferences(ctx contractapi.TransactionContextInterface, stops []StopInJourney) error {
+	for _, stop := range stops {
+		for _, item := range stop.Items {
+			if item.POID == "" && item.POLineID == "" {
+				continue
+			}
+			if stop.Action != "DELIVERY" {
+				return fmt.Errorf("purchase order references are only allowed on DELIVERY stops (asset %s)", item.AssetID)
+			}
+			po, err := s.readPurchaseOrder(ctx, item.POID)
+			if err != nil {
+				return err
+			}
+			if !containsString(deliverablePOStatuses, po.Status) {
+				return fmt.Errorf("purchase order %s with status '%s' cannot receive deliveries", po.POID, po.Status)
+			}
+			if po.BuyerFacilityID != stop.FacilityID {
+				return fmt.Errorf("purchase order %s is for buyer %s, not delivery facility %s", po.POID, po.BuyerFacilityID, stop.FacilityID)
+			}
+			if findPurchaseOrderLine(po, item.POLineID) == nil {
+				return fmt.Errorf("purchase order %s has no line %s", po.POID, item.POLineID)
+			}
+			asset, err := s.readAsset(ctx, item.AssetID)
+			if err != nil {
+				return err
+			}
+			if err := requirePurchaseOrderSeller(po, asset); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// applyDeliveryToPurchaseOrders cộng số lượng đã giao vào các dòng đơn đặt hàng được tham chiếu,
+// cập nhật chênh lệch giao thừa/thiếu và chuyển đơn sang PARTIALLY_FULFILLED hoặc FULFILLED.
+func (s *SmartContract) applyDeliveryToPurchaseOrders(ctx contractapi.TransactionContextInterface, shipmentID string, facilityID string, deliveredItems []ItemInShipment) error {
+	// Gộp theo đơn đặt hàng vì một transaction không đọc được dữ liệu chính nó vừa ghi.
+	var poOrder []string
+	itemsByPO := make(map[string][]ItemInShipment)
+	for _, item := range deliveredItems {
+		if item.POID == "" {
+			continue
+		}
+		if _, exists := itemsByPO[item.POID]; !exists {
+			poOrder = append(poOrder, item.POID)
+		}
+		itemsByPO[item.POID] = append(itemsByPO[item.POID], item)
+	}
+
+	for _, poID := range poOrder {
+		po, err := s.readPurchaseOrder(ctx, poID)
+		if err != nil {
+			return err
+		}
+		if !containsString(deliverablePOStatuses, po.Status) {
+			return fmt.Errorf("purchase order %s with status '%s' cannot receive deliveries", poID, po.Status)
+		}
+		if po.BuyerFacilityID != facilityID {
+			return fmt.Errorf("purchase order %s is for buyer %s, not delivery facility %s", poID, po.BuyerFacilityID, facilityID)
+		}
+
+		var deliveredLines []map[string]interface{}
+		for _, item := range itemsByPO[poID] {
+			line := findPurchaseOrderLine(po, item.POLineID)
+			if line == nil {
+				return fmt.Errorf("purchase order %s has no line %s", poID, item.POLineID)
+			}
+			asset, err := s.readAsset(ctx, item.AssetID)
+			if err != nil {
+				return err
+			}
+			if err := requirePurchaseOrderSeller(po, asset); err != nil {
+				return err
+			}
+			if asset.SKU != line.SKU {
+				return fmt.Errorf("asset %s has SKU %s but purchase order line %s/%s is for SKU %s", item.AssetID, asset.SKU, poID, line.LineID, line.SKU)
+			}
+			if item.Quantity.Unit != line.Quantity.Unit {
+				return fmt.Errorf("unit '%s' for asset %s does not match purchase order line unit '%s'", item.Quantity.Unit, item.AssetID, line.Quantity.Unit)
+			}
+			line.DeliveredQuantity.Value += item.Quantity.Value
+			updatePurchaseOrderLineStatus(line)
+			deliveredLines = append(deliveredLines, map[string]interface{}{
+				"lineID":   line.LineID,
+				"assetID":  item.AssetID,
+				"quantity": item.Quantity,
+				"variance": line.Variance,
+			})
+		}
+
+		newStatus := POStatusFulfilled
+		for _, line := range po.Lines {
+			if line.Variance < -quantityTolerance {
+				newStatus = POStatusPartiallyFulfilled
+				break
+			}
+		}
+		if po.Status == POStatusFulfilled {
+			newStatus = POStatusFulfilled
+		}
+		details := map[string]interface{}{
+			"shipmentID": shipmentID,
+			"facilityID": facilityID,
+			"lines":      deliveredLines,
+		}
+		if err := s.addPurchaseOrderEvent(ctx, po, "PO_DELIVERY_RECEIVED", newStatus, details); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// requirePurchaseOrderSeller kiểm tra hàng giao cho đơn đặt hàng thuộc về bên bán của đơn.
+func requirePurchaseOrderSeller(po *PurchaseOrder, asset *MeatAsset) error {
+	if asset.OwnerOrg != po.SellerFacilityID {
+		return fmt.Errorf("asset %s belongs to facility %s but purchase order %s is sold by %s", asset.AssetID, asset.OwnerOrg, po.POID, po.SellerFacilityID)
+	}
+	return nil
+}
+
+// updatePurchaseOrderLineStatus tính lại chênh lệch và trạng thái của một dòng đơn đặt hàng.
+func updatePurchaseOrderLineStatus(line *PurchaseOrderLine) {
+	line.Variance = line.DeliveredQuantity.Value - line.Quantity.Value
+	switch {
+	case line.DeliveredQuantity.Value <= quantityTolerance:
+		line.Status = "OPEN"
+	case line.Variance < -quantityTolerance:
+		line.Status = "PARTIALLY_DELIVERED"
+	case line.Variance > quantityTolerance:
+		line.Status = "OVER_DELIVERED"
+	default:
+		line.Status = "DELIVERED"
+	}
+}
+
+// findPurchaseOrderLine trả về con trỏ tới dòng có lineID trong đơn đặt hàng, hoặc nil nếu không có.
+func findPurchaseOrderLine(po *PurchaseOrder, lineID string) *PurchaseOrderLine {
+	for i := range po.Lines {
+		if po.Lines[i].LineID == lineID {
+			return &po.Lines[i]
+		}
+	}
+	return nil
+}
+
+// requirePurchaseOrderParty kiểm tra người gọi thuộc cơ sở facilityID (bên mua hoặc bên bán của đơn).
+func requirePurchaseOrderParty(ctx contractapi.TransactionContextInterface, facilityID string) error {
+	callerFacilityID, found, err := getClientAttribute(ctx, "facilityID")
+	if err != nil {
+		return fmt.Errorf("failed to get 'facilityID' attribute: %v", err)
+	}
+	if !found || callerFacilityID != facilityID {
+		return fmt.Errorf("caller from facility '%s' is not authorized for this purchase order (expected '%s')", callerFacilityID, facilityID)
+	}
+	return nil
+}
+
+// Thêm một sự kiện vào đơn đặt hàng, cập nhật trạng thái mới và lưu lại.
+func (s *SmartContract) addPurchaseOrderEvent(ctx contractapi.TransactionContextInterface, po *PurchaseOrder, eventType string, newStatus string, details interface{}) error {
+	event, err := s.createEvent(ctx, eventType, details)
+	if err != nil {
+		return err
+	}
+	po.History = append(po.History, *event)
+	po.Status = newStatus
+	return s.updatePurchaseOrder(ctx, po)
+}
+
+// Lưu đơn đặt hàng vào world state.
+func (s *SmartContract) updatePurchaseOrder(ctx contractapi.TransactionContextInterface, po *PurchaseOrder) error {
+	poJSON, err := json.Marshal(po)
+	if err != nil {
+		return err
+	}
+	return ctx.GetStub().PutState(po.POID, poJSON)
+}
+
+// Đọc thông tin đơn đặt hàng từ world state.
+func (s *SmartContract) readPurchaseOrder(ctx contractapi.TransactionContextInterface, poID string) (*PurchaseOrder, error) {
+	poJSON, err := ctx.GetStub().GetState(poID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read from world state: %v", err)
+	}
+	if poJSON == nil {
+		return nil, fmt.Errorf("the purchase order %s does not exist", poID)
+	}
+	var po PurchaseOrder
+	if err := json.Unmarshal(poJSON, &po); err != nil {
+		return nil, err
+	}
+	if po.ObjectType != "PurchaseOrder" {
+		return nil, fmt.Errorf("%s is not a purchase order", poID)
+	}
+	return &po, nil
+}
@@ -0,0 +1,142 @@
+package main
+
+import "testing"
+
+// testOrderLines là các dòng của đơn đặt hàng PO-1: 8 con lợn nguyên con.
+var testOrderLines = []PurchaseOrderLine{{LineID: "L1", SKU: skuPorkCarcass, Quantity: Quantity{Unit: "head", Value: 8}}}
+
+// withOrder dựng đơn đặt hàng PO-1 của PROC-1 gửi FARM-1, chưa được chấp nhận.
+func withOrder(c *testChannel) {
+	withFarmBatch(c)
+	c.mustSubmit(processorAdmin, "CreatePurchaseOrder", "PO-1", "FARM-1", testOrderLines, "2024-03-10")
+}
+
+// withAcceptedOrder dựng đơn đặt hàng PO-1 đã được FARM-1 chấp nhận.
+func withAcceptedOrder(c *testChannel) {
+	withOrder(c)
+	c.mustSubmit(farmAdmin, "AcceptPurchaseOrder", "PO-1")
+}
+
+// deliverOrder giao quantity con từ FARM-BATCH-1 tới PROC-1 theo dòng L1 của PO-1 trên lô hàng shipmentID.
+func (c *testChannel) deliverOrder(shipmentID string, quantity float64) {
+	c.t.Helper()
+	item := testItem("FARM-BATCH-1", "head", quantity)
+	delivery := testStop("PROC-1", "DELIVERY", ItemInShipment{AssetID: "FARM-BATCH-1", Quantity: item.Quantity, POID: "PO-1", POLineID: "L1"})
+	c.mustSubmit(farmAdmin, "CreateShipment", shipmentID, "REFRIGERATED", "driver-1", "Nguyen Van A", "51C-123.45",
+		[]StopInJourney{testStop("FARM-1", "PICKUP", item), delivery})
+	c.mustSubmit(driver, "AddPickupProof", shipmentID, "FARM-1", testProof("FARM-1"))
+	c.mustSubmit(farmAdmin, "ConfirmPickup", shipmentID, "FARM-1", []ItemInShipment{item})
+	c.mustSubmit(driver, "StartShipment", shipmentID, []string{"SEAL-" + shipmentID})
+	c.mustSubmit(driver, "AddDeliveryProof", shipmentID, "PROC-1", testProof("PROC-1"))
+	c.mustSubmit(processorAdmin, "ConfirmShipmentDelivery", shipmentID, "PROC-1", "RCV-"+shipmentID, []string{"SEAL-" + shipmentID})
+}
+
+func TestCreatePurchaseOrder(t *testing.T) {
+	runTransactionCases(t, withOrder, []transactionCase{
+		{name: "buyer creates order", identity: processorAdmin, function: "CreatePurchaseOrder", args: []interface{}{"PO-2", "FARM-1", testOrderLines, "2024-03-10T08:00:00Z"}},
+		{name: "duplicate order", identity: processorAdmin, function: "CreatePurchaseOrder", args: []interface{}{"PO-1", "FARM-1", testOrderLines, "2024-03-10"},
+			wantErr: "purchase order PO-1 already exists"},
+		{name: "seller must differ from buyer", identity: processorAdmin, function: "CreatePurchaseOrder", args: []interface{}{"PO-2", "PROC-1", testOrderLines, "2024-03-10"},
+			wantErr: "seller facility must be set and different from the buyer facility"},
+		{name: "invalid delivery date", identity: processorAdmin, function: "CreatePurchaseOrder", args: []interface{}{"PO-2", "FARM-1", testOrderLines, "10/03/2024"},
+			wantErr: "invalid requestedDeliveryDate '10/03/2024'"},
+		{name: "no lines", identity: processorAdmin, function: "CreatePurchaseOrder", args: []interface{}{"PO-2", "FARM-1", []PurchaseOrderLine{}, "2024-03-10"},
+			wantErr: "purchase order PO-2 must have at least one line"},
+		{name: "duplicate line", identity: processorAdmin, function: "CreatePurchaseOrder",
+			args:    []interface{}{"PO-2", "FARM-1", append(append([]PurchaseOrderLine{}, testOrderLines...), testOrderLines...), "2024-03-10"},
+			wantErr: "line 2 of purchase order PO-2 must have a unique lineID"},
+		{name: "unknown SKU", identity: processorAdmin, function: "CreatePurchaseOrder",
+			args:    []interface{}{"PO-2", "FARM-1", []PurchaseOrderLine{{LineID: "L1", SKU: "BEEF", Quantity: Quantity{Unit: "kg", Value: 1}}}, "2024-03-10"},
+			wantErr: "product with SKU BEEF does not exist"},
+		{name: "non-positive quantity", identity: processorAdmin, function: "CreatePurchaseOrder",
+			args:    []interface{}{"PO-2", "FARM-1", []PurchaseOrderLine{{LineID: "L1", SKU: skuPorkCarcass}}, "2024-03-10"},
+			wantErr: "quantity of line L1 must be positive"},
+		{name: "driver denied", identity: driver, function: "CreatePurchaseOrder", args: []interface{}{"PO-2", "FARM-1", testOrderLines, "2024-03-10"},
+			wantErr: "access to CreatePurchaseOrder denied"},
+	})
+}
+
+func TestPurchaseOrderDecisions(t *testing.T) {
+	runTransactionCases(t, withOrder, []transactionCase{
+		{name: "seller accepts", identity: farmAdmin, function: "AcceptPurchaseOrder", args: []interface{}{"PO-1"}},
+		{name: "buyer cannot accept", identity: processorAdmin, function: "AcceptPurchaseOrder", args: []interface{}{"PO-1"},
+			wantErr: "caller from facility 'PROC-1' is not authorized for this purchase order (expected 'FARM-1')"},
+		{name: "worker denied", identity: farmWorker, function: "AcceptPurchaseOrder", args: []interface{}{"PO-1"}, wantErr: "access to AcceptPurchaseOrder denied"},
+		{name: "seller rejects", identity: farmAdmin, function: "RejectPurchaseOrder", args: []interface{}{"PO-1", "Out of stock"}},
+		{name: "reject reason required", identity: farmAdmin, function: "RejectPurchaseOrder", args: []interface{}{"PO-1", ""}, wantErr: "a reason is required"},
+		{name: "other farm cannot reject", identity: otherFarmAdmin, function: "RejectPurchaseOrder", args: []interface{}{"PO-1", "x"}, wantErr: "is not authorized for this purchase order"},
+		{name: "cannot fulfil before acceptance", identity: processorAdmin, function: "FulfilPurchaseOrder", args: []interface{}{"PO-1", ""},
+			wantErr: "purchase order PO-1 with status 'CREATED' cannot be fulfilled"},
+		{name: "unknown order", identity: farmAdmin, function: "AcceptPurchaseOrder", args: []interface{}{"PO-9"}, wantErr: "the purchase order PO-9 does not exist"},
+		{name: "asset is not an order", identity: farmAdmin, function: "AcceptPurchaseOrder", args: []interface{}{"FARM-BATCH-1"}, wantErr: "FARM-BATCH-1 is not a purchase order"},
+	})
+	runTransactionCases(t, withAcceptedOrder, []transactionCase{
+		{name: "accept twice", identity: farmAdmin, function: "AcceptPurchaseOrder", args: []interface{}{"PO-1"}, wantErr: "with status 'ACCEPTED' cannot be accepted"},
+		{name: "reject after acceptance", identity: farmAdmin, function: "RejectPurchaseOrder", args: []interface{}{"PO-1", "x"}, wantErr: "with status 'ACCEPTED' cannot be rejected"},
+		{name: "buyer closes short order", identity: processorAdmin, function: "FulfilPurchaseOrder", args: []interface{}{"PO-1", "Remaining pigs not needed"}},
+		{name: "seller cannot fulfil", identity: farmAdmin, function: "FulfilPurchaseOrder", args: []interface{}{"PO-1", ""}, wantErr: "is not authorized for this purchase order"},
+	})
+}
+
+func TestPurchaseOrderDeliveries(t *testing.T) {
+	t.Run("deliveries update lines and status", func(t *testing.T) {
+		c := newTestChannel(t)
+		withAcceptedOrder(c)
+		c.deliverOrder("SHIP-1", 5)
+
+		po := c.mustEvaluate(farmAdmin, "GetPurchaseOrder", "PO-1").(*PurchaseOrder)
+		if po.Status != POStatusPartiallyFulfilled || po.Lines[0].Status != "PARTIALLY_DELIVERED" || po.Lines[0].Variance != -3 {
+			t.Fatalf("after first delivery: order %s, line %s, variance %v", po.Status, po.Lines[0].Status, po.Lines[0].Variance)
+		}
+		if details := c.asset("RCV-SHIP-1-0").History[0].Details.(map[string]interface{}); details["poID"] != "PO-1" || details["poLineID"] != "L1" {
+			t.Fatalf("receiving event does not reference the order: %v", details)
+		}
+
+		c.deliverOrder("SHIP-2", 4)
+		po = c.mustEvaluate(processorAdmin, "GetPurchaseOrder", "PO-1").(*PurchaseOrder)
+		if po.Status != POStatusFulfilled || po.Lines[0].Status != "OVER_DELIVERED" || po.Lines[0].Variance != 1 {
+			t.Fatalf("after second delivery: order %s, line %s, variance %v", po.Status, po.Lines[0].Status, po.Lines[0].Variance)
+		}
+	})
+
+	t.Run("shipment must reference a deliverable order between the sender and the receiver", func(t *testing.T) {
+		c := newTestChannel(t)
+		withOrder(c)
+		book := func(facilityID string, lineID string) error {
+			item := testItem("FARM-BATCH-1", "head", 1)
+			delivery := testStop(facilityID, "DELIVERY", ItemInShipment{AssetID: "FARM-BATCH-1", Quantity: item.Quantity, POID: "PO-1", POLineID: lineID})
+			_, err := c.submit(farmAdmin, "CreateShipment", "SHIP-1", "REFRIGERATED", "driver-1", "Nguyen Van A", "51C-123.45",
+				[]StopInJourney{testStop("FARM-1", "PICKUP", item), delivery})
+			return err
+		}
+		expectError(t, book("PROC-1", "L1"), "purchase order PO-1 with status 'CREATED' cannot receive deliveries")
+		c.mustSubmit(farmAdmin, "AcceptPurchaseOrder", "PO-1")
+		expectError(t, book("WH-1", "L1"), "purchase order PO-1 is for buyer PROC-1, not delivery facility WH-1")
+		expectError(t, book("PROC-1", "L9"), "purchase order PO-1 has no line L9")
+		expectError(t, book("PROC-1", "L1"), "")
+
+		// Hàng của một trang trại khác không đáp ứng được đơn của FARM-1
+		c.createFarmBatch(otherFarmAdmin, "FARM-BATCH-2", skuPorkCarcass, Quantity{Unit: "head", Value: 5})
+		item := testItem("FARM-BATCH-2", "head", 1)
+		delivery := testStop("PROC-1", "DELIVERY", ItemInShipment{AssetID: "FARM-BATCH-2", Quantity: item.Quantity, POID: "PO-1", POLineID: "L1"})
+		_, err := c.submit(otherFarmAdmin, "CreateShipment", "SHIP-2", "REFRIGERATED", "driver-1", "Nguyen Van A", "51C-123.45",
+			[]StopInJourney{testStop("FARM-2", "PICKUP", item), delivery})
+		expectError(t, err, "asset FARM-BATCH-2 belongs to facility FARM-2 but purchase order PO-1 is sold by FARM-1")
+	})
+}
+
+func TestQueryPurchaseOrdersByFacility(t *testing.T) {
+	c := newTestChannel(t)
+	withOrder(c)
+	c.mustSubmit(retailerAdmin, "CreatePurchaseOrder", "PO-2", "PROC-1", []PurchaseOrderLine{{LineID: "L1", SKU: skuPorkTray, Quantity: Quantity{Unit: "tray", Value: 24}}}, "2024-03-12")
+
+	if orders := c.mustEvaluate(processorAdmin, "QueryPurchaseOrdersByFacility", "PROC-1").([]*PurchaseOrder); len(orders) != 2 || orders[0].POID != "PO-2" {
+		t.Fatalf("PROC-1 must see both orders, newest first")
+	}
+	if orders := c.mustEvaluate(farmAdmin, "QueryPurchaseOrdersByFacility", "FARM-1").([]*PurchaseOrder); len(orders) != 1 || orders[0].POID != "PO-1" {
+		t.Fatalf("FARM-1 must only see PO-1")
+	}
+	if orders := c.mustEvaluate(regulator, "QueryPurchaseOrdersByFacility", "WH-1").([]*PurchaseOrder); len(orders) != 0 {
+		t.Fatalf("WH-1 has %d orders, want none", len(orders))
+	}
+}
@@ -0,0 +1,267 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// Các trạng thái quản lý của asset.
+const (
+	RegulatoryStatusClear    = "CLEAR"
+	RegulatoryStatusHold     = "HOLD"
+	RegulatoryStatusRecalled = "RECALLED"
+)
+
+// Các loại sự kiện mà cơ quan quản lý được ghi bằng RecordRegulatoryEvent.
+var regulatoryEventTypes = []string{"INSPECTION", "SAMPLING", "LAB_RESULT", "NOTICE"}
+
+// Các sự kiện đưa hàng tiếp đi trong chuỗi cung ứng, bị chặn khi asset đang bị tạm giữ hoặc thu hồi.
+var regulatoryBlockedEvents = []string{
+	"PROCESSING",
+	"SPLIT_INTO_UNITS",
+	"SOLD",
+	"BOOKED_ON_SHIPMENT",
+	"PICKED_UP_FOR_SHIPMENT",
+	"SHIPPING_STARTED",
+	"RESERVED",
+	"HOLD_RELEASED",
+}
+
+// RecordRegulatoryEvent ghi một sự kiện quản lý (thanh tra, lấy mẫu, kết quả xét nghiệm, thông báo) cho asset.
+func (s *SmartContract) RecordRegulatoryEvent(ctx contractapi.TransactionContextInterface, assetID string, eventType string, detailsJSON string) error {
+	if !containsString(regulatoryEventTypes, eventType) {
+		return fmt.Errorf("invalid regulatory event type '%s', expected one of %v", eventType, regulatoryEventTypes)
+	}
+	var details map[string]interface{}
+	if detailsJSON != "" {
+		if err := json.Unmarshal([]byte(detailsJSON), &details); err != nil {
+			return fmt.Errorf("failed to unmarshal detailsJSON: %v", err)
+		}
+	}
+	if _, err := s.readAsset(ctx, assetID); err != nil {
+		return err
+	}
+	status, err := readRegulatoryStatus(ctx, assetID)
+	if err != nil {
+		return err
+	}
+	return s.addRegulatoryEvent(ctx, status, eventType, status.Status, details)
+}
+
+// PlaceRegulatoryHold tạm giữ một asset theo quyết định của cơ quan quản lý;
+// asset không thể được chế biến, vận chuyển hay bán cho đến khi được gỡ tạm giữ.
+func (s *SmartContract) PlaceRegulatoryHold(ctx contractapi.TransactionContextInterface, assetID string, reason string) error {
+	if reason == "" {
+		return fmt.Errorf("a reason is required to place a regulatory hold on asset %s", assetID)
+	}
+	if _, err := s.readAsset(ctx, assetID); err != nil {
+		return err
+	}
+	status, err := readRegulatoryStatus(ctx, assetID)
+	if err != nil {
+		return err
+	}
+	if status.Status != RegulatoryStatusClear {
+		return fmt.Errorf("asset %s already has regulatory status '%s'", assetID, status.Status)
+	}
+	status.Reason = reason
+	return s.addRegulatoryEvent(ctx, status, "REGULATORY_HOLD_PLACED", RegulatoryStatusHold, map[string]interface{}{"reason": reason})
+}
+
+// ReleaseRegulatoryHold gỡ tạm giữ quản lý của một asset.
+func (s *SmartContract) ReleaseRegulatoryHold(ctx contractapi.TransactionContextInterface, assetID string, resolution string) error {
+	if resolution == "" {
+		return fmt.Errorf("a resolution is required to release the regulatory hold on asset %s", assetID)
+	}
+	status, err := readRegulatoryStatus(ctx, assetID)
+	if err != nil {
+		return err
+	}
+	if status.Status != RegulatoryStatusHold {
+		return fmt.Errorf("asset %s with regulatory status '%s' is not on hold", assetID, status.Status)
+	}
+	status.Reason = ""
+	return s.addRegulatoryEvent(ctx, status, "REGULATORY_HOLD_RELEASED", RegulatoryStatusClear, map[string]interface{}{"resolution": resolution})
+}
+
+// RecallAsset thu hồi một asset cùng toàn bộ các asset con cháu được tạo ra từ nó
+// (qua chế biến, chia đơn vị hoặc nhận hàng từ lô vận chuyển).
+func (s *SmartContract) RecallAsset(ctx contractapi.TransactionContextInterface, assetID string, recallID string, reason string) error {
+	if recallID == "" || reason == "" {
+		return fmt.Errorf("a recall ID and a reason are required to recall asset %s", assetID)
+	}
+	if _, err := s.readAsset(ctx, assetID); err != nil {
+		return err
+	}
+
+	queue := []string{assetID}
+	processedIDs := map[string]bool{assetID: true}
+	for len(queue) > 0 {
+		currentID := queue[0]
+		queue = queue[1:]
+
+		status, err := readRegulatoryStatus(ctx, currentID)
+		if err != nil {
+			return err
+		}
+		if status.Status != RegulatoryStatusRecalled {
+			status.Reason = reason
+			status.RecallID = recallID
+			details := map[string]interface{}{"recallID": recallID, "reason": reason, "rootAssetID": assetID}
+			if err := s.addRegulatoryEvent(ctx, status, "RECALLED", RegulatoryStatusRecalled, details); err != nil {
+				return err
+			}
+		}
+
+		childIDs, err := queryChildAssetIDs(ctx, currentID)
+		if err != nil {
+			return err
+		}
+		for _, childID := range childIDs {
+			if !processedIDs[childID] {
+				processedIDs[childID] = true
+				queue = append(queue, childID)
+			}
+		}
+	}
+	return nil
+}
+
+// GetRegulatoryStatus trả về hồ sơ quản lý của asset (CLEAR nếu chưa có sự kiện quản lý nào).
+func (s *SmartContract) GetRegulatoryStatus(ctx contractapi.TransactionContextInterface, assetID string) (*RegulatoryStatus, error) {
+	return readRegulatoryStatus(ctx, assetID)
+}
+
+// --- Các hàm hỗ trợ cho hồ sơ quản lý ---
+
+// requireNoRegulatoryBlock từ chối các sự kiện đưa asset tiếp đi khi asset đang bị tạm giữ hoặc thu hồi.
+func requireNoRegulatoryBlock(ctx contractapi.TransactionContextInterface, asset *MeatAsset, eventType string) error {
+	if !containsString(regulatoryBlockedEvents, eventType) {
+		return nil
+	}
+	status, err := readRegulatoryStatus(ctx, asset.AssetID)
+	if err != nil {
+		return err
+	}
+	if status.Status != RegulatoryStatusClear {
+		return fmt.Errorf("asset %s is under regulatory status '%s' (%s)", asset.AssetID, status.Status, status.Reason)
+	}
+	return nil
+}
+
+// inheritRegulatoryStatus chép trạng thái tạm giữ hoặc thu hồi của asset nguồn sang asset nhận được khi giao hàng,
+// để lệnh tạm giữ/thu hồi đặt lúc hàng đang trên đường vẫn chặn hàng tại bên nhận. Tạm giữ của asset nhận được
+// phải được cơ quan quản lý gỡ riêng.
+func (s *SmartContract) inheritRegulatoryStatus(ctx contractapi.TransactionContextInterface, sourceAssetID string, assetID string) error {
+	sourceStatus, err := readRegulatoryStatus(ctx, sourceAssetID)
+	if err != nil {
+		return err
+	}
+	if sourceStatus.Status == RegulatoryStatusClear {
+		return nil
+	}
+	status, err := readRegulatoryStatus(ctx, assetID)
+	if err != nil {
+		return err
+	}
+	status.Reason = sourceStatus.Reason
+	status.RecallID = sourceStatus.RecallID
+	details := map[string]interface{}{"sourceAssetID": sourceAssetID, "reason": sourceStatus.Reason}
+	if sourceStatus.RecallID != "" {
+		details["recallID"] = sourceStatus.RecallID
+	}
+	return s.addRegulatoryEvent(ctx, status, "REGULATORY_STATUS_INHERITED", sourceStatus.Status, details)
+}
+
+// Thêm một sự kiện vào hồ sơ quản lý, cập nhật trạng thái mới và lưu lại.
+func (s *SmartContract) addRegulatoryEvent(ctx contractapi.TransactionContextInterface, status *RegulatoryStatus, eventType string, newStatus string, details interface{}) error {
+	event, err := s.createEvent(ctx, eventType, details)
+	if err != nil {
+		return err
+	}
+	status.Events = append(status.Events, *event)
+	status.Status = newStatus
+
+	statusJSON, err := json.Marshal(status)
+	if err != nil {
+		return err
+	}
+	key, err := regulatoryStatusKey(ctx, status.AssetID)
+	if err != nil {
+		return err
+	}
+	if err := ctx.GetStub().PutState(key, statusJSON); err != nil {
+		return err
+	}
+	// Hồ sơ tạm giữ/thu hồi chỉ được thay đổi khi có xác nhận của peer cơ quan quản lý
+	return setKeyEndorsementPolicy(ctx, key, RegulatorOrgMSP)
+}
+
+// Đọc hồ sơ quản lý của asset từ world state.
+func readRegulatoryStatus(ctx contractapi.TransactionContextInterface, assetID string) (*RegulatoryStatus, error) {
+	key, err := regulatoryStatusKey(ctx, assetID)
+	if err != nil {
+		return nil, err
+	}
+	statusJSON, err := ctx.GetStub().GetState(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read from world state: %v", err)
+	}
+	if statusJSON == nil {
+		return &RegulatoryStatus{
+			ObjectType: "RegulatoryStatus",
+			AssetID:    assetID,
+			Status:     RegulatoryStatusClear,
+			Events:     []Event{},
+		}, nil
+	}
+	var status RegulatoryStatus
+	if err := json.Unmarshal(statusJSON, &status); err != nil {
+		return nil, err
+	}
+	return &status, nil
+}
+
+// regulatoryStatusKey tạo composite key của hồ sơ quản lý.
+func regulatoryStatusKey(ctx contractapi.TransactionContextInterface, assetID string) (string, error) {
+	key, err := ctx.GetStub().CreateCompositeKey("RegulatoryStatus", []string{assetID})
+	if err != nil {
+		return "", fmt.Errorf("failed to create composite key: %v", err)
+	}
+	return key, nil
+}
+
+// queryChildAssetIDs tìm các asset có assetID trong danh sách asset cha.
+func queryChildAssetIDs(ctx contractapi.TransactionContextInterface, assetID string) ([]string, error) {
+	query := map[string]interface{}{
+		"selector": map[string]interface{}{
+			"docType": "MeatAsset",
+			"parentAssetIDs": map[string]interface{}{
+				"$elemMatch": map[string]interface{}{"$eq": assetID},
+			},
+		},
+		"fields": []string{"assetID"},
+	}
+	queryBytes, err := json.Marshal(query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build query: %v", err)
+	}
+
+	resultsIterator, err := ctx.GetStub().GetQueryResult(string(queryBytes))
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute rich query: %v", err)
+	}
+	defer resultsIterator.Close()
+
+	var childIDs []string
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+		childIDs = append(childIDs, queryResponse.Key)
+	}
+	return childIDs, nil
+}
@@ -0,0 +1,150 @@
+package main
+
+import "testing"
+
+func TestRecordRegulatoryEvent(t *testing.T) {
+	details := map[string]interface{}{"inspector": "inspector-1", "result": "PASS"}
+	runTransactionCases(t, withFarmBatch, []transactionCase{
+		{name: "regulator records inspection", identity: regulator, function: "RecordRegulatoryEvent", args: []interface{}{"FARM-BATCH-1", "INSPECTION", details}},
+		{name: "details are optional", identity: regulator, function: "RecordRegulatoryEvent", args: []interface{}{"FARM-BATCH-1", "NOTICE", ""}},
+		{name: "invalid event type", identity: regulator, function: "RecordRegulatoryEvent", args: []interface{}{"FARM-BATCH-1", "FINE", details},
+			wantErr: "invalid regulatory event type 'FINE'"},
+		{name: "invalid details JSON", identity: regulator, function: "RecordRegulatoryEvent", args: []interface{}{"FARM-BATCH-1", "SAMPLING", "["},
+			wantErr: "failed to unmarshal detailsJSON"},
+		{name: "unknown asset", identity: regulator, function: "RecordRegulatoryEvent", args: []interface{}{"FARM-BATCH-9", "LAB_RESULT", details},
+			wantErr: "does not exist"},
+		{name: "supply chain admin denied", identity: farmAdmin, function: "RecordRegulatoryEvent", args: []interface{}{"FARM-BATCH-1", "INSPECTION", details},
+			wantErr: "access to RecordRegulatoryEvent denied"},
+		{name: "regulator role from wrong MSP denied", identity: newTestIdentity(MeatSupplyOrgMSP, "fake-inspector", RegulatorRole, "", ""),
+			function: "RecordRegulatoryEvent", args: []interface{}{"FARM-BATCH-1", "INSPECTION", details}, wantErr: "access to RecordRegulatoryEvent denied"},
+	})
+}
+
+func TestRegulatoryHold(t *testing.T) {
+	onHold := func(c *testChannel) {
+		withFarmBatch(c)
+		c.mustSubmit(regulator, "PlaceRegulatoryHold", "FARM-BATCH-1", "Suspected ASF outbreak")
+	}
+	runTransactionCases(t, withFarmBatch, []transactionCase{
+		{name: "regulator places hold", identity: regulator, function: "PlaceRegulatoryHold", args: []interface{}{"FARM-BATCH-1", "Suspected ASF outbreak"}},
+		{name: "reason required", identity: regulator, function: "PlaceRegulatoryHold", args: []interface{}{"FARM-BATCH-1", ""}, wantErr: "a reason is required"},
+		{name: "unknown asset", identity: regulator, function: "PlaceRegulatoryHold", args: []interface{}{"FARM-BATCH-9", "x"}, wantErr: "does not exist"},
+		{name: "admin denied", identity: farmAdmin, function: "PlaceRegulatoryHold", args: []interface{}{"FARM-BATCH-1", "x"}, wantErr: "access to PlaceRegulatoryHold denied"},
+		{name: "release without hold", identity: regulator, function: "ReleaseRegulatoryHold", args: []interface{}{"FARM-BATCH-1", "ok"},
+			wantErr: "asset FARM-BATCH-1 with regulatory status 'CLEAR' is not on hold"},
+	})
+	runTransactionCases(t, onHold, []transactionCase{
+		{name: "regulator releases hold", identity: regulator, function: "ReleaseRegulatoryHold", args: []interface{}{"FARM-BATCH-1", "Lab results negative"}},
+		{name: "resolution required", identity: regulator, function: "ReleaseRegulatoryHold", args: []interface{}{"FARM-BATCH-1", ""}, wantErr: "a resolution is required"},
+		{name: "hold twice", identity: regulator, function: "PlaceRegulatoryHold", args: []interface{}{"FARM-BATCH-1", "x"}, wantErr: "already has regulatory status 'HOLD'"},
+		{name: "admin cannot release", identity: farmAdmin, function: "ReleaseRegulatoryHold", args: []interface{}{"FARM-BATCH-1", "x"}, wantErr: "access to ReleaseRegulatoryHold denied"},
+		{name: "held asset cannot be booked", identity: farmAdmin, function: "CreateShipment",
+			args: []interface{}{"SHIP-1", "REFRIGERATED", "driver-1", "Nguyen Van A", "51C-123.45",
+				[]StopInJourney{testStop("FARM-1", "PICKUP", testItem("FARM-BATCH-1", "head", 1)), testStop("PROC-1", "DELIVERY")}},
+			wantErr: "asset FARM-BATCH-1 is under regulatory status 'HOLD' (Suspected ASF outbreak)"},
+		{name: "held asset cannot be reserved", identity: farmAdmin, function: "ReserveAsset", args: []interface{}{"FARM-BATCH-1", "ORDER", "PO-1", Quantity{Value: 1}},
+			wantErr: "is under regulatory status 'HOLD'"},
+		{name: "held asset can still be updated at the farm", identity: farmAdmin, function: "AddFeedToFarmingBatch", args: []interface{}{"FARM-BATCH-1", Feed{Name: "Corn mix"}}},
+	})
+
+	t.Run("status records every regulatory event", func(t *testing.T) {
+		c := newTestChannel(t)
+		onHold(c)
+		c.mustSubmit(regulator, "RecordRegulatoryEvent", "FARM-BATCH-1", "SAMPLING", map[string]interface{}{"sampleID": "S-1"})
+		c.mustSubmit(regulator, "ReleaseRegulatoryHold", "FARM-BATCH-1", "Lab results negative")
+
+		status := c.mustEvaluate(farmAdmin, "GetRegulatoryStatus", "FARM-BATCH-1").(*RegulatoryStatus)
+		if status.Status != RegulatoryStatusClear || len(status.Events) != 3 {
+			t.Fatalf("status %s with %d events, want CLEAR with 3 events", status.Status, len(status.Events))
+		}
+		if status.Events[1].Type != "SAMPLING" || status.Events[1].ActorMSP != RegulatorOrgMSP {
+			t.Fatalf("unexpected event %+v", status.Events[1])
+		}
+		if clear := c.mustEvaluate(farmAdmin, "GetRegulatoryStatus", "UNKNOWN").(*RegulatoryStatus); clear.Status != RegulatoryStatusClear {
+			t.Fatalf("asset without regulatory events has status %s", clear.Status)
+		}
+	})
+}
+
+func TestRegulatoryStatusFollowsDeliveredGoods(t *testing.T) {
+	inTransit := func(c *testChannel) {
+		withFarmBatch(c)
+		c.pickUpAndStart("SHIP-1", farmAdmin, "PROC-1", testItem("FARM-BATCH-1", "head", 4))
+		c.mustSubmit(driver, "AddDeliveryProof", "SHIP-1", "PROC-1", testProof("PROC-1"))
+	}
+
+	t.Run("hold placed in transit", func(t *testing.T) {
+		c := newTestChannel(t)
+		inTransit(c)
+		c.mustSubmit(regulator, "PlaceRegulatoryHold", "FARM-BATCH-1", "Suspected ASF outbreak")
+		c.mustSubmit(processorAdmin, "ConfirmShipmentDelivery", "SHIP-1", "PROC-1", "PROC-BATCH", []string{"SEAL-SHIP-1"})
+
+		status := c.mustEvaluate(regulator, "GetRegulatoryStatus", "PROC-BATCH-0").(*RegulatoryStatus)
+		if status.Status != RegulatoryStatusHold || status.Reason != "Suspected ASF outbreak" || status.Events[0].Type != "REGULATORY_STATUS_INHERITED" {
+			t.Fatalf("received asset has regulatory status %+v, want an inherited HOLD", status)
+		}
+		_, err := c.submit(processorAdmin, "ReserveAsset", "PROC-BATCH-0", "ORDER", "PO-1", Quantity{Unit: "head", Value: 1})
+		expectError(t, err, "asset PROC-BATCH-0 is under regulatory status 'HOLD' (Suspected ASF outbreak)")
+
+		c.mustSubmit(regulator, "ReleaseRegulatoryHold", "PROC-BATCH-0", "Lab results negative")
+		c.mustSubmit(processorAdmin, "ReserveAsset", "PROC-BATCH-0", "ORDER", "PO-1", Quantity{Unit: "head", Value: 1})
+	})
+
+	t.Run("recall issued in transit", func(t *testing.T) {
+		c := newTestChannel(t)
+		inTransit(c)
+		c.mustSubmit(regulator, "RecallAsset", "FARM-BATCH-1", "RC-2024-01", "Salmonella")
+		c.mustSubmit(processorAdmin, "ConfirmShipmentDelivery", "SHIP-1", "PROC-1", "PROC-BATCH", []string{"SEAL-SHIP-1"})
+
+		status := c.mustEvaluate(regulator, "GetRegulatoryStatus", "PROC-BATCH-0").(*RegulatoryStatus)
+		if status.Status != RegulatoryStatusRecalled || status.RecallID != "RC-2024-01" {
+			t.Fatalf("received asset has regulatory status %s (recall %s), want RECALLED", status.Status, status.RecallID)
+		}
+	})
+
+	t.Run("clear goods stay clear", func(t *testing.T) {
+		c := newTestChannel(t)
+		inTransit(c)
+		c.mustSubmit(processorAdmin, "ConfirmShipmentDelivery", "SHIP-1", "PROC-1", "PROC-BATCH", []string{"SEAL-SHIP-1"})
+		if status := c.mustEvaluate(regulator, "GetRegulatoryStatus", "PROC-BATCH-0").(*RegulatoryStatus); status.Status != RegulatoryStatusClear || len(status.Events) != 0 {
+			t.Fatalf("received asset has regulatory status %+v, want CLEAR", status)
+		}
+	})
+}
+
+func TestRecallAsset(t *testing.T) {
+	runTransactionCases(t, atRetailer, []transactionCase{
+		{name: "regulator recalls", identity: regulator, function: "RecallAsset", args: []interface{}{"FARM-BATCH-1", "RC-2024-01", "Salmonella"}},
+		{name: "recall ID required", identity: regulator, function: "RecallAsset", args: []interface{}{"FARM-BATCH-1", "", "Salmonella"}, wantErr: "a recall ID and a reason are required"},
+		{name: "unknown asset", identity: regulator, function: "RecallAsset", args: []interface{}{"FARM-BATCH-9", "RC-1", "x"}, wantErr: "does not exist"},
+		{name: "admin denied", identity: processorAdmin, function: "RecallAsset", args: []interface{}{"FARM-BATCH-1", "RC-1", "x"}, wantErr: "access to RecallAsset denied"},
+	})
+
+	t.Run("recall reaches every descendant", func(t *testing.T) {
+		c := newTestChannel(t)
+		atRetailer(c)
+		c.mustSubmit(retailerAdmin, "SplitBatchToUnits", "RETAIL-BATCH-0", 2, "UNIT-")
+		c.createFarmBatch(farmAdmin, "FARM-BATCH-2", skuPorkCarcass, Quantity{Unit: "head", Value: 1})
+		c.mustSubmit(regulator, "RecallAsset", "FARM-BATCH-1", "RC-2024-01", "Salmonella")
+
+		for _, assetID := range []string{"FARM-BATCH-1", "PROC-BATCH-0", "TRAY-1", "RETAIL-BATCH-0", "UNIT-1", "UNIT-2"} {
+			status := c.mustEvaluate(regulator, "GetRegulatoryStatus", assetID).(*RegulatoryStatus)
+			if status.Status != RegulatoryStatusRecalled || status.RecallID != "RC-2024-01" {
+				t.Fatalf("asset %s has regulatory status %s (recall %s), want RECALLED", assetID, status.Status, status.RecallID)
+			}
+		}
+		if status := c.mustEvaluate(regulator, "GetRegulatoryStatus", "FARM-BATCH-2").(*RegulatoryStatus); status.Status != RegulatoryStatusClear {
+			t.Fatalf("unrelated batch FARM-BATCH-2 was recalled")
+		}
+		_, err := c.submit(retailerAdmin, "MarkAsSold", "UNIT-1", map[string]interface{}{})
+		expectError(t, err, "asset UNIT-1 is under regulatory status 'RECALLED' (Salmonella)")
+		_, err = c.submit(regulator, "ReleaseRegulatoryHold", "UNIT-1", "x")
+		expectError(t, err, "is not on hold")
+
+		// Thu hồi lại không ghi thêm sự kiện cho asset đã bị thu hồi
+		c.mustSubmit(regulator, "RecallAsset", "TRAY-1", "RC-2024-02", "Follow-up")
+		if status := c.mustEvaluate(regulator, "GetRegulatoryStatus", "UNIT-1").(*RegulatoryStatus); len(status.Events) != 1 || status.RecallID != "RC-2024-01" {
+			t.Fatalf("recalled unit got %d events and recall %s", len(status.Events), status.RecallID)
+		}
+	})
+}
@@ -0,0 +1,318 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// ReserveAsset giữ chỗ một phần số lượng của asset cho một đơn hàng (ORDER) hoặc lô hàng (SHIPMENT).
+// Giữ chỗ thêm cho cùng một tham chiếu sẽ được cộng dồn. Chỉ cơ sở sở hữu asset mới được giữ chỗ.
+// Giữ chỗ ORDER với referenceID là mã đơn đặt hàng được dùng khi một lô hàng giao asset theo đơn đó đặt chỗ hoặc lấy hàng.
+func (s *SmartContract) ReserveAsset(ctx contractapi.TransactionContextInterface, assetID string, referenceType string, referenceID string, quantityJSON string) error {
+	if referenceType != "ORDER" && referenceType != "SHIPMENT" {
+		return fmt.Errorf("invalid reference type '%s', expected ORDER or SHIPMENT", referenceType)
+	}
+	if referenceID == "" {
+		return fmt.Errorf("a reference ID is required to reserve asset %s", assetID)
+	}
+	asset, err := s.readAsset(ctx, assetID)
+	if err != nil {
+		return err
+	}
+	if err := requireOwnership(ctx, asset); err != nil {
+		return err
+	}
+	if err := requireAssetAction(ctx, asset, "ReserveAsset"); err != nil {
+		return err
+	}
+
+	var quantity Quantity
+	if err := json.Unmarshal([]byte(quantityJSON), &quantity); err != nil {
+		return fmt.Errorf("failed to unmarshal quantityJSON: %v", err)
+	}
+	if quantity.Value <= 0 {
+		return fmt.Errorf("reserved quantity for asset %s must be positive", assetID)
+	}
+	if quantity.Unit != "" && quantity.Unit != asset.CurrentQuantity.Unit {
+		return fmt.Errorf("unit '%s' for asset %s does not match asset unit '%s'", quantity.Unit, assetID, asset.CurrentQuantity.Unit)
+	}
+	quantity.Unit = asset.CurrentQuantity.Unit
+	if available := availableQuantity(asset); available < quantity.Value-quantityTolerance {
+		return fmt.Errorf("cannot reserve %f of asset %s: only %f available", quantity.Value, assetID, available)
+	}
+
+	merged := false
+	for i, reservation := range asset.Reservations {
+		if reservation.ReferenceType == referenceType && reservation.ReferenceID == referenceID {
+			asset.Reservations[i].Quantity.Value += quantity.Value
+			merged = true
+			break
+		}
+	}
+	if !merged {
+		asset.Reservations = append(asset.Reservations, Reservation{
+			ReferenceType: referenceType,
+			ReferenceID:   referenceID,
+			Quantity:      quantity,
+			Timestamp:     s.getTxTimestamp(ctx),
+		})
+	}
+
+	details := map[string]interface{}{
+		"referenceType": referenceType,
+		"referenceID":   referenceID,
+		"quantity":      quantity,
+	}
+	return s.addEvent(ctx, asset, "RESERVED", asset.Status, details)
+}
+
+// UnreserveAsset giải phóng toàn bộ phần giữ chỗ của một đơn hàng (ORDER) trên asset.
+// Chỗ đã đặt trên lô hàng (SHIPMENT) chỉ được giải phóng khi lô hàng bị hủy, trả về hoặc đóng cưỡng bức,
+// để hàng không bị đặt chỗ lần nữa trên lô hàng khác khi lô hàng đầu vẫn còn hiệu lực.
+func (s *SmartContract) UnreserveAsset(ctx contractapi.TransactionContextInterface, assetID string, referenceType string, referenceID string) error {
+	if referenceType == "SHIPMENT" {
+		return fmt.Errorf("shipment bookings on asset %s are released by cancelling, returning or closing shipment %s", assetID, referenceID)
+	}
+	asset, err := s.readAsset(ctx, assetID)
+	if err != nil {
+		return err
+	}
+	if err := requireOwnership(ctx, asset); err != nil {
+		return err
+	}
+	released := releaseReservation(asset, referenceType, referenceID)
+	if released == nil {
+		return fmt.Errorf("asset %s has no reservation for %s %s", assetID, referenceType, referenceID)
+	}
+
+	details := map[string]interface{}{
+		"referenceType": referenceType,
+		"referenceID":   referenceID,
+		"quantity":      *released,
+	}
+	return s.addEvent(ctx, asset, "UNRESERVED", asset.Status, details)
+}
+
+// bookShipmentItems giữ chỗ (BOOKED_ON_SHIPMENT) số lượng của các asset được khai báo tại các điểm lấy hàng,
+// từ chối nếu số lượng khả dụng (hiện có trừ phần đã giữ chỗ cho lô hàng khác) không đủ.
+// Chỉ cơ sở của điểm lấy hàng hoặc tài xế được chỉ định mới được đặt chỗ, và chỉ cho asset do cơ sở đó sở hữu,
+// để không ai khóa được hàng của cơ sở khác.
+func (s *SmartContract) bookShipmentItems(ctx contractapi.TransactionContextInterface, shipmentID string, driverEnrollmentID string, stops []StopInJourney) error {
+	callerFacilityID, _, err := getClientAttribute(ctx, "facilityID")
+	if err != nil {
+		return fmt.Errorf("failed to get 'facilityID' attribute: %v", err)
+	}
+	callerEnrollmentID, err := getEnrollmentID(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get client enrollment ID: %v", err)
+	}
+
+	// Gộp số lượng theo asset vì một transaction không đọc được dữ liệu chính nó vừa ghi.
+	var assetOrder []string
+	bookings := make(map[string]Quantity)
+	bookingFacilities := make(map[string]string)
+	for _, stop := range stops {
+		if stop.Action != "PICKUP" || len(stop.Items) == 0 {
+			continue
+		}
+		if callerFacilityID != stop.FacilityID && callerEnrollmentID != driverEnrollmentID {
+			return fmt.Errorf("caller '%s' can only book stock at facility %s as that facility or as the assigned driver", callerEnrollmentID, stop.FacilityID)
+		}
+		for _, item := range stop.Items {
+			if facilityID, exists := bookingFacilities[item.AssetID]; exists && facilityID != stop.FacilityID {
+				return fmt.Errorf("asset %s is booked for pickup at both %s and %s", item.AssetID, facilityID, stop.FacilityID)
+			}
+			bookingFacilities[item.AssetID] = stop.FacilityID
+			if item.Quantity.Value <= 0 {
+				return fmt.Errorf("booked quantity for asset %s must be positive", item.AssetID)
+			}
+			booked, exists := bookings[item.AssetID]
+			if !exists {
+				assetOrder = append(assetOrder, item.AssetID)
+				booked.Unit = item.Quantity.Unit
+			}
+			booked.Value += item.Quantity.Value
+			bookings[item.AssetID] = booked
+		}
+	}
+
+	for _, assetID := range assetOrder {
+		booked := bookings[assetID]
+		asset, err := s.readAsset(ctx, assetID)
+		if err != nil {
+			return err
+		}
+		if asset.OwnerOrg != bookingFacilities[assetID] {
+			return fmt.Errorf("asset %s is owned by '%s' and cannot be picked up at facility %s", assetID, asset.OwnerOrg, bookingFacilities[assetID])
+		}
+		if booked.Unit != "" && booked.Unit != asset.CurrentQuantity.Unit {
+			return fmt.Errorf("unit '%s' for asset %s does not match asset unit '%s'", booked.Unit, assetID, asset.CurrentQuantity.Unit)
+		}
+		booked.Unit = asset.CurrentQuantity.Unit
+		// Phần giữ chỗ cho các đơn đặt hàng mà lô hàng giao tới được chuyển thành phần đặt chỗ của lô hàng
+		consumedOrders := consumeOrderReservations(asset, stops, booked.Value)
+		if available := availableQuantity(asset); available < booked.Value-quantityTolerance {
+			return fmt.Errorf("cannot book %f of asset %s on shipment %s: only %f available", booked.Value, assetID, shipmentID, available)
+		}
+
+		asset.Reservations = append(asset.Reservations, Reservation{
+			ReferenceType: "SHIPMENT",
+			ReferenceID:   shipmentID,
+			Quantity:      booked,
+			Timestamp:     s.getTxTimestamp(ctx),
+		})
+		details := map[string]interface{}{
+			"shipmentID": shipmentID,
+			"quantity":   booked,
+		}
+		if len(consumedOrders) > 0 {
+			details["orderReservationsUsed"] = consumedOrders
+		}
+		if err := s.addEvent(ctx, asset, "BOOKED_ON_SHIPMENT", asset.Status, details); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// releaseShipmentBookings giải phóng phần giữ chỗ của lô hàng trên các asset trong danh sách và ghi sự kiện BOOKING_RELEASED.
+func (s *SmartContract) releaseShipmentBookings(ctx contractapi.TransactionContextInterface, shipmentID string, items []ItemInShipment, reason string) error {
+	released := make(map[string]bool)
+	for _, item := range items {
+		if released[item.AssetID] {
+			continue
+		}
+		released[item.AssetID] = true
+
+		asset, err := s.readAsset(ctx, item.AssetID)
+		if err != nil {
+			return err
+		}
+		quantity := releaseReservation(asset, "SHIPMENT", shipmentID)
+		if quantity == nil {
+			continue
+		}
+		details := map[string]interface{}{
+			"shipmentID": shipmentID,
+			"quantity":   *quantity,
+			"reason":     reason,
+		}
+		if err := s.addEvent(ctx, asset, "BOOKING_RELEASED", asset.Status, details); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// releaseReservation xóa phần giữ chỗ của tham chiếu khỏi asset (chỉ trong bộ nhớ) và trả về số lượng đã giải phóng,
+// hoặc nil nếu asset không có phần giữ chỗ nào cho tham chiếu đó.
+func releaseReservation(asset *MeatAsset, referenceType string, referenceID string) *Quantity {
+	var released *Quantity
+	remaining := []Reservation{}
+	for _, reservation := range asset.Reservations {
+		if reservation.ReferenceType == referenceType && reservation.ReferenceID == referenceID {
+			if released == nil {
+				released = &Quantity{Unit: reservation.Quantity.Unit}
+			}
+			released.Value += reservation.Quantity.Value
+			continue
+		}
+		remaining = append(remaining, reservation)
+	}
+	asset.Reservations = remaining
+	return released
+}
+
+// consumeOrderReservations dùng phần giữ chỗ ORDER của các đơn đặt hàng mà lô hàng giao asset tới (tham chiếu POID
+// tại các điểm giao), để hàng đã giữ cho một đơn được chở đi theo chính đơn đó. Mỗi đơn được trừ tối đa số lượng
+// điểm giao dự kiến cho đơn (hoặc toàn bộ nếu điểm giao không ghi số lượng), tổng cộng không vượt quá quantity.
+// Hàm chỉ thay đổi asset trong bộ nhớ và trả về các phần giữ chỗ đã dùng.
+func consumeOrderReservations(asset *MeatAsset, stops []StopInJourney, quantity float64) []Reservation {
+	var poOrder []string
+	planned := make(map[string]float64)
+	for _, stop := range stops {
+		if stop.Action != "DELIVERY" {
+			continue
+		}
+		for _, item := range stop.Items {
+			if item.AssetID != asset.AssetID || item.POID == "" {
+				continue
+			}
+			if _, exists := planned[item.POID]; !exists {
+				poOrder = append(poOrder, item.POID)
+			}
+			planned[item.POID] += item.Quantity.Value
+		}
+	}
+
+	var consumed []Reservation
+	remaining := quantity
+	for _, poID := range poOrder {
+		for i := range asset.Reservations {
+			reservation := &asset.Reservations[i]
+			if remaining <= quantityTolerance {
+				break
+			}
+			if reservation.ReferenceType != "ORDER" || reservation.ReferenceID != poID {
+				continue
+			}
+			used := reservation.Quantity.Value
+			if planned[poID] > 0 && used > planned[poID] {
+				used = planned[poID]
+			}
+			if used > remaining {
+				used = remaining
+			}
+			reservation.Quantity.Value -= used
+			remaining -= used
+			consumed = append(consumed, Reservation{
+				ReferenceType: reservation.ReferenceType,
+				ReferenceID:   reservation.ReferenceID,
+				Quantity:      Quantity{Unit: reservation.Quantity.Unit, Value: used},
+			})
+		}
+	}
+
+	kept := []Reservation{}
+	for _, reservation := range asset.Reservations {
+		if reservation.Quantity.Value > quantityTolerance {
+			kept = append(kept, reservation)
+		}
+	}
+	asset.Reservations = kept
+	return consumed
+}
+
+// refreshAvailability tính lại số lượng đã giữ chỗ và số lượng khả dụng của asset.
+func refreshAvailability(asset *MeatAsset) {
+	unit := asset.CurrentQuantity.Unit
+	asset.ReservedQuantity = Quantity{Unit: unit, Value: reservedQuantity(asset)}
+	asset.AvailableQuantity = Quantity{Unit: unit, Value: availableQuantity(asset)}
+}
+
+// reservedQuantity tính tổng số lượng đang được giữ chỗ trên asset.
+func reservedQuantity(asset *MeatAsset) float64 {
+	total := 0.0
+	for _, reservation := range asset.Reservations {
+		total += reservation.Quantity.Value
+	}
+	return total
+}
+
+// availableQuantity là số lượng còn có thể cam kết: số lượng hiện có trừ phần đã giữ chỗ.
+func availableQuantity(asset *MeatAsset) float64 {
+	return asset.CurrentQuantity.Value - reservedQuantity(asset)
+}
+
+// pendingPickupItems liệt kê hàng đã đặt chỗ tại các điểm lấy hàng chưa hoàn tất.
+func pendingPickupItems(shipment *ShipmentAsset) []ItemInShipment {
+	var items []ItemInShipment
+	for _, stop := range shipment.Stops {
+		if stop.Action == "PICKUP" && stop.Status != "COMPLETED" {
+			items = append(items, stop.Items...)
+		}
+	}
+	return items
+}
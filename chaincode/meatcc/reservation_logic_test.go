@@ -0,0 +1,135 @@
+package main
+
+import "testing"
+
+func TestReserveAsset(t *testing.T) {
+	quantity := Quantity{Unit: "head", Value: 3}
+	runTransactionCases(t, withPendingShipment, []transactionCase{
+		{name: "owner reserves for order", identity: farmWorker, function: "ReserveAsset", args: []interface{}{"FARM-BATCH-1", "ORDER", "PO-1", quantity}},
+		{name: "unit defaults to asset unit", identity: farmWorker, function: "ReserveAsset", args: []interface{}{"FARM-BATCH-1", "ORDER", "PO-1", Quantity{Value: 4}}},
+		{name: "more than available", identity: farmWorker, function: "ReserveAsset", args: []interface{}{"FARM-BATCH-1", "ORDER", "PO-1", Quantity{Value: 5}},
+			wantErr: "cannot reserve 5.000000 of asset FARM-BATCH-1: only 4.000000 available"},
+		{name: "unit mismatch", identity: farmWorker, function: "ReserveAsset", args: []interface{}{"FARM-BATCH-1", "ORDER", "PO-1", Quantity{Unit: "kg", Value: 1}},
+			wantErr: "unit 'kg' for asset FARM-BATCH-1 does not match asset unit 'head'"},
+		{name: "non-positive quantity", identity: farmWorker, function: "ReserveAsset", args: []interface{}{"FARM-BATCH-1", "ORDER", "PO-1", Quantity{Value: 0}},
+			wantErr: "reserved quantity for asset FARM-BATCH-1 must be positive"},
+		{name: "invalid reference type", identity: farmWorker, function: "ReserveAsset", args: []interface{}{"FARM-BATCH-1", "INVOICE", "INV-1", quantity},
+			wantErr: "invalid reference type 'INVOICE'"},
+		{name: "reference required", identity: farmWorker, function: "ReserveAsset", args: []interface{}{"FARM-BATCH-1", "ORDER", "", quantity},
+			wantErr: "a reference ID is required"},
+		{name: "not the owner", identity: otherFarmAdmin, function: "ReserveAsset", args: []interface{}{"FARM-BATCH-1", "ORDER", "PO-1", quantity},
+			wantErr: "is not the owner"},
+		{name: "driver denied", identity: driver, function: "ReserveAsset", args: []interface{}{"FARM-BATCH-1", "ORDER", "PO-1", quantity},
+			wantErr: "access to ReserveAsset denied"},
+	})
+
+	t.Run("reservations for the same reference are merged", func(t *testing.T) {
+		c := newTestChannel(t)
+		withFarmBatch(c)
+		c.mustSubmit(farmWorker, "ReserveAsset", "FARM-BATCH-1", "ORDER", "PO-1", Quantity{Value: 2})
+		c.mustSubmit(farmWorker, "ReserveAsset", "FARM-BATCH-1", "ORDER", "PO-1", Quantity{Value: 3})
+		c.mustSubmit(farmWorker, "ReserveAsset", "FARM-BATCH-1", "SHIPMENT", "SHIP-7", Quantity{Value: 1})
+
+		asset := c.asset("FARM-BATCH-1")
+		if len(asset.Reservations) != 2 || asset.Reservations[0].Quantity.Value != 5 {
+			t.Fatalf("unexpected reservations %+v", asset.Reservations)
+		}
+		if asset.ReservedQuantity.Value != 6 || asset.AvailableQuantity.Value != 4 || asset.CurrentQuantity.Value != 10 {
+			t.Fatalf("reserved %v, available %v, current %v", asset.ReservedQuantity.Value, asset.AvailableQuantity.Value, asset.CurrentQuantity.Value)
+		}
+		_, err := c.submit(farmAdmin, "CreateShipment", "SHIP-1", "REFRIGERATED", "driver-1", "Nguyen Van A", "51C-123.45",
+			[]StopInJourney{testStop("FARM-1", "PICKUP", testItem("FARM-BATCH-1", "head", 5)), testStop("PROC-1", "DELIVERY")})
+		expectError(t, err, "only 4.000000 available")
+	})
+}
+
+func TestUnreserveAsset(t *testing.T) {
+	setup := func(c *testChannel) {
+		withFarmBatch(c)
+		c.mustSubmit(farmWorker, "ReserveAsset", "FARM-BATCH-1", "ORDER", "PO-1", Quantity{Value: 2})
+	}
+	runTransactionCases(t, setup, []transactionCase{
+		{name: "owner releases reservation", identity: farmWorker, function: "UnreserveAsset", args: []interface{}{"FARM-BATCH-1", "ORDER", "PO-1"}},
+		{name: "no such reservation", identity: farmWorker, function: "UnreserveAsset", args: []interface{}{"FARM-BATCH-1", "ORDER", "PO-2"},
+			wantErr: "asset FARM-BATCH-1 has no reservation for ORDER PO-2"},
+		{name: "not the owner", identity: otherFarmAdmin, function: "UnreserveAsset", args: []interface{}{"FARM-BATCH-1", "ORDER", "PO-1"},
+			wantErr: "is not the owner"},
+		{name: "regulator denied", identity: regulator, function: "UnreserveAsset", args: []interface{}{"FARM-BATCH-1", "ORDER", "PO-1"},
+			wantErr: "access to UnreserveAsset denied"},
+	})
+
+	t.Run("released stock is available again", func(t *testing.T) {
+		c := newTestChannel(t)
+		setup(c)
+		c.mustSubmit(farmWorker, "UnreserveAsset", "FARM-BATCH-1", "ORDER", "PO-1")
+		if asset := c.asset("FARM-BATCH-1"); len(asset.Reservations) != 0 || asset.AvailableQuantity.Value != 10 || lastEvent(asset).Type != "UNRESERVED" {
+			t.Fatalf("unexpected asset after unreserve: reservations %+v, available %v", asset.Reservations, asset.AvailableQuantity.Value)
+		}
+	})
+}
+
+func TestShipmentBookingReleasedOnlyByShipment(t *testing.T) {
+	c := newTestChannel(t)
+	withPendingShipment(c)
+	_, err := c.submit(farmWorker, "UnreserveAsset", "FARM-BATCH-1", "SHIPMENT", "SHIP-1")
+	expectError(t, err, "shipment bookings on asset FARM-BATCH-1 are released by cancelling, returning or closing shipment SHIP-1")
+	if asset := c.asset("FARM-BATCH-1"); asset.AvailableQuantity.Value != 4 {
+		t.Fatalf("available quantity is %v, want the booking of SHIP-1 to stay in place", asset.AvailableQuantity.Value)
+	}
+
+	c.mustSubmit(farmAdmin, "CancelShipment", "SHIP-1", "Truck broke down")
+	if asset := c.asset("FARM-BATCH-1"); len(asset.Reservations) != 0 || asset.AvailableQuantity.Value != 10 {
+		t.Fatalf("unexpected asset after cancel: reservations %+v, available %v", asset.Reservations, asset.AvailableQuantity.Value)
+	}
+}
+
+func TestOrderReservationUsedByShipment(t *testing.T) {
+	// PO-1 đặt 8 con; FARM-1 giữ chỗ 8 con cho PO-1 rồi giao theo đơn
+	setup := func(c *testChannel) {
+		withAcceptedOrder(c)
+		c.mustSubmit(farmAdmin, "ReserveAsset", "FARM-BATCH-1", "ORDER", "PO-1", Quantity{Value: 8})
+	}
+	orderStops := func(pickupItems ...ItemInShipment) []StopInJourney {
+		delivery := ItemInShipment{AssetID: "FARM-BATCH-1", Quantity: Quantity{Unit: "head", Value: 8}, POID: "PO-1", POLineID: "L1"}
+		return []StopInJourney{testStop("FARM-1", "PICKUP", pickupItems...), testStop("PROC-1", "DELIVERY", delivery)}
+	}
+
+	t.Run("booking takes over the order reservation", func(t *testing.T) {
+		c := newTestChannel(t)
+		setup(c)
+		c.mustSubmit(farmAdmin, "CreateShipment", "SHIP-1", "REFRIGERATED", "driver-1", "Nguyen Van A", "51C-123.45", orderStops(testItem("FARM-BATCH-1", "head", 8)))
+		asset := c.asset("FARM-BATCH-1")
+		if len(asset.Reservations) != 1 || asset.Reservations[0].ReferenceType != "SHIPMENT" || asset.AvailableQuantity.Value != 2 {
+			t.Fatalf("unexpected reservations %+v (available %v)", asset.Reservations, asset.AvailableQuantity.Value)
+		}
+
+		c.mustSubmit(driver, "AddPickupProof", "SHIP-1", "FARM-1", testProof("FARM-1"))
+		c.mustSubmit(farmAdmin, "ConfirmPickup", "SHIP-1", "FARM-1", []ItemInShipment{testItem("FARM-BATCH-1", "head", 8)})
+		if asset := c.asset("FARM-BATCH-1"); len(asset.Reservations) != 0 || asset.CurrentQuantity.Value != 2 {
+			t.Fatalf("asset has %v head and reservations %+v after pickup", asset.CurrentQuantity.Value, asset.Reservations)
+		}
+	})
+
+	t.Run("pickup without booking uses the order reservation", func(t *testing.T) {
+		c := newTestChannel(t)
+		setup(c)
+		c.mustSubmit(farmAdmin, "CreateShipment", "SHIP-1", "REFRIGERATED", "driver-1", "Nguyen Van A", "51C-123.45", orderStops())
+		c.mustSubmit(driver, "AddPickupProof", "SHIP-1", "FARM-1", testProof("FARM-1"))
+		c.mustSubmit(farmAdmin, "ConfirmPickup", "SHIP-1", "FARM-1", []ItemInShipment{testItem("FARM-BATCH-1", "head", 6)})
+		asset := c.asset("FARM-BATCH-1")
+		if len(asset.Reservations) != 1 || asset.Reservations[0].ReferenceID != "PO-1" || asset.Reservations[0].Quantity.Value != 2 {
+			t.Fatalf("unexpected reservations %+v after picking up 6 of 8 ordered", asset.Reservations)
+		}
+		if details, _ := lastEvent(asset).Details.(map[string]interface{}); details["orderReservationsUsed"] == nil {
+			t.Fatalf("pickup event %+v does not record the order reservation used", lastEvent(asset).Details)
+		}
+	})
+
+	t.Run("reservations for other orders are kept", func(t *testing.T) {
+		c := newTestChannel(t)
+		setup(c)
+		_, err := c.submit(farmAdmin, "CreateShipment", "SHIP-1", "REFRIGERATED", "driver-1", "Nguyen Van A", "51C-123.45",
+			[]StopInJourney{testStop("FARM-1", "PICKUP", testItem("FARM-BATCH-1", "head", 8)), testStop("PROC-1", "DELIVERY")})
+		expectError(t, err, "only 2.000000 available")
+	})
+}
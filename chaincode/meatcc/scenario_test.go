@@ -0,0 +1,295 @@
+package main
+
+import (
+	"testing"
+)
+
+// Các test kịch bản chạy trọn hành trình của hàng hóa qua nhiều tổ chức trên cùng một kênh,
+// kiểm tra trạng thái và số lượng của mọi asset trung gian sau từng bước.
+
+// assetState là trạng thái và số lượng hiện có mong đợi của một asset.
+type assetState struct {
+	status   string
+	quantity float64
+}
+
+// expectAssets kiểm tra trạng thái và số lượng hiện có của nhiều asset cùng lúc.
+func (c *testChannel) expectAssets(states map[string]assetState) {
+	c.t.Helper()
+	for assetID, state := range states {
+		c.expectStatus(assetID, state.status)
+		c.expectQuantity(assetID, state.quantity)
+	}
+}
+
+// expectConserved kiểm tra tổng số lượng hiện có của các asset bằng total (cùng đơn vị):
+// hàng được chuyển giữa các asset nhưng không sinh thêm hay mất đi.
+func (c *testChannel) expectConserved(total float64, assetIDs ...string) {
+	c.t.Helper()
+	sum := 0.0
+	for _, assetID := range assetIDs {
+		sum += c.asset(assetID).CurrentQuantity.Value
+	}
+	if !approxEqual(sum, total) {
+		c.t.Fatalf("assets %v hold %s in total, want %s", assetIDs, formatFloat(sum), formatFloat(total))
+	}
+}
+
+// massKg quy đổi số lượng hiện có (hoặc ban đầu) của asset ra kg theo trọng lượng trung bình của sản phẩm.
+func massKg(asset *MeatAsset, quantity Quantity) float64 {
+	if quantity.Unit == "kg" {
+		return quantity.Value
+	}
+	return quantity.Value * asset.AverageWeight.Value
+}
+
+// runFarmToFork chạy hành trình đầy đủ từ trang trại tới quầy bán lẻ:
+//
+//	FARM-BATCH-1 (20 con) --SHIP-1 (12 con)--> PROC-BATCH-0
+//	PROC-BATCH-0 --chế biến--> LOIN-1 (240 kg), TRAY-1 (600 khay)
+//	TRAY-1 --SHIP-2--> WH-BATCH-0 (200 khay, WH-1), RETAIL-BATCH-0 (400 khay, RETAIL-1)
+//	RETAIL-BATCH-0 --chia đơn vị--> UNIT-1..UNIT-3, UNIT-1 được bán
+func runFarmToFork(t *testing.T, c *testChannel) {
+	c.withCatalog()
+
+	// Trang trại
+	c.createFarmBatch(farmAdmin, "FARM-BATCH-1", skuPorkCarcass, Quantity{Unit: "head", Value: 20})
+	c.mustSubmit(farmWorker, "AddFeedToFarmingBatch", "FARM-BATCH-1", Feed{Name: "Grower pellets", DosageKg: 2.5, StartDate: "2023-10-01"})
+	c.mustSubmit(farmAdmin, "UpdateHarvestDate", "FARM-BATCH-1", "2024-02-28")
+	c.expectAssets(map[string]assetState{"FARM-BATCH-1": {AssetStatusAtFarm, 20}})
+
+	// Trang trại -> nhà máy chế biến
+	c.pickUpAndStart("SHIP-1", farmAdmin, "PROC-1", testItem("FARM-BATCH-1", "head", 12))
+	c.expectAssets(map[string]assetState{"FARM-BATCH-1": {AssetStatusPartiallyShipped, 8}})
+	if shipment := c.shipment("SHIP-1"); shipment.Status != ShipmentStatusInTransit || shipment.Manifest[0].LoadedQuantity.Value != 12 {
+		t.Fatalf("SHIP-1 is %s with %v loaded", shipment.Status, shipment.Manifest[0].LoadedQuantity)
+	}
+	c.mustSubmit(driver, "AddDeliveryProof", "SHIP-1", "PROC-1", testProof("PROC-1"))
+	c.mustSubmit(processorAdmin, "ConfirmShipmentDelivery", "SHIP-1", "PROC-1", "PROC-BATCH", []string{"SEAL-SHIP-1"})
+	c.expectAssets(map[string]assetState{
+		"FARM-BATCH-1": {AssetStatusPartiallyShipped, 8},
+		"PROC-BATCH-0": {AssetStatusAtProcessor, 12},
+	})
+	c.expectConserved(20, "FARM-BATCH-1", "PROC-BATCH-0")
+
+	// Chế biến
+	c.mustSubmit(processorAdmin, "ProcessAndSplitBatch", "PROC-BATCH-0", []ChildAssetInput{
+		{AssetID: "LOIN-1", ProductName: "Pork loin", SKU: skuPorkLoin, Quantity: Quantity{Unit: "kg", Value: 240}},
+		{AssetID: "TRAY-1", ProductName: "Pork belly tray", SKU: skuPorkTray, Quantity: Quantity{Unit: "tray", Value: 600}},
+	}, ProcessingDetails{ProcessorOrgName: "Song Than Foods", FacilityName: "Song Than Processing", Address: testFacilityAddresses["PROC-1"]})
+	c.expectAssets(map[string]assetState{
+		"PROC-BATCH-0": {AssetStatusProcessedAndSplit, 12},
+		"LOIN-1":       {AssetStatusPackaged, 240},
+		"TRAY-1":       {AssetStatusPackaged, 600},
+	})
+	carcasses := c.asset("PROC-BATCH-0")
+	outputKg := 0.0
+	for _, childID := range []string{"LOIN-1", "TRAY-1"} {
+		child := c.asset(childID)
+		outputKg += massKg(child, child.OriginalQuantity)
+	}
+	if inputKg := massKg(carcasses, carcasses.OriginalQuantity); outputKg > inputKg {
+		t.Fatalf("processing produced %s kg from %s kg of carcasses", formatFloat(outputKg), formatFloat(inputKg))
+	}
+
+	// Nhà máy -> kho lạnh và cửa hàng trên cùng một chuyến
+	c.mustSubmit(processorAdmin, "CreateShipment", "SHIP-2", "REFRIGERATED", "driver-1", "Nguyen Van A", "51C-123.45", []StopInJourney{
+		testStop("PROC-1", "PICKUP", testItem("TRAY-1", "tray", 600)),
+		testStop("WH-1", "DELIVERY", testItem("TRAY-1", "tray", 200)),
+		testStop("RETAIL-1", "DELIVERY", testItem("TRAY-1", "tray", 400)),
+	})
+	c.mustSubmit(driver, "AddPickupProof", "SHIP-2", "PROC-1", testProof("PROC-1"))
+	c.mustSubmit(processorAdmin, "ConfirmPickup", "SHIP-2", "PROC-1", []ItemInShipment{testItem("TRAY-1", "tray", 600)})
+	c.mustSubmit(driver, "StartShipment", "SHIP-2", []string{"SEAL-2A"})
+	c.expectAssets(map[string]assetState{"TRAY-1": {AssetStatusShippedFull, 0}})
+
+	c.mustSubmit(driver, "AddDeliveryProof", "SHIP-2", "WH-1", testProof("WH-1"))
+	c.mustSubmit(warehouseAdmin, "ConfirmShipmentDelivery", "SHIP-2", "WH-1", "WH-BATCH", []string{"SEAL-2A"})
+	c.mustSubmit(warehouseAdmin, "ResealShipment", "SHIP-2", "WH-1", []string{"SEAL-2B"})
+	if shipment := c.shipment("SHIP-2"); shipment.Status != ShipmentStatusInTransit {
+		t.Fatalf("SHIP-2 is %s after the first delivery", shipment.Status)
+	}
+	c.mustSubmit(driver, "AddDeliveryProof", "SHIP-2", "RETAIL-1", testProof("RETAIL-1"))
+	c.mustSubmit(retailerAdmin, "ConfirmShipmentDelivery", "SHIP-2", "RETAIL-1", "RETAIL-BATCH", []string{"SEAL-2B"})
+	c.expectAssets(map[string]assetState{
+		"TRAY-1":         {AssetStatusShippedFull, 0},
+		"WH-BATCH-0":     {AssetStatusAtWarehouse, 200},
+		"RETAIL-BATCH-0": {AssetStatusAtRetailer, 400},
+	})
+	c.expectConserved(600, "TRAY-1", "WH-BATCH-0", "RETAIL-BATCH-0")
+
+	// Cửa hàng
+	c.mustSubmit(retailerWorker, "UpdateStorageInfo", "RETAIL-BATCH-0", StorageDetails{FacilityName: "District 1 Store", LocationInStore: "Chiller 3", Temperature: "2°C"})
+	c.mustSubmit(retailerWorker, "SplitBatchToUnits", "RETAIL-BATCH-0", 3, "UNIT-")
+	c.expectAssets(map[string]assetState{
+		"RETAIL-BATCH-0": {AssetStatusSplitIntoUnitsCompleted, 397},
+		"UNIT-1":         {AssetStatusOnShelf, 1},
+		"UNIT-2":         {AssetStatusOnShelf, 1},
+		"UNIT-3":         {AssetStatusOnShelf, 1},
+	})
+	c.expectConserved(400, "RETAIL-BATCH-0", "UNIT-1", "UNIT-2", "UNIT-3")
+
+	c.mustSubmit(retailerWorker, "MarkAsSold", "UNIT-1", SoldDetails{RetailerOrgName: "Fresh Mart", FacilityName: "District 1 Store", Address: testFacilityAddresses["RETAIL-1"]})
+}
+
+func TestScenarioFarmToFork(t *testing.T) {
+	c := newTestChannel(t)
+	runFarmToFork(t, c)
+
+	// Trạng thái cuối của mọi asset trên hành trình
+	c.expectAssets(map[string]assetState{
+		"FARM-BATCH-1":   {AssetStatusPartiallyShipped, 8},
+		"PROC-BATCH-0":   {AssetStatusProcessedAndSplit, 12},
+		"LOIN-1":         {AssetStatusPackaged, 240},
+		"TRAY-1":         {AssetStatusShippedFull, 0},
+		"WH-BATCH-0":     {AssetStatusAtWarehouse, 200},
+		"RETAIL-BATCH-0": {AssetStatusSplitIntoUnitsCompleted, 397},
+		"UNIT-1":         {AssetStatusSold, 1},
+		"UNIT-2":         {AssetStatusOnShelf, 1},
+		"UNIT-3":         {AssetStatusOnShelf, 1},
+	})
+	for _, shipmentID := range []string{"SHIP-1", "SHIP-2"} {
+		if shipment := c.shipment(shipmentID); shipment.Status != ShipmentStatusCompleted {
+			t.Fatalf("%s has status %s", shipmentID, shipment.Status)
+		}
+	}
+
+	trace := c.mustEvaluate(regulator, "GetAssetWithFullHistory", "UNIT-1").(*FullAssetTrace)
+	if trace.Status != AssetStatusSold || trace.ProductName != "Pork belly tray" || trace.OriginalQuantity.Value != 1 || trace.CurrentQuantity.Value != 1 {
+		t.Fatalf("unexpected trace header %+v", trace)
+	}
+	if len(trace.ParentAssetIDs) != 1 || trace.ParentAssetIDs[0] != "RETAIL-BATCH-0" {
+		t.Fatalf("UNIT-1 has parents %v", trace.ParentAssetIDs)
+	}
+
+	// Lịch sử đầy đủ gồm sự kiện của UNIT-1 và các tổ tiên RETAIL-BATCH-0, TRAY-1, PROC-BATCH-0, FARM-BATCH-1,
+	// không gồm nhánh anh em (LOIN-1, WH-BATCH-0, UNIT-2, UNIT-3). Thức ăn được gộp vào sự kiện FARMING,
+	// và lấy hàng đủ số lượng đã đặt chỗ nên không có BOOKING_RELEASED.
+	wantCounts := map[string]int{
+		"FARMING":                 1,
+		"BOOKED_ON_SHIPMENT":      2,
+		"PICKED_UP_FOR_SHIPMENT":  2,
+		"SHIPPING_STARTED":        2,
+		"RECEIVING":               2,
+		"PROCESSING":              1,
+		"CREATED_FROM_PROCESSING": 1,
+		"STORAGE_UPDATE":          1,
+		"SPLIT_INTO_UNITS":        1,
+		"CREATED_AS_UNIT":         1,
+		"SOLD":                    1,
+	}
+	counts := make(map[string]int)
+	for _, event := range trace.FullHistory {
+		counts[event.Type]++
+		if event.ActorMSP != MeatSupplyOrgMSP || event.TxID == "" || event.Timestamp == "" {
+			t.Fatalf("event %s has incomplete provenance %+v", event.Type, event)
+		}
+	}
+	for eventType, want := range wantCounts {
+		if counts[eventType] != want {
+			t.Errorf("expected %d %s events in the full history, got %d", want, eventType, counts[eventType])
+		}
+	}
+	total := 0
+	for _, count := range wantCounts {
+		total += count
+	}
+	if len(trace.FullHistory) != total {
+		t.Errorf("expected %d events in the full history, got %d: %v", total, len(trace.FullHistory), counts)
+	}
+
+	// Các mốc chính được ghi ở các transaction khác nhau nên phải xuất hiện đúng thứ tự
+	milestones := []string{"FARMING", "SHIPPING_STARTED", "RECEIVING", "PROCESSING", "SHIPPING_STARTED", "RECEIVING", "STORAGE_UPDATE", "SPLIT_INTO_UNITS", "SOLD"}
+	next := 0
+	for _, event := range trace.FullHistory {
+		if next < len(milestones) && event.Type == milestones[next] {
+			next++
+		}
+	}
+	if next != len(milestones) {
+		t.Fatalf("full history does not follow the journey; stopped at milestone %s", milestones[next])
+	}
+	for i := 1; i < len(trace.FullHistory); i++ {
+		if trace.FullHistory[i].Timestamp < trace.FullHistory[i-1].Timestamp {
+			t.Fatalf("full history is not ordered: %s before %s", trace.FullHistory[i-1].Timestamp, trace.FullHistory[i].Timestamp)
+		}
+	}
+
+	// Nhánh kho lạnh truy ngược về cùng lô nuôi nhưng không chứa sự kiện của cửa hàng
+	warehouse := c.mustEvaluate(regulator, "GetAssetWithFullHistory", "WH-BATCH-0").(*FullAssetTrace)
+	for _, event := range warehouse.FullHistory {
+		if event.Type == "SPLIT_INTO_UNITS" || event.Type == "SOLD" || event.Type == "STORAGE_UPDATE" {
+			t.Fatalf("warehouse history contains retail event %s", event.Type)
+		}
+	}
+}
+
+func TestScenarioRecallAfterSale(t *testing.T) {
+	c := newTestChannel(t)
+	runFarmToFork(t, c)
+
+	c.mustSubmit(regulator, "RecallAsset", "FARM-BATCH-1", "RC-2024-07", "Salmonella in carcass samples")
+
+	// Mọi asset hạ nguồn của lô nuôi bị thu hồi, kể cả nhánh kho lạnh và đơn vị đã bán
+	for _, assetID := range []string{"FARM-BATCH-1", "PROC-BATCH-0", "LOIN-1", "TRAY-1", "WH-BATCH-0", "RETAIL-BATCH-0", "UNIT-1", "UNIT-2", "UNIT-3"} {
+		status := c.mustEvaluate(regulator, "GetRegulatoryStatus", assetID).(*RegulatoryStatus)
+		if status.Status != RegulatoryStatusRecalled || status.RecallID != "RC-2024-07" {
+			t.Fatalf("asset %s has regulatory status %s", assetID, status.Status)
+		}
+	}
+
+	_, err := c.submit(retailerWorker, "MarkAsSold", "UNIT-2", SoldDetails{RetailerOrgName: "Fresh Mart"})
+	expectError(t, err, "asset UNIT-2 is under regulatory status 'RECALLED' (Salmonella in carcass samples)")
+	_, err = c.submit(farmAdmin, "CreateShipment", "SHIP-3", "REFRIGERATED", "driver-1", "Nguyen Van A", "51C-123.45",
+		[]StopInJourney{testStop("FARM-1", "PICKUP", testItem("FARM-BATCH-1", "head", 8)), testStop("PROC-1", "DELIVERY")})
+	expectError(t, err, "is under regulatory status 'RECALLED'")
+	c.expectAssets(map[string]assetState{
+		"FARM-BATCH-1": {AssetStatusPartiallyShipped, 8},
+		"UNIT-2":       {AssetStatusOnShelf, 1},
+	})
+
+	trace := c.mustEvaluate(retailerWorker, "GetConsumerTrace", "UNIT-1").(*ConsumerTrace)
+	if trace.RecallNotice != "This product is subject to recall RC-2024-07: Salmonella in carcass samples" {
+		t.Fatalf("consumer trace has recall notice %q", trace.RecallNotice)
+	}
+}
+
+func TestScenarioBrokenSealAtRetailer(t *testing.T) {
+	c := newTestChannel(t)
+	c.withCatalog()
+	c.createFarmBatch(farmAdmin, "FARM-BATCH-1", skuPorkCarcass, Quantity{Unit: "head", Value: 10})
+	c.ship("SHIP-1", farmAdmin, processorAdmin, "PROC-BATCH", testItem("FARM-BATCH-1", "head", 10))
+	c.mustSubmit(processorAdmin, "ProcessAndSplitBatch", "PROC-BATCH-0", []ChildAssetInput{
+		{AssetID: "TRAY-1", ProductName: "Pork belly tray", SKU: skuPorkTray, Quantity: Quantity{Unit: "tray", Value: 300}},
+	}, ProcessingDetails{FacilityName: "Song Than Processing"})
+
+	// Niêm phong khi đến không khớp: hàng nhận được bị tạm giữ và không thể chia đơn vị cho tới khi được gỡ
+	c.pickUpAndStart("SHIP-2", processorAdmin, "RETAIL-1", testItem("TRAY-1", "tray", 300))
+	c.mustSubmit(driver, "AddDeliveryProof", "SHIP-2", "RETAIL-1", testProof("RETAIL-1"))
+	c.mustSubmit(retailerAdmin, "ConfirmShipmentDelivery", "SHIP-2", "RETAIL-1", "RETAIL-BATCH", []string{"SEAL-TAMPERED"})
+	c.expectAssets(map[string]assetState{
+		"TRAY-1":         {AssetStatusShippedFull, 0},
+		"RETAIL-BATCH-0": {AssetStatusOnHold, 300},
+	})
+	_, err := c.submit(retailerWorker, "SplitBatchToUnits", "RETAIL-BATCH-0", 2, "UNIT-")
+	expectError(t, err, "SplitBatchToUnits is not allowed for asset RETAIL-BATCH-0 with status 'ON_HOLD'")
+
+	c.mustSubmit(retailerAdmin, "ReleaseAssetHold", "RETAIL-BATCH-0", "Temperature log and contents checked")
+	c.mustSubmit(retailerWorker, "SplitBatchToUnits", "RETAIL-BATCH-0", 2, "UNIT-")
+	c.mustSubmit(retailerWorker, "MarkAsSold", "UNIT-1", SoldDetails{RetailerOrgName: "Fresh Mart"})
+	c.expectAssets(map[string]assetState{
+		"RETAIL-BATCH-0": {AssetStatusSplitIntoUnitsCompleted, 298},
+		"UNIT-1":         {AssetStatusSold, 1},
+		"UNIT-2":         {AssetStatusOnShelf, 1},
+	})
+	c.expectConserved(300, "TRAY-1", "RETAIL-BATCH-0", "UNIT-1", "UNIT-2")
+
+	trace := c.mustEvaluate(regulator, "GetConsumerTrace", "UNIT-1").(*ConsumerTrace)
+	var stages []string
+	for _, step := range trace.Journey {
+		stages = append(stages, step.Stage)
+	}
+	if !containsString(stages, "SEAL_CHECK_FAILED") || !containsString(stages, "QUALITY_RELEASED") {
+		t.Fatalf("consumer journey %v does not show the seal incident", stages)
+	}
+}
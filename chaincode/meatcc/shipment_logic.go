@@ -4,15 +4,13 @@ import (
 	"encoding/json"
 	"fmt"
 	"sort"
+	"strings"
 
 	"github.com/hyperledger/fabric-contract-api-go/contractapi"
 )
 
 // Tạo một lô vận chuyển mới, lưu thông tin tài xế, phương tiện, các điểm dừng và ghi lại sự kiện khởi tạo shipment.
 func (s *SmartContract) CreateShipment(ctx contractapi.TransactionContextInterface, shipmentID string, shipmentType, driverEnrollmentID, driverName, vehiclePlate string, stopsJSON string) error {
-	if err := requireRole(ctx, "admin", "driver"); err != nil {
-		return err
-	}
 	exists, err := s.assetExists(ctx, shipmentID)
 	if err != nil {
 		return err
@@ -28,6 +26,18 @@ func (s *SmartContract) CreateShipment(ctx contractapi.TransactionContextInterfa
 
 	for i := range stops {
 		stops[i].Status = "PENDING"
+		if err := validatePlannedWindow(stops[i]); err != nil {
+			return fmt.Errorf("invalid planned window for stop %d: %v", i+1, err)
+		}
+	}
+
+	// Giữ chỗ số lượng cho các asset được khai báo tại các điểm lấy hàng
+	if err := s.bookShipmentItems(ctx, shipmentID, driverEnrollmentID, stops); err != nil {
+		return err
+	}
+	// Kiểm tra các tham chiếu đến dòng đơn đặt hàng tại các điểm giao
+	if err := s.validatePurchaseOrderReferences(ctx, stops); err != nil {
+		return err
 	}
 
 	event, err := s.createEvent(ctx, "SHIPMENT_CREATED", "Shipment created and pending.")
@@ -42,7 +52,7 @@ func (s *SmartContract) CreateShipment(ctx contractapi.TransactionContextInterfa
 		DriverEnrollmentID: driverEnrollmentID,
 		DriverName:         driverName,
 		VehiclePlate:       vehiclePlate,
-		Status:             "PENDING",
+		Status:             ShipmentStatusPending,
 		Stops:              stops,
 		Timeline:           []ShipmentTimeline{},
 		History:            []Event{*event},
@@ -61,6 +71,9 @@ func (s *SmartContract) AddPickupProof(ctx contractapi.TransactionContextInterfa
 	if err := requireAssignedDriver(ctx, shipment); err != nil {
 		return err
 	}
+	if err := requireShipmentAction(shipment, "AddPickupProof"); err != nil {
+		return err
+	}
 
 	// Unmarshal proofJSON để sử dụng
 	var proofDetails map[string]interface{}
@@ -69,23 +82,32 @@ func (s *SmartContract) AddPickupProof(ctx contractapi.TransactionContextInterfa
 	}
 
 	// Tìm đúng điểm dừng để lấy địa chỉ
-	var stopLocation string
-	for _, stop := range shipment.Stops {
-		if stop.FacilityID == facilityID {
-			stopLocation = stop.FacilityAddress.FullText
+	var proofStop *StopInJourney
+	for i := range shipment.Stops {
+		if shipment.Stops[i].FacilityID == facilityID {
+			proofStop = &shipment.Stops[i]
 			break
 		}
 	}
-	if stopLocation == "" {
+	if proofStop == nil || proofStop.FacilityAddress.FullText == "" {
 		return fmt.Errorf("no stop found for facility %s in shipment %s", facilityID, shipmentID)
 	}
+	stopLocation := proofStop.FacilityAddress.FullText
+
+	// Đối chiếu tọa độ GPS trong bằng chứng với vị trí của cơ sở
+	geofenceCheck, err := s.checkGeofence(ctx, proofStop, proofDetails)
+	if err != nil {
+		return err
+	}
 
 	// Tạo sự kiện mới và thêm vào Timeline
 	proofEvent := ShipmentTimeline{
-		Type:      "pickup_proof_added",
-		Timestamp: s.getTxTimestamp(ctx),
-		Location:  stopLocation,
-		Proof:     proofDetails,
+		Type:       "pickup_proof_added",
+		Timestamp:  s.getTxTimestamp(ctx),
+		Location:   stopLocation,
+		FacilityID: facilityID,
+		Proof:      proofDetails,
+		Geofence:   geofenceCheck,
 	}
 	shipment.Timeline = append(shipment.Timeline, proofEvent)
 
@@ -98,31 +120,32 @@ func (s *SmartContract) ConfirmPickup(ctx contractapi.TransactionContextInterfac
 	if err != nil {
 		return err
 	}
-	if shipment.Status != "PENDING" {
-		return fmt.Errorf("shipment %s is not in PENDING state", shipmentID)
-	}
-
-	proofExists := false
-	for _, event := range shipment.Timeline {
-		if event.Type == "pickup_proof_added" && event.Proof["facilityID"] == facilityID {
-			proofExists = true
-			break
-		}
+	if err := requireShipmentAction(shipment, "ConfirmPickup"); err != nil {
+		return err
 	}
 
-	if !proofExists {
-		return fmt.Errorf("pickup proof for facility %s has not been added by the driver yet", facilityID)
+	if err := s.requireStopProof(ctx, shipment, "pickup", facilityID); err != nil {
+		return err
 	}
 
 	var actualItems []ItemInShipment
 	if err := json.Unmarshal([]byte(actualItemsJSON), &actualItems); err != nil {
 		return fmt.Errorf("failed to unmarshal actualItemsJSON: %v", err)
 	}
+	// Mỗi asset chỉ được khai báo một lần: transaction không đọc được dữ liệu chính nó vừa ghi,
+	// nên hai dòng cùng asset sẽ làm mất một lần cập nhật số lượng.
+	seenAssets := make(map[string]bool)
+	for _, actualItem := range actualItems {
+		if seenAssets[actualItem.AssetID] {
+			return fmt.Errorf("asset %s is listed more than once in the picked up items", actualItem.AssetID)
+		}
+		seenAssets[actualItem.AssetID] = true
+	}
 
 	stopFound := false
 	for i, stop := range shipment.Stops {
-		if stop.FacilityID == facilityID && stop.Action == "PICKUP" {
-			if err := requireRole(ctx, "admin", "worker"); err != nil {
+		if stop.FacilityID == facilityID && stop.Action == "PICKUP" && stop.Status == "PENDING" {
+			if err := requireStopInRouteOrder(shipment, i); err != nil {
 				return err
 			}
 
@@ -143,7 +166,23 @@ func (s *SmartContract) ConfirmPickup(ctx contractapi.TransactionContextInterfac
 				if err := requireOwnership(ctx, asset); err != nil {
 					return err
 				}
-				if asset.CurrentQuantity.Value < actualItem.Quantity.Value {
+				if actualItem.Quantity.Value <= 0 {
+					return fmt.Errorf("picked up quantity for asset %s must be positive", actualItem.AssetID)
+				}
+				if actualItem.Quantity.Unit != "" && actualItem.Quantity.Unit != asset.CurrentQuantity.Unit {
+					return fmt.Errorf("unit '%s' for asset %s does not match asset unit '%s'", actualItem.Quantity.Unit, actualItem.AssetID, asset.CurrentQuantity.Unit)
+				}
+				actualItem.Quantity.Unit = asset.CurrentQuantity.Unit
+				// Lấy hàng giải phóng phần giữ chỗ của chính lô hàng này; phần giữ chỗ của lô hàng khác vẫn được bảo toàn.
+				releasedBooking := releaseReservation(asset, "SHIPMENT", shipmentID)
+				// Hàng đã giữ chỗ cho các đơn đặt hàng mà lô hàng này giao tới được dùng cho chính lần lấy hàng này;
+				// phần đã đặt chỗ lúc tạo lô hàng đã được chuyển từ giữ chỗ ORDER nên không bị trừ lần nữa.
+				unbooked := actualItem.Quantity.Value
+				if releasedBooking != nil {
+					unbooked -= releasedBooking.Value
+				}
+				consumedOrders := consumeOrderReservations(asset, shipment.Stops, unbooked)
+				if availableQuantity(asset) < actualItem.Quantity.Value-quantityTolerance {
 					return fmt.Errorf("insufficient quantity for asset %s", actualItem.AssetID)
 				}
 				asset.CurrentQuantity.Value -= actualItem.Quantity.Value
@@ -151,17 +190,43 @@ func (s *SmartContract) ConfirmPickup(ctx contractapi.TransactionContextInterfac
 				// === NÂNG CẤP SỰ KIỆN ===
 				// Ghi lại cả bằng chứng ảnh vào sự kiện của asset
 				eventDetails := map[string]interface{}{
-					"shipmentID": shipmentID,
-					"quantity":   actualItem.Quantity,
-					"proof":      make(map[string]interface{}), // Không có bằng chứng cụ thể lúc này
+					"shipmentID":      shipmentID,
+					"quantity":        actualItem.Quantity,
+					"bookingReleased": releasedBooking,
+					"proof":           make(map[string]interface{}), // Không có bằng chứng cụ thể lúc này
+				}
+				if len(consumedOrders) > 0 {
+					eventDetails["orderReservationsUsed"] = consumedOrders
 				}
 				err = s.addEvent(ctx, asset, "PICKED_UP_FOR_SHIPMENT", asset.Status, eventDetails)
 				if err != nil {
 					return err
 				}
+				addToManifest(shipment, facilityID, actualItem)
+			}
+
+			// Các asset đã đặt chỗ tại điểm dừng nhưng không được lấy thì giải phóng phần giữ chỗ
+			var notPickedUp []ItemInShipment
+			for _, planned := range stop.Items {
+				pickedUp := false
+				for _, actualItem := range actualItems {
+					if actualItem.AssetID == planned.AssetID {
+						pickedUp = true
+						break
+					}
+				}
+				if !pickedUp {
+					notPickedUp = append(notPickedUp, planned)
+				}
+			}
+			if err := s.releaseShipmentBookings(ctx, shipmentID, notPickedUp, "not picked up"); err != nil {
+				return err
 			}
 			shipment.Stops[i].Items = actualItems
 			shipment.Stops[i].Status = "COMPLETED"
+			if err := s.recordStopTiming(ctx, &shipment.Stops[i]); err != nil {
+				return err
+			}
 			stopFound = true
 			break
 		}
@@ -173,11 +238,8 @@ func (s *SmartContract) ConfirmPickup(ctx contractapi.TransactionContextInterfac
 	return s.updateShipment(ctx, shipment)
 }
 
-// Bắt đầu quá trình vận chuyển, cập nhật trạng thái shipment thành IN_TRANSIT và ghi lại sự kiện khởi hành.
-func (s *SmartContract) StartShipment(ctx contractapi.TransactionContextInterface, shipmentID string) error {
-	if err := requireRole(ctx, "admin", "driver"); err != nil {
-		return err
-	}
+// Bắt đầu quá trình vận chuyển, ghi lại số niêm phong của container/xe, cập nhật trạng thái shipment thành IN_TRANSIT và ghi lại sự kiện khởi hành.
+func (s *SmartContract) StartShipment(ctx contractapi.TransactionContextInterface, shipmentID string, sealIDsJSON string) error {
 	shipment, err := s.readShipmentAsset(ctx, shipmentID)
 	if err != nil {
 		return err
@@ -185,9 +247,18 @@ func (s *SmartContract) StartShipment(ctx contractapi.TransactionContextInterfac
 	if err := requireAssignedDriver(ctx, shipment); err != nil {
 		return err
 	}
-	if shipment.Status != "PENDING" {
-		return fmt.Errorf("shipment %s has already started or is completed", shipmentID)
+	if err := requireShipmentAction(shipment, "StartShipment"); err != nil {
+		return err
+	}
+
+	sealIDs, err := parseSealIDs(sealIDsJSON)
+	if err != nil {
+		return err
+	}
+	if len(sealIDs) == 0 {
+		return fmt.Errorf("at least one seal ID is required to start shipment %s", shipmentID)
 	}
+	shipment.SealIDs = sealIDs
 
 	var departureLocation string // Biến để lưu địa điểm khởi hành
 
@@ -205,11 +276,12 @@ func (s *SmartContract) StartShipment(ctx contractapi.TransactionContextInterfac
 				}
 				var newStatus string
 				if asset.CurrentQuantity.Value > 0 {
-					newStatus = "PARTIALLY_SHIPPED"
+					newStatus = AssetStatusPartiallyShipped
 				} else {
-					newStatus = "SHIPPED_FULL"
+					newStatus = AssetStatusShippedFull
 				}
-				err = s.addEvent(ctx, asset, "SHIPPING_STARTED", newStatus, map[string]string{"shipmentID": shipmentID})
+				shippingDetails := map[string]string{"shipmentID": shipmentID, "previousStatus": asset.Status}
+				err = s.addEvent(ctx, asset, "SHIPPING_STARTED", newStatus, shippingDetails)
 				if err != nil {
 					return fmt.Errorf("failed to update event for asset %s: %v", item.AssetID, err)
 				}
@@ -217,13 +289,15 @@ func (s *SmartContract) StartShipment(ctx contractapi.TransactionContextInterfac
 		}
 	}
 
-	shipment.Status = "IN_TRANSIT"
+	if err := s.transitionShipment(ctx, shipment, "StartShipment", ShipmentStatusInTransit, "SHIPMENT_STARTED", map[string]interface{}{"sealIDs": sealIDs}); err != nil {
+		return err
+	}
 	timelineEvent := ShipmentTimeline{
 		Type:      "departure",
 		Timestamp: s.getTxTimestamp(ctx),
 		Location:  departureLocation,
 		FacilityID: "", // Không có FacilityID cụ thể cho sự kiện khởi hành
-		Proof:     make(map[string]interface{}), // Không có bằng chứng cụ thể lúc này
+		Proof:     map[string]interface{}{"sealIDs": sealIDs},
 	}
 	shipment.Timeline = append(shipment.Timeline, timelineEvent)
 
@@ -240,6 +314,9 @@ func (s *SmartContract) AddDeliveryProof(ctx contractapi.TransactionContextInter
 	if err := requireAssignedDriver(ctx, shipment); err != nil {
 		return err
 	}
+	if err := requireShipmentAction(shipment, "AddDeliveryProof"); err != nil {
+		return err
+	}
 
 	// Unmarshal proofJSON để sử dụng
 	var proofDetails map[string]interface{}
@@ -248,63 +325,92 @@ func (s *SmartContract) AddDeliveryProof(ctx contractapi.TransactionContextInter
 	}
 
 	// Tìm đúng điểm dừng để lấy địa chỉ
-	var stopLocation string
-	for _, stop := range shipment.Stops {
-		if stop.FacilityID == facilityID {
-			stopLocation = stop.FacilityAddress.FullText
+	var proofStop *StopInJourney
+	for i := range shipment.Stops {
+		if shipment.Stops[i].FacilityID == facilityID {
+			proofStop = &shipment.Stops[i]
 			break
 		}
 	}
-	if stopLocation == "" {
+	if proofStop == nil || proofStop.FacilityAddress.FullText == "" {
 		return fmt.Errorf("no stop found for facility %s in shipment %s", facilityID, shipmentID)
 	}
+	stopLocation := proofStop.FacilityAddress.FullText
+
+	// Đối chiếu tọa độ GPS trong bằng chứng với vị trí của cơ sở
+	geofenceCheck, err := s.checkGeofence(ctx, proofStop, proofDetails)
+	if err != nil {
+		return err
+	}
 
 	// Tạo sự kiện mới và thêm vào Timeline
 	proofEvent := ShipmentTimeline{
-		Type:      "delivery_proof_added", // <-- TÊN SỰ KIỆN MỚI
-		Timestamp: s.getTxTimestamp(ctx),
-		Location:  stopLocation,
-		Proof:     proofDetails,
+		Type:       "delivery_proof_added", // <-- TÊN SỰ KIỆN MỚI
+		Timestamp:  s.getTxTimestamp(ctx),
+		Location:   stopLocation,
+		FacilityID: facilityID,
+		Proof:      proofDetails,
+		Geofence:   geofenceCheck,
 	}
 	shipment.Timeline = append(shipment.Timeline, proofEvent)
 
 	return s.updateShipment(ctx, shipment)
 }
 
-// Xác nhận việc giao hàng tại một điểm dừng, tạo asset mới cho bên nhận và cập nhật trạng thái shipment nếu đã giao hết.
-func (s *SmartContract) ConfirmShipmentDelivery(ctx contractapi.TransactionContextInterface, shipmentID string, facilityID string, newAssetIDPrefix string) error {
-	if err := requireRole(ctx, "admin", "worker"); err != nil {
-		return err
-	}
+// Xác nhận việc giao hàng tại một điểm dừng, đối chiếu số niêm phong khi hàng đến, tạo asset mới cho bên nhận và cập nhật trạng thái shipment nếu đã giao hết.
+// Nếu niêm phong bị thiếu hoặc không khớp, điểm giao bị đánh dấu SEAL_BROKEN và các asset nhận được bị tạm giữ (ON_HOLD).
+// Asset nhận được mang theo trạng thái tạm giữ/thu hồi quản lý (HOLD, RECALLED) của asset nguồn.
+func (s *SmartContract) ConfirmShipmentDelivery(ctx contractapi.TransactionContextInterface, shipmentID string, facilityID string, newAssetIDPrefix string, arrivalSealIDsJSON string) error {
 
-	receiverFacilityID, _, _ := ctx.GetClientIdentity().GetAttributeValue("facilityID")   
-	receiverFacilityType, _, _ := ctx.GetClientIdentity().GetAttributeValue("facilityType") 
+	receiverFacilityID, _, _ := getClientAttribute(ctx, "facilityID")   
+	receiverFacilityType, _, _ := getClientAttribute(ctx, "facilityType") 
 
 
 	shipment, err := s.readShipmentAsset(ctx, shipmentID)
 	if err != nil {
 		return err
 	}
-	if shipment.Status != "IN_TRANSIT" {
-		return fmt.Errorf("shipment %s is not in transit", shipmentID)
+	if err := requireShipmentAction(shipment, "ConfirmShipmentDelivery"); err != nil {
+		return err
 	}
 
-	proofExists := false
-	for _, event := range shipment.Timeline {
-		if event.Type == "delivery_proof_added" && event.Proof["facilityID"] == facilityID {
-			proofExists = true
-			break
-		}
+	if err := s.requireStopProof(ctx, shipment, "delivery", facilityID); err != nil {
+		return err
 	}
-	if !proofExists {
-		return fmt.Errorf("delivery proof for facility %s has not been added by the driver yet", facilityID)
+
+	arrivalSealIDs, err := parseSealIDs(arrivalSealIDsJSON)
+	if err != nil {
+		return err
 	}
+	sealIntact := sealsMatch(shipment.SealIDs, arrivalSealIDs)
 
 	stopFound := false
+	var deliveredItems []ItemInShipment
 	for i, stop := range shipment.Stops {
 		if stop.FacilityID == facilityID && stop.Action == "DELIVERY" && stop.Status == "PENDING" {
 			stopFound = true
+			if err := requireStopInRouteOrder(shipment, i); err != nil {
+				return err
+			}
+
+			// Hàng giao tại điểm dừng được phân bổ từ manifest đã xác nhận lúc lấy hàng,
+			// không dựa vào danh sách do client khai báo trong stopsJSON.
+			deliveredItems, err = allocateFromManifest(shipment, stop.Items)
+			if err != nil {
+				return fmt.Errorf("invalid delivery at facility %s: %v", facilityID, err)
+			}
+			stop.Items = deliveredItems
+			shipment.Stops[i].Items = deliveredItems
 			shipment.Stops[i].Status = "COMPLETED"
+			if err := s.recordStopTiming(ctx, &shipment.Stops[i]); err != nil {
+				return err
+			}
+			shipment.Stops[i].ArrivalSealIDs = arrivalSealIDs
+			if sealIntact {
+				shipment.Stops[i].SealStatus = "SEAL_INTACT"
+			} else {
+				shipment.Stops[i].SealStatus = "SEAL_BROKEN"
+			}
 
 			arrivalEvent := ShipmentTimeline{
 				Type:      "arrival",
@@ -315,6 +421,23 @@ func (s *SmartContract) ConfirmShipmentDelivery(ctx contractapi.TransactionConte
 			}
 			shipment.Timeline = append(shipment.Timeline, arrivalEvent)
 
+			sealDetails := map[string]interface{}{
+				"shipmentID":      shipmentID,
+				"facilityID":      facilityID,
+				"expectedSealIDs": shipment.SealIDs,
+				"arrivalSealIDs":  arrivalSealIDs,
+			}
+			if !sealIntact {
+				sealEvent := ShipmentTimeline{
+					Type:       "seal_broken",
+					Timestamp:  s.getTxTimestamp(ctx),
+					Location:   stop.FacilityAddress.FullText,
+					FacilityID: stop.FacilityID,
+					Proof:      sealDetails,
+				}
+				shipment.Timeline = append(shipment.Timeline, sealEvent)
+			}
+
 			for j, item := range stop.Items {
 				parentAsset, err := s.readAsset(ctx, item.AssetID)
 				if err != nil {
@@ -323,18 +446,30 @@ func (s *SmartContract) ConfirmShipmentDelivery(ctx contractapi.TransactionConte
 
 				var newStatus string
 				switch receiverFacilityType {
-				case "RETAILER":
-					newStatus = "AT_RETAILER"
-				case "PROCESSOR":
-					newStatus = "AT_PROCESSOR"
-				case "WAREHOUSE":
-					newStatus = "AT_WAREHOUSE"
+				case FacilityTypeRetailer:
+					newStatus = AssetStatusAtRetailer
+				case FacilityTypeProcessor:
+					newStatus = AssetStatusAtProcessor
+				case FacilityTypeWarehouse:
+					newStatus = AssetStatusAtWarehouse
 				default:
-					newStatus = "RECEIVED"
+					newStatus = AssetStatusReceived
 				}
 
 				newAssetID := fmt.Sprintf("%s-%d", newAssetIDPrefix, j)
-				event, err := s.createEvent(ctx, "RECEIVING", map[string]interface{}{"shipmentID": shipmentID, "quantityReceived": item.Quantity})
+				exists, err := s.assetExists(ctx, newAssetID)
+				if err != nil {
+					return err
+				}
+				if exists {
+					return fmt.Errorf("asset %s already exists", newAssetID)
+				}
+				receivingDetails := map[string]interface{}{"shipmentID": shipmentID, "quantityReceived": item.Quantity}
+				if item.POID != "" {
+					receivingDetails["poID"] = item.POID
+					receivingDetails["poLineID"] = item.POLineID
+				}
+				event, err := s.createEvent(ctx, "RECEIVING", receivingDetails)
 				if err != nil {
 					return err
 				}
@@ -343,6 +478,9 @@ func (s *SmartContract) ConfirmShipmentDelivery(ctx contractapi.TransactionConte
 					ObjectType:       "MeatAsset",
 					AssetID:          newAssetID,
 					SKU:              parentAsset.SKU,
+					GTIN:             parentAsset.GTIN,
+					LotNumber:        parentAsset.LotNumber,
+					SerialNumber:     parentAsset.SerialNumber,
 					AverageWeight:    parentAsset.AverageWeight,
 					ParentAssetIDs:   []string{item.AssetID},
 					ProductName:      parentAsset.ProductName,
@@ -352,10 +490,34 @@ func (s *SmartContract) ConfirmShipmentDelivery(ctx contractapi.TransactionConte
 					CurrentQuantity:  item.Quantity,
 					History:          []Event{*event},
 				}
+				if !sealIntact {
+					// Niêm phong không còn nguyên vẹn: tạm giữ hàng nhận được cho đến khi được kiểm tra.
+					sealEvent, err := s.createEvent(ctx, "SEAL_BROKEN", sealDetails)
+					if err != nil {
+						return err
+					}
+					newAsset.History = append(newAsset.History, *sealEvent)
+					newAsset.StatusBeforeHold = newAsset.Status
+					newAsset.Status = AssetStatusOnHold
+				}
+				// Hàng đổi chủ: tổ chức của bên nhận trở thành bên phải xác nhận các thay đổi tiếp theo
+				if err := setAssetEndorsementPolicy(ctx, &newAsset); err != nil {
+					return err
+				}
 				err = s.updateAsset(ctx, &newAsset)
 				if err != nil {
 					return err
 				}
+				// Mã GS1 đi theo hàng: quét Digital Link sẽ ra bên vừa nhận hàng
+				if newAsset.GTIN != "" {
+					if err := moveDigitalLinkToHolder(ctx, &newAsset); err != nil {
+						return err
+					}
+				}
+				// Tạm giữ hoặc thu hồi được đặt khi hàng đang trên đường đi theo hàng sang bên nhận
+				if err := s.inheritRegulatoryStatus(ctx, item.AssetID, newAssetID); err != nil {
+					return err
+				}
 			}
 			break
 		}
@@ -364,6 +526,11 @@ func (s *SmartContract) ConfirmShipmentDelivery(ctx contractapi.TransactionConte
 		return fmt.Errorf("no pending delivery stop found for facility %s", facilityID)
 	}
 
+	// Ghi nhận số lượng đã giao vào các dòng đơn đặt hàng được tham chiếu
+	if err := s.applyDeliveryToPurchaseOrders(ctx, shipmentID, facilityID, deliveredItems); err != nil {
+		return err
+	}
+
 	allDelivered := true
 	for _, stop := range shipment.Stops {
 		if stop.Status != "COMPLETED" {
@@ -372,7 +539,9 @@ func (s *SmartContract) ConfirmShipmentDelivery(ctx contractapi.TransactionConte
 		}
 	}
 	if allDelivered {
-		shipment.Status = "COMPLETED"
+		if err := s.transitionShipment(ctx, shipment, "ConfirmShipmentDelivery", ShipmentStatusCompleted, "SHIPMENT_COMPLETED", "All stops completed."); err != nil {
+			return err
+		}
 	}
 
 	return s.updateShipment(ctx, shipment)
@@ -475,15 +644,476 @@ func (s *SmartContract) QueryShipmentsByFacility(ctx contractapi.TransactionCont
 	return shipments, nil
 }
 
+// AllowOutOfOrderStop cho phép admin hoàn tất một điểm dừng mà không cần chờ các điểm dừng trước đó trong lộ trình.
+func (s *SmartContract) AllowOutOfOrderStop(ctx contractapi.TransactionContextInterface, shipmentID string, facilityID string, action string, reason string) error {
+	if reason == "" {
+		return fmt.Errorf("a reason is required to override the stop order")
+	}
+	shipment, err := s.readShipmentAsset(ctx, shipmentID)
+	if err != nil {
+		return err
+	}
+	if err := requireShipmentAction(shipment, "AllowOutOfOrderStop"); err != nil {
+		return err
+	}
 
-// hàm để test hoàn thành 1 shipment theo shipmentID
-func (s *SmartContract) CompleteShipment(ctx contractapi.TransactionContextInterface, shipmentID string) error {
+	stopFound := false
+	for i, stop := range shipment.Stops {
+		if stop.FacilityID == facilityID && stop.Action == action && stop.Status == "PENDING" {
+			shipment.Stops[i].OutOfOrderAllowed = true
+			stopFound = true
+			break
+		}
+	}
+	if !stopFound {
+		return fmt.Errorf("no pending %s stop found for facility %s in shipment %s", action, facilityID, shipmentID)
+	}
+
+	event, err := s.createEvent(ctx, "STOP_ORDER_OVERRIDDEN", map[string]interface{}{
+		"facilityID": facilityID,
+		"action":     action,
+		"reason":     reason,
+	})
+	if err != nil {
+		return err
+	}
+	shipment.History = append(shipment.History, *event)
+
+	return s.updateShipment(ctx, shipment)
+}
+
+
+// ResealShipment ghi lại niêm phong mới sau khi container được mở tại một điểm giao, để các điểm giao tiếp theo
+// đối chiếu với niêm phong mới. Chỉ nhân viên của cơ sở vừa nhận hàng mới được ghi, ngay sau khi điểm giao của cơ sở
+// đó hoàn tất và trước điểm dừng kế tiếp; mỗi điểm giao chỉ được niêm phong lại một lần.
+func (s *SmartContract) ResealShipment(ctx contractapi.TransactionContextInterface, shipmentID string, facilityID string, sealIDsJSON string) error {
 	shipment, err := s.readShipmentAsset(ctx, shipmentID)
 	if err != nil {
 		return err
 	}
+	if err := requireShipmentAction(shipment, "ResealShipment"); err != nil {
+		return err
+	}
+	callerFacilityID, _, err := getClientAttribute(ctx, "facilityID")
+	if err != nil {
+		return fmt.Errorf("failed to get 'facilityID' attribute: %v", err)
+	}
+	if callerFacilityID != facilityID {
+		return fmt.Errorf("caller from facility '%s' cannot reseal shipment %s at facility %s", callerFacilityID, shipmentID, facilityID)
+	}
+	sealIDs, err := parseSealIDs(sealIDsJSON)
+	if err != nil {
+		return err
+	}
+	if len(sealIDs) == 0 {
+		return fmt.Errorf("at least one seal ID is required to reseal shipment %s", shipmentID)
+	}
+
+	stopIndex := lastCompletedStop(shipment)
+	if stopIndex < 0 || shipment.Stops[stopIndex].Action != "DELIVERY" || shipment.Stops[stopIndex].FacilityID != facilityID {
+		return fmt.Errorf("shipment %s can only be resealed right after a completed delivery at facility %s", shipmentID, facilityID)
+	}
+	stop := &shipment.Stops[stopIndex]
+	if len(stop.ResealedSealIDs) > 0 {
+		return fmt.Errorf("shipment %s has already been resealed at facility %s", shipmentID, facilityID)
+	}
+	stop.ResealedSealIDs = sealIDs
+
+	resealEvent := ShipmentTimeline{
+		Type:       "resealed",
+		Timestamp:  s.getTxTimestamp(ctx),
+		Location:   stop.FacilityAddress.FullText,
+		FacilityID: facilityID,
+		Proof: map[string]interface{}{
+			"stop":            stopIndex + 1,
+			"arrivalSealIDs":  stop.ArrivalSealIDs,
+			"previousSealIDs": shipment.SealIDs,
+			"sealIDs":         sealIDs,
+		},
+	}
+	shipment.Timeline = append(shipment.Timeline, resealEvent)
+	shipment.SealIDs = sealIDs
 
-	// Cập nhật trạng thái của shipment
-	shipment.Status = "COMPLETED"
 	return s.updateShipment(ctx, shipment)
-}
\ No newline at end of file
+}
+
+// CancelShipment hủy một lô hàng chưa khởi hành; hàng đã bốc lên xe được hoàn lại cho asset nguồn
+// và các điểm dừng chưa hoàn tất được đánh dấu SKIPPED.
+func (s *SmartContract) CancelShipment(ctx contractapi.TransactionContextInterface, shipmentID string, reason string) error {
+	if reason == "" {
+		return fmt.Errorf("a reason is required to cancel shipment %s", shipmentID)
+	}
+	shipment, err := s.readShipmentAsset(ctx, shipmentID)
+	if err != nil {
+		return err
+	}
+	details := map[string]interface{}{"reason": reason, "skippedStops": skipPendingStops(shipment)}
+	if err := s.transitionShipment(ctx, shipment, "CancelShipment", ShipmentStatusCancelled, "SHIPMENT_CANCELLED", details); err != nil {
+		return err
+	}
+	if err := s.restoreUndeliveredToSources(ctx, shipment, "SHIPMENT_CANCELLED", reason); err != nil {
+		return err
+	}
+	return s.updateShipment(ctx, shipment)
+}
+
+// ReturnShipment đánh dấu một lô hàng đang vận chuyển bị trả về; phần hàng chưa giao được hoàn lại cho asset nguồn
+// và các điểm dừng chưa hoàn tất được đánh dấu SKIPPED.
+func (s *SmartContract) ReturnShipment(ctx contractapi.TransactionContextInterface, shipmentID string, reason string) error {
+	if reason == "" {
+		return fmt.Errorf("a reason is required to return shipment %s", shipmentID)
+	}
+	shipment, err := s.readShipmentAsset(ctx, shipmentID)
+	if err != nil {
+		return err
+	}
+	details := map[string]interface{}{"reason": reason, "skippedStops": skipPendingStops(shipment)}
+	if err := s.transitionShipment(ctx, shipment, "ReturnShipment", ShipmentStatusReturned, "SHIPMENT_RETURNED", details); err != nil {
+		return err
+	}
+	if err := s.restoreUndeliveredToSources(ctx, shipment, "RETURNED_FROM_SHIPMENT", reason); err != nil {
+		return err
+	}
+	return s.updateShipment(ctx, shipment)
+}
+
+// ForceCloseShipment cho phép admin đóng cưỡng bức một lô hàng sang COMPLETED, CANCELLED hoặc RETURNED.
+// Lý do bắt buộc và được ghi vào History; các điểm dừng chưa hoàn tất được đánh dấu SKIPPED.
+// Khi đóng sang CANCELLED hoặc RETURNED, hàng chưa giao được hoàn lại cho asset nguồn như CancelShipment/ReturnShipment;
+// khi đóng sang COMPLETED, hàng còn trên xe không được hoàn lại, việc đối soát do admin thực hiện riêng.
+func (s *SmartContract) ForceCloseShipment(ctx contractapi.TransactionContextInterface, shipmentID string, targetStatus string, reason string) error {
+	if reason == "" {
+		return fmt.Errorf("a reason is required to force-close shipment %s", shipmentID)
+	}
+	shipment, err := s.readShipmentAsset(ctx, shipmentID)
+	if err != nil {
+		return err
+	}
+
+	previousStatus := shipment.Status
+	details := map[string]interface{}{
+		"reason":         reason,
+		"previousStatus": previousStatus,
+		"skippedStops":   skipPendingStops(shipment),
+	}
+	if err := s.transitionShipment(ctx, shipment, "ForceCloseShipment", targetStatus, "SHIPMENT_FORCE_CLOSED", details); err != nil {
+		return err
+	}
+	switch {
+	case targetStatus == ShipmentStatusCompleted:
+		err = s.releaseShipmentBookings(ctx, shipmentID, pendingPickupItems(shipment), reason)
+	case previousStatus == ShipmentStatusPending:
+		// Sự kiện trên asset nguồn phản ánh lô hàng đã khởi hành hay chưa, giống CancelShipment/ReturnShipment
+		err = s.restoreUndeliveredToSources(ctx, shipment, "SHIPMENT_CANCELLED", reason)
+	default:
+		err = s.restoreUndeliveredToSources(ctx, shipment, "RETURNED_FROM_SHIPMENT", reason)
+	}
+	if err != nil {
+		return err
+	}
+	return s.updateShipment(ctx, shipment)
+}
+
+// skipPendingStops đánh dấu SKIPPED các điểm dừng chưa hoàn tất khi lô hàng bị đóng và trả về danh sách các điểm đó.
+func skipPendingStops(shipment *ShipmentAsset) []string {
+	skippedStops := []string{}
+	for i, stop := range shipment.Stops {
+		if stop.Status == "PENDING" {
+			shipment.Stops[i].Status = "SKIPPED"
+			skippedStops = append(skippedStops, fmt.Sprintf("%s@%s", stop.Action, stop.FacilityID))
+		}
+	}
+	return skippedStops
+}
+
+// --- Các hàm hỗ trợ cho điểm dừng và manifest ---
+
+// Sai số cho phép khi so sánh số lượng kiểu float64.
+const quantityTolerance = 1e-9
+
+// requireStopInRouteOrder kiểm tra tất cả điểm dừng đứng trước trong lộ trình đã hoàn tất,
+// trừ khi điểm dừng này đã được admin cho phép bỏ qua thứ tự.
+func requireStopInRouteOrder(shipment *ShipmentAsset, stopIndex int) error {
+	stop := shipment.Stops[stopIndex]
+	if stop.OutOfOrderAllowed {
+		return nil
+	}
+	for i := 0; i < stopIndex; i++ {
+		previous := shipment.Stops[i]
+		if previous.Status != "COMPLETED" {
+			return fmt.Errorf("stop %d (%s at %s) of shipment %s must be completed before %s at %s", i+1, previous.Action, previous.FacilityID, shipment.ShipmentID, stop.Action, stop.FacilityID)
+		}
+	}
+	return nil
+}
+
+// lastCompletedStop trả về vị trí của điểm dừng được xác nhận gần nhất (theo Timeline), hoặc -1 nếu chưa có.
+// Không dùng thứ tự trong Stops vì điểm dừng có thể được admin cho phép hoàn tất không theo lộ trình.
+func lastCompletedStop(shipment *ShipmentAsset) int {
+	for i := len(shipment.Timeline) - 1; i >= 0; i-- {
+		event := shipment.Timeline[i]
+		var action string
+		switch event.Type {
+		case "arrival":
+			action = "DELIVERY"
+		case "pickup_confirmed":
+			action = "PICKUP"
+		default:
+			continue
+		}
+		// Nếu một cơ sở có nhiều điểm dừng cùng loại, điểm dừng hoàn tất sau cùng là điểm đứng sau trong lộ trình
+		for j := len(shipment.Stops) - 1; j >= 0; j-- {
+			stop := shipment.Stops[j]
+			if stop.FacilityID == event.FacilityID && stop.Action == action && stop.Status == "COMPLETED" {
+				return j
+			}
+		}
+		return -1
+	}
+	return -1
+}
+
+// addToManifest ghi nhận hàng đã bốc lên xe. Mỗi dòng manifest ứng với một bộ (asset, đơn vị, cơ sở lấy hàng);
+// số lượng chỉ được gộp vào dòng có cùng bộ khóa đó.
+func addToManifest(shipment *ShipmentAsset, facilityID string, item ItemInShipment) {
+	for i, entry := range shipment.Manifest {
+		if entry.AssetID == item.AssetID && entry.LoadedQuantity.Unit == item.Quantity.Unit && entry.SourceFacilityID == facilityID {
+			shipment.Manifest[i].LoadedQuantity.Value += item.Quantity.Value
+			return
+		}
+	}
+	shipment.Manifest = append(shipment.Manifest, ManifestItem{
+		AssetID:           item.AssetID,
+		SourceFacilityID:  facilityID,
+		LoadedQuantity:    item.Quantity,
+		DeliveredQuantity: Quantity{Unit: item.Quantity.Unit, Value: 0},
+	})
+}
+
+// allocateFromManifest phân bổ hàng cho một điểm giao từ phần còn lại của manifest.
+// Nếu điểm giao không khai báo hàng, toàn bộ phần còn lại của manifest sẽ được giao.
+// Từ chối các asset chưa từng được bốc lên xe hoặc số lượng vượt quá phần còn lại.
+func allocateFromManifest(shipment *ShipmentAsset, plannedItems []ItemInShipment) ([]ItemInShipment, error) {
+	var allocated []ItemInShipment
+
+	if len(plannedItems) == 0 {
+		for i, entry := range shipment.Manifest {
+			remaining := entry.LoadedQuantity.Value - entry.DeliveredQuantity.Value
+			if remaining <= quantityTolerance {
+				continue
+			}
+			shipment.Manifest[i].DeliveredQuantity.Value += remaining
+			allocated = append(allocated, ItemInShipment{
+				AssetID:  entry.AssetID,
+				Quantity: Quantity{Unit: entry.LoadedQuantity.Unit, Value: remaining},
+			})
+		}
+		if len(allocated) == 0 {
+			return nil, fmt.Errorf("nothing left on the manifest of shipment %s to deliver", shipment.ShipmentID)
+		}
+		return allocated, nil
+	}
+
+	for _, item := range plannedItems {
+		if item.Quantity.Value <= 0 {
+			return nil, fmt.Errorf("delivered quantity for asset %s must be positive", item.AssetID)
+		}
+		// Các dòng manifest của asset có cùng đơn vị; nếu điểm giao không ghi đơn vị thì asset phải chỉ có một đơn vị trên xe.
+		var lines []int
+		unit := item.Quantity.Unit
+		remaining := 0.0
+		for i, entry := range shipment.Manifest {
+			if entry.AssetID != item.AssetID {
+				continue
+			}
+			if unit == "" {
+				unit = entry.LoadedQuantity.Unit
+			}
+			if entry.LoadedQuantity.Unit != unit {
+				if item.Quantity.Unit == "" {
+					return nil, fmt.Errorf("asset %s was loaded on shipment %s in more than one unit, the delivered unit must be given", item.AssetID, shipment.ShipmentID)
+				}
+				continue
+			}
+			lines = append(lines, i)
+			remaining += entry.LoadedQuantity.Value - entry.DeliveredQuantity.Value
+		}
+		if len(lines) == 0 {
+			if item.Quantity.Unit != "" && manifestHasAsset(shipment, item.AssetID) {
+				return nil, fmt.Errorf("asset %s was never loaded on shipment %s in unit '%s'", item.AssetID, shipment.ShipmentID, item.Quantity.Unit)
+			}
+			return nil, fmt.Errorf("asset %s was never loaded on shipment %s", item.AssetID, shipment.ShipmentID)
+		}
+		if item.Quantity.Value > remaining+quantityTolerance {
+			return nil, fmt.Errorf("cannot deliver %f of asset %s: only %f left on shipment %s", item.Quantity.Value, item.AssetID, remaining, shipment.ShipmentID)
+		}
+		toDeliver := item.Quantity.Value
+		for _, i := range lines {
+			entry := &shipment.Manifest[i]
+			lineRemaining := entry.LoadedQuantity.Value - entry.DeliveredQuantity.Value
+			if lineRemaining <= quantityTolerance {
+				continue
+			}
+			taken := toDeliver
+			if taken > lineRemaining {
+				taken = lineRemaining
+			}
+			entry.DeliveredQuantity.Value += taken
+			toDeliver -= taken
+			if toDeliver <= quantityTolerance {
+				break
+			}
+		}
+		allocated = append(allocated, ItemInShipment{
+			AssetID:  item.AssetID,
+			Quantity: Quantity{Unit: unit, Value: item.Quantity.Value},
+			POID:     item.POID,
+			POLineID: item.POLineID,
+		})
+	}
+	return allocated, nil
+}
+
+// manifestHasAsset kiểm tra asset có ít nhất một dòng trong manifest của lô hàng.
+func manifestHasAsset(shipment *ShipmentAsset, assetID string) bool {
+	for _, entry := range shipment.Manifest {
+		if entry.AssetID == assetID {
+			return true
+		}
+	}
+	return false
+}
+
+// parseSealIDs đọc danh sách số niêm phong, bỏ khoảng trắng thừa và từ chối số niêm phong trùng lặp.
+// Chuỗi rỗng được hiểu là không có niêm phong nào.
+func parseSealIDs(sealIDsJSON string) ([]string, error) {
+	if strings.TrimSpace(sealIDsJSON) == "" {
+		return []string{}, nil
+	}
+	var rawSealIDs []string
+	if err := json.Unmarshal([]byte(sealIDsJSON), &rawSealIDs); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal sealIDsJSON: %v", err)
+	}
+	seen := make(map[string]bool)
+	sealIDs := []string{}
+	for _, sealID := range rawSealIDs {
+		sealID = strings.TrimSpace(sealID)
+		if sealID == "" {
+			return nil, fmt.Errorf("seal ID must not be empty")
+		}
+		if seen[sealID] {
+			return nil, fmt.Errorf("duplicate seal ID %s", sealID)
+		}
+		seen[sealID] = true
+		sealIDs = append(sealIDs, sealID)
+	}
+	return sealIDs, nil
+}
+
+// sealsMatch trả về true nếu niêm phong khi đến khớp chính xác với niêm phong lúc khởi hành.
+func sealsMatch(expected []string, actual []string) bool {
+	if len(expected) == 0 || len(expected) != len(actual) {
+		return false
+	}
+	remaining := make(map[string]bool)
+	for _, sealID := range expected {
+		remaining[sealID] = true
+	}
+	for _, sealID := range actual {
+		if !remaining[sealID] {
+			return false
+		}
+		delete(remaining, sealID)
+	}
+	return true
+}
+
+// restoreUndeliveredToSources hoàn lại phần hàng chưa giao trong manifest cho các asset nguồn, khôi phục trạng thái
+// của asset nguồn trước khi khởi hành (nếu lô hàng đã khởi hành) và giải phóng phần giữ chỗ của các điểm lấy hàng chưa hoàn tất.
+// Transaction không đọc được dữ liệu chính nó vừa ghi, nên mọi thay đổi của một asset được gộp lại và ghi một lần.
+func (s *SmartContract) restoreUndeliveredToSources(ctx contractapi.TransactionContextInterface, shipment *ShipmentAsset, eventType string, reason string) error {
+	var assetOrder []string
+	restored := make(map[string]Quantity)
+	for _, entry := range shipment.Manifest {
+		remaining := entry.LoadedQuantity.Value - entry.DeliveredQuantity.Value
+		if remaining <= quantityTolerance {
+			continue
+		}
+		quantity, exists := restored[entry.AssetID]
+		if !exists {
+			assetOrder = append(assetOrder, entry.AssetID)
+			quantity.Unit = entry.LoadedQuantity.Unit
+		}
+		quantity.Value += remaining
+		restored[entry.AssetID] = quantity
+	}
+	for _, item := range pendingPickupItems(shipment) {
+		if !containsString(assetOrder, item.AssetID) {
+			assetOrder = append(assetOrder, item.AssetID)
+		}
+	}
+
+	for _, assetID := range assetOrder {
+		asset, err := s.readAsset(ctx, assetID)
+		if err != nil {
+			return err
+		}
+		releasedBooking := releaseReservation(asset, "SHIPMENT", shipment.ShipmentID)
+
+		quantity, hasRestore := restored[assetID]
+		if !hasRestore {
+			if releasedBooking == nil {
+				continue
+			}
+			details := map[string]interface{}{
+				"shipmentID": shipment.ShipmentID,
+				"quantity":   *releasedBooking,
+				"reason":     reason,
+			}
+			if err := s.addEvent(ctx, asset, "BOOKING_RELEASED", asset.Status, details); err != nil {
+				return err
+			}
+			continue
+		}
+
+		asset.CurrentQuantity.Value += quantity.Value
+		newStatus := asset.Status
+		if asset.Status == AssetStatusPartiallyShipped || asset.Status == AssetStatusShippedFull {
+			// Asset đã có hàng trở lại; nếu không tìm thấy trạng thái trước khi khởi hành (hoặc lúc đó asset đã hết hàng
+			// do lô hàng khác) thì coi như còn hàng một phần.
+			newStatus = AssetStatusPartiallyShipped
+			if previousStatus := statusBeforeShipment(asset, shipment.ShipmentID); previousStatus != "" && previousStatus != AssetStatusShippedFull {
+				newStatus = previousStatus
+			}
+		}
+		details := map[string]interface{}{
+			"shipmentID":       shipment.ShipmentID,
+			"quantityRestored": quantity,
+			"reason":           reason,
+		}
+		if releasedBooking != nil {
+			details["bookingReleased"] = *releasedBooking
+		}
+		if err := s.addEvent(ctx, asset, eventType, newStatus, details); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// statusBeforeShipment tìm trạng thái của asset ngay trước sự kiện SHIPPING_STARTED của lô hàng.
+func statusBeforeShipment(asset *MeatAsset, shipmentID string) string {
+	for i := len(asset.History) - 1; i >= 0; i-- {
+		event := asset.History[i]
+		if event.Type != "SHIPPING_STARTED" {
+			continue
+		}
+		details, ok := event.Details.(map[string]interface{})
+		if !ok || details["shipmentID"] != shipmentID {
+			continue
+		}
+		previousStatus, _ := details["previousStatus"].(string)
+		return previousStatus
+	}
+	return ""
+}
@@ -0,0 +1,572 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+// withFarmBatch dựng danh mục sản phẩm và lô nuôi FARM-BATCH-1 (10 con) tại FARM-1.
+func withFarmBatch(c *testChannel) {
+	c.withCatalog()
+	c.createFarmBatch(farmAdmin, "FARM-BATCH-1", skuPorkCarcass, Quantity{Unit: "head", Value: 10})
+}
+
+// withPendingShipment dựng lô vận chuyển SHIP-1 chở 6 con từ FARM-1 tới PROC-1, chưa lấy hàng.
+func withPendingShipment(c *testChannel) {
+	withFarmBatch(c)
+	stops := []StopInJourney{
+		testStop("FARM-1", "PICKUP", testItem("FARM-BATCH-1", "head", 6)),
+		testStop("PROC-1", "DELIVERY"),
+	}
+	c.mustSubmit(farmAdmin, "CreateShipment", "SHIP-1", "REFRIGERATED", "driver-1", "Nguyen Van A", "51C-123.45", stops)
+}
+
+// withShipmentInTransit dựng lô vận chuyển SHIP-1 đã bốc 6 con từ FARM-1 và đang trên đường tới PROC-1.
+func withShipmentInTransit(c *testChannel) {
+	withFarmBatch(c)
+	c.pickUpAndStart("SHIP-1", farmAdmin, "PROC-1", testItem("FARM-BATCH-1", "head", 6))
+}
+
+func TestCreateShipment(t *testing.T) {
+	stops := func(quantity float64) []StopInJourney {
+		return []StopInJourney{
+			testStop("FARM-1", "PICKUP", testItem("FARM-BATCH-1", "head", quantity)),
+			testStop("PROC-1", "DELIVERY"),
+		}
+	}
+	lateWindow := stops(4)
+	lateWindow[1].PlannedWindowStart = "2024-03-02T10:00:00Z"
+	lateWindow[1].PlannedWindowEnd = "2024-03-02T08:00:00Z"
+
+	runTransactionCases(t, withPendingShipment, []transactionCase{
+		{name: "admin books remaining stock", identity: farmAdmin, function: "CreateShipment",
+			args: []interface{}{"SHIP-2", "REFRIGERATED", "driver-1", "Nguyen Van A", "51C-123.45", stops(4)}},
+		{name: "driver creates shipment", identity: driver, function: "CreateShipment",
+			args: []interface{}{"SHIP-2", "REFRIGERATED", "driver-1", "Nguyen Van A", "51C-123.45", stops(1)}},
+		{name: "overbooking", identity: farmAdmin, function: "CreateShipment",
+			args:    []interface{}{"SHIP-2", "REFRIGERATED", "driver-1", "Nguyen Van A", "51C-123.45", stops(5)},
+			wantErr: "cannot book 5.000000 of asset FARM-BATCH-1 on shipment SHIP-2: only 4.000000 available"},
+		{name: "non-positive quantity", identity: farmAdmin, function: "CreateShipment",
+			args:    []interface{}{"SHIP-2", "REFRIGERATED", "driver-1", "Nguyen Van A", "51C-123.45", stops(0)},
+			wantErr: "booked quantity for asset FARM-BATCH-1 must be positive"},
+		{name: "duplicate shipment", identity: farmAdmin, function: "CreateShipment",
+			args:    []interface{}{"SHIP-1", "REFRIGERATED", "driver-1", "Nguyen Van A", "51C-123.45", stops(1)},
+			wantErr: "shipment SHIP-1 already exists"},
+		{name: "invalid planned window", identity: farmAdmin, function: "CreateShipment",
+			args:    []interface{}{"SHIP-2", "REFRIGERATED", "driver-1", "Nguyen Van A", "51C-123.45", lateWindow},
+			wantErr: "invalid planned window for stop 2: plannedWindowEnd is before plannedWindowStart"},
+		{name: "invalid stops JSON", identity: farmAdmin, function: "CreateShipment",
+			args:    []interface{}{"SHIP-2", "REFRIGERATED", "driver-1", "Nguyen Van A", "51C-123.45", "{"},
+			wantErr: "failed to unmarshal stopsJSON"},
+		{name: "worker denied", identity: farmWorker, function: "CreateShipment",
+			args:    []interface{}{"SHIP-2", "REFRIGERATED", "driver-1", "Nguyen Van A", "51C-123.45", stops(1)},
+			wantErr: "access to CreateShipment denied"},
+		{name: "other facility books stock", identity: otherFarmAdmin, function: "CreateShipment",
+			args:    []interface{}{"SHIP-2", "REFRIGERATED", "driver-1", "Nguyen Van A", "51C-123.45", stops(4)},
+			wantErr: "caller 'farm2-admin' can only book stock at facility FARM-1 as that facility or as the assigned driver"},
+		{name: "unassigned driver books stock", identity: otherDriver, function: "CreateShipment",
+			args:    []interface{}{"SHIP-2", "REFRIGERATED", "driver-1", "Nguyen Van A", "51C-123.45", stops(1)},
+			wantErr: "caller 'driver-2' can only book stock at facility FARM-1"},
+		{name: "asset not owned by the pickup facility", identity: otherFarmAdmin, function: "CreateShipment",
+			args: []interface{}{"SHIP-2", "REFRIGERATED", "driver-1", "Nguyen Van A", "51C-123.45", []StopInJourney{
+				testStop("FARM-2", "PICKUP", testItem("FARM-BATCH-1", "head", 4)),
+				testStop("PROC-1", "DELIVERY"),
+			}},
+			wantErr: "asset FARM-BATCH-1 is owned by 'FARM-1' and cannot be picked up at facility FARM-2"},
+	})
+
+	t.Run("booking reserves stock", func(t *testing.T) {
+		c := newTestChannel(t)
+		withPendingShipment(c)
+		shipment := c.shipment("SHIP-1")
+		if shipment.Status != ShipmentStatusPending || shipment.Stops[0].Status != "PENDING" {
+			t.Fatalf("unexpected shipment status %s / stop %s", shipment.Status, shipment.Stops[0].Status)
+		}
+		asset := c.asset("FARM-BATCH-1")
+		if len(asset.Reservations) != 1 || asset.Reservations[0].ReferenceID != "SHIP-1" || asset.AvailableQuantity.Value != 4 {
+			t.Fatalf("unexpected reservations %+v (available %v)", asset.Reservations, asset.AvailableQuantity.Value)
+		}
+		if lastEvent(asset).Type != "BOOKED_ON_SHIPMENT" {
+			t.Fatalf("last event is %s, want BOOKED_ON_SHIPMENT", lastEvent(asset).Type)
+		}
+	})
+}
+
+func TestPickup(t *testing.T) {
+	withProof := func(c *testChannel) {
+		withPendingShipment(c)
+		c.mustSubmit(driver, "AddPickupProof", "SHIP-1", "FARM-1", testProof("FARM-1"))
+	}
+	items := []ItemInShipment{testItem("FARM-BATCH-1", "head", 6)}
+
+	runTransactionCases(t, withPendingShipment, []transactionCase{
+		{name: "assigned driver adds proof", identity: driver, function: "AddPickupProof",
+			args: []interface{}{"SHIP-1", "FARM-1", testProof("FARM-1")}},
+		{name: "other driver", identity: otherDriver, function: "AddPickupProof",
+			args: []interface{}{"SHIP-1", "FARM-1", testProof("FARM-1")}, wantErr: "caller 'driver-2' is not the designated driver for shipment SHIP-1"},
+		{name: "unknown stop", identity: driver, function: "AddPickupProof",
+			args: []interface{}{"SHIP-1", "WH-1", testProof("WH-1")}, wantErr: "no stop found for facility WH-1 in shipment SHIP-1"},
+		{name: "invalid proof JSON", identity: driver, function: "AddPickupProof",
+			args: []interface{}{"SHIP-1", "FARM-1", "["}, wantErr: "failed to unmarshal proofJSON"},
+		{name: "regulator denied", identity: regulator, function: "AddPickupProof",
+			args: []interface{}{"SHIP-1", "FARM-1", testProof("FARM-1")}, wantErr: "access to AddPickupProof denied"},
+		{name: "confirm without proof", identity: farmWorker, function: "ConfirmPickup",
+			args: []interface{}{"SHIP-1", "FARM-1", items}, wantErr: "pickup proof for facility FARM-1 has not been added by the driver yet"},
+	})
+
+	runTransactionCases(t, withProof, []transactionCase{
+		{name: "owner confirms pickup", identity: farmWorker, function: "ConfirmPickup",
+			args: []interface{}{"SHIP-1", "FARM-1", items}},
+		{name: "not the owner", identity: otherFarmAdmin, function: "ConfirmPickup",
+			args: []interface{}{"SHIP-1", "FARM-1", items}, wantErr: "is not the owner of asset FARM-BATCH-1"},
+		{name: "more than available", identity: farmWorker, function: "ConfirmPickup",
+			args: []interface{}{"SHIP-1", "FARM-1", []ItemInShipment{testItem("FARM-BATCH-1", "head", 11)}}, wantErr: "insufficient quantity for asset FARM-BATCH-1"},
+		{name: "non-positive quantity", identity: farmWorker, function: "ConfirmPickup",
+			args: []interface{}{"SHIP-1", "FARM-1", []ItemInShipment{testItem("FARM-BATCH-1", "head", 0)}}, wantErr: "picked up quantity for asset FARM-BATCH-1 must be positive"},
+		{name: "duplicate asset", identity: farmWorker, function: "ConfirmPickup",
+			args: []interface{}{"SHIP-1", "FARM-1", []ItemInShipment{testItem("FARM-BATCH-1", "head", 3), testItem("FARM-BATCH-1", "head", 3)}}, wantErr: "asset FARM-BATCH-1 is listed more than once in the picked up items"},
+		{name: "unit mismatch", identity: farmWorker, function: "ConfirmPickup",
+			args: []interface{}{"SHIP-1", "FARM-1", []ItemInShipment{testItem("FARM-BATCH-1", "kg", 6)}}, wantErr: "unit 'kg' for asset FARM-BATCH-1 does not match asset unit 'head'"},
+		{name: "not a pickup stop", identity: processorAdmin, function: "ConfirmPickup",
+			args: []interface{}{"SHIP-1", "PROC-1", items}, wantErr: "pickup proof for facility PROC-1 has not been added"},
+		{name: "driver denied", identity: driver, function: "ConfirmPickup",
+			args: []interface{}{"SHIP-1", "FARM-1", items}, wantErr: "access to ConfirmPickup denied"},
+	})
+
+	t.Run("pickup moves stock onto the manifest", func(t *testing.T) {
+		c := newTestChannel(t)
+		withProof(c)
+		c.mustSubmit(farmWorker, "ConfirmPickup", "SHIP-1", "FARM-1", []ItemInShipment{testItem("FARM-BATCH-1", "head", 5)})
+
+		asset := c.asset("FARM-BATCH-1")
+		if asset.CurrentQuantity.Value != 5 || len(asset.Reservations) != 0 {
+			t.Fatalf("asset has %v head and reservations %+v, want 5 head and no reservation", asset.CurrentQuantity.Value, asset.Reservations)
+		}
+		shipment := c.shipment("SHIP-1")
+		if shipment.Stops[0].Status != "COMPLETED" || len(shipment.Manifest) != 1 || shipment.Manifest[0].LoadedQuantity.Value != 5 {
+			t.Fatalf("unexpected stop %+v / manifest %+v", shipment.Stops[0], shipment.Manifest)
+		}
+		_, err := c.submit(farmWorker, "ConfirmPickup", "SHIP-1", "FARM-1", items)
+		expectError(t, err, "no pending pickup stop found for facility FARM-1")
+	})
+}
+
+func TestStartShipment(t *testing.T) {
+	pickedUp := func(c *testChannel) {
+		withPendingShipment(c)
+		c.mustSubmit(driver, "AddPickupProof", "SHIP-1", "FARM-1", testProof("FARM-1"))
+		c.mustSubmit(farmAdmin, "ConfirmPickup", "SHIP-1", "FARM-1", []ItemInShipment{testItem("FARM-BATCH-1", "head", 6)})
+	}
+	runTransactionCases(t, pickedUp, []transactionCase{
+		{name: "driver starts shipment", identity: driver, function: "StartShipment",
+			args: []interface{}{"SHIP-1", []string{"SEAL-1", "SEAL-2"}}},
+		{name: "seal required", identity: driver, function: "StartShipment",
+			args: []interface{}{"SHIP-1", ""}, wantErr: "at least one seal ID is required to start shipment SHIP-1"},
+		{name: "duplicate seal", identity: driver, function: "StartShipment",
+			args: []interface{}{"SHIP-1", []string{"SEAL-1", " SEAL-1"}}, wantErr: "duplicate seal ID SEAL-1"},
+		{name: "other driver", identity: otherDriver, function: "StartShipment",
+			args: []interface{}{"SHIP-1", []string{"SEAL-1"}}, wantErr: "is not the designated driver"},
+		{name: "worker denied", identity: farmWorker, function: "StartShipment",
+			args: []interface{}{"SHIP-1", []string{"SEAL-1"}}, wantErr: "access to StartShipment denied"},
+	})
+
+	t.Run("source asset is marked shipped", func(t *testing.T) {
+		c := newTestChannel(t)
+		pickedUp(c)
+		c.mustSubmit(driver, "StartShipment", "SHIP-1", []string{"SEAL-1"})
+		c.expectStatus("FARM-BATCH-1", AssetStatusPartiallyShipped)
+		if shipment := c.shipment("SHIP-1"); shipment.Status != ShipmentStatusInTransit || shipment.SealIDs[0] != "SEAL-1" {
+			t.Fatalf("shipment status %s with seals %v", shipment.Status, shipment.SealIDs)
+		}
+		_, err := c.submit(driver, "StartShipment", "SHIP-1", []string{"SEAL-1"})
+		expectError(t, err, "StartShipment is not allowed for shipment SHIP-1 in status 'IN_TRANSIT'")
+	})
+}
+
+func TestDelivery(t *testing.T) {
+	withProof := func(c *testChannel) {
+		withShipmentInTransit(c)
+		c.mustSubmit(driver, "AddDeliveryProof", "SHIP-1", "PROC-1", testProof("PROC-1"))
+	}
+	seals := []string{"SEAL-SHIP-1"}
+
+	runTransactionCases(t, withShipmentInTransit, []transactionCase{
+		{name: "assigned driver adds proof", identity: driver, function: "AddDeliveryProof",
+			args: []interface{}{"SHIP-1", "PROC-1", testProof("PROC-1")}},
+		{name: "other driver", identity: otherDriver, function: "AddDeliveryProof",
+			args: []interface{}{"SHIP-1", "PROC-1", testProof("PROC-1")}, wantErr: "is not the designated driver"},
+		{name: "unknown stop", identity: driver, function: "AddDeliveryProof",
+			args: []interface{}{"SHIP-1", "RETAIL-1", testProof("RETAIL-1")}, wantErr: "no stop found for facility RETAIL-1"},
+		{name: "confirm without proof", identity: processorAdmin, function: "ConfirmShipmentDelivery",
+			args: []interface{}{"SHIP-1", "PROC-1", "PROC-BATCH", seals}, wantErr: "delivery proof for facility PROC-1 has not been added by the driver yet"},
+	})
+
+	runTransactionCases(t, withProof, []transactionCase{
+		{name: "receiver confirms delivery", identity: processorAdmin, function: "ConfirmShipmentDelivery",
+			args: []interface{}{"SHIP-1", "PROC-1", "PROC-BATCH", seals}},
+		{name: "invalid seals JSON", identity: processorAdmin, function: "ConfirmShipmentDelivery",
+			args: []interface{}{"SHIP-1", "PROC-1", "PROC-BATCH", "{"}, wantErr: "failed to unmarshal sealIDsJSON"},
+		{name: "not a delivery stop", identity: farmAdmin, function: "ConfirmShipmentDelivery",
+			args: []interface{}{"SHIP-1", "FARM-1", "PROC-BATCH", seals}, wantErr: "delivery proof for facility FARM-1 has not been added"},
+		{name: "driver denied", identity: driver, function: "ConfirmShipmentDelivery",
+			args: []interface{}{"SHIP-1", "PROC-1", "PROC-BATCH", seals}, wantErr: "access to ConfirmShipmentDelivery denied"},
+	})
+
+	t.Run("receiver gets a new asset", func(t *testing.T) {
+		c := newTestChannel(t)
+		withProof(c)
+		c.mustSubmit(processorAdmin, "ConfirmShipmentDelivery", "SHIP-1", "PROC-1", "PROC-BATCH", seals)
+
+		received := c.asset("PROC-BATCH-0")
+		if received.Status != AssetStatusAtProcessor || received.OwnerOrg != "PROC-1" || received.CurrentQuantity.Value != 6 {
+			t.Fatalf("unexpected received asset: status %s owner %s quantity %v", received.Status, received.OwnerOrg, received.CurrentQuantity.Value)
+		}
+		if len(received.ParentAssetIDs) != 1 || received.ParentAssetIDs[0] != "FARM-BATCH-1" || received.GTIN != c.asset("FARM-BATCH-1").GTIN {
+			t.Fatalf("received asset does not link to its source: %+v", received)
+		}
+		shipment := c.shipment("SHIP-1")
+		if shipment.Status != ShipmentStatusCompleted || shipment.Stops[1].SealStatus != "SEAL_INTACT" {
+			t.Fatalf("shipment status %s, seal %s", shipment.Status, shipment.Stops[1].SealStatus)
+		}
+		_, err := c.submit(processorAdmin, "ConfirmShipmentDelivery", "SHIP-1", "PROC-1", "PROC-BATCH-B", seals)
+		expectError(t, err, "ConfirmShipmentDelivery is not allowed for shipment SHIP-1 in status 'COMPLETED'")
+	})
+
+	t.Run("received asset must not overwrite an existing asset", func(t *testing.T) {
+		c := newTestChannel(t)
+		withProof(c)
+		c.createFarmBatch(farmAdmin, "PROC-BATCH-0", skuPorkCarcass, Quantity{Unit: "head", Value: 1})
+		_, err := c.submit(processorAdmin, "ConfirmShipmentDelivery", "SHIP-1", "PROC-1", "PROC-BATCH", seals)
+		expectError(t, err, "asset PROC-BATCH-0 already exists")
+		if asset := c.asset("PROC-BATCH-0"); asset.OwnerOrg != "FARM-1" {
+			t.Fatalf("asset PROC-BATCH-0 was overwritten by the delivery")
+		}
+	})
+
+	t.Run("status depends on receiving facility type", func(t *testing.T) {
+		receivers := map[*mockIdentity]string{
+			warehouseAdmin: AssetStatusAtWarehouse,
+			retailerAdmin:  AssetStatusAtRetailer,
+			newTestIdentity(MeatSupplyOrgMSP, "depot-admin", "admin", "WH-1", ""): AssetStatusReceived,
+		}
+		for receiver, status := range receivers {
+			c := newTestChannel(t)
+			withFarmBatch(c)
+			c.ship("SHIP-1", farmAdmin, receiver, "RECEIVED", testItem("FARM-BATCH-1", "head", 3))
+			c.expectStatus("RECEIVED-0", status)
+		}
+	})
+
+	t.Run("broken seal puts goods on hold", func(t *testing.T) {
+		c := newTestChannel(t)
+		withProof(c)
+		c.mustSubmit(processorAdmin, "ConfirmShipmentDelivery", "SHIP-1", "PROC-1", "PROC-BATCH", []string{"SEAL-TAMPERED"})
+
+		held := c.asset("PROC-BATCH-0")
+		if held.Status != AssetStatusOnHold || held.StatusBeforeHold != AssetStatusAtProcessor || lastEvent(held).Type != "SEAL_BROKEN" {
+			t.Fatalf("unexpected held asset: status %s before hold %s", held.Status, held.StatusBeforeHold)
+		}
+		if stop := c.shipment("SHIP-1").Stops[1]; stop.SealStatus != "SEAL_BROKEN" {
+			t.Fatalf("stop seal status %s, want SEAL_BROKEN", stop.SealStatus)
+		}
+	})
+}
+
+func TestMultiStopShipment(t *testing.T) {
+	setup := func(c *testChannel) {
+		withFarmBatch(c)
+		c.createFarmBatch(otherFarmAdmin, "FARM2-BATCH-1", skuPorkCarcass, Quantity{Unit: "head", Value: 4})
+		stops := []StopInJourney{
+			testStop("FARM-1", "PICKUP", testItem("FARM-BATCH-1", "head", 6)),
+			testStop("FARM-2", "PICKUP", testItem("FARM2-BATCH-1", "head", 4)),
+			testStop("WH-1", "DELIVERY", testItem("FARM-BATCH-1", "head", 6)),
+			testStop("PROC-1", "DELIVERY"),
+		}
+		// Lộ trình lấy hàng ở hai trang trại do tài xế được chỉ định tạo
+		c.mustSubmit(driver, "CreateShipment", "SHIP-1", "REFRIGERATED", "driver-1", "Nguyen Van A", "51C-123.45", stops)
+		c.mustSubmit(driver, "AddPickupProof", "SHIP-1", "FARM-2", testProof("FARM-2"))
+	}
+
+	runTransactionCases(t, setup, []transactionCase{
+		{name: "stops must follow the route", identity: otherFarmAdmin, function: "ConfirmPickup",
+			args:    []interface{}{"SHIP-1", "FARM-2", []ItemInShipment{testItem("FARM2-BATCH-1", "head", 4)}},
+			wantErr: "stop 1 (PICKUP at FARM-1) of shipment SHIP-1 must be completed before PICKUP at FARM-2"},
+		{name: "admin allows out of order stop", identity: farmAdmin, function: "AllowOutOfOrderStop",
+			args: []interface{}{"SHIP-1", "FARM-2", "PICKUP", "Road closed"}},
+		{name: "reason required", identity: farmAdmin, function: "AllowOutOfOrderStop",
+			args: []interface{}{"SHIP-1", "FARM-2", "PICKUP", ""}, wantErr: "a reason is required to override the stop order"},
+		{name: "no such stop", identity: farmAdmin, function: "AllowOutOfOrderStop",
+			args: []interface{}{"SHIP-1", "FARM-2", "DELIVERY", "Road closed"}, wantErr: "no pending DELIVERY stop found for facility FARM-2"},
+		{name: "worker denied", identity: farmWorker, function: "AllowOutOfOrderStop",
+			args: []interface{}{"SHIP-1", "FARM-2", "PICKUP", "Road closed"}, wantErr: "access to AllowOutOfOrderStop denied"},
+	})
+
+	t.Run("deliveries are allocated from the manifest", func(t *testing.T) {
+		c := newTestChannel(t)
+		setup(c)
+		c.mustSubmit(farmAdmin, "AllowOutOfOrderStop", "SHIP-1", "FARM-2", "PICKUP", "Road closed")
+		c.mustSubmit(otherFarmAdmin, "ConfirmPickup", "SHIP-1", "FARM-2", []ItemInShipment{testItem("FARM2-BATCH-1", "head", 4)})
+		c.mustSubmit(driver, "AddPickupProof", "SHIP-1", "FARM-1", testProof("FARM-1"))
+		c.mustSubmit(farmAdmin, "ConfirmPickup", "SHIP-1", "FARM-1", []ItemInShipment{testItem("FARM-BATCH-1", "head", 6)})
+		c.mustSubmit(driver, "StartShipment", "SHIP-1", []string{"SEAL-1"})
+
+		c.mustSubmit(driver, "AddDeliveryProof", "SHIP-1", "WH-1", testProof("WH-1"))
+		c.mustSubmit(warehouseAdmin, "ConfirmShipmentDelivery", "SHIP-1", "WH-1", "WH-BATCH", []string{"SEAL-1"})
+		c.mustSubmit(warehouseAdmin, "ResealShipment", "SHIP-1", "WH-1", []string{"SEAL-2"})
+		c.mustSubmit(driver, "AddDeliveryProof", "SHIP-1", "PROC-1", testProof("PROC-1"))
+		c.mustSubmit(processorAdmin, "ConfirmShipmentDelivery", "SHIP-1", "PROC-1", "PROC-BATCH", []string{"SEAL-2"})
+
+		c.expectQuantity("WH-BATCH-0", 6)
+		c.expectQuantity("PROC-BATCH-0", 4)
+		if c.asset("PROC-BATCH-0").ParentAssetIDs[0] != "FARM2-BATCH-1" {
+			t.Fatal("processor must receive what is left on the manifest")
+		}
+		if c.exists("PROC-BATCH-1") {
+			t.Fatal("processor received goods already delivered to the warehouse")
+		}
+		if shipment := c.shipment("SHIP-1"); shipment.Status != ShipmentStatusCompleted || shipment.Stops[3].SealStatus != "SEAL_INTACT" {
+			t.Fatalf("shipment status %s, last seal %s", shipment.Status, shipment.Stops[3].SealStatus)
+		}
+	})
+}
+
+func TestResealShipment(t *testing.T) {
+	// SHIP-1 chở 6 con từ FARM-1 tới WH-1 (2 con) rồi PROC-1 (4 con)
+	withTwoDeliveries := func(c *testChannel) {
+		withFarmBatch(c)
+		stops := []StopInJourney{
+			testStop("FARM-1", "PICKUP", testItem("FARM-BATCH-1", "head", 6)),
+			testStop("WH-1", "DELIVERY", testItem("FARM-BATCH-1", "head", 2)),
+			testStop("PROC-1", "DELIVERY"),
+		}
+		c.mustSubmit(farmAdmin, "CreateShipment", "SHIP-1", "REFRIGERATED", "driver-1", "Nguyen Van A", "51C-123.45", stops)
+		c.mustSubmit(driver, "AddPickupProof", "SHIP-1", "FARM-1", testProof("FARM-1"))
+		c.mustSubmit(farmAdmin, "ConfirmPickup", "SHIP-1", "FARM-1", []ItemInShipment{testItem("FARM-BATCH-1", "head", 6)})
+		c.mustSubmit(driver, "StartShipment", "SHIP-1", []string{"SEAL-1"})
+	}
+	deliveredToWarehouse := func(c *testChannel) {
+		withTwoDeliveries(c)
+		c.mustSubmit(driver, "AddDeliveryProof", "SHIP-1", "WH-1", testProof("WH-1"))
+		c.mustSubmit(warehouseAdmin, "ConfirmShipmentDelivery", "SHIP-1", "WH-1", "WH-BATCH", []string{"SEAL-1"})
+	}
+
+	runTransactionCases(t, deliveredToWarehouse, []transactionCase{
+		{name: "receiving facility reseals", identity: warehouseAdmin, function: "ResealShipment",
+			args: []interface{}{"SHIP-1", "WH-1", []string{"SEAL-2"}}},
+		{name: "seal required", identity: warehouseAdmin, function: "ResealShipment",
+			args: []interface{}{"SHIP-1", "WH-1", []string{}}, wantErr: "at least one seal ID is required to reseal shipment SHIP-1"},
+		{name: "driver alone", identity: driver, function: "ResealShipment",
+			args: []interface{}{"SHIP-1", "WH-1", []string{"SEAL-2"}}, wantErr: "access to ResealShipment denied"},
+		{name: "other facility", identity: processorAdmin, function: "ResealShipment",
+			args: []interface{}{"SHIP-1", "WH-1", []string{"SEAL-2"}}, wantErr: "caller from facility 'PROC-1' cannot reseal shipment SHIP-1 at facility WH-1"},
+		{name: "facility not delivered yet", identity: processorAdmin, function: "ResealShipment",
+			args: []interface{}{"SHIP-1", "PROC-1", []string{"SEAL-2"}}, wantErr: "can only be resealed right after a completed delivery at facility PROC-1"},
+	})
+	runTransactionCases(t, withTwoDeliveries, []transactionCase{
+		{name: "before any delivery", identity: warehouseAdmin, function: "ResealShipment",
+			args: []interface{}{"SHIP-1", "WH-1", []string{"NEW-SEAL"}}, wantErr: "can only be resealed right after a completed delivery at facility WH-1"},
+	})
+	runTransactionCases(t, withPendingShipment, []transactionCase{
+		{name: "not in transit", identity: warehouseAdmin, function: "ResealShipment",
+			args: []interface{}{"SHIP-1", "WH-1", []string{"SEAL-2"}}, wantErr: "ResealShipment is not allowed for shipment SHIP-1 in status 'PENDING'"},
+	})
+
+	t.Run("reseal is recorded on the stop once", func(t *testing.T) {
+		c := newTestChannel(t)
+		deliveredToWarehouse(c)
+		c.mustSubmit(warehouseAdmin, "ResealShipment", "SHIP-1", "WH-1", []string{"SEAL-2"})
+		_, err := c.submit(warehouseAdmin, "ResealShipment", "SHIP-1", "WH-1", []string{"SEAL-3"})
+		expectError(t, err, "shipment SHIP-1 has already been resealed at facility WH-1")
+
+		shipment := c.shipment("SHIP-1")
+		if got := shipment.Stops[1].ResealedSealIDs; len(got) != 1 || got[0] != "SEAL-2" || shipment.SealIDs[0] != "SEAL-2" {
+			t.Fatalf("stop resealed with %v, shipment seals %v", got, shipment.SealIDs)
+		}
+		if event := shipment.Timeline[len(shipment.Timeline)-1]; event.Type != "resealed" || event.FacilityID != "WH-1" {
+			t.Fatalf("last timeline event is %s at %s", event.Type, event.FacilityID)
+		}
+	})
+}
+
+func TestCancelShipment(t *testing.T) {
+	pickedUp := func(c *testChannel) {
+		withPendingShipment(c)
+		c.mustSubmit(driver, "AddPickupProof", "SHIP-1", "FARM-1", testProof("FARM-1"))
+		c.mustSubmit(farmAdmin, "ConfirmPickup", "SHIP-1", "FARM-1", []ItemInShipment{testItem("FARM-BATCH-1", "head", 6)})
+	}
+	runTransactionCases(t, pickedUp, []transactionCase{
+		{name: "admin cancels", identity: farmAdmin, function: "CancelShipment", args: []interface{}{"SHIP-1", "Truck broke down"}},
+		{name: "reason required", identity: farmAdmin, function: "CancelShipment", args: []interface{}{"SHIP-1", ""}, wantErr: "a reason is required to cancel shipment SHIP-1"},
+		{name: "unknown shipment", identity: farmAdmin, function: "CancelShipment", args: []interface{}{"SHIP-9", "x"}, wantErr: "does not exist"},
+		{name: "driver denied", identity: driver, function: "CancelShipment", args: []interface{}{"SHIP-1", "x"}, wantErr: "access to CancelShipment denied"},
+	})
+	runTransactionCases(t, withShipmentInTransit, []transactionCase{
+		{name: "already departed", identity: farmAdmin, function: "CancelShipment", args: []interface{}{"SHIP-1", "x"}, wantErr: "CancelShipment is not allowed for shipment SHIP-1 in status 'IN_TRANSIT'"},
+	})
+
+	t.Run("loaded goods are restored", func(t *testing.T) {
+		c := newTestChannel(t)
+		pickedUp(c)
+		c.mustSubmit(farmAdmin, "CancelShipment", "SHIP-1", "Truck broke down")
+		c.expectQuantity("FARM-BATCH-1", 10)
+		c.expectStatus("FARM-BATCH-1", AssetStatusAtFarm)
+		if shipment := c.shipment("SHIP-1"); shipment.Status != ShipmentStatusCancelled || shipment.Stops[1].Status != "SKIPPED" {
+			t.Fatalf("shipment status %s, delivery stop %s, want CANCELLED and SKIPPED", shipment.Status, shipment.Stops[1].Status)
+		}
+	})
+
+	t.Run("bookings are released", func(t *testing.T) {
+		c := newTestChannel(t)
+		withPendingShipment(c)
+		c.mustSubmit(farmAdmin, "CancelShipment", "SHIP-1", "Order withdrawn")
+		if asset := c.asset("FARM-BATCH-1"); len(asset.Reservations) != 0 || asset.AvailableQuantity.Value != 10 {
+			t.Fatalf("reservations %+v remain after cancel", asset.Reservations)
+		}
+	})
+
+	t.Run("asset loaded and still booked is written once", func(t *testing.T) {
+		c := newTestChannel(t)
+		withFarmBatch(c)
+		stops := []StopInJourney{
+			testStop("FARM-1", "PICKUP", testItem("FARM-BATCH-1", "head", 3)),
+			testStop("FARM-1", "PICKUP", testItem("FARM-BATCH-1", "head", 3)),
+			testStop("PROC-1", "DELIVERY"),
+		}
+		c.mustSubmit(farmAdmin, "CreateShipment", "SHIP-1", "REFRIGERATED", "driver-1", "Nguyen Van A", "51C-123.45", stops)
+		c.mustSubmit(driver, "AddPickupProof", "SHIP-1", "FARM-1", testProof("FARM-1"))
+		c.mustSubmit(farmAdmin, "ConfirmPickup", "SHIP-1", "FARM-1", []ItemInShipment{testItem("FARM-BATCH-1", "head", 3)})
+		c.mustSubmit(farmAdmin, "ReserveAsset", "FARM-BATCH-1", "SHIPMENT", "SHIP-1", Quantity{Unit: "head", Value: 2})
+
+		c.mustSubmit(farmAdmin, "CancelShipment", "SHIP-1", "Order withdrawn")
+		asset := c.asset("FARM-BATCH-1")
+		if asset.CurrentQuantity.Value != 10 || len(asset.Reservations) != 0 {
+			t.Fatalf("asset has %v head and reservations %+v, want 10 head and no reservation", asset.CurrentQuantity.Value, asset.Reservations)
+		}
+		event := lastEvent(asset)
+		if details, _ := event.Details.(map[string]interface{}); event.Type != "SHIPMENT_CANCELLED" || details["bookingReleased"] == nil {
+			t.Fatalf("last event %s %+v, want SHIPMENT_CANCELLED releasing the booking", event.Type, event.Details)
+		}
+	})
+}
+
+func TestReturnShipment(t *testing.T) {
+	runTransactionCases(t, withShipmentInTransit, []transactionCase{
+		{name: "admin returns", identity: farmAdmin, function: "ReturnShipment", args: []interface{}{"SHIP-1", "Rejected at gate"}},
+		{name: "reason required", identity: farmAdmin, function: "ReturnShipment", args: []interface{}{"SHIP-1", ""}, wantErr: "a reason is required to return shipment SHIP-1"},
+		{name: "worker denied", identity: farmWorker, function: "ReturnShipment", args: []interface{}{"SHIP-1", "x"}, wantErr: "access to ReturnShipment denied"},
+	})
+	runTransactionCases(t, withPendingShipment, []transactionCase{
+		{name: "not departed", identity: farmAdmin, function: "ReturnShipment", args: []interface{}{"SHIP-1", "x"}, wantErr: "ReturnShipment is not allowed for shipment SHIP-1 in status 'PENDING'"},
+	})
+
+	t.Run("undelivered goods return to source", func(t *testing.T) {
+		c := newTestChannel(t)
+		withShipmentInTransit(c)
+		c.mustSubmit(farmAdmin, "ReturnShipment", "SHIP-1", "Rejected at gate")
+		c.expectQuantity("FARM-BATCH-1", 10)
+		c.expectStatus("FARM-BATCH-1", AssetStatusAtFarm)
+		if last := lastEvent(c.asset("FARM-BATCH-1")); last.Type != "RETURNED_FROM_SHIPMENT" {
+			t.Fatalf("last event %s, want RETURNED_FROM_SHIPMENT", last.Type)
+		}
+		if stop := c.shipment("SHIP-1").Stops[1]; stop.Status != "SKIPPED" {
+			t.Fatalf("delivery stop %s, want SKIPPED", stop.Status)
+		}
+	})
+}
+
+func TestForceCloseShipment(t *testing.T) {
+	runTransactionCases(t, withShipmentInTransit, []transactionCase{
+		{name: "close as completed", identity: processorAdmin, function: "ForceCloseShipment", args: []interface{}{"SHIP-1", ShipmentStatusCompleted, "Paper delivery note"}},
+		{name: "close as returned", identity: farmAdmin, function: "ForceCloseShipment", args: []interface{}{"SHIP-1", ShipmentStatusReturned, "Lost contact"}},
+		{name: "invalid target", identity: farmAdmin, function: "ForceCloseShipment", args: []interface{}{"SHIP-1", ShipmentStatusInTransit, "x"}, wantErr: "ForceCloseShipment cannot move shipment SHIP-1 from 'IN_TRANSIT' to 'IN_TRANSIT'"},
+		{name: "reason required", identity: farmAdmin, function: "ForceCloseShipment", args: []interface{}{"SHIP-1", ShipmentStatusCompleted, ""}, wantErr: "a reason is required"},
+		{name: "driver denied", identity: driver, function: "ForceCloseShipment", args: []interface{}{"SHIP-1", ShipmentStatusCompleted, "x"}, wantErr: "access to ForceCloseShipment denied"},
+	})
+
+	t.Run("cancel restores loaded goods and skips pending stops", func(t *testing.T) {
+		c := newTestChannel(t)
+		withShipmentInTransit(c)
+		c.mustSubmit(farmAdmin, "ForceCloseShipment", "SHIP-1", ShipmentStatusCancelled, "Vehicle accident")
+		shipment := c.shipment("SHIP-1")
+		if shipment.Status != ShipmentStatusCancelled || shipment.Stops[1].Status != "SKIPPED" {
+			t.Fatalf("shipment status %s, delivery stop %s", shipment.Status, shipment.Stops[1].Status)
+		}
+		c.expectQuantity("FARM-BATCH-1", 10)
+		c.expectStatus("FARM-BATCH-1", AssetStatusAtFarm)
+		_, err := c.submit(farmAdmin, "ForceCloseShipment", "SHIP-1", ShipmentStatusCompleted, "again")
+		expectError(t, err, "ForceCloseShipment is not allowed for shipment SHIP-1 in status 'CANCELLED'")
+	})
+
+	t.Run("completed leaves quantities to offline reconciliation", func(t *testing.T) {
+		c := newTestChannel(t)
+		withShipmentInTransit(c)
+		c.mustSubmit(farmAdmin, "ForceCloseShipment", "SHIP-1", ShipmentStatusCompleted, "Paper delivery note")
+		c.expectQuantity("FARM-BATCH-1", 4)
+	})
+}
+
+// Hai lô hàng cùng lấy hết hàng của một asset: SHIP-1 (4 con) khởi hành trước làm asset hết hàng (SHIPPED_FULL),
+// SHIP-2 (6 con) vẫn phải khởi hành, hủy và trả về được.
+func TestShipmentsSharingAnAsset(t *testing.T) {
+	bothPickedUp := func(c *testChannel) {
+		withFarmBatch(c)
+		for _, booking := range []struct {
+			shipmentID string
+			heads      float64
+		}{{"SHIP-1", 4}, {"SHIP-2", 6}} {
+			item := testItem("FARM-BATCH-1", "head", booking.heads)
+			stops := []StopInJourney{testStop("FARM-1", "PICKUP", item), testStop("PROC-1", "DELIVERY")}
+			c.mustSubmit(farmAdmin, "CreateShipment", booking.shipmentID, "REFRIGERATED", "driver-1", "Nguyen Van A", "51C-123.45", stops)
+			c.mustSubmit(driver, "AddPickupProof", booking.shipmentID, "FARM-1", testProof("FARM-1"))
+			c.mustSubmit(farmAdmin, "ConfirmPickup", booking.shipmentID, "FARM-1", []ItemInShipment{item})
+		}
+		c.mustSubmit(driver, "StartShipment", "SHIP-1", []string{"SEAL-SHIP-1"})
+		c.expectAssets(map[string]assetState{"FARM-BATCH-1": {AssetStatusShippedFull, 0}})
+	}
+
+	t.Run("second shipment departs", func(t *testing.T) {
+		c := newTestChannel(t)
+		bothPickedUp(c)
+		c.mustSubmit(driver, "StartShipment", "SHIP-2", []string{"SEAL-SHIP-2"})
+		c.expectAssets(map[string]assetState{"FARM-BATCH-1": {AssetStatusShippedFull, 0}})
+	})
+
+	t.Run("second shipment is cancelled", func(t *testing.T) {
+		c := newTestChannel(t)
+		bothPickedUp(c)
+		c.mustSubmit(farmAdmin, "CancelShipment", "SHIP-2", "Order withdrawn")
+		c.expectAssets(map[string]assetState{"FARM-BATCH-1": {AssetStatusPartiallyShipped, 6}})
+	})
+
+	t.Run("both shipments are returned", func(t *testing.T) {
+		c := newTestChannel(t)
+		bothPickedUp(c)
+		c.mustSubmit(driver, "StartShipment", "SHIP-2", []string{"SEAL-SHIP-2"})
+		c.mustSubmit(farmAdmin, "ReturnShipment", "SHIP-2", "Rejected at gate")
+		c.expectAssets(map[string]assetState{"FARM-BATCH-1": {AssetStatusPartiallyShipped, 6}})
+		c.mustSubmit(farmAdmin, "ReturnShipment", "SHIP-1", "Rejected at gate")
+		c.expectAssets(map[string]assetState{"FARM-BATCH-1": {AssetStatusAtFarm, 10}})
+	})
+}
+
+func TestShipmentQueries(t *testing.T) {
+	c := newTestChannel(t)
+	withFarmBatch(c)
+	c.pickUpAndStart("SHIP-1", farmAdmin, "PROC-1", testItem("FARM-BATCH-1", "head", 2))
+	c.advance(time.Hour)
+	c.mustSubmit(farmAdmin, "CreateShipment", "SHIP-2", "DRY", "driver-2", "Tran Van B", "51C-678.90",
+		[]StopInJourney{testStop("FARM-1", "PICKUP", testItem("FARM-BATCH-1", "head", 2)), testStop("WH-1", "DELIVERY")})
+
+	if shipments := c.mustEvaluate(driver, "QueryShipmentsByDriver", "driver-1").([]*ShipmentAsset); len(shipments) != 1 || shipments[0].ShipmentID != "SHIP-1" {
+		t.Fatalf("QueryShipmentsByDriver returned %d shipments", len(shipments))
+	}
+	atFarm := c.mustEvaluate(farmAdmin, "QueryShipmentsByFacility", "FARM-1").([]*ShipmentAsset)
+	if len(atFarm) != 2 || atFarm[0].ShipmentID != "SHIP-2" {
+		t.Fatalf("QueryShipmentsByFacility must return both shipments, newest first")
+	}
+	if atWarehouse := c.mustEvaluate(regulator, "QueryShipmentsByFacility", "WH-1").([]*ShipmentAsset); len(atWarehouse) != 1 {
+		t.Fatalf("QueryShipmentsByFacility(WH-1) returned %d shipments, want 1", len(atWarehouse))
+	}
+	_, err := c.evaluate(driver, "GetShipment", "SHIP-9")
+	expectError(t, err, "does not exist")
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// Các trạng thái của một lô vận chuyển.
+const (
+	ShipmentStatusPending   = "PENDING"
+	ShipmentStatusInTransit = "IN_TRANSIT"
+	ShipmentStatusCompleted = "COMPLETED"
+	ShipmentStatusCancelled = "CANCELLED"
+	ShipmentStatusReturned  = "RETURNED"
+)
+
+// shipmentTransition mô tả trạng thái mà một hàm được phép thao tác (From)
+// và các trạng thái mà hàm đó được phép chuyển lô hàng sang (To).
+// To rỗng nghĩa là hàm không đổi trạng thái lô hàng.
+type shipmentTransition struct {
+	From []string
+	To   []string
+}
+
+// shipmentTransitions là bảng chuyển trạng thái duy nhất mà mọi hàm xử lý shipment đều tra cứu.
+var shipmentTransitions = map[string]shipmentTransition{
+	"AddPickupProof":      {From: []string{ShipmentStatusPending}},
+	"ConfirmPickup":       {From: []string{ShipmentStatusPending}},
+	"AllowOutOfOrderStop": {From: []string{ShipmentStatusPending, ShipmentStatusInTransit}},
+	"StartShipment":       {From: []string{ShipmentStatusPending}, To: []string{ShipmentStatusInTransit}},
+	"RecordLocationPings": {From: []string{ShipmentStatusInTransit}},
+	"ResealShipment":      {From: []string{ShipmentStatusInTransit}},
+	"AddDeliveryProof":    {From: []string{ShipmentStatusInTransit}},
+	"ConfirmShipmentDelivery": {
+		From: []string{ShipmentStatusInTransit},
+		To:   []string{ShipmentStatusCompleted},
+	},
+	"CancelShipment": {From: []string{ShipmentStatusPending}, To: []string{ShipmentStatusCancelled}},
+	"ReturnShipment": {From: []string{ShipmentStatusInTransit}, To: []string{ShipmentStatusReturned}},
+	"ForceCloseShipment": {
+		From: []string{ShipmentStatusPending, ShipmentStatusInTransit},
+		To:   []string{ShipmentStatusCompleted, ShipmentStatusCancelled, ShipmentStatusReturned},
+	},
+}
+
+// requireShipmentAction kiểm tra hàm action được phép thao tác trên lô hàng ở trạng thái hiện tại.
+func requireShipmentAction(shipment *ShipmentAsset, action string) error {
+	transition, ok := shipmentTransitions[action]
+	if !ok {
+		return fmt.Errorf("action %s is not defined in the shipment state machine", action)
+	}
+	if !containsString(transition.From, shipment.Status) {
+		return fmt.Errorf("%s is not allowed for shipment %s in status '%s'", action, shipment.ShipmentID, shipment.Status)
+	}
+	return nil
+}
+
+// transitionShipment chuyển lô hàng sang trạng thái mới theo bảng chuyển trạng thái
+// và ghi lại sự kiện tương ứng vào History. Hàm không lưu lô hàng vào world state.
+func (s *SmartContract) transitionShipment(ctx contractapi.TransactionContextInterface, shipment *ShipmentAsset, action string, newStatus string, eventType string, details interface{}) error {
+	if err := requireShipmentAction(shipment, action); err != nil {
+		return err
+	}
+	if !containsString(shipmentTransitions[action].To, newStatus) {
+		return fmt.Errorf("%s cannot move shipment %s from '%s' to '%s'", action, shipment.ShipmentID, shipment.Status, newStatus)
+	}
+
+	event, err := s.createEvent(ctx, eventType, details)
+	if err != nil {
+		return err
+	}
+	shipment.History = append(shipment.History, *event)
+	shipment.Status = newStatus
+	return nil
+}
+
+// containsString kiểm tra một chuỗi có nằm trong danh sách không.
+func containsString(values []string, target string) bool {
+	for _, value := range values {
+		if value == target {
+			return true
+		}
+	}
+	return false
+}
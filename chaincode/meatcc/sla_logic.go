@@ -0,0 +1,124 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// QueryLateShipments thực hiện một truy vấn CouchDB để tìm các lô hàng có điểm dừng tại một cơ sở
+// bị xác nhận trễ so với khung giờ dự kiến trong khoảng thời gian [from, to].
+// from hoặc to để trống sẽ bỏ qua giới hạn tương ứng.
+func (s *SmartContract) QueryLateShipments(ctx contractapi.TransactionContextInterface, facilityID string, from string, to string) ([]*ShipmentAsset, error) {
+	stopSelector := map[string]interface{}{
+		"facilityID": facilityID,
+		"late":       true,
+	}
+	actualTimeRange := map[string]interface{}{}
+	if from != "" {
+		fromTime, err := time.Parse(time.RFC3339, from)
+		if err != nil {
+			return nil, fmt.Errorf("invalid 'from' timestamp: %v", err)
+		}
+		actualTimeRange["$gte"] = fromTime.UTC().Format(time.RFC3339)
+	}
+	if to != "" {
+		toTime, err := time.Parse(time.RFC3339, to)
+		if err != nil {
+			return nil, fmt.Errorf("invalid 'to' timestamp: %v", err)
+		}
+		actualTimeRange["$lte"] = toTime.UTC().Format(time.RFC3339)
+	}
+	if len(actualTimeRange) > 0 {
+		stopSelector["actualTime"] = actualTimeRange
+	}
+
+	query := map[string]interface{}{
+		"selector": map[string]interface{}{
+			"docType": "ShipmentAsset",
+			"stops": map[string]interface{}{
+				"$elemMatch": stopSelector,
+			},
+		},
+	}
+	queryBytes, err := json.Marshal(query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build query: %v", err)
+	}
+
+	resultsIterator, err := ctx.GetStub().GetQueryResult(string(queryBytes))
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute rich query: %v", err)
+	}
+	defer resultsIterator.Close()
+
+	var shipments []*ShipmentAsset
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+		var shipment ShipmentAsset
+		if err := json.Unmarshal(queryResponse.Value, &shipment); err != nil {
+			return nil, err
+		}
+		shipments = append(shipments, &shipment)
+	}
+
+	// Sắp xếp theo thời gian tạo lô hàng, mới nhất trước
+	sort.Slice(shipments, func(i, j int) bool {
+		if len(shipments[i].History) == 0 { return false }
+		if len(shipments[j].History) == 0 { return true }
+		return shipments[i].History[0].Timestamp > shipments[j].History[0].Timestamp
+	})
+
+	return shipments, nil
+}
+
+// validatePlannedWindow kiểm tra khung giờ dự kiến của một điểm dừng (nếu có) đúng định dạng RFC3339.
+func validatePlannedWindow(stop StopInJourney) error {
+	var start, end time.Time
+	var err error
+	if stop.PlannedWindowStart != "" {
+		if start, err = time.Parse(time.RFC3339, stop.PlannedWindowStart); err != nil {
+			return fmt.Errorf("plannedWindowStart: %v", err)
+		}
+	}
+	if stop.PlannedWindowEnd != "" {
+		if end, err = time.Parse(time.RFC3339, stop.PlannedWindowEnd); err != nil {
+			return fmt.Errorf("plannedWindowEnd: %v", err)
+		}
+	}
+	if !start.IsZero() && !end.IsZero() && end.Before(start) {
+		return fmt.Errorf("plannedWindowEnd is before plannedWindowStart")
+	}
+	return nil
+}
+
+// recordStopTiming ghi lại thời điểm xác nhận thực tế của điểm dừng và độ trễ so với cuối khung giờ dự kiến.
+func (s *SmartContract) recordStopTiming(ctx contractapi.TransactionContextInterface, stop *StopInJourney) error {
+	ts, err := ctx.GetStub().GetTxTimestamp()
+	if err != nil {
+		return err
+	}
+	actual := time.Unix(ts.Seconds, int64(ts.Nanos)).UTC()
+	stop.ActualTime = actual.Format(time.RFC3339)
+	stop.Late = false
+	stop.LatenessMinutes = 0
+
+	if stop.PlannedWindowEnd == "" {
+		return nil
+	}
+	plannedEnd, err := time.Parse(time.RFC3339, stop.PlannedWindowEnd)
+	if err != nil {
+		return fmt.Errorf("invalid plannedWindowEnd for stop at %s: %v", stop.FacilityID, err)
+	}
+	if actual.After(plannedEnd) {
+		stop.Late = true
+		stop.LatenessMinutes = actual.Sub(plannedEnd).Minutes()
+	}
+	return nil
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestQueryLateShipments(t *testing.T) {
+	c := newTestChannel(t)
+	withFarmBatch(c)
+
+	// SHIP-1 giao đúng hạn, SHIP-2 giao trễ hơn 1 giờ so với cuối khung giờ dự kiến
+	onTime := testStop("PROC-1", "DELIVERY")
+	onTime.PlannedWindowEnd = c.clock.Add(24 * time.Hour).Format(time.RFC3339)
+	late := testStop("PROC-1", "DELIVERY")
+	late.PlannedWindowStart = c.clock.Format(time.RFC3339)
+	late.PlannedWindowEnd = c.clock.Add(2 * time.Hour).Format(time.RFC3339)
+
+	for i, delivery := range []StopInJourney{onTime, late} {
+		shipmentID := []string{"SHIP-1", "SHIP-2"}[i]
+		c.mustSubmit(farmAdmin, "CreateShipment", shipmentID, "REFRIGERATED", "driver-1", "Nguyen Van A", "51C-123.45",
+			[]StopInJourney{testStop("FARM-1", "PICKUP", testItem("FARM-BATCH-1", "head", 1)), delivery})
+		c.mustSubmit(driver, "AddPickupProof", shipmentID, "FARM-1", testProof("FARM-1"))
+		c.mustSubmit(farmAdmin, "ConfirmPickup", shipmentID, "FARM-1", []ItemInShipment{testItem("FARM-BATCH-1", "head", 1)})
+		c.mustSubmit(driver, "StartShipment", shipmentID, []string{"SEAL-" + shipmentID})
+		c.mustSubmit(driver, "AddDeliveryProof", shipmentID, "PROC-1", testProof("PROC-1"))
+	}
+	c.mustSubmit(processorAdmin, "ConfirmShipmentDelivery", "SHIP-1", "PROC-1", "PROC-BATCH-A", []string{"SEAL-SHIP-1"})
+	c.advance(3 * time.Hour)
+	c.mustSubmit(processorAdmin, "ConfirmShipmentDelivery", "SHIP-2", "PROC-1", "PROC-BATCH-B", []string{"SEAL-SHIP-2"})
+
+	stop := c.shipment("SHIP-2").Stops[1]
+	if !stop.Late || stop.LatenessMinutes < 60 || stop.ActualTime == "" {
+		t.Fatalf("unexpected SLA result for SHIP-2: late=%v lateness=%v actual=%s", stop.Late, stop.LatenessMinutes, stop.ActualTime)
+	}
+	if stop := c.shipment("SHIP-1").Stops[1]; stop.Late {
+		t.Fatalf("SHIP-1 delivered on time is marked late")
+	}
+
+	lateShipments := c.mustEvaluate(processorAdmin, "QueryLateShipments", "PROC-1", "", "").([]*ShipmentAsset)
+	if len(lateShipments) != 1 || lateShipments[0].ShipmentID != "SHIP-2" {
+		t.Fatalf("QueryLateShipments returned %d shipments, want SHIP-2 only", len(lateShipments))
+	}
+	future := c.clock.Add(time.Hour).Format(time.RFC3339)
+	if shipments := c.mustEvaluate(processorAdmin, "QueryLateShipments", "PROC-1", future, "").([]*ShipmentAsset); len(shipments) != 0 {
+		t.Fatalf("QueryLateShipments from %s returned %d shipments", future, len(shipments))
+	}
+	if shipments := c.mustEvaluate(regulator, "QueryLateShipments", "FARM-1", "", "").([]*ShipmentAsset); len(shipments) != 0 {
+		t.Fatalf("pickup stops at FARM-1 were not late, got %d shipments", len(shipments))
+	}
+	_, err := c.evaluate(processorAdmin, "QueryLateShipments", "PROC-1", "yesterday", "")
+	expectError(t, err, "invalid 'from' timestamp")
+	_, err = c.evaluate(processorAdmin, "QueryLateShipments", "PROC-1", "", "tomorrow")
+	expectError(t, err, "invalid 'to' timestamp")
+}
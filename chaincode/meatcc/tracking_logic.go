@@ -0,0 +1,121 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// Tên object type của composite key lưu các điểm GPS, tách biệt khỏi document shipment.
+const locationPingObjectType = "LocationPing"
+
+// Bán kính trung bình của Trái Đất (km) dùng cho công thức haversine.
+const earthRadiusKm = 6371.0
+
+// RecordLocationPings cho phép tài xế được chỉ định gửi theo lô các điểm GPS của lô hàng đang vận chuyển.
+// Mỗi điểm được lưu dưới composite key (shipmentID, thời điểm), điểm gửi trùng sẽ được bỏ qua.
+func (s *SmartContract) RecordLocationPings(ctx contractapi.TransactionContextInterface, shipmentID string, pingsJSON string) error {
+	shipment, err := s.readShipmentAsset(ctx, shipmentID)
+	if err != nil {
+		return err
+	}
+	if err := requireAssignedDriver(ctx, shipment); err != nil {
+		return err
+	}
+	if err := requireShipmentAction(shipment, "RecordLocationPings"); err != nil {
+		return err
+	}
+
+	var pings []LocationPing
+	if err := json.Unmarshal([]byte(pingsJSON), &pings); err != nil {
+		return fmt.Errorf("failed to unmarshal pingsJSON: %v", err)
+	}
+	if len(pings) == 0 {
+		return fmt.Errorf("no location pings provided")
+	}
+
+	for _, ping := range pings {
+		if ping.Latitude < -90 || ping.Latitude > 90 || ping.Longitude < -180 || ping.Longitude > 180 {
+			return fmt.Errorf("invalid coordinates (%f, %f)", ping.Latitude, ping.Longitude)
+		}
+		if ping.SpeedKmh < 0 {
+			return fmt.Errorf("invalid speed %f at %s", ping.SpeedKmh, ping.Timestamp)
+		}
+		pingTime, err := time.Parse(time.RFC3339, ping.Timestamp)
+		if err != nil {
+			return fmt.Errorf("invalid timestamp '%s': %v", ping.Timestamp, err)
+		}
+
+		// Khóa theo nano giây được đệm số 0 để thứ tự từ điển trùng với thứ tự thời gian.
+		key, err := ctx.GetStub().CreateCompositeKey(locationPingObjectType, []string{shipmentID, fmt.Sprintf("%020d", pingTime.UnixNano())})
+		if err != nil {
+			return fmt.Errorf("failed to create composite key: %v", err)
+		}
+		existing, err := ctx.GetStub().GetState(key)
+		if err != nil {
+			return fmt.Errorf("failed to read from world state: %v", err)
+		}
+		if existing != nil {
+			continue
+		}
+
+		ping.ObjectType = locationPingObjectType
+		ping.ShipmentID = shipmentID
+		pingBytes, err := json.Marshal(ping)
+		if err != nil {
+			return err
+		}
+		if err := ctx.GetStub().PutState(key, pingBytes); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// GetShipmentTrack trả về các điểm GPS của lô hàng theo thứ tự thời gian cùng tổng quãng đường đã đi.
+func (s *SmartContract) GetShipmentTrack(ctx contractapi.TransactionContextInterface, shipmentID string) (*ShipmentTrack, error) {
+	if _, err := s.readShipmentAsset(ctx, shipmentID); err != nil {
+		return nil, err
+	}
+
+	resultsIterator, err := ctx.GetStub().GetStateByPartialCompositeKey(locationPingObjectType, []string{shipmentID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read location pings: %v", err)
+	}
+	defer resultsIterator.Close()
+
+	track := ShipmentTrack{
+		ShipmentID: shipmentID,
+		Points:     []LocationPing{},
+	}
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+		var ping LocationPing
+		if err := json.Unmarshal(queryResponse.Value, &ping); err != nil {
+			return nil, err
+		}
+		if n := len(track.Points); n > 0 {
+			previous := track.Points[n-1]
+			track.TotalDistanceKm += haversineKm(previous.Latitude, previous.Longitude, ping.Latitude, ping.Longitude)
+		}
+		track.Points = append(track.Points, ping)
+	}
+
+	return &track, nil
+}
+
+// haversineKm tính khoảng cách đường tròn lớn (km) giữa hai tọa độ.
+func haversineKm(lat1, lon1, lat2, lon2 float64) float64 {
+	toRad := func(deg float64) float64 { return deg * math.Pi / 180 }
+	dLat := toRad(lat2 - lat1)
+	dLon := toRad(lon2 - lon1)
+	a := math.Sin(dLat/2)*math.Sin(dLat/2) + math.Cos(toRad(lat1))*math.Cos(toRad(lat2))*math.Sin(dLon/2)*math.Sin(dLon/2)
+	return 2 * earthRadiusKm * math.Atan2(math.Sqrt(a), math.Sqrt(1-a))
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"math"
+	"testing"
+)
+
+func TestRecordLocationPings(t *testing.T) {
+	pings := []LocationPing{
+		{Latitude: 11.0500, Longitude: 106.7900, SpeedKmh: 0, Timestamp: "2024-03-01T08:10:00Z"},
+		{Latitude: 10.9000, Longitude: 106.7600, SpeedKmh: 45, Timestamp: "2024-03-01T08:40:00Z"},
+	}
+	runTransactionCases(t, withShipmentInTransit, []transactionCase{
+		{name: "driver records pings", identity: driver, function: "RecordLocationPings", args: []interface{}{"SHIP-1", pings}},
+		{name: "no pings", identity: driver, function: "RecordLocationPings", args: []interface{}{"SHIP-1", []LocationPing{}}, wantErr: "no location pings provided"},
+		{name: "invalid coordinates", identity: driver, function: "RecordLocationPings",
+			args: []interface{}{"SHIP-1", []LocationPing{{Latitude: 91, Longitude: 0, Timestamp: "2024-03-01T08:10:00Z"}}}, wantErr: "invalid coordinates"},
+		{name: "negative speed", identity: driver, function: "RecordLocationPings",
+			args: []interface{}{"SHIP-1", []LocationPing{{SpeedKmh: -1, Timestamp: "2024-03-01T08:10:00Z"}}}, wantErr: "invalid speed"},
+		{name: "invalid timestamp", identity: driver, function: "RecordLocationPings",
+			args: []interface{}{"SHIP-1", []LocationPing{{Timestamp: "yesterday"}}}, wantErr: "invalid timestamp 'yesterday'"},
+		{name: "other driver", identity: otherDriver, function: "RecordLocationPings", args: []interface{}{"SHIP-1", pings}, wantErr: "is not the designated driver"},
+		{name: "regulator denied", identity: regulator, function: "RecordLocationPings", args: []interface{}{"SHIP-1", pings}, wantErr: "access to RecordLocationPings denied"},
+	})
+	runTransactionCases(t, withPendingShipment, []transactionCase{
+		{name: "not in transit", identity: driver, function: "RecordLocationPings", args: []interface{}{"SHIP-1", pings},
+			wantErr: "RecordLocationPings is not allowed for shipment SHIP-1 in status 'PENDING'"},
+	})
+}
+
+func TestGetShipmentTrack(t *testing.T) {
+	c := newTestChannel(t)
+	withShipmentInTransit(c)
+
+	// Gửi không theo thứ tự và gửi lặp lại một điểm đã có
+	c.mustSubmit(driver, "RecordLocationPings", "SHIP-1", []LocationPing{
+		{Latitude: 10.9000, Longitude: 106.7600, SpeedKmh: 40, Timestamp: "2024-03-01T09:00:00Z"},
+		{Latitude: 11.0500, Longitude: 106.7900, SpeedKmh: 0, Timestamp: "2024-03-01T08:00:00+00:00"},
+	})
+	c.mustSubmit(driver, "RecordLocationPings", "SHIP-1", []LocationPing{
+		{Latitude: 10.9000, Longitude: 106.7600, SpeedKmh: 40, Timestamp: "2024-03-01T16:00:00+07:00"},
+		{Latitude: 10.9000, Longitude: 106.7700, SpeedKmh: 10, Timestamp: "2024-03-01T09:30:00Z"},
+	})
+
+	track := c.mustEvaluate(regulator, "GetShipmentTrack", "SHIP-1").(*ShipmentTrack)
+	if len(track.Points) != 3 {
+		t.Fatalf("got %d points, want 3 (duplicate ping must be ignored)", len(track.Points))
+	}
+	if track.Points[0].Timestamp != "2024-03-01T08:00:00+00:00" || track.Points[2].Timestamp != "2024-03-01T09:30:00Z" {
+		t.Fatalf("points are not in chronological order: %+v", track.Points)
+	}
+	want := haversineKm(11.05, 106.79, 10.9, 106.76) + haversineKm(10.9, 106.76, 10.9, 106.77)
+	if math.Abs(track.TotalDistanceKm-want) > 1e-9 {
+		t.Fatalf("total distance %.3f km, want %.3f km", track.TotalDistanceKm, want)
+	}
+
+	_, err := c.evaluate(regulator, "GetShipmentTrack", "SHIP-9")
+	expectError(t, err, "does not exist")
+}
+
+func TestHaversineKm(t *testing.T) {
+	// Hà Nội - TP. Hồ Chí Minh khoảng 1.140 km theo đường chim bay
+	if distance := haversineKm(21.0285, 105.8542, 10.8231, 106.6297); distance < 1130 || distance > 1150 {
+		t.Fatalf("distance is %.1f km, want about 1140 km", distance)
+	}
+	if distance := haversineKm(10.77, 106.70, 10.77, 106.70); distance != 0 {
+		t.Fatalf("distance between identical points is %f", distance)
+	}
+}
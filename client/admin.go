@@ -0,0 +1,50 @@
+package client
+
+import (
+	"context"
+
+	"github.com/your-repo/meatcc/models"
+)
+
+// GetAccessPolicy trả về access policy đang có hiệu lực trên ledger.
+func (c *Client) GetAccessPolicy(ctx context.Context) ([]models.AccessRule, error) {
+	var rules []models.AccessRule
+	if err := c.evaluate(ctx, "GetAccessPolicy", &rules); err != nil {
+		return nil, err
+	}
+	return rules, nil
+}
+
+// InitAccessPolicy ghi quy tắc mặc định lên ledger cho các transaction chưa có quy tắc; gọi sau khi triển khai
+// hoặc nâng cấp chaincode.
+func (c *Client) InitAccessPolicy(ctx context.Context) error {
+	_, err := c.submit(ctx, "InitAccessPolicy")
+	return err
+}
+
+// SetAccessRule thêm hoặc ghi đè quy tắc truy cập của một transaction.
+func (c *Client) SetAccessRule(ctx context.Context, rule models.AccessRule) error {
+	_, err := c.submit(ctx, "SetAccessRule", rule)
+	return err
+}
+
+// ResetAccessRule đưa một transaction về chính sách truy cập mặc định.
+func (c *Client) ResetAccessRule(ctx context.Context, transaction string) error {
+	_, err := c.submit(ctx, "ResetAccessRule", transaction)
+	return err
+}
+
+// GetGeofenceConfig đọc cấu hình geofence của kênh.
+func (c *Client) GetGeofenceConfig(ctx context.Context) (*models.GeofenceConfig, error) {
+	var config models.GeofenceConfig
+	if err := c.evaluate(ctx, "GetGeofenceConfig", &config); err != nil {
+		return nil, err
+	}
+	return &config, nil
+}
+
+// SetGeofenceConfig cập nhật bán kính (mét) và chế độ (REJECT hoặc FLAG) kiểm tra vị trí bằng chứng.
+func (c *Client) SetGeofenceConfig(ctx context.Context, radiusMeters float64, mode string) error {
+	_, err := c.submit(ctx, "SetGeofenceConfig", radiusMeters, mode)
+	return err
+}
@@ -0,0 +1,164 @@
+package client
+
+import (
+	"context"
+
+	"github.com/your-repo/meatcc/models"
+)
+
+// --- Trang trại ---
+
+// CreateFarmingBatch tạo một lô nuôi mới tại trang trại của người gọi.
+func (c *Client) CreateFarmingBatch(ctx context.Context, assetID, productName, sku string, quantity models.Quantity, farmDetails models.FarmDetails, averageWeight models.Weight) error {
+	_, err := c.submit(ctx, "CreateFarmingBatch", assetID, productName, sku, quantity, farmDetails, averageWeight)
+	return err
+}
+
+// UpdateFarmingDetails gộp các trường trong updatedDetails vào chi tiết trang trại của lô nuôi;
+// chỉ các trường được truyền mới bị ghi đè.
+func (c *Client) UpdateFarmingDetails(ctx context.Context, assetID string, updatedDetails map[string]interface{}) error {
+	_, err := c.submit(ctx, "UpdateFarmingDetails", assetID, updatedDetails)
+	return err
+}
+
+// AddFeedToFarmingBatch ghi nhận một lần cho ăn của lô nuôi.
+func (c *Client) AddFeedToFarmingBatch(ctx context.Context, assetID string, feed models.Feed) error {
+	_, err := c.submit(ctx, "AddFeedToFarmingBatch", assetID, feed)
+	return err
+}
+
+// AddMedicationToFarmingBatch ghi nhận một lần dùng thuốc của lô nuôi.
+func (c *Client) AddMedicationToFarmingBatch(ctx context.Context, assetID string, medication models.Medication) error {
+	_, err := c.submit(ctx, "AddMedicationToFarmingBatch", assetID, medication)
+	return err
+}
+
+// AddCertificatesToFarmingBatch bổ sung chứng nhận cho lô nuôi.
+func (c *Client) AddCertificatesToFarmingBatch(ctx context.Context, assetID string, certificates []models.Certificate) error {
+	_, err := c.submit(ctx, "AddCertificatesToFarmingBatch", assetID, certificates)
+	return err
+}
+
+// UpdateAverageWeight cập nhật trọng lượng trung bình của lô nuôi.
+func (c *Client) UpdateAverageWeight(ctx context.Context, assetID string, averageWeight models.Weight) error {
+	_, err := c.submit(ctx, "UpdateAverageWeight", assetID, averageWeight)
+	return err
+}
+
+// UpdateHarvestDate ghi ngày thu hoạch thực tế của lô nuôi.
+func (c *Client) UpdateHarvestDate(ctx context.Context, assetID, harvestDate string) error {
+	_, err := c.submit(ctx, "UpdateHarvestDate", assetID, harvestDate)
+	return err
+}
+
+// UpdateExpectedHarvestDate cập nhật ngày dự kiến thu hoạch của lô nuôi.
+func (c *Client) UpdateExpectedHarvestDate(ctx context.Context, assetID, expectedHarvestDate string) error {
+	_, err := c.submit(ctx, "UpdateExpectedHarvestDate", assetID, expectedHarvestDate)
+	return err
+}
+
+// GetAssetAtFarmByID đọc một lô nuôi còn ở trang trại.
+func (c *Client) GetAssetAtFarmByID(ctx context.Context, assetID string) (*models.MeatAsset, error) {
+	return c.evaluateAsset(ctx, "GetAssetAtFarmByID", assetID)
+}
+
+// --- Chế biến, lưu kho và bán lẻ ---
+
+// ProcessAndSplitBatch chế biến lô cha tại nhà máy thành các lô con.
+func (c *Client) ProcessAndSplitBatch(ctx context.Context, parentAssetID string, children []models.ChildAssetInput, details models.ProcessingDetails) error {
+	_, err := c.submit(ctx, "ProcessAndSplitBatch", parentAssetID, children, details)
+	return err
+}
+
+// SplitBatchToUnits chia một lô tại nhà bán lẻ thành unitCount đơn vị có mã unitIDPrefix1, unitIDPrefix2...
+func (c *Client) SplitBatchToUnits(ctx context.Context, parentAssetID string, unitCount int, unitIDPrefix string) error {
+	_, err := c.submit(ctx, "SplitBatchToUnits", parentAssetID, unitCount, unitIDPrefix)
+	return err
+}
+
+// UpdateStorageInfo ghi nhận thông tin lưu kho của asset.
+func (c *Client) UpdateStorageInfo(ctx context.Context, assetID string, details models.StorageDetails) error {
+	_, err := c.submit(ctx, "UpdateStorageInfo", assetID, details)
+	return err
+}
+
+// MarkAsSold đánh dấu một đơn vị đã được bán; thời điểm bán do chaincode ghi theo thời điểm giao dịch.
+func (c *Client) MarkAsSold(ctx context.Context, assetID string, details models.SoldDetails) error {
+	_, err := c.submit(ctx, "MarkAsSold", assetID, details)
+	return err
+}
+
+// ReleaseAssetHold gỡ tạm giữ (ví dụ do niêm phong không khớp) sau khi đã kiểm tra hàng.
+func (c *Client) ReleaseAssetHold(ctx context.Context, assetID, resolution string) error {
+	_, err := c.submit(ctx, "ReleaseAssetHold", assetID, resolution)
+	return err
+}
+
+// --- Giữ chỗ ---
+
+// ReserveAsset giữ chỗ một phần số lượng của asset cho một đơn hàng (ORDER) hoặc lô hàng (SHIPMENT).
+func (c *Client) ReserveAsset(ctx context.Context, assetID, referenceType, referenceID string, quantity models.Quantity) error {
+	_, err := c.submit(ctx, "ReserveAsset", assetID, referenceType, referenceID, quantity)
+	return err
+}
+
+// UnreserveAsset giải phóng toàn bộ phần giữ chỗ của một đơn hàng (ORDER) trên asset;
+// chỗ đặt trên lô hàng chỉ được giải phóng qua CancelShipment, ReturnShipment hoặc ForceCloseShipment.
+func (c *Client) UnreserveAsset(ctx context.Context, assetID, referenceType, referenceID string) error {
+	_, err := c.submit(ctx, "UnreserveAsset", assetID, referenceType, referenceID)
+	return err
+}
+
+// --- Truy vấn asset ---
+
+// GetAsset đọc một asset.
+func (c *Client) GetAsset(ctx context.Context, assetID string) (*models.MeatAsset, error) {
+	return c.evaluateAsset(ctx, "GetAsset", assetID)
+}
+
+// GetAllowedActions liệt kê các transaction người gọi được phép thực hiện trên asset lúc này.
+func (c *Client) GetAllowedActions(ctx context.Context, assetID string) ([]models.AllowedAction, error) {
+	var actions []models.AllowedAction
+	if err := c.evaluate(ctx, "GetAllowedActions", &actions, assetID); err != nil {
+		return nil, err
+	}
+	return actions, nil
+}
+
+// QueryAssetsByFacility liệt kê các asset thuộc một cơ sở.
+func (c *Client) QueryAssetsByFacility(ctx context.Context, facilityID string) ([]*models.MeatAsset, error) {
+	return c.evaluateAssets(ctx, "QueryAssetsByFacility", facilityID)
+}
+
+// QueryAssetsByFacilityAndSKU liệt kê các asset của một sản phẩm tại một cơ sở.
+func (c *Client) QueryAssetsByFacilityAndSKU(ctx context.Context, facilityID, sku string) ([]*models.MeatAsset, error) {
+	return c.evaluateAssets(ctx, "QueryAssetsByFacilityAndSKU", facilityID, sku)
+}
+
+// QueryAssetsAtProcessorByStatus liệt kê các asset theo trạng thái tại một nhà máy chế biến.
+func (c *Client) QueryAssetsAtProcessorByStatus(ctx context.Context, facilityID, status string) ([]*models.MeatAsset, error) {
+	return c.evaluateAssets(ctx, "QueryAssetsAtProcessorByStatus", facilityID, status)
+}
+
+// QueryAssetsAtRetailerByStatus liệt kê các asset theo trạng thái tại một nhà bán lẻ.
+func (c *Client) QueryAssetsAtRetailerByStatus(ctx context.Context, facilityID, status string) ([]*models.MeatAsset, error) {
+	return c.evaluateAssets(ctx, "QueryAssetsAtRetailerByStatus", facilityID, status)
+}
+
+// evaluateAsset gọi một truy vấn trả về một asset.
+func (c *Client) evaluateAsset(ctx context.Context, transaction string, args ...interface{}) (*models.MeatAsset, error) {
+	var asset models.MeatAsset
+	if err := c.evaluate(ctx, transaction, &asset, args...); err != nil {
+		return nil, err
+	}
+	return &asset, nil
+}
+
+// evaluateAssets gọi một truy vấn trả về danh sách asset.
+func (c *Client) evaluateAssets(ctx context.Context, transaction string, args ...interface{}) ([]*models.MeatAsset, error) {
+	var assets []*models.MeatAsset
+	if err := c.evaluate(ctx, transaction, &assets, args...); err != nil {
+		return nil, err
+	}
+	return assets, nil
+}
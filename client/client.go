@@ -0,0 +1,138 @@
+// Package client là SDK Go để gọi chaincode meatcc qua Fabric Gateway.
+//
+// Mỗi transaction của SmartContract có một method cùng tên nhận tham số có kiểu (các struct của package models
+// trong chaincode) thay vì chuỗi JSON. Transaction ghi dữ liệu được gửi bằng Submit (endorse, order và chờ commit),
+// transaction chỉ đọc được gọi bằng Evaluate trên một peer. Lỗi do chaincode trả về được chuyển thành *ContractError,
+// phân loại bằng các lỗi ErrNotFound, ErrAccessDenied... để dùng với errors.Is.
+//
+//	gw, _ := gateway.Connect(id, gateway.WithSign(sign), gateway.WithClientConnection(conn))
+//	meat := client.New(gw.GetNetwork("meatchannel").GetContract("meatcc"))
+//	asset, err := meat.GetAsset(ctx, "FARM-BATCH-1")
+//	if errors.Is(err, client.ErrNotFound) { ... }
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	gateway "github.com/hyperledger/fabric-gateway/pkg/client"
+)
+
+// Contract là phần của Fabric Gateway mà Client cần để gọi chaincode. New dùng *gateway.Contract;
+// NewWithContract cho phép thay bằng cài đặt khác (ví dụ trong test).
+type Contract interface {
+	// Submit gửi một transaction ghi dữ liệu và chờ transaction được commit.
+	Submit(ctx context.Context, transaction string, args []string, transient map[string][]byte) ([]byte, error)
+	// Evaluate gọi một transaction chỉ đọc; kết quả không được gửi tới orderer.
+	Evaluate(ctx context.Context, transaction string, args []string) ([]byte, error)
+}
+
+// Client gọi các transaction của chaincode meatcc với tham số và kết quả có kiểu.
+type Client struct {
+	contract Contract
+}
+
+// New tạo Client trên một contract của Fabric Gateway.
+func New(contract *gateway.Contract) *Client {
+	return NewWithContract(gatewayContract{contract: contract})
+}
+
+// NewWithContract tạo Client trên một cài đặt Contract bất kỳ.
+func NewWithContract(contract Contract) *Client {
+	return &Client{contract: contract}
+}
+
+// gatewayContract chuyển Contract sang API của Fabric Gateway.
+type gatewayContract struct {
+	contract *gateway.Contract
+}
+
+func (g gatewayContract) Submit(ctx context.Context, transaction string, args []string, transient map[string][]byte) ([]byte, error) {
+	options := []gateway.ProposalOption{gateway.WithArguments(args...)}
+	if len(transient) > 0 {
+		options = append(options, gateway.WithTransient(transient))
+	}
+	return g.contract.SubmitWithContext(ctx, transaction, options...)
+}
+
+func (g gatewayContract) Evaluate(ctx context.Context, transaction string, args []string) ([]byte, error) {
+	return g.contract.EvaluateWithContext(ctx, transaction, gateway.WithArguments(args...))
+}
+
+// --- Các hàm hỗ trợ gọi transaction ---
+
+// submit gửi transaction với các tham số đã được chuyển sang chuỗi bằng arguments.
+func (c *Client) submit(ctx context.Context, transaction string, args ...interface{}) ([]byte, error) {
+	return c.submitWithTransient(ctx, transaction, nil, args...)
+}
+
+// submitWithTransient gửi transaction kèm dữ liệu riêng tư trong transient map.
+func (c *Client) submitWithTransient(ctx context.Context, transaction string, transient map[string][]byte, args ...interface{}) ([]byte, error) {
+	stringArgs, err := arguments(transaction, args)
+	if err != nil {
+		return nil, err
+	}
+	result, err := c.contract.Submit(ctx, transaction, stringArgs, transient)
+	if err != nil {
+		return nil, parseError(transaction, err)
+	}
+	return result, nil
+}
+
+// evaluate gọi transaction chỉ đọc và giải mã kết quả JSON vào result (bỏ qua nếu chaincode không trả về gì).
+func (c *Client) evaluate(ctx context.Context, transaction string, result interface{}, args ...interface{}) error {
+	payload, err := c.evaluatePayload(ctx, transaction, args...)
+	if err != nil {
+		return err
+	}
+	return decodeResult(transaction, payload, result)
+}
+
+// evaluatePayload gọi transaction chỉ đọc và trả về kết quả thô, dùng cho transaction trả về chuỗi hoặc bool.
+func (c *Client) evaluatePayload(ctx context.Context, transaction string, args ...interface{}) ([]byte, error) {
+	stringArgs, err := arguments(transaction, args)
+	if err != nil {
+		return nil, err
+	}
+	payload, err := c.contract.Evaluate(ctx, transaction, stringArgs)
+	if err != nil {
+		return nil, parseError(transaction, err)
+	}
+	return payload, nil
+}
+
+// decodeResult giải mã kết quả JSON của transaction vào result.
+func decodeResult(transaction string, payload []byte, result interface{}) error {
+	if len(payload) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(payload, result); err != nil {
+		return fmt.Errorf("%s: failed to unmarshal result: %v", transaction, err)
+	}
+	return nil
+}
+
+// arguments chuyển tham số sang dạng chuỗi mà contract API nhận: chuỗi giữ nguyên, số được định dạng,
+// các kiểu còn lại (struct của models, slice, map) được marshal sang JSON.
+func arguments(transaction string, args []interface{}) ([]string, error) {
+	stringArgs := make([]string, 0, len(args))
+	for i, arg := range args {
+		switch value := arg.(type) {
+		case string:
+			stringArgs = append(stringArgs, value)
+		case int:
+			stringArgs = append(stringArgs, strconv.Itoa(value))
+		case float64:
+			stringArgs = append(stringArgs, strconv.FormatFloat(value, 'f', -1, 64))
+		default:
+			argJSON, err := json.Marshal(value)
+			if err != nil {
+				return nil, fmt.Errorf("%s: failed to marshal argument %d: %v", transaction, i+1, err)
+			}
+			stringArgs = append(stringArgs, string(argJSON))
+		}
+	}
+	return stringArgs, nil
+}
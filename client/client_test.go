@@ -0,0 +1,169 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/your-repo/meatcc/models"
+)
+
+// call là một lần gọi chaincode được fakeContract ghi lại.
+type call struct {
+	submit      bool
+	transaction string
+	args        []string
+	transient   map[string][]byte
+}
+
+// fakeContract ghi lại các lần gọi và trả về result (hoặc err) cho mọi transaction.
+type fakeContract struct {
+	calls  []call
+	result []byte
+	err    error
+}
+
+func (f *fakeContract) Submit(ctx context.Context, transaction string, args []string, transient map[string][]byte) ([]byte, error) {
+	f.calls = append(f.calls, call{submit: true, transaction: transaction, args: args, transient: transient})
+	return f.result, f.err
+}
+
+func (f *fakeContract) Evaluate(ctx context.Context, transaction string, args []string) ([]byte, error) {
+	f.calls = append(f.calls, call{transaction: transaction, args: args})
+	return f.result, f.err
+}
+
+func (f *fakeContract) lastCall(t *testing.T) call {
+	t.Helper()
+	if len(f.calls) != 1 {
+		t.Fatalf("expected exactly one call, got %d", len(f.calls))
+	}
+	return f.calls[0]
+}
+
+func TestArgumentsAreMarshalled(t *testing.T) {
+	ctx := context.Background()
+	quantity := models.Quantity{Unit: "kg", Value: 120.5}
+	stops := []models.StopInJourney{{FacilityID: "FARM-1", Action: "PICKUP"}}
+	stopsJSON, _ := json.Marshal(stops)
+
+	tests := []struct {
+		name   string
+		invoke func(c *Client) error
+		submit bool
+		tx     string
+		args   []string
+	}{
+		{"strings pass through", func(c *Client) error { return c.UpdateHarvestDate(ctx, "FARM-BATCH-1", "2024-05-01") },
+			true, "UpdateHarvestDate", []string{"FARM-BATCH-1", "2024-05-01"}},
+		{"struct as JSON", func(c *Client) error { return c.ReserveAsset(ctx, "FARM-BATCH-1", "ORDER", "PO-1", quantity) },
+			true, "ReserveAsset", []string{"FARM-BATCH-1", "ORDER", "PO-1", `{"unit":"kg","value":120.5}`}},
+		{"slice as JSON", func(c *Client) error {
+			return c.CreateShipment(ctx, "SHIP-1", "INBOUND", "driver-1", "Driver", "29A-12345", stops)
+		},
+			true, "CreateShipment", []string{"SHIP-1", "INBOUND", "driver-1", "Driver", "29A-12345", string(stopsJSON)}},
+		{"int", func(c *Client) error { return c.SplitBatchToUnits(ctx, "RETAIL-BATCH-0", 4, "UNIT-") },
+			true, "SplitBatchToUnits", []string{"RETAIL-BATCH-0", "4", "UNIT-"}},
+		{"float", func(c *Client) error { return c.SetGeofenceConfig(ctx, 250.5, "REJECT") },
+			true, "SetGeofenceConfig", []string{"250.5", "REJECT"}},
+		{"empty regulatory details", func(c *Client) error { return c.RecordRegulatoryEvent(ctx, "FARM-BATCH-1", "INSPECTION", nil) },
+			true, "RecordRegulatoryEvent", []string{"FARM-BATCH-1", "INSPECTION", ""}},
+		{"query is evaluated", func(c *Client) error { _, err := c.QueryProducts(ctx, "PIG", ""); return err },
+			false, "QueryProducts", []string{"PIG", ""}},
+		{"no arguments", func(c *Client) error { _, err := c.GetAccessPolicy(ctx); return err },
+			false, "GetAccessPolicy", []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			contract := &fakeContract{}
+			if err := tt.invoke(NewWithContract(contract)); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			got := contract.lastCall(t)
+			if got.submit != tt.submit || got.transaction != tt.tx {
+				t.Fatalf("expected %s (submit=%v), got %s (submit=%v)", tt.tx, tt.submit, got.transaction, got.submit)
+			}
+			if !reflect.DeepEqual(got.args, tt.args) {
+				t.Fatalf("expected arguments %q, got %q", tt.args, got.args)
+			}
+		})
+	}
+}
+
+func TestResultsAreDecoded(t *testing.T) {
+	ctx := context.Background()
+
+	contract := &fakeContract{result: []byte(`{"assetID":"FARM-BATCH-1","status":"AT_FARM"}`)}
+	asset, err := NewWithContract(contract).GetAsset(ctx, "FARM-BATCH-1")
+	if err != nil || asset.AssetID != "FARM-BATCH-1" || asset.Status != "AT_FARM" {
+		t.Fatalf("unexpected asset %+v (%v)", asset, err)
+	}
+
+	// Chaincode trả về danh sách rỗng dưới dạng payload rỗng
+	contract = &fakeContract{}
+	assets, err := NewWithContract(contract).QueryAssetsByFacility(ctx, "FARM-1")
+	if err != nil || len(assets) != 0 {
+		t.Fatalf("expected no assets, got %v (%v)", assets, err)
+	}
+
+	// Chuỗi được trả về nguyên vẹn, không phải JSON
+	contract = &fakeContract{result: []byte("https://id.gs1.org/01/09506000134352/10/LOT-1")}
+	link, err := NewWithContract(contract).GetDigitalLink(ctx, "LOT-1")
+	if err != nil || link != "https://id.gs1.org/01/09506000134352/10/LOT-1" {
+		t.Fatalf("unexpected digital link %q (%v)", link, err)
+	}
+
+	contract = &fakeContract{result: []byte("true")}
+	valid, err := NewWithContract(contract).VerifyCommercialTerms(ctx, "PURCHASE_ORDER", "PO-1")
+	if err != nil || !valid {
+		t.Fatalf("expected valid terms, got %v (%v)", valid, err)
+	}
+
+	contract = &fakeContract{result: []byte("not json")}
+	if _, err := NewWithContract(contract).GetProduct(ctx, "PORK-LOIN"); err == nil {
+		t.Fatal("expected an error for a malformed result")
+	}
+}
+
+func TestCommercialTermsUseTransientMap(t *testing.T) {
+	contract := &fakeContract{}
+	terms := models.CommercialTerms{Currency: "VND", LinePrices: []models.LinePrice{{LineID: "L1", UnitPrice: 95000}}}
+	if err := NewWithContract(contract).SetPurchaseOrderTerms(context.Background(), "PO-1", terms); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	got := contract.lastCall(t)
+	if !got.submit || got.transaction != "SetPurchaseOrderTerms" || !reflect.DeepEqual(got.args, []string{"PO-1"}) {
+		t.Fatalf("unexpected call %+v", got)
+	}
+	var sent models.CommercialTerms
+	if err := json.Unmarshal(got.transient["commercialTerms"], &sent); err != nil {
+		t.Fatalf("commercial terms missing from transient map: %v", err)
+	}
+	if sent.Currency != "VND" || len(sent.LinePrices) != 1 || sent.LinePrices[0].UnitPrice != 95000 {
+		t.Fatalf("unexpected commercial terms %+v", sent)
+	}
+	if len(got.transient["commercialTermsSalt"]) != 32 {
+		t.Fatalf("expected a 32-byte salt in the transient map, got %d bytes", len(got.transient["commercialTermsSalt"]))
+	}
+}
+
+func TestIssueInvoiceSendsOrderTerms(t *testing.T) {
+	termsJSON := []byte(`{"docType":"CommercialTerms","referenceType":"PURCHASE_ORDER","referenceID":"PO-1","currency":"VND"}`)
+	contract := &fakeContract{result: termsJSON}
+	if err := NewWithContract(contract).IssueInvoice(context.Background(), "INV-1", "SHIP-1", "PO-1", "2024-04-01"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(contract.calls) != 2 || contract.calls[0].submit || contract.calls[0].transaction != "GetCommercialTerms" {
+		t.Fatalf("expected the order terms to be read first, got %+v", contract.calls)
+	}
+	got := contract.calls[1]
+	if !got.submit || got.transaction != "IssueInvoice" || !reflect.DeepEqual(got.args, []string{"INV-1", "SHIP-1", "PO-1", "2024-04-01"}) {
+		t.Fatalf("unexpected call %+v", got)
+	}
+	if string(got.transient["commercialTerms"]) != string(termsJSON) || len(got.transient["commercialTermsSalt"]) != 32 {
+		t.Fatalf("unexpected transient map %q", got.transient)
+	}
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/your-repo/meatcc/models"
+)
+
+var assetCommands = []command{
+	{"get", "<assetID>", "show an asset and its history", getAsset},
+	{"list", "-facility FACILITY [-sku SKU]", "list the assets of a facility", listAssets},
+	{"trace", "<assetID>", "show the asset with the merged history of all its ancestors", traceAsset},
+	{"lineage", "<assetID>", "show the ancestors, descendants and shipments of an asset", assetLineage},
+	{"actions", "<assetID>", "list the transactions the caller may run on an asset now", assetActions},
+}
+
+func getAsset(env *environment, args []string) error {
+	positional, err := parseArgs(newFlagSet("asset get"), args, 1)
+	if err != nil {
+		return err
+	}
+	asset, err := env.client.GetAsset(env.ctx, positional[0])
+	if err != nil {
+		return err
+	}
+	return env.out.print(asset, assetTable(asset), historyTable(asset.History))
+}
+
+func listAssets(env *environment, args []string) error {
+	flags := newFlagSet("asset list")
+	facilityID := flags.String("facility", "", "facility ID")
+	sku := flags.String("sku", "", "only assets of this product")
+	if _, err := parseArgs(flags, args, 0); err != nil {
+		return err
+	}
+	if err := requireFlags(flags, "facility"); err != nil {
+		return err
+	}
+	var assets []*models.MeatAsset
+	var err error
+	if *sku != "" {
+		assets, err = env.client.QueryAssetsByFacilityAndSKU(env.ctx, *facilityID, *sku)
+	} else {
+		assets, err = env.client.QueryAssetsByFacility(env.ctx, *facilityID)
+	}
+	if err != nil {
+		return err
+	}
+	return env.out.print(assets, assetTable(assets...))
+}
+
+func traceAsset(env *environment, args []string) error {
+	positional, err := parseArgs(newFlagSet("asset trace"), args, 1)
+	if err != nil {
+		return err
+	}
+	trace, err := env.client.GetAssetWithFullHistory(env.ctx, positional[0])
+	if err != nil {
+		return err
+	}
+	header := &table{headers: []string{"ASSET", "PRODUCT", "STATUS", "ORIGINAL", "CURRENT", "PARENTS"}}
+	header.add(trace.AssetID, trace.ProductName, trace.Status, formatQuantity(trace.OriginalQuantity),
+		formatQuantity(trace.CurrentQuantity), joinOrDash(trace.ParentAssetIDs))
+	return env.out.print(trace, header, historyTable(trace.FullHistory))
+}
+
+func assetLineage(env *environment, args []string) error {
+	positional, err := parseArgs(newFlagSet("asset lineage"), args, 1)
+	if err != nil {
+		return err
+	}
+	graph, err := env.client.GetLineageGraph(env.ctx, positional[0])
+	if err != nil {
+		return err
+	}
+	nodes := &table{headers: []string{"NODE", "TYPE", "PRODUCT", "STATUS", "FACILITY", "ORIGINAL", "CURRENT"}}
+	for _, node := range graph.Nodes {
+		nodes.add(node.ID, node.Type, orDash(node.ProductName), node.Status, orDash(node.FacilityID),
+			formatQuantityPtr(node.OriginalQuantity), formatQuantityPtr(node.CurrentQuantity))
+	}
+	edges := &table{headers: []string{"FROM", "TO", "TYPE", "QUANTITY"}}
+	for _, edge := range graph.Edges {
+		edges.add(edge.From, edge.To, edge.Type, formatQuantityPtr(edge.Quantity))
+	}
+	return env.out.print(graph, nodes, edges)
+}
+
+func assetActions(env *environment, args []string) error {
+	positional, err := parseArgs(newFlagSet("asset actions"), args, 1)
+	if err != nil {
+		return err
+	}
+	actions, err := env.client.GetAllowedActions(env.ctx, positional[0])
+	if err != nil {
+		return err
+	}
+	t := &table{headers: []string{"TRANSACTION", "NEXT STATUS"}}
+	for _, action := range actions {
+		t.add(action.Transaction, joinOrDash(action.NextStatuses))
+	}
+	return env.out.print(actions, t)
+}
+
+func assetTable(assets ...*models.MeatAsset) *table {
+	t := &table{headers: []string{"ASSET", "SKU", "PRODUCT", "STATUS", "OWNER", "ORIGINAL", "CURRENT", "AVAILABLE", "PARENTS"}}
+	for _, a := range assets {
+		t.add(a.AssetID, a.SKU, a.ProductName, a.Status, a.OwnerOrg, formatQuantity(a.OriginalQuantity),
+			formatQuantity(a.CurrentQuantity), formatQuantity(a.AvailableQuantity), joinOrDash(a.ParentAssetIDs))
+	}
+	return t
+}
+
+// historyTable liệt kê các sự kiện; chi tiết sự kiện được in dưới dạng JSON một dòng.
+func historyTable(events []models.Event) *table {
+	t := &table{headers: []string{"TIMESTAMP", "EVENT", "ACTOR", "TX", "DETAILS"}}
+	for _, event := range events {
+		details := "-"
+		if event.Details != nil {
+			if detailsJSON, err := json.Marshal(event.Details); err == nil {
+				details = string(detailsJSON)
+			}
+		}
+		t.add(event.Timestamp, event.Type, strings.TrimPrefix(event.ActorMSP+"/"+event.ActorID, "/"), shortTxID(event.TxID), details)
+	}
+	return t
+}
+
+// shortTxID rút gọn mã giao dịch cho vừa bảng; định dạng json giữ mã đầy đủ.
+func shortTxID(txID string) string {
+	if len(txID) > 12 {
+		return txID[:12]
+	}
+	return orDash(txID)
+}
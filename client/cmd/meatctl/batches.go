@@ -0,0 +1,142 @@
+package main
+
+import (
+	"github.com/your-repo/meatcc/models"
+)
+
+var batchCommands = []command{
+	{"create", "<assetID> -product-name NAME -sku SKU -quantity VALUE -unit UNIT [-facility FACILITY -facility-name NAME] " +
+		"[-address TEXT -lat LAT -lon LON] [-start-date DATE] [-expected-harvest-date DATE] [-weight VALUE -weight-unit UNIT]",
+		"create a farming batch at the caller's farm", createBatch},
+	{"get", "<assetID>", "show a farming batch that is still at the farm", getBatch},
+	{"add-feed", "<assetID> -name NAME -dosage-kg KG -start-date DATE [-end-date DATE] [-notes TEXT]",
+		"record a feed given to a farming batch", addBatchFeed},
+	{"add-medication", "<assetID> -name NAME -dose DOSE -date DATE [-next-due-date DATE]",
+		"record a medication given to a farming batch", addBatchMedication},
+	{"weight", "<assetID> -weight VALUE [-weight-unit UNIT]", "update the average weight of a farming batch", updateBatchWeight},
+	{"harvest", "<assetID> <date>", "record the actual harvest date", updateBatchHarvestDate},
+	{"expected-harvest", "<assetID> <date>", "update the expected harvest date", updateBatchExpectedHarvestDate},
+}
+
+func createBatch(env *environment, args []string) error {
+	flags := newFlagSet("batch create")
+	productName := flags.String("product-name", "", "product name")
+	sku := flags.String("sku", "", "product SKU")
+	quantity := flags.Float64("quantity", 0, "number of animals or amount in the batch")
+	unit := flags.String("unit", "", "unit of the quantity (head, kg...)")
+	var farm models.FarmDetails
+	flags.StringVar(&farm.FacilityID, "facility", "", "farm facility ID")
+	flags.StringVar(&farm.FacilityName, "facility-name", "", "farm name")
+	flags.StringVar(&farm.Address.FullText, "address", "", "farm address")
+	flags.Float64Var(&farm.Address.Latitude, "lat", 0, "farm latitude")
+	flags.Float64Var(&farm.Address.Longitude, "lon", 0, "farm longitude")
+	flags.StringVar(&farm.StartDate, "start-date", "", "start date of farming (YYYY-MM-DD)")
+	flags.StringVar(&farm.ExpectedHarvestDate, "expected-harvest-date", "", "expected harvest date (YYYY-MM-DD)")
+	weight := flags.Float64("weight", 0, "average weight")
+	weightUnit := flags.String("weight-unit", "kg", "unit of the average weight")
+	positional, err := parseArgs(flags, args, 1)
+	if err != nil {
+		return err
+	}
+	if err := requireFlags(flags, "product-name", "sku", "quantity", "unit"); err != nil {
+		return err
+	}
+	err = env.client.CreateFarmingBatch(env.ctx, positional[0], *productName, *sku,
+		models.Quantity{Value: *quantity, Unit: *unit}, farm, models.Weight{Value: *weight, Unit: *weightUnit})
+	if err != nil {
+		return err
+	}
+	return env.out.submitted("CreateFarmingBatch", positional[0])
+}
+
+func getBatch(env *environment, args []string) error {
+	positional, err := parseArgs(newFlagSet("batch get"), args, 1)
+	if err != nil {
+		return err
+	}
+	asset, err := env.client.GetAssetAtFarmByID(env.ctx, positional[0])
+	if err != nil {
+		return err
+	}
+	return env.out.print(asset, assetTable(asset), historyTable(asset.History))
+}
+
+func addBatchFeed(env *environment, args []string) error {
+	flags := newFlagSet("batch add-feed")
+	var feed models.Feed
+	flags.StringVar(&feed.Name, "name", "", "feed name")
+	flags.Float64Var(&feed.DosageKg, "dosage-kg", 0, "daily dosage in kg")
+	flags.StringVar(&feed.StartDate, "start-date", "", "first day of use (YYYY-MM-DD)")
+	flags.StringVar(&feed.EndDate, "end-date", "", "last day of use (YYYY-MM-DD)")
+	flags.StringVar(&feed.Notes, "notes", "", "notes")
+	positional, err := parseArgs(flags, args, 1)
+	if err != nil {
+		return err
+	}
+	if err := requireFlags(flags, "name", "dosage-kg", "start-date"); err != nil {
+		return err
+	}
+	if err := env.client.AddFeedToFarmingBatch(env.ctx, positional[0], feed); err != nil {
+		return err
+	}
+	return env.out.submitted("AddFeedToFarmingBatch", positional[0])
+}
+
+func addBatchMedication(env *environment, args []string) error {
+	flags := newFlagSet("batch add-medication")
+	var medication models.Medication
+	flags.StringVar(&medication.Name, "name", "", "medication or supplement name")
+	flags.StringVar(&medication.Dose, "dose", "", "dose (500mg, 2ml/head...)")
+	flags.StringVar(&medication.DateApplied, "date", "", "date applied (YYYY-MM-DD)")
+	flags.StringVar(&medication.NextDueDate, "next-due-date", "", "next due date (YYYY-MM-DD)")
+	positional, err := parseArgs(flags, args, 1)
+	if err != nil {
+		return err
+	}
+	if err := requireFlags(flags, "name", "dose", "date"); err != nil {
+		return err
+	}
+	if err := env.client.AddMedicationToFarmingBatch(env.ctx, positional[0], medication); err != nil {
+		return err
+	}
+	return env.out.submitted("AddMedicationToFarmingBatch", positional[0])
+}
+
+func updateBatchWeight(env *environment, args []string) error {
+	flags := newFlagSet("batch weight")
+	weight := flags.Float64("weight", 0, "average weight")
+	weightUnit := flags.String("weight-unit", "kg", "unit of the average weight")
+	positional, err := parseArgs(flags, args, 1)
+	if err != nil {
+		return err
+	}
+	if err := requireFlags(flags, "weight"); err != nil {
+		return err
+	}
+	if err := env.client.UpdateAverageWeight(env.ctx, positional[0], models.Weight{Value: *weight, Unit: *weightUnit}); err != nil {
+		return err
+	}
+	return env.out.submitted("UpdateAverageWeight", positional[0])
+}
+
+func updateBatchHarvestDate(env *environment, args []string) error {
+	positional, err := parseArgs(newFlagSet("batch harvest"), args, 2)
+	if err != nil {
+		return err
+	}
+	if err := env.client.UpdateHarvestDate(env.ctx, positional[0], positional[1]); err != nil {
+		return err
+	}
+	return env.out.submitted("UpdateHarvestDate", positional[0])
+}
+
+func updateBatchExpectedHarvestDate(env *environment, args []string) error {
+	positional, err := parseArgs(newFlagSet("batch expected-harvest"), args, 2)
+	if err != nil {
+		return err
+	}
+	if err := env.client.UpdateExpectedHarvestDate(env.ctx, positional[0], positional[1]); err != nil {
+		return err
+	}
+	return env.out.submitted("UpdateExpectedHarvestDate", positional[0])
+}
@@ -0,0 +1,182 @@
+// meatctl là công cụ dòng lệnh cho người vận hành, gọi chaincode meatcc qua Fabric Gateway bằng client SDK.
+// Danh tính được đọc từ thư mục MSP mà scripts/registerEnroll.sh tạo ra trong network/crypto-config.
+//
+//	export CHANNEL_NAME=meatchannel
+//	meatctl -user SuperAdmin product list
+//	meatctl -org regulator -user User1 recall create FARM-BATCH-1 RECALL-1 -reason "salmonella"
+//	meatctl -o json asset trace RETAIL-BATCH-0
+//	meatctl shipment confirm-pickup SHIP-1 FARM-1 FARM-BATCH-1=40kg
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/your-repo/meatcc/client"
+)
+
+// command là một lệnh con của một nhóm lệnh (ví dụ "shipment start").
+type command struct {
+	name    string
+	args    string // Cú pháp tham số, hiển thị trong hướng dẫn
+	summary string
+	run     func(env *environment, args []string) error
+}
+
+// groups là các nhóm lệnh theo đối tượng nghiệp vụ.
+var groups = map[string][]command{
+	"product":  productCommands,
+	"batch":    batchCommands,
+	"asset":    assetCommands,
+	"shipment": shipmentCommands,
+	"recall":   recallCommands,
+}
+
+// environment là ngữ cảnh chung của một lần chạy lệnh.
+type environment struct {
+	ctx    context.Context
+	client *client.Client
+	out    *output
+}
+
+// connectFunc kết nối tới Fabric Gateway theo cấu hình và trả về client cùng hàm đóng kết nối.
+type connectFunc func(cfg *config) (*client.Client, func(), error)
+
+// errUsage báo lỗi cú pháp; hướng dẫn sử dụng đã được in ra.
+var errUsage = errors.New("invalid usage")
+
+func main() {
+	if err := run(os.Args[1:], os.Stdout, os.Stderr, connect); err != nil {
+		if !errors.Is(err, errUsage) && !errors.Is(err, flag.ErrHelp) {
+			fmt.Fprintf(os.Stderr, "meatctl: %v\n", err)
+		}
+		os.Exit(1)
+	}
+}
+
+func run(args []string, stdout io.Writer, stderr io.Writer, connect connectFunc) error {
+	cfg := &config{}
+	flags := flag.NewFlagSet("meatctl", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	cfg.register(flags)
+	flags.Usage = func() { usage(stderr, flags) }
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if cfg.output != outputTable && cfg.output != outputJSON {
+		return fmt.Errorf("invalid output format '%s', expected %s or %s", cfg.output, outputTable, outputJSON)
+	}
+
+	args = flags.Args()
+	if len(args) < 2 {
+		flags.Usage()
+		return errUsage
+	}
+	cmd, ok := findCommand(args[0], args[1])
+	if !ok {
+		fmt.Fprintf(stderr, "unknown command '%s'\n\n", strings.Join(args[:2], " "))
+		flags.Usage()
+		return errUsage
+	}
+
+	meat, closeConnection, err := connect(cfg)
+	if err != nil {
+		return err
+	}
+	defer closeConnection()
+
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.timeout)
+	defer cancel()
+	env := &environment{ctx: ctx, client: meat, out: &output{w: stdout, format: cfg.output}}
+	return cmd.run(env, args[2:])
+}
+
+func findCommand(group, name string) (command, bool) {
+	for _, cmd := range groups[group] {
+		if cmd.name == name {
+			return cmd, true
+		}
+	}
+	return command{}, false
+}
+
+func usage(w io.Writer, flags *flag.FlagSet) {
+	fmt.Fprintln(w, "Usage: meatctl [flags] <group> <command> [arguments]")
+	fmt.Fprintln(w, "\nFlags:")
+	flags.PrintDefaults()
+	fmt.Fprintln(w, "\nCommands:")
+	names := make([]string, 0, len(groups))
+	for group := range groups {
+		names = append(names, group)
+	}
+	sort.Strings(names)
+	for _, group := range names {
+		for _, cmd := range groups[group] {
+			fmt.Fprintf(w, "  %s %s %s\n      %s\n", group, cmd.name, cmd.args, cmd.summary)
+		}
+	}
+}
+
+// --- Phân tích tham số của lệnh con ---
+
+// newFlagSet tạo FlagSet cho một lệnh con; lỗi cú pháp được trả về thay vì thoát chương trình.
+func newFlagSet(name string) *flag.FlagSet {
+	flags := flag.NewFlagSet(name, flag.ContinueOnError)
+	flags.SetOutput(io.Discard)
+	return flags
+}
+
+// parseArgs phân tích flag của lệnh con, cho phép flag đứng trước hoặc sau tham số vị trí,
+// và kiểm tra số tham số vị trí.
+func parseArgs(flags *flag.FlagSet, args []string, want int) ([]string, error) {
+	var positional []string
+	for {
+		if err := flags.Parse(args); err != nil {
+			return nil, fmt.Errorf("%s: %v", flags.Name(), err)
+		}
+		args = flags.Args()
+		if len(args) == 0 {
+			break
+		}
+		positional = append(positional, args[0])
+		args = args[1:]
+	}
+	if want >= 0 && len(positional) != want {
+		return nil, fmt.Errorf("%s: expected %d argument(s), got %d", flags.Name(), want, len(positional))
+	}
+	return positional, nil
+}
+
+// requireFlags kiểm tra các flag bắt buộc của lệnh con đã được truyền.
+func requireFlags(flags *flag.FlagSet, names ...string) error {
+	set := make(map[string]bool)
+	flags.Visit(func(f *flag.Flag) { set[f.Name] = true })
+	for _, name := range names {
+		if !set[name] {
+			return fmt.Errorf("%s: flag -%s is required", flags.Name(), name)
+		}
+	}
+	return nil
+}
+
+// stringList là flag có thể lặp lại (ví dụ -seal S1 -seal S2).
+type stringList []string
+
+func (l *stringList) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *stringList) Set(value string) error {
+	*l = append(*l, value)
+	return nil
+}
+
+// defaultTimeout là thời gian tối đa cho một lệnh, gồm cả chờ transaction được commit.
+const defaultTimeout = time.Minute
@@ -0,0 +1,208 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/your-repo/meatcc/client"
+	"github.com/your-repo/meatcc/models"
+)
+
+// fakeContract ghi lại các lần gọi chaincode và trả về result cho mọi transaction.
+type fakeContract struct {
+	submit      bool
+	transaction string
+	args        []string
+	result      []byte
+}
+
+func (f *fakeContract) Submit(ctx context.Context, transaction string, args []string, transient map[string][]byte) ([]byte, error) {
+	f.submit, f.transaction, f.args = true, transaction, args
+	return f.result, nil
+}
+
+func (f *fakeContract) Evaluate(ctx context.Context, transaction string, args []string) ([]byte, error) {
+	f.submit, f.transaction, f.args = false, transaction, args
+	return f.result, nil
+}
+
+// runWith chạy meatctl trên fakeContract và trả về đầu ra chuẩn.
+func runWith(t *testing.T, contract *fakeContract, args ...string) (string, error) {
+	t.Helper()
+	var stdout, stderr bytes.Buffer
+	connect := func(cfg *config) (*client.Client, func(), error) {
+		return client.NewWithContract(contract), func() {}, nil
+	}
+	err := run(args, &stdout, &stderr, connect)
+	return stdout.String(), err
+}
+
+func TestCommandsCallTransactions(t *testing.T) {
+	tests := []struct {
+		args   []string
+		submit bool
+		tx     string
+		txArgs []string
+	}{
+		{[]string{"product", "list", "-source-type", "PORK"}, false, "QueryProducts", []string{"PORK", ""}},
+		{[]string{"product", "create", "PORK-LOIN", "-name", "Pork loin", "-unit", "tray", "-source-type", "PORK", "-category", "FINISHED_GOOD", "-weight", "0.5"},
+			true, "CreateProduct", []string{"PORK-LOIN", "Pork loin", "", "tray", "PORK", "FINISHED_GOOD", `{"value":0.5,"unit":"kg"}`}},
+		{[]string{"batch", "harvest", "FARM-BATCH-1", "2024-05-01"}, true, "UpdateHarvestDate", []string{"FARM-BATCH-1", "2024-05-01"}},
+		{[]string{"asset", "trace", "RETAIL-BATCH-0"}, false, "GetAssetWithFullHistory", []string{"RETAIL-BATCH-0"}},
+		{[]string{"asset", "list", "-facility", "WH-1", "-sku", "PORK-LOIN"}, false, "QueryAssetsByFacilityAndSKU", []string{"WH-1", "PORK-LOIN"}},
+		{[]string{"shipment", "confirm-pickup", "SHIP-1", "FARM-1", "FARM-BATCH-1=40kg", "FARM-BATCH-2=12.5head"}, true, "ConfirmPickup",
+			[]string{"SHIP-1", "FARM-1", `[{"assetID":"FARM-BATCH-1","quantity":{"unit":"kg","value":40}},{"assetID":"FARM-BATCH-2","quantity":{"unit":"head","value":12.5}}]`}},
+		{[]string{"shipment", "start", "SHIP-1", "-seal", "S1", "-seal", "S2"}, true, "StartShipment", []string{"SHIP-1", `["S1","S2"]`}},
+		{[]string{"shipment", "cancel", "-reason", "truck broke down", "SHIP-1"}, true, "CancelShipment", []string{"SHIP-1", "truck broke down"}},
+		{[]string{"shipment", "list", "-driver", "driver-1"}, false, "QueryShipmentsByDriver", []string{"driver-1"}},
+		{[]string{"recall", "create", "FARM-BATCH-1", "RECALL-1", "-reason", "salmonella"}, true, "RecallAsset", []string{"FARM-BATCH-1", "RECALL-1", "salmonella"}},
+	}
+	for _, tt := range tests {
+		t.Run(strings.Join(tt.args[:2], " "), func(t *testing.T) {
+			contract := &fakeContract{}
+			if _, err := runWith(t, contract, tt.args...); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if contract.submit != tt.submit || contract.transaction != tt.tx {
+				t.Fatalf("expected %s (submit=%v), got %s (submit=%v)", tt.tx, tt.submit, contract.transaction, contract.submit)
+			}
+			if !reflect.DeepEqual(contract.args, tt.txArgs) {
+				t.Fatalf("expected arguments %q, got %q", tt.txArgs, contract.args)
+			}
+		})
+	}
+}
+
+func TestInvalidUsage(t *testing.T) {
+	tests := []struct {
+		args    []string
+		wantErr string
+	}{
+		{[]string{"shipment", "teleport", "SHIP-1"}, "invalid usage"},
+		{[]string{"asset", "get"}, "expected 1 argument(s), got 0"},
+		{[]string{"recall", "create", "FARM-BATCH-1", "RECALL-1"}, "flag -reason is required"},
+		{[]string{"shipment", "confirm-pickup", "SHIP-1", "FARM-1", "FARM-BATCH-1=kg"}, "invalid item 'FARM-BATCH-1=kg'"},
+		{[]string{"shipment", "list", "-facility", "WH-1", "-driver", "driver-1"}, "exactly one of -facility and -driver"},
+		{[]string{"-o", "yaml", "asset", "get", "FARM-BATCH-1"}, "invalid output format 'yaml'"},
+	}
+	for _, tt := range tests {
+		contract := &fakeContract{}
+		_, err := runWith(t, contract, tt.args...)
+		if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("%v: expected error containing %q, got %v", tt.args, tt.wantErr, err)
+		}
+		if contract.transaction != "" {
+			t.Errorf("%v: chaincode was called despite invalid usage", tt.args)
+		}
+	}
+}
+
+func TestOutputFormats(t *testing.T) {
+	asset := models.MeatAsset{AssetID: "FARM-BATCH-1", SKU: "PIG", ProductName: "Pig", Status: "AT_FARM", OwnerOrg: "FARM-1",
+		CurrentQuantity: models.Quantity{Unit: "head", Value: 100},
+		History:         []models.Event{{Type: "FARMING", ActorMSP: "MeatSupplyOrgMSP", ActorID: "farm-admin", TxID: "0123456789abcdef"}}}
+	assetJSON, _ := json.Marshal(asset)
+
+	out, err := runWith(t, &fakeContract{result: assetJSON}, "asset", "get", "FARM-BATCH-1")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, want := range []string{"ASSET", "FARM-BATCH-1", "AT_FARM", "100 head", "FARMING", "MeatSupplyOrgMSP/farm-admin", "0123456789ab "} {
+		if !strings.Contains(out, want) {
+			t.Errorf("table output does not contain %q:\n%s", want, out)
+		}
+	}
+
+	out, err = runWith(t, &fakeContract{result: assetJSON}, "-o", "json", "asset", "get", "FARM-BATCH-1")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var decoded models.MeatAsset
+	if err := json.Unmarshal([]byte(out), &decoded); err != nil || !reflect.DeepEqual(decoded, asset) {
+		t.Fatalf("json output does not round-trip: %v\n%s", err, out)
+	}
+
+	out, err = runWith(t, &fakeContract{}, "-o", "json", "product", "activate", "PORK-LOIN")
+	if err != nil || !strings.Contains(out, `"transaction": "ActivateProduct"`) {
+		t.Fatalf("unexpected submit output %q (%v)", out, err)
+	}
+}
+
+func TestWalletLayout(t *testing.T) {
+	cryptoPath := t.TempDir()
+	cfg := &config{cryptoPath: cryptoPath, org: "regulator", user: "User1", channel: "meatchannel"}
+	org, err := cfg.resolve()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	wantMSP := filepath.Join(cryptoPath, "peerOrganizations", "regulator.example.com", "users", "User1@regulator.example.com", "msp")
+	wantTLS := filepath.Join(cryptoPath, "peerOrganizations", "regulator.example.com", "peers", "peer0.regulator.example.com", "tls", "ca.crt")
+	if cfg.mspDir != wantMSP || cfg.tlsCert != wantTLS || org.mspID != "RegulatorOrgMSP" {
+		t.Fatalf("unexpected resolved config %+v (%s)", cfg, org.mspID)
+	}
+
+	// Thư mục MSP như fabric-ca-client enroll tạo ra: signcerts/cert.pem và keystore/<hash>_sk
+	writeEnrollment(t, cfg.mspDir)
+	id, sign, err := loadIdentity(cfg.mspDir, org.mspID)
+	if err != nil {
+		t.Fatalf("failed to load identity: %v", err)
+	}
+	if id.MspID() != "RegulatorOrgMSP" || sign == nil {
+		t.Fatalf("unexpected identity %s", id.MspID())
+	}
+	if _, err := sign(make([]byte, 32)); err != nil {
+		t.Fatalf("failed to sign with the loaded key: %v", err)
+	}
+
+	if _, _, err := loadIdentity(filepath.Join(cryptoPath, "missing"), org.mspID); err == nil {
+		t.Fatal("expected an error for a missing MSP directory")
+	}
+	if _, err := (&config{org: "retailer", channel: "meatchannel"}).resolve(); err == nil {
+		t.Fatal("expected an error for an unknown organization")
+	}
+}
+
+func writeEnrollment(t *testing.T, mspDir string) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{SerialNumber: big.NewInt(1), Subject: pkix.Name{CommonName: "user1"},
+		NotBefore: time.Now(), NotAfter: time.Now().Add(time.Hour)}
+	certificate, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	privateKey, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := map[string][]byte{
+		filepath.Join(mspDir, "signcerts", "cert.pem"):                      pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: certificate}),
+		filepath.Join(mspDir, "keystore", "5f1c0d2e9a7b3c4d_sk"):            pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: privateKey}),
+		filepath.Join(mspDir, "cacerts", "localhost-8054-ca-regulator.pem"): nil,
+	}
+	for path, content := range files {
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, content, 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/your-repo/meatcc/models"
+)
+
+// Các định dạng đầu ra của meatctl.
+const (
+	outputTable = "table"
+	outputJSON  = "json"
+)
+
+// output in kết quả của lệnh theo định dạng được chọn bằng flag -o.
+type output struct {
+	w      io.Writer
+	format string
+}
+
+// table là một bảng văn bản căn cột; các bảng của cùng một kết quả được in cách nhau một dòng trống.
+type table struct {
+	headers []string
+	rows    [][]string
+}
+
+func (t *table) add(cells ...string) {
+	t.rows = append(t.rows, cells)
+}
+
+// print in value dưới dạng JSON, hoặc các bảng do tables dựng từ value.
+func (o *output) print(value interface{}, tables ...*table) error {
+	if o.format == outputJSON {
+		encoder := json.NewEncoder(o.w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(value)
+	}
+	for i, t := range tables {
+		if i > 0 {
+			fmt.Fprintln(o.w)
+		}
+		writer := tabwriter.NewWriter(o.w, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(writer, strings.Join(t.headers, "\t"))
+		for _, row := range t.rows {
+			fmt.Fprintln(writer, strings.Join(row, "\t"))
+		}
+		if err := writer.Flush(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// submitted in kết quả của một transaction ghi dữ liệu đã được commit.
+func (o *output) submitted(transaction, id string) error {
+	result := struct {
+		Transaction string `json:"transaction"`
+		ID          string `json:"id"`
+		Status      string `json:"status"`
+	}{transaction, id, "COMMITTED"}
+	t := &table{headers: []string{"TRANSACTION", "ID", "STATUS"}}
+	t.add(result.Transaction, result.ID, result.Status)
+	return o.print(result, t)
+}
+
+// --- Định dạng giá trị trong bảng ---
+
+func formatQuantity(q models.Quantity) string {
+	return strings.TrimSpace(strconv.FormatFloat(q.Value, 'f', -1, 64) + " " + q.Unit)
+}
+
+func formatQuantityPtr(q *models.Quantity) string {
+	if q == nil {
+		return "-"
+	}
+	return formatQuantity(*q)
+}
+
+func formatWeight(w models.Weight) string {
+	return formatQuantity(models.Quantity{Value: w.Value, Unit: w.Unit})
+}
+
+func orDash(value string) string {
+	if value == "" {
+		return "-"
+	}
+	return value
+}
+
+func joinOrDash(values []string) string {
+	return orDash(strings.Join(values, ","))
+}
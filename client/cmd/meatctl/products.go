@@ -0,0 +1,107 @@
+package main
+
+import (
+	"strconv"
+
+	"github.com/your-repo/meatcc/models"
+)
+
+var productCommands = []command{
+	{"list", "[-source-type TYPE] [-category CATEGORY]", "list products in the catalogue", listProducts},
+	{"get", "<sku>", "show a product", getProduct},
+	{"create", "<sku> -name NAME -unit UNIT -source-type TYPE -category CATEGORY [-description TEXT] [-weight VALUE -weight-unit UNIT]",
+		"register a new product", createProduct},
+	{"activate", "<sku>", "allow a discontinued product to be used again", activateProduct},
+	{"deactivate", "<sku>", "discontinue a product", deactivateProduct},
+	{"set-gtin", "<sku> <gtin>", "assign a GS1 GTIN to a product", setProductGTIN},
+}
+
+func listProducts(env *environment, args []string) error {
+	flags := newFlagSet("product list")
+	sourceType := flags.String("source-type", "", "filter by source type (BEEF, PORK, CHICKEN)")
+	category := flags.String("category", "", "filter by category (RAW_MATERIAL, FINISHED_GOOD)")
+	if _, err := parseArgs(flags, args, 0); err != nil {
+		return err
+	}
+	products, err := env.client.QueryProducts(env.ctx, *sourceType, *category)
+	if err != nil {
+		return err
+	}
+	return env.out.print(products, productTable(products...))
+}
+
+func getProduct(env *environment, args []string) error {
+	positional, err := parseArgs(newFlagSet("product get"), args, 1)
+	if err != nil {
+		return err
+	}
+	product, err := env.client.GetProduct(env.ctx, positional[0])
+	if err != nil {
+		return err
+	}
+	return env.out.print(product, productTable(product))
+}
+
+func createProduct(env *environment, args []string) error {
+	flags := newFlagSet("product create")
+	name := flags.String("name", "", "product name")
+	description := flags.String("description", "", "product description")
+	unit := flags.String("unit", "", "unit of the product (box, tray, piece...)")
+	sourceType := flags.String("source-type", "", "source type (BEEF, PORK, CHICKEN)")
+	category := flags.String("category", "", "category (RAW_MATERIAL, FINISHED_GOOD)")
+	weight := flags.Float64("weight", 0, "average weight")
+	weightUnit := flags.String("weight-unit", "kg", "unit of the average weight")
+	positional, err := parseArgs(flags, args, 1)
+	if err != nil {
+		return err
+	}
+	if err := requireFlags(flags, "name", "unit", "source-type", "category"); err != nil {
+		return err
+	}
+	averageWeight := models.Weight{Value: *weight, Unit: *weightUnit}
+	if err := env.client.CreateProduct(env.ctx, positional[0], *name, *description, *unit, *sourceType, *category, averageWeight); err != nil {
+		return err
+	}
+	return env.out.submitted("CreateProduct", positional[0])
+}
+
+func activateProduct(env *environment, args []string) error {
+	positional, err := parseArgs(newFlagSet("product activate"), args, 1)
+	if err != nil {
+		return err
+	}
+	if err := env.client.ActivateProduct(env.ctx, positional[0]); err != nil {
+		return err
+	}
+	return env.out.submitted("ActivateProduct", positional[0])
+}
+
+func deactivateProduct(env *environment, args []string) error {
+	positional, err := parseArgs(newFlagSet("product deactivate"), args, 1)
+	if err != nil {
+		return err
+	}
+	if err := env.client.DeactivateProduct(env.ctx, positional[0]); err != nil {
+		return err
+	}
+	return env.out.submitted("DeactivateProduct", positional[0])
+}
+
+func setProductGTIN(env *environment, args []string) error {
+	positional, err := parseArgs(newFlagSet("product set-gtin"), args, 2)
+	if err != nil {
+		return err
+	}
+	if err := env.client.SetProductGTIN(env.ctx, positional[0], positional[1]); err != nil {
+		return err
+	}
+	return env.out.submitted("SetProductGTIN", positional[0])
+}
+
+func productTable(products ...*models.Product) *table {
+	t := &table{headers: []string{"SKU", "NAME", "SOURCE", "CATEGORY", "UNIT", "AVG WEIGHT", "GTIN", "ACTIVE"}}
+	for _, p := range products {
+		t.add(p.SKU, p.Name, p.SourceType, p.Category, p.Unit, formatWeight(p.AverageWeight), orDash(p.GTIN), strconv.FormatBool(p.Active))
+	}
+	return t
+}
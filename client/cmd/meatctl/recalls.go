@@ -0,0 +1,78 @@
+package main
+
+import (
+	"github.com/your-repo/meatcc/models"
+)
+
+var recallCommands = []command{
+	{"create", "<assetID> <recallID> -reason TEXT", "recall an asset and every asset derived from it", recallAsset},
+	{"status", "<assetID>", "show the regulatory status and regulatory events of an asset", regulatoryStatus},
+	{"hold", "<assetID> -reason TEXT", "place a regulatory hold on an asset", placeHold},
+	{"release", "<assetID> -resolution TEXT", "release a regulatory hold", releaseHold},
+}
+
+func recallAsset(env *environment, args []string) error {
+	flags := newFlagSet("recall create")
+	reason := flags.String("reason", "", "reason for the recall")
+	positional, err := parseArgs(flags, args, 2)
+	if err != nil {
+		return err
+	}
+	if err := requireFlags(flags, "reason"); err != nil {
+		return err
+	}
+	if err := env.client.RecallAsset(env.ctx, positional[0], positional[1], *reason); err != nil {
+		return err
+	}
+	return env.out.submitted("RecallAsset", positional[0])
+}
+
+func regulatoryStatus(env *environment, args []string) error {
+	positional, err := parseArgs(newFlagSet("recall status"), args, 1)
+	if err != nil {
+		return err
+	}
+	status, err := env.client.GetRegulatoryStatus(env.ctx, positional[0])
+	if err != nil {
+		return err
+	}
+	return env.out.print(status, regulatoryStatusTable(status), historyTable(status.Events))
+}
+
+func placeHold(env *environment, args []string) error {
+	flags := newFlagSet("recall hold")
+	reason := flags.String("reason", "", "reason for the hold")
+	positional, err := parseArgs(flags, args, 1)
+	if err != nil {
+		return err
+	}
+	if err := requireFlags(flags, "reason"); err != nil {
+		return err
+	}
+	if err := env.client.PlaceRegulatoryHold(env.ctx, positional[0], *reason); err != nil {
+		return err
+	}
+	return env.out.submitted("PlaceRegulatoryHold", positional[0])
+}
+
+func releaseHold(env *environment, args []string) error {
+	flags := newFlagSet("recall release")
+	resolution := flags.String("resolution", "", "outcome of the investigation")
+	positional, err := parseArgs(flags, args, 1)
+	if err != nil {
+		return err
+	}
+	if err := requireFlags(flags, "resolution"); err != nil {
+		return err
+	}
+	if err := env.client.ReleaseRegulatoryHold(env.ctx, positional[0], *resolution); err != nil {
+		return err
+	}
+	return env.out.submitted("ReleaseRegulatoryHold", positional[0])
+}
+
+func regulatoryStatusTable(status *models.RegulatoryStatus) *table {
+	t := &table{headers: []string{"ASSET", "STATUS", "RECALL", "REASON"}}
+	t.add(status.AssetID, status.Status, orDash(status.RecallID), orDash(status.Reason))
+	return t
+}
@@ -0,0 +1,257 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/your-repo/meatcc/models"
+)
+
+var shipmentCommands = []command{
+	{"create", "<shipmentID> -type TYPE -driver ENROLLMENT_ID -driver-name NAME -vehicle PLATE -stops FILE",
+		"create a shipment from a JSON array of stops (- reads stdin)", createShipment},
+	{"get", "<shipmentID>", "show a shipment with its stops and manifest", getShipment},
+	{"list", "-facility FACILITY | -driver ENROLLMENT_ID", "list the shipments of a facility or a driver", listShipments},
+	{"confirm-pickup", "<shipmentID> <facilityID> <assetID>=<quantity><unit>...",
+		"confirm the quantities loaded at a pickup stop, e.g. FARM-BATCH-1=40kg", confirmPickup},
+	{"start", "<shipmentID> -seal SEAL...", "seal the vehicle and start the shipment", startShipment},
+	{"deliver", "<shipmentID> <facilityID> -prefix ASSET_ID_PREFIX [-seal SEAL...]",
+		"confirm delivery at a stop, recording the seals found on arrival", deliverShipment},
+	{"cancel", "<shipmentID> -reason TEXT", "cancel a shipment that has not started", cancelShipment},
+	{"return", "<shipmentID> -reason TEXT", "return an in-transit shipment to its sources", returnShipment},
+	{"track", "<shipmentID>", "show the GPS track of a shipment", trackShipment},
+}
+
+func createShipment(env *environment, args []string) error {
+	flags := newFlagSet("shipment create")
+	shipmentType := flags.String("type", "", "shipment type")
+	driver := flags.String("driver", "", "enrollment ID of the driver")
+	driverName := flags.String("driver-name", "", "driver name")
+	vehicle := flags.String("vehicle", "", "vehicle plate")
+	stopsFile := flags.String("stops", "", "JSON file with the stops of the journey (- for stdin)")
+	positional, err := parseArgs(flags, args, 1)
+	if err != nil {
+		return err
+	}
+	if err := requireFlags(flags, "type", "driver", "vehicle", "stops"); err != nil {
+		return err
+	}
+	stops, err := readStops(*stopsFile)
+	if err != nil {
+		return err
+	}
+	if err := env.client.CreateShipment(env.ctx, positional[0], *shipmentType, *driver, *driverName, *vehicle, stops); err != nil {
+		return err
+	}
+	return env.out.submitted("CreateShipment", positional[0])
+}
+
+// readStops đọc lộ trình của lô hàng từ tệp JSON (mảng StopInJourney).
+func readStops(path string) ([]models.StopInJourney, error) {
+	var reader io.Reader = os.Stdin
+	if path != "-" {
+		file, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		defer file.Close()
+		reader = file
+	}
+	var stops []models.StopInJourney
+	if err := json.NewDecoder(reader).Decode(&stops); err != nil {
+		return nil, fmt.Errorf("failed to parse stops: %v", err)
+	}
+	return stops, nil
+}
+
+func getShipment(env *environment, args []string) error {
+	positional, err := parseArgs(newFlagSet("shipment get"), args, 1)
+	if err != nil {
+		return err
+	}
+	shipment, err := env.client.GetShipment(env.ctx, positional[0])
+	if err != nil {
+		return err
+	}
+	stops := &table{headers: []string{"#", "FACILITY", "ACTION", "STATUS", "ITEMS", "SEAL", "LATE"}}
+	for i, stop := range shipment.Stops {
+		late := "-"
+		if stop.Late {
+			late = strconv.FormatFloat(stop.LatenessMinutes, 'f', 0, 64) + "m"
+		}
+		stops.add(strconv.Itoa(i+1), stop.FacilityID, stop.Action, stop.Status, formatItems(stop.Items), orDash(stop.SealStatus), late)
+	}
+	manifest := &table{headers: []string{"ASSET", "SOURCE", "LOADED", "DELIVERED"}}
+	for _, item := range shipment.Manifest {
+		manifest.add(item.AssetID, item.SourceFacilityID, formatQuantity(item.LoadedQuantity), formatQuantity(item.DeliveredQuantity))
+	}
+	return env.out.print(shipment, shipmentTable(shipment), stops, manifest)
+}
+
+func listShipments(env *environment, args []string) error {
+	flags := newFlagSet("shipment list")
+	facilityID := flags.String("facility", "", "facility ID")
+	driver := flags.String("driver", "", "enrollment ID of the driver")
+	if _, err := parseArgs(flags, args, 0); err != nil {
+		return err
+	}
+	if (*facilityID == "") == (*driver == "") {
+		return fmt.Errorf("shipment list: exactly one of -facility and -driver is required")
+	}
+	var shipments []*models.ShipmentAsset
+	var err error
+	if *driver != "" {
+		shipments, err = env.client.QueryShipmentsByDriver(env.ctx, *driver)
+	} else {
+		shipments, err = env.client.QueryShipmentsByFacility(env.ctx, *facilityID)
+	}
+	if err != nil {
+		return err
+	}
+	return env.out.print(shipments, shipmentTable(shipments...))
+}
+
+func confirmPickup(env *environment, args []string) error {
+	positional, err := parseArgs(newFlagSet("shipment confirm-pickup"), args, -1)
+	if err != nil {
+		return err
+	}
+	if len(positional) < 3 {
+		return fmt.Errorf("shipment confirm-pickup: expected a shipment, a facility and at least one item")
+	}
+	items := make([]models.ItemInShipment, 0, len(positional)-2)
+	for _, arg := range positional[2:] {
+		item, err := parseItem(arg)
+		if err != nil {
+			return err
+		}
+		items = append(items, item)
+	}
+	if err := env.client.ConfirmPickup(env.ctx, positional[0], positional[1], items); err != nil {
+		return err
+	}
+	return env.out.submitted("ConfirmPickup", positional[0])
+}
+
+// parseItem đọc một dòng hàng dạng <assetID>=<số lượng><đơn vị>, ví dụ FARM-BATCH-1=40kg hoặc TRAY-1=12.5kg.
+func parseItem(arg string) (models.ItemInShipment, error) {
+	assetID, amount, ok := strings.Cut(arg, "=")
+	if !ok || assetID == "" {
+		return models.ItemInShipment{}, fmt.Errorf("invalid item '%s', expected <assetID>=<quantity><unit>", arg)
+	}
+	split := strings.IndexFunc(amount, func(r rune) bool { return (r < '0' || r > '9') && r != '.' })
+	if split <= 0 {
+		return models.ItemInShipment{}, fmt.Errorf("invalid item '%s', expected <assetID>=<quantity><unit>", arg)
+	}
+	value, err := strconv.ParseFloat(amount[:split], 64)
+	if err != nil {
+		return models.ItemInShipment{}, fmt.Errorf("invalid quantity in item '%s': %v", arg, err)
+	}
+	return models.ItemInShipment{AssetID: assetID, Quantity: models.Quantity{Value: value, Unit: amount[split:]}}, nil
+}
+
+func startShipment(env *environment, args []string) error {
+	flags := newFlagSet("shipment start")
+	var seals stringList
+	flags.Var(&seals, "seal", "seal number (repeatable)")
+	positional, err := parseArgs(flags, args, 1)
+	if err != nil {
+		return err
+	}
+	if err := requireFlags(flags, "seal"); err != nil {
+		return err
+	}
+	if err := env.client.StartShipment(env.ctx, positional[0], seals); err != nil {
+		return err
+	}
+	return env.out.submitted("StartShipment", positional[0])
+}
+
+func deliverShipment(env *environment, args []string) error {
+	flags := newFlagSet("shipment deliver")
+	prefix := flags.String("prefix", "", "prefix of the asset IDs created from the delivered goods")
+	var seals stringList
+	flags.Var(&seals, "seal", "seal number found on arrival (repeatable)")
+	positional, err := parseArgs(flags, args, 2)
+	if err != nil {
+		return err
+	}
+	if err := requireFlags(flags, "prefix"); err != nil {
+		return err
+	}
+	if err := env.client.ConfirmShipmentDelivery(env.ctx, positional[0], positional[1], *prefix, seals); err != nil {
+		return err
+	}
+	return env.out.submitted("ConfirmShipmentDelivery", positional[0])
+}
+
+func cancelShipment(env *environment, args []string) error {
+	flags := newFlagSet("shipment cancel")
+	reason := flags.String("reason", "", "reason for the cancellation")
+	positional, err := parseArgs(flags, args, 1)
+	if err != nil {
+		return err
+	}
+	if err := requireFlags(flags, "reason"); err != nil {
+		return err
+	}
+	if err := env.client.CancelShipment(env.ctx, positional[0], *reason); err != nil {
+		return err
+	}
+	return env.out.submitted("CancelShipment", positional[0])
+}
+
+func returnShipment(env *environment, args []string) error {
+	flags := newFlagSet("shipment return")
+	reason := flags.String("reason", "", "reason for the return")
+	positional, err := parseArgs(flags, args, 1)
+	if err != nil {
+		return err
+	}
+	if err := requireFlags(flags, "reason"); err != nil {
+		return err
+	}
+	if err := env.client.ReturnShipment(env.ctx, positional[0], *reason); err != nil {
+		return err
+	}
+	return env.out.submitted("ReturnShipment", positional[0])
+}
+
+func trackShipment(env *environment, args []string) error {
+	positional, err := parseArgs(newFlagSet("shipment track"), args, 1)
+	if err != nil {
+		return err
+	}
+	track, err := env.client.GetShipmentTrack(env.ctx, positional[0])
+	if err != nil {
+		return err
+	}
+	summary := &table{headers: []string{"SHIPMENT", "POINTS", "DISTANCE"}}
+	summary.add(track.ShipmentID, strconv.Itoa(len(track.Points)), strconv.FormatFloat(track.TotalDistanceKm, 'f', 2, 64)+" km")
+	points := &table{headers: []string{"TIMESTAMP", "LATITUDE", "LONGITUDE", "SPEED"}}
+	for _, point := range track.Points {
+		points.add(point.Timestamp, strconv.FormatFloat(point.Latitude, 'f', 6, 64), strconv.FormatFloat(point.Longitude, 'f', 6, 64),
+			strconv.FormatFloat(point.SpeedKmh, 'f', 1, 64)+" km/h")
+	}
+	return env.out.print(track, summary, points)
+}
+
+func shipmentTable(shipments ...*models.ShipmentAsset) *table {
+	t := &table{headers: []string{"SHIPMENT", "TYPE", "STATUS", "DRIVER", "VEHICLE", "STOPS", "SEALS"}}
+	for _, s := range shipments {
+		t.add(s.ShipmentID, s.ShipmentType, s.Status, s.DriverEnrollmentID, s.VehiclePlate, strconv.Itoa(len(s.Stops)), joinOrDash(s.SealIDs))
+	}
+	return t
+}
+
+func formatItems(items []models.ItemInShipment) string {
+	formatted := make([]string, 0, len(items))
+	for _, item := range items {
+		formatted = append(formatted, item.AssetID+"="+formatQuantity(item.Quantity))
+	}
+	return joinOrDash(formatted)
+}
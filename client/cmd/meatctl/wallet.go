@@ -0,0 +1,177 @@
+package main
+
+import (
+	"crypto/x509"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	gateway "github.com/hyperledger/fabric-gateway/pkg/client"
+	"github.com/hyperledger/fabric-gateway/pkg/identity"
+	"github.com/your-repo/meatcc/client"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+)
+
+// organization mô tả một tổ chức của mạng theo cách scripts/registerEnroll.sh và scripts/envVar.sh dựng nó.
+type organization struct {
+	domain      string
+	mspID       string
+	peerPortEnv string // Biến môi trường chứa cổng của peer0 (trong .env của mạng)
+	defaultPort string
+}
+
+var organizations = map[string]organization{
+	"meatsupply": {domain: "meatsupply.example.com", mspID: "MeatSupplyOrgMSP", peerPortEnv: "PEER0_ORG1_PORT", defaultPort: "7051"},
+	"regulator":  {domain: "regulator.example.com", mspID: "RegulatorOrgMSP", peerPortEnv: "PEER0_ORG2_PORT", defaultPort: "9051"},
+}
+
+// config là cấu hình kết nối và định dạng đầu ra, đọc từ flag chung của meatctl.
+type config struct {
+	cryptoPath string
+	org        string
+	user       string
+	mspDir     string
+	peer       string
+	tlsCert    string
+	channel    string
+	chaincode  string
+	output     string
+	timeout    time.Duration
+}
+
+func (cfg *config) register(flags *flag.FlagSet) {
+	flags.StringVar(&cfg.cryptoPath, "crypto", envOr("CRYPTO_PATH", "network/crypto-config"), "crypto-config directory produced by scripts/registerEnroll.sh")
+	flags.StringVar(&cfg.org, "org", envOr("MEATCTL_ORG", "meatsupply"), "organization of the identity (meatsupply or regulator)")
+	flags.StringVar(&cfg.user, "user", envOr("MEATCTL_USER", "SuperAdmin"), "identity name, i.e. users/<user>@<org domain>/msp in the wallet")
+	flags.StringVar(&cfg.mspDir, "msp", "", "MSP directory of the identity (overrides -crypto, -org and -user)")
+	flags.StringVar(&cfg.peer, "peer", "", "gateway peer address (default localhost:<peer0 port of the organization>)")
+	flags.StringVar(&cfg.tlsCert, "tls-cert", "", "TLS CA certificate of the gateway peer (default peer0 tls/ca.crt of the organization)")
+	flags.StringVar(&cfg.channel, "channel", os.Getenv("CHANNEL_NAME"), "channel name")
+	flags.StringVar(&cfg.chaincode, "chaincode", envOr("CC_NAME", "meatcc"), "chaincode name")
+	flags.StringVar(&cfg.output, "o", outputTable, "output format (table or json)")
+	flags.DurationVar(&cfg.timeout, "timeout", defaultTimeout, "timeout of a command, including the wait for commit")
+}
+
+func envOr(name, fallback string) string {
+	if value := os.Getenv(name); value != "" {
+		return value
+	}
+	return fallback
+}
+
+// resolve điền các đường dẫn và địa chỉ mặc định theo cấu trúc thư mục của crypto-config.
+func (cfg *config) resolve() (organization, error) {
+	org, ok := organizations[cfg.org]
+	if !ok {
+		return organization{}, fmt.Errorf("unknown organization '%s', expected meatsupply or regulator", cfg.org)
+	}
+	if cfg.channel == "" {
+		return organization{}, fmt.Errorf("channel name is required (-channel or CHANNEL_NAME)")
+	}
+	orgDir := filepath.Join(cfg.cryptoPath, "peerOrganizations", org.domain)
+	if cfg.mspDir == "" {
+		cfg.mspDir = filepath.Join(orgDir, "users", cfg.user+"@"+org.domain, "msp")
+	}
+	if cfg.tlsCert == "" {
+		cfg.tlsCert = filepath.Join(orgDir, "peers", "peer0."+org.domain, "tls", "ca.crt")
+	}
+	if cfg.peer == "" {
+		cfg.peer = "localhost:" + envOr(org.peerPortEnv, org.defaultPort)
+	}
+	return org, nil
+}
+
+// connect mở kết nối gRPC tới peer và Fabric Gateway với danh tính trong wallet.
+func connect(cfg *config) (*client.Client, func(), error) {
+	org, err := cfg.resolve()
+	if err != nil {
+		return nil, nil, err
+	}
+	id, sign, err := loadIdentity(cfg.mspDir, org.mspID)
+	if err != nil {
+		return nil, nil, err
+	}
+	connection, err := dialPeer(cfg.peer, cfg.tlsCert, "peer0."+org.domain)
+	if err != nil {
+		return nil, nil, err
+	}
+	gw, err := gateway.Connect(id, gateway.WithSign(sign), gateway.WithClientConnection(connection))
+	if err != nil {
+		connection.Close()
+		return nil, nil, fmt.Errorf("failed to connect to gateway: %v", err)
+	}
+	closeAll := func() {
+		gw.Close()
+		connection.Close()
+	}
+	return client.New(gw.GetNetwork(cfg.channel).GetContract(cfg.chaincode)), closeAll, nil
+}
+
+// loadIdentity đọc chứng chỉ (signcerts) và khóa riêng (keystore) từ thư mục MSP do fabric-ca-client enroll tạo ra.
+func loadIdentity(mspDir, mspID string) (*identity.X509Identity, identity.Sign, error) {
+	certificatePEM, err := readOnlyFile(filepath.Join(mspDir, "signcerts"))
+	if err != nil {
+		return nil, nil, err
+	}
+	certificate, err := identity.CertificateFromPEM(certificatePEM)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to parse certificate of %s: %v", mspDir, err)
+	}
+	id, err := identity.NewX509Identity(mspID, certificate)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	privateKeyPEM, err := readOnlyFile(filepath.Join(mspDir, "keystore"))
+	if err != nil {
+		return nil, nil, err
+	}
+	privateKey, err := identity.PrivateKeyFromPEM(privateKeyPEM)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to parse private key of %s: %v", mspDir, err)
+	}
+	sign, err := identity.NewPrivateKeySign(privateKey)
+	if err != nil {
+		return nil, nil, err
+	}
+	return id, sign, nil
+}
+
+// readOnlyFile đọc tệp duy nhất trong một thư mục của MSP (fabric-ca-client đặt tên khóa riêng theo hash).
+func readOnlyFile(dir string) ([]byte, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read identity: %v", err)
+	}
+	var files []string
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			files = append(files, entry.Name())
+		}
+	}
+	if len(files) != 1 {
+		return nil, fmt.Errorf("expected exactly one file in %s, found %d", dir, len(files))
+	}
+	return os.ReadFile(filepath.Join(dir, files[0]))
+}
+
+// dialPeer mở kết nối gRPC có TLS tới peer; serverName là tên host trong chứng chỉ TLS của peer.
+func dialPeer(address, tlsCertPath, serverName string) (*grpc.ClientConn, error) {
+	certificatePEM, err := os.ReadFile(tlsCertPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read TLS certificate: %v", err)
+	}
+	certificates := x509.NewCertPool()
+	if !certificates.AppendCertsFromPEM(certificatePEM) {
+		return nil, fmt.Errorf("no certificate found in %s", tlsCertPath)
+	}
+	transportCredentials := credentials.NewClientTLSFromCert(certificates, serverName)
+	connection, err := grpc.Dial(address, grpc.WithTransportCredentials(transportCredentials))
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to peer %s: %v", address, err)
+	}
+	return connection, nil
+}
@@ -0,0 +1,108 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/hyperledger/fabric-protos-go-apiv2/gateway"
+	"google.golang.org/grpc/status"
+)
+
+// Các loại lỗi của chaincode, dùng với errors.Is trên lỗi trả về từ Client.
+var (
+	ErrNotFound             = errors.New("not found")
+	ErrAlreadyExists        = errors.New("already exists")
+	ErrAccessDenied         = errors.New("access denied")
+	ErrInvalidArgument      = errors.New("invalid argument")
+	ErrInvalidState         = errors.New("invalid state")
+	ErrInsufficientQuantity = errors.New("insufficient quantity")
+	ErrRegulatoryBlock      = errors.New("blocked by regulatory status")
+)
+
+// ContractError là lỗi do chaincode trả về khi thực thi một transaction.
+type ContractError struct {
+	Transaction string // Tên transaction
+	Message     string // Thông báo lỗi của chaincode
+	Kind        error  // Loại lỗi (ErrNotFound, ErrAccessDenied...), nil nếu không phân loại được
+	Err         error  // Lỗi gốc của Fabric Gateway (*gateway.EndorseError...)
+}
+
+func (e *ContractError) Error() string {
+	return e.Transaction + ": " + e.Message
+}
+
+// Is cho phép errors.Is(err, ErrNotFound)... so khớp theo loại lỗi.
+func (e *ContractError) Is(target error) bool {
+	return e.Kind != nil && e.Kind == target
+}
+
+// Unwrap trả về lỗi gốc để errors.As lấy được lỗi của Fabric Gateway (mã giao dịch, mã gRPC).
+func (e *ContractError) Unwrap() error {
+	return e.Err
+}
+
+// errorKinds phân loại thông báo lỗi của chaincode theo các cụm từ đặc trưng, xét theo thứ tự:
+// lỗi phân quyền được nhận diện trước vì thông báo của chúng có thể chứa cụm từ của các loại khác.
+var errorKinds = []struct {
+	kind    error
+	phrases []string
+}{
+	{ErrAccessDenied, []string{"access to ", "is not authorized", "is not the owner of", "is not the designated driver",
+		"is not a party", "is not a member of collection", "is not valid for organization", "does not have a"}},
+	{ErrRegulatoryBlock, []string{"is under regulatory status"}},
+	{ErrNotFound, []string{"does not exist", "no asset is registered", "no product is registered", "no commercial terms found",
+		"has no commercial terms attached", "has no line", "has no reservation for", "no stop found", "was never loaded on shipment"}},
+	{ErrAlreadyExists, []string{"already exists", "is already assigned", "has already been invoiced"}},
+	{ErrInsufficientQuantity, []string{"insufficient quantity", "cannot book", "cannot reserve", "cannot deliver", "exceed",
+		"nothing left on the manifest"}},
+	{ErrInvalidState, []string{"is not allowed for", "cannot move", "cannot be ", "is not active", "must be completed before",
+		"has not been added by the driver", "no pending", "is not on hold", "is not at farm", "already has regulatory status"}},
+	{ErrInvalidArgument, []string{"failed to unmarshal", "is required", "must be positive", "must not be", "invalid ",
+		"does not match", "unsupported ", "must have", "must be", "expected"}},
+}
+
+// classifyMessage trả về loại lỗi của thông báo lỗi chaincode, hoặc nil.
+func classifyMessage(message string) error {
+	for _, errorKind := range errorKinds {
+		for _, phrase := range errorKind.phrases {
+			if strings.Contains(message, phrase) {
+				return errorKind.kind
+			}
+		}
+	}
+	return nil
+}
+
+// chaincodeResponse tách thông báo lỗi của chaincode khỏi thông báo của peer ("chaincode response 500, ...").
+var chaincodeResponse = regexp.MustCompile(`chaincode response \d+, (.*)`)
+
+// parseError chuyển lỗi của Fabric Gateway thành *ContractError nếu lỗi đến từ chaincode;
+// các lỗi khác (kết nối, orderer, commit) được trả về nguyên vẹn kèm tên transaction.
+func parseError(transaction string, err error) error {
+	if message, ok := chaincodeMessage(err); ok {
+		return &ContractError{Transaction: transaction, Message: message, Kind: classifyMessage(message), Err: err}
+	}
+	return fmt.Errorf("%s: %w", transaction, err)
+}
+
+// chaincodeMessage tìm thông báo lỗi của chaincode trong chi tiết của gRPC status (từng peer endorse),
+// rồi trong chính thông báo của status.
+func chaincodeMessage(err error) (string, bool) {
+	grpcStatus, ok := status.FromError(err)
+	if !ok {
+		return "", false
+	}
+	for _, detail := range grpcStatus.Details() {
+		if errorDetail, ok := detail.(*gateway.ErrorDetail); ok {
+			if match := chaincodeResponse.FindStringSubmatch(errorDetail.GetMessage()); match != nil {
+				return match[1], true
+			}
+		}
+	}
+	if match := chaincodeResponse.FindStringSubmatch(grpcStatus.Message()); match != nil {
+		return match[1], true
+	}
+	return "", false
+}
@@ -0,0 +1,72 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/hyperledger/fabric-protos-go-apiv2/gateway"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// endorseFailure dựng lỗi gRPC mà Fabric Gateway trả về khi chaincode từ chối một transaction.
+func endorseFailure(t *testing.T, message string) error {
+	t.Helper()
+	grpcStatus, err := status.New(codes.Aborted, "failed to endorse transaction, see attached details for more info").WithDetails(
+		&gateway.ErrorDetail{Address: "peer0.org1.example.com:7051", MspId: "MeatSupplyOrgMSP", Message: "chaincode response 500, " + message})
+	if err != nil {
+		t.Fatalf("failed to build status: %v", err)
+	}
+	return grpcStatus.Err()
+}
+
+func TestChaincodeErrorsAreClassified(t *testing.T) {
+	tests := []struct {
+		message string
+		kind    error
+	}{
+		{"asset FARM-BATCH-9 does not exist", ErrNotFound},
+		{"product PORK-LOIN already exists", ErrAlreadyExists},
+		{"access to CreateFarmingBatch denied: role 'worker' is not authorized", ErrAccessDenied},
+		{"caller from facility 'PROC-1' is not the owner of asset FARM-BATCH-1 (owner is 'FARM-1')", ErrAccessDenied},
+		{"asset FARM-BATCH-1 is under regulatory status 'HOLD' (suspected contamination)", ErrRegulatoryBlock},
+		{"cannot reserve 50.000000 of asset FARM-BATCH-1: only 20.000000 available", ErrInsufficientQuantity},
+		{"ProcessAndSplitBatch is not allowed for asset FARM-BATCH-1 with status 'AT_FARM'", ErrInvalidState},
+		{"failed to unmarshal quantityJSON: unexpected end of JSON input", ErrInvalidArgument},
+	}
+	for _, tt := range tests {
+		t.Run(tt.message, func(t *testing.T) {
+			contract := &fakeContract{err: endorseFailure(t, tt.message)}
+			_, err := NewWithContract(contract).GetAsset(context.Background(), "FARM-BATCH-1")
+
+			var contractErr *ContractError
+			if !errors.As(err, &contractErr) {
+				t.Fatalf("expected a *ContractError, got %T: %v", err, err)
+			}
+			if contractErr.Transaction != "GetAsset" || contractErr.Message != tt.message {
+				t.Fatalf("unexpected contract error %+v", contractErr)
+			}
+			if !errors.Is(err, tt.kind) {
+				t.Fatalf("expected %v, got kind %v", tt.kind, contractErr.Kind)
+			}
+			if _, ok := status.FromError(errors.Unwrap(err)); !ok {
+				t.Fatal("the gRPC status of the gateway error is not reachable through Unwrap")
+			}
+		})
+	}
+}
+
+func TestNonChaincodeErrorsAreWrapped(t *testing.T) {
+	unavailable := status.Error(codes.Unavailable, "connection refused")
+	contract := &fakeContract{err: unavailable}
+	err := NewWithContract(contract).AcceptInvoice(context.Background(), "INV-1")
+
+	var contractErr *ContractError
+	if errors.As(err, &contractErr) {
+		t.Fatalf("connection failure reported as a chaincode error: %v", contractErr)
+	}
+	if !errors.Is(err, unavailable) || err.Error() != "AcceptInvoice: "+unavailable.Error() {
+		t.Fatalf("unexpected error %v", err)
+	}
+}
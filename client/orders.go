@@ -0,0 +1,177 @@
+package client
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+
+	"github.com/your-repo/meatcc/models"
+)
+
+// commercialTermsTransientKey là khóa của transient map mà chaincode đọc điều khoản thương mại.
+const commercialTermsTransientKey = "commercialTerms"
+
+// commercialTermsSaltTransientKey là khóa của transient map chứa salt được ghi cùng điều khoản thương mại.
+const commercialTermsSaltTransientKey = "commercialTermsSalt"
+
+// --- Đơn đặt hàng ---
+
+// CreatePurchaseOrder tạo đơn đặt hàng từ cơ sở của người gọi tới cơ sở bán sellerFacilityID.
+func (c *Client) CreatePurchaseOrder(ctx context.Context, poID, sellerFacilityID string, lines []models.PurchaseOrderLine, requestedDeliveryDate string) error {
+	_, err := c.submit(ctx, "CreatePurchaseOrder", poID, sellerFacilityID, lines, requestedDeliveryDate)
+	return err
+}
+
+// AcceptPurchaseOrder được cơ sở bán gọi để chấp nhận đơn đặt hàng.
+func (c *Client) AcceptPurchaseOrder(ctx context.Context, poID string) error {
+	_, err := c.submit(ctx, "AcceptPurchaseOrder", poID)
+	return err
+}
+
+// RejectPurchaseOrder được cơ sở bán gọi để từ chối đơn đặt hàng.
+func (c *Client) RejectPurchaseOrder(ctx context.Context, poID, reason string) error {
+	_, err := c.submit(ctx, "RejectPurchaseOrder", poID, reason)
+	return err
+}
+
+// FulfilPurchaseOrder đóng đơn đặt hàng khi bên mua chấp nhận phần đã giao là đủ.
+func (c *Client) FulfilPurchaseOrder(ctx context.Context, poID, note string) error {
+	_, err := c.submit(ctx, "FulfilPurchaseOrder", poID, note)
+	return err
+}
+
+// GetPurchaseOrder đọc một đơn đặt hàng.
+func (c *Client) GetPurchaseOrder(ctx context.Context, poID string) (*models.PurchaseOrder, error) {
+	var po models.PurchaseOrder
+	if err := c.evaluate(ctx, "GetPurchaseOrder", &po, poID); err != nil {
+		return nil, err
+	}
+	return &po, nil
+}
+
+// QueryPurchaseOrdersByFacility liệt kê các đơn đặt hàng mà cơ sở là bên mua hoặc bên bán.
+func (c *Client) QueryPurchaseOrdersByFacility(ctx context.Context, facilityID string) ([]*models.PurchaseOrder, error) {
+	var orders []*models.PurchaseOrder
+	if err := c.evaluate(ctx, "QueryPurchaseOrdersByFacility", &orders, facilityID); err != nil {
+		return nil, err
+	}
+	return orders, nil
+}
+
+// --- Hóa đơn ---
+
+// IssueInvoice lập hóa đơn cho số lượng đã giao của lô hàng theo đơn đặt hàng. Điều khoản của đơn đặt hàng được đọc
+// từ private data rồi gửi lại qua transient map để chaincode đối chiếu với hash trên ledger thay vì tự đọc private data;
+// số tiền do chaincode tính được ghi vào private data cùng một salt ngẫu nhiên.
+func (c *Client) IssueInvoice(ctx context.Context, invoiceID, shipmentID, poID, dueDate string) error {
+	termsJSON, err := c.evaluatePayload(ctx, "GetCommercialTerms", "PURCHASE_ORDER", poID)
+	if err != nil {
+		return err
+	}
+	transient, err := saltTransient("IssueInvoice")
+	if err != nil {
+		return err
+	}
+	transient[commercialTermsTransientKey] = termsJSON
+	_, err = c.submitWithTransient(ctx, "IssueInvoice", transient, invoiceID, shipmentID, poID, dueDate)
+	return err
+}
+
+// AcceptInvoice được bên mua gọi để chấp nhận hóa đơn.
+func (c *Client) AcceptInvoice(ctx context.Context, invoiceID string) error {
+	_, err := c.submit(ctx, "AcceptInvoice", invoiceID)
+	return err
+}
+
+// DisputeInvoice được bên mua gọi để khiếu nại hóa đơn.
+func (c *Client) DisputeInvoice(ctx context.Context, invoiceID, reason string) error {
+	_, err := c.submit(ctx, "DisputeInvoice", invoiceID, reason)
+	return err
+}
+
+// MarkInvoicePaid ghi nhận hóa đơn đã được thanh toán.
+func (c *Client) MarkInvoicePaid(ctx context.Context, invoiceID, paymentReference string) error {
+	_, err := c.submit(ctx, "MarkInvoicePaid", invoiceID, paymentReference)
+	return err
+}
+
+// GetInvoice đọc một hóa đơn.
+func (c *Client) GetInvoice(ctx context.Context, invoiceID string) (*models.Invoice, error) {
+	var invoice models.Invoice
+	if err := c.evaluate(ctx, "GetInvoice", &invoice, invoiceID); err != nil {
+		return nil, err
+	}
+	return &invoice, nil
+}
+
+// QueryInvoicesByFacility liệt kê các hóa đơn mà cơ sở là bên mua hoặc bên bán.
+func (c *Client) QueryInvoicesByFacility(ctx context.Context, facilityID string) ([]*models.Invoice, error) {
+	var invoices []*models.Invoice
+	if err := c.evaluate(ctx, "QueryInvoicesByFacility", &invoices, facilityID); err != nil {
+		return nil, err
+	}
+	return invoices, nil
+}
+
+// --- Điều khoản thương mại (private data) ---
+
+// SetPurchaseOrderTerms được bên mua gọi để gắn đơn giá khi đơn đặt hàng còn ở trạng thái CREATED; điều khoản bị
+// khóa khi bên bán chấp nhận đơn. Điều khoản được gửi qua transient map nên không xuất hiện trong proposal công khai.
+func (c *Client) SetPurchaseOrderTerms(ctx context.Context, poID string, terms models.CommercialTerms) error {
+	return c.submitCommercialTerms(ctx, "SetPurchaseOrderTerms", poID, terms)
+}
+
+// SetAssetSupplyContract gắn hợp đồng cung ứng cho asset của cơ sở người gọi.
+func (c *Client) SetAssetSupplyContract(ctx context.Context, assetID string, terms models.CommercialTerms) error {
+	return c.submitCommercialTerms(ctx, "SetAssetSupplyContract", assetID, terms)
+}
+
+// SetShipmentTerms gắn điều khoản vận chuyển (cước phí, bên mua) cho lô hàng.
+func (c *Client) SetShipmentTerms(ctx context.Context, shipmentID string, terms models.CommercialTerms) error {
+	return c.submitCommercialTerms(ctx, "SetShipmentTerms", shipmentID, terms)
+}
+
+// GetCommercialTerms đọc điều khoản thương mại của một tài liệu (PURCHASE_ORDER, ASSET, SHIPMENT, INVOICE);
+// chỉ thành viên của private data collection đọc được.
+func (c *Client) GetCommercialTerms(ctx context.Context, referenceType, referenceID string) (*models.CommercialTerms, error) {
+	var terms models.CommercialTerms
+	if err := c.evaluate(ctx, "GetCommercialTerms", &terms, referenceType, referenceID); err != nil {
+		return nil, err
+	}
+	return &terms, nil
+}
+
+// VerifyCommercialTerms kiểm tra điều khoản thương mại trên ledger còn khớp với hash trên tài liệu công khai.
+func (c *Client) VerifyCommercialTerms(ctx context.Context, referenceType, referenceID string) (bool, error) {
+	var valid bool
+	if err := c.evaluate(ctx, "VerifyCommercialTerms", &valid, referenceType, referenceID); err != nil {
+		return false, err
+	}
+	return valid, nil
+}
+
+// submitCommercialTerms gửi transaction với điều khoản thương mại trong transient map.
+func (c *Client) submitCommercialTerms(ctx context.Context, transaction, referenceID string, terms models.CommercialTerms) error {
+	termsJSON, err := json.Marshal(terms)
+	if err != nil {
+		return fmt.Errorf("%s: failed to marshal commercial terms: %v", transaction, err)
+	}
+	transient, err := saltTransient(transaction)
+	if err != nil {
+		return err
+	}
+	transient[commercialTermsTransientKey] = termsJSON
+	_, err = c.submitWithTransient(ctx, transaction, transient, referenceID)
+	return err
+}
+
+// saltTransient tạo transient map chứa một salt ngẫu nhiên 32 byte để hash công khai của điều khoản
+// thương mại không thể bị dò ngược.
+func saltTransient(transaction string) (map[string][]byte, error) {
+	salt := make([]byte, 32)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, fmt.Errorf("%s: failed to generate salt: %v", transaction, err)
+	}
+	return map[string][]byte{commercialTermsSaltTransientKey: salt}, nil
+}
@@ -0,0 +1,55 @@
+package client
+
+import (
+	"context"
+
+	"github.com/your-repo/meatcc/models"
+)
+
+// CreateProduct đăng ký một sản phẩm mới vào danh mục.
+func (c *Client) CreateProduct(ctx context.Context, sku, name, description, unit, sourceType, category string, averageWeight models.Weight) error {
+	_, err := c.submit(ctx, "CreateProduct", sku, name, description, unit, sourceType, category, averageWeight)
+	return err
+}
+
+// UpdateProduct cập nhật tên, mô tả và đơn vị của sản phẩm.
+func (c *Client) UpdateProduct(ctx context.Context, sku, name, description, unit string) error {
+	_, err := c.submit(ctx, "UpdateProduct", sku, name, description, unit)
+	return err
+}
+
+// ActivateProduct cho phép dùng lại một sản phẩm đã ngừng kinh doanh.
+func (c *Client) ActivateProduct(ctx context.Context, sku string) error {
+	_, err := c.submit(ctx, "ActivateProduct", sku)
+	return err
+}
+
+// DeactivateProduct ngừng kinh doanh một sản phẩm.
+func (c *Client) DeactivateProduct(ctx context.Context, sku string) error {
+	_, err := c.submit(ctx, "DeactivateProduct", sku)
+	return err
+}
+
+// SetProductGTIN gán mã GTIN (GS1) cho sản phẩm.
+func (c *Client) SetProductGTIN(ctx context.Context, sku, gtin string) error {
+	_, err := c.submit(ctx, "SetProductGTIN", sku, gtin)
+	return err
+}
+
+// GetProduct đọc một sản phẩm theo SKU.
+func (c *Client) GetProduct(ctx context.Context, sku string) (*models.Product, error) {
+	var product models.Product
+	if err := c.evaluate(ctx, "GetProduct", &product, sku); err != nil {
+		return nil, err
+	}
+	return &product, nil
+}
+
+// QueryProducts liệt kê sản phẩm theo nguồn gốc và phân loại (chuỗi rỗng = không lọc).
+func (c *Client) QueryProducts(ctx context.Context, sourceType, category string) ([]*models.Product, error) {
+	var products []*models.Product
+	if err := c.evaluate(ctx, "QueryProducts", &products, sourceType, category); err != nil {
+		return nil, err
+	}
+	return products, nil
+}
@@ -0,0 +1,45 @@
+package client
+
+import (
+	"context"
+
+	"github.com/your-repo/meatcc/models"
+)
+
+// RecordRegulatoryEvent ghi một sự kiện quản lý (INSPECTION, SAMPLING, LAB_RESULT hoặc NOTICE) cho asset;
+// details có thể nil.
+func (c *Client) RecordRegulatoryEvent(ctx context.Context, assetID, eventType string, details map[string]interface{}) error {
+	var detailsArg interface{} = ""
+	if details != nil {
+		detailsArg = details
+	}
+	_, err := c.submit(ctx, "RecordRegulatoryEvent", assetID, eventType, detailsArg)
+	return err
+}
+
+// PlaceRegulatoryHold tạm giữ một asset theo quyết định của cơ quan quản lý.
+func (c *Client) PlaceRegulatoryHold(ctx context.Context, assetID, reason string) error {
+	_, err := c.submit(ctx, "PlaceRegulatoryHold", assetID, reason)
+	return err
+}
+
+// ReleaseRegulatoryHold gỡ tạm giữ của cơ quan quản lý.
+func (c *Client) ReleaseRegulatoryHold(ctx context.Context, assetID, resolution string) error {
+	_, err := c.submit(ctx, "ReleaseRegulatoryHold", assetID, resolution)
+	return err
+}
+
+// RecallAsset thu hồi một asset cùng toàn bộ các asset con cháu của nó.
+func (c *Client) RecallAsset(ctx context.Context, assetID, recallID, reason string) error {
+	_, err := c.submit(ctx, "RecallAsset", assetID, recallID, reason)
+	return err
+}
+
+// GetRegulatoryStatus đọc trạng thái quản lý và lịch sử sự kiện quản lý của asset.
+func (c *Client) GetRegulatoryStatus(ctx context.Context, assetID string) (*models.RegulatoryStatus, error) {
+	var regulatoryStatus models.RegulatoryStatus
+	if err := c.evaluate(ctx, "GetRegulatoryStatus", &regulatoryStatus, assetID); err != nil {
+		return nil, err
+	}
+	return &regulatoryStatus, nil
+}
@@ -0,0 +1,122 @@
+package client
+
+import (
+	"context"
+
+	"github.com/your-repo/meatcc/models"
+)
+
+// CreateShipment tạo lô vận chuyển và đặt chỗ hàng khai báo tại các điểm lấy hàng.
+func (c *Client) CreateShipment(ctx context.Context, shipmentID, shipmentType, driverEnrollmentID, driverName, vehiclePlate string, stops []models.StopInJourney) error {
+	_, err := c.submit(ctx, "CreateShipment", shipmentID, shipmentType, driverEnrollmentID, driverName, vehiclePlate, stops)
+	return err
+}
+
+// AddPickupProof ghi bằng chứng lấy hàng của tài xế (ảnh, tọa độ latitude/longitude...) tại một cơ sở.
+func (c *Client) AddPickupProof(ctx context.Context, shipmentID, facilityID string, proof map[string]interface{}) error {
+	_, err := c.submit(ctx, "AddPickupProof", shipmentID, facilityID, proof)
+	return err
+}
+
+// ConfirmPickup xác nhận số lượng thực tế được bốc lên xe tại một điểm lấy hàng.
+func (c *Client) ConfirmPickup(ctx context.Context, shipmentID, facilityID string, actualItems []models.ItemInShipment) error {
+	_, err := c.submit(ctx, "ConfirmPickup", shipmentID, facilityID, actualItems)
+	return err
+}
+
+// StartShipment niêm phong xe và bắt đầu vận chuyển.
+func (c *Client) StartShipment(ctx context.Context, shipmentID string, sealIDs []string) error {
+	_, err := c.submit(ctx, "StartShipment", shipmentID, sealIDs)
+	return err
+}
+
+// RecordLocationPings ghi các điểm GPS của xe trong quá trình vận chuyển.
+func (c *Client) RecordLocationPings(ctx context.Context, shipmentID string, pings []models.LocationPing) error {
+	_, err := c.submit(ctx, "RecordLocationPings", shipmentID, pings)
+	return err
+}
+
+// ResealShipment ghi niêm phong mới do cơ sở vừa nhận hàng gắn sau khi mở container tại điểm giao của cơ sở đó.
+func (c *Client) ResealShipment(ctx context.Context, shipmentID, facilityID string, sealIDs []string) error {
+	_, err := c.submit(ctx, "ResealShipment", shipmentID, facilityID, sealIDs)
+	return err
+}
+
+// AddDeliveryProof ghi bằng chứng giao hàng của tài xế tại một cơ sở.
+func (c *Client) AddDeliveryProof(ctx context.Context, shipmentID, facilityID string, proof map[string]interface{}) error {
+	_, err := c.submit(ctx, "AddDeliveryProof", shipmentID, facilityID, proof)
+	return err
+}
+
+// ConfirmShipmentDelivery xác nhận nhận hàng tại một điểm giao, đối chiếu niêm phong khi hàng đến;
+// hàng nhận được trở thành các asset newAssetIDPrefix-0, newAssetIDPrefix-1...
+func (c *Client) ConfirmShipmentDelivery(ctx context.Context, shipmentID, facilityID, newAssetIDPrefix string, arrivalSealIDs []string) error {
+	_, err := c.submit(ctx, "ConfirmShipmentDelivery", shipmentID, facilityID, newAssetIDPrefix, arrivalSealIDs)
+	return err
+}
+
+// AllowOutOfOrderStop cho phép hoàn tất một điểm dừng (PICKUP hoặc DELIVERY) không theo thứ tự lộ trình.
+func (c *Client) AllowOutOfOrderStop(ctx context.Context, shipmentID, facilityID, action, reason string) error {
+	_, err := c.submit(ctx, "AllowOutOfOrderStop", shipmentID, facilityID, action, reason)
+	return err
+}
+
+// CancelShipment hủy một lô hàng chưa khởi hành; hàng đã bốc được hoàn lại cho asset nguồn.
+func (c *Client) CancelShipment(ctx context.Context, shipmentID, reason string) error {
+	_, err := c.submit(ctx, "CancelShipment", shipmentID, reason)
+	return err
+}
+
+// ReturnShipment trả về một lô hàng đang vận chuyển; phần chưa giao được hoàn lại cho asset nguồn.
+func (c *Client) ReturnShipment(ctx context.Context, shipmentID, reason string) error {
+	_, err := c.submit(ctx, "ReturnShipment", shipmentID, reason)
+	return err
+}
+
+// ForceCloseShipment đóng cưỡng bức lô hàng sang COMPLETED, CANCELLED hoặc RETURNED mà không thay đổi số lượng asset.
+func (c *Client) ForceCloseShipment(ctx context.Context, shipmentID, targetStatus, reason string) error {
+	_, err := c.submit(ctx, "ForceCloseShipment", shipmentID, targetStatus, reason)
+	return err
+}
+
+// GetShipment đọc một lô hàng.
+func (c *Client) GetShipment(ctx context.Context, shipmentID string) (*models.ShipmentAsset, error) {
+	var shipment models.ShipmentAsset
+	if err := c.evaluate(ctx, "GetShipment", &shipment, shipmentID); err != nil {
+		return nil, err
+	}
+	return &shipment, nil
+}
+
+// GetShipmentTrack dựng lại lộ trình thực tế của lô hàng từ các điểm GPS.
+func (c *Client) GetShipmentTrack(ctx context.Context, shipmentID string) (*models.ShipmentTrack, error) {
+	var track models.ShipmentTrack
+	if err := c.evaluate(ctx, "GetShipmentTrack", &track, shipmentID); err != nil {
+		return nil, err
+	}
+	return &track, nil
+}
+
+// QueryShipmentsByDriver liệt kê các lô hàng được giao cho một tài xế.
+func (c *Client) QueryShipmentsByDriver(ctx context.Context, driverEnrollmentID string) ([]*models.ShipmentAsset, error) {
+	return c.evaluateShipments(ctx, "QueryShipmentsByDriver", driverEnrollmentID)
+}
+
+// QueryShipmentsByFacility liệt kê các lô hàng có điểm dừng tại một cơ sở.
+func (c *Client) QueryShipmentsByFacility(ctx context.Context, facilityID string) ([]*models.ShipmentAsset, error) {
+	return c.evaluateShipments(ctx, "QueryShipmentsByFacility", facilityID)
+}
+
+// QueryLateShipments liệt kê các lô hàng giao trễ tại một cơ sở trong khoảng thời gian [from, to] (RFC3339).
+func (c *Client) QueryLateShipments(ctx context.Context, facilityID, from, to string) ([]*models.ShipmentAsset, error) {
+	return c.evaluateShipments(ctx, "QueryLateShipments", facilityID, from, to)
+}
+
+// evaluateShipments gọi một truy vấn trả về danh sách lô hàng.
+func (c *Client) evaluateShipments(ctx context.Context, transaction string, args ...interface{}) ([]*models.ShipmentAsset, error) {
+	var shipments []*models.ShipmentAsset
+	if err := c.evaluate(ctx, transaction, &shipments, args...); err != nil {
+		return nil, err
+	}
+	return shipments, nil
+}
@@ -0,0 +1,70 @@
+package client
+
+import (
+	"context"
+
+	"github.com/your-repo/meatcc/models"
+)
+
+// GetAssetWithFullHistory đọc asset cùng lịch sử và chuỗi asset cha của nó.
+func (c *Client) GetAssetWithFullHistory(ctx context.Context, assetID string) (*models.FullAssetTrace, error) {
+	var trace models.FullAssetTrace
+	if err := c.evaluate(ctx, "GetAssetWithFullHistory", &trace, assetID); err != nil {
+		return nil, err
+	}
+	return &trace, nil
+}
+
+// GetConsumerTrace trả về bản tóm tắt nguồn gốc dành cho người tiêu dùng của một đơn vị bán lẻ.
+func (c *Client) GetConsumerTrace(ctx context.Context, unitAssetID string) (*models.ConsumerTrace, error) {
+	var trace models.ConsumerTrace
+	if err := c.evaluate(ctx, "GetConsumerTrace", &trace, unitAssetID); err != nil {
+		return nil, err
+	}
+	return &trace, nil
+}
+
+// GetLineageGraph trả về đồ thị nguồn gốc (asset tổ tiên, con cháu và các lô vận chuyển) của một asset.
+func (c *Client) GetLineageGraph(ctx context.Context, assetID string) (*models.LineageGraph, error) {
+	var graph models.LineageGraph
+	if err := c.evaluate(ctx, "GetLineageGraph", &graph, assetID); err != nil {
+		return nil, err
+	}
+	return &graph, nil
+}
+
+// ExportEPCIS xuất nguồn gốc của asset thành tài liệu EPCIS 2.0.
+func (c *Client) ExportEPCIS(ctx context.Context, assetID string) (*models.EPCISDocument, error) {
+	var document models.EPCISDocument
+	if err := c.evaluate(ctx, "ExportEPCIS", &document, assetID); err != nil {
+		return nil, err
+	}
+	return &document, nil
+}
+
+// ImportEPCIS ghi các sự kiện của một tài liệu EPCIS 2.0 lên ledger và trả về các sự kiện đã áp dụng hoặc bỏ qua.
+func (c *Client) ImportEPCIS(ctx context.Context, document models.EPCISDocument) (*models.EPCISImportResult, error) {
+	payload, err := c.submit(ctx, "ImportEPCIS", document)
+	if err != nil {
+		return nil, err
+	}
+	var result models.EPCISImportResult
+	if err := decodeResult("ImportEPCIS", payload, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// GetDigitalLink trả về URI GS1 Digital Link của asset.
+func (c *Client) GetDigitalLink(ctx context.Context, assetID string) (string, error) {
+	payload, err := c.evaluatePayload(ctx, "GetDigitalLink", assetID)
+	if err != nil {
+		return "", err
+	}
+	return string(payload), nil
+}
+
+// ResolveDigitalLink tìm asset tương ứng với một URI GS1 Digital Link.
+func (c *Client) ResolveDigitalLink(ctx context.Context, digitalLink string) (*models.MeatAsset, error) {
+	return c.evaluateAsset(ctx, "ResolveDigitalLink", digitalLink)
+}
@@ -14,6 +14,8 @@ PEER0_ORG2_CA=${CRYPTO_PATH}/peerOrganizations/regulator.example.com/peers/peer0
 # =================================================================
 
 CC_PACKAGE_FILE="${CC_NAME}.tar.gz"
+# Cấu hình private data collection (giá, điều khoản thương mại)
+CC_COLLECTIONS_CONFIG=${CC_COLLECTIONS_CONFIG:-${CC_SRC_PATH}/collections_config.json}
 
 # 1. Đóng gói chaincode
 echo "--- Đóng gói chaincode ${CC_NAME} ---"
@@ -38,12 +40,14 @@ echo "Package ID là: ${PACKAGE_ID}"
 echo "--- Phê duyệt chaincode cho Org1 ---"
 peer lifecycle chaincode approveformyorg -o localhost:${ORDERER1_PORT} --ordererTLSHostnameOverride orderer1.meatsupply.example.com \
 --channelID ${CHANNEL_NAME} --name ${CC_NAME} --version ${CC_VERSION} --package-id "${PACKAGE_ID}" --sequence ${CC_SEQUENCE} --tls \
+--collections-config "$CC_COLLECTIONS_CONFIG" \
 --cafile "$ORDERER_CA"
 
 echo "--- Phê duyệt chaincode cho Org2 ---"
 export_org_vars 2
 peer lifecycle chaincode approveformyorg -o localhost:${ORDERER1_PORT} --ordererTLSHostnameOverride orderer1.meatsupply.example.com \
 --channelID ${CHANNEL_NAME} --name ${CC_NAME} --version ${CC_VERSION} --package-id "${PACKAGE_ID}" --sequence ${CC_SEQUENCE} --tls \
+--collections-config "$CC_COLLECTIONS_CONFIG" \
 --cafile "$ORDERER_CA"
 
 # 4. Commit chaincode
@@ -51,8 +55,22 @@ echo "--- Commit chaincode definition ---"
 export_org_vars 1
 peer lifecycle chaincode commit -o localhost:${ORDERER1_PORT} --ordererTLSHostnameOverride orderer1.meatsupply.example.com \
 --channelID ${CHANNEL_NAME} --name ${CC_NAME} --version ${CC_VERSION} --sequence ${CC_SEQUENCE} --tls \
+--collections-config "$CC_COLLECTIONS_CONFIG" \
 --cafile "$ORDERER_CA" \
 --peerAddresses localhost:${PEER0_ORG1_PORT} --tlsRootCertFiles "$PEER0_ORG1_CA" \
 --peerAddresses localhost:${PEER0_ORG2_PORT} --tlsRootCertFiles "$PEER0_ORG2_CA"
 
-echo "--- Chaincode đã được commit thành công! ---"
\ No newline at end of file
+echo "--- Chaincode đã được commit thành công! ---"
+# 5. Ghi access policy mặc định lên ledger (chạy lại sau mỗi lần nâng cấp để bổ sung quy tắc của transaction mới;
+# các quy tắc đã có được giữ nguyên). Cần danh tính có thuộc tính role=superadmin.
+echo "--- Khởi tạo access policy trên ledger ---"
+export_org_vars 1
+export CORE_PEER_MSPCONFIGPATH=${CRYPTO_PATH}/peerOrganizations/meatsupply.example.com/users/ApiServer@meatsupply.example.com/msp
+sleep 3
+peer chaincode invoke -o localhost:${ORDERER1_PORT} --ordererTLSHostnameOverride orderer1.meatsupply.example.com \
+--channelID ${CHANNEL_NAME} --name ${CC_NAME} --tls --cafile "$ORDERER_CA" \
+--peerAddresses localhost:${PEER0_ORG1_PORT} --tlsRootCertFiles "$PEER0_ORG1_CA" \
+--peerAddresses localhost:${PEER0_ORG2_PORT} --tlsRootCertFiles "$PEER0_ORG2_CA" \
+-c '{"function":"InitAccessPolicy","Args":[]}' --waitForEvent
+
+echo "--- Access policy đã được khởi tạo! ---"
@@ -0,0 +1,3 @@
+module github.com/your-repo/meatcc/tools/lineage2dot
+
+go 1.19
@@ -0,0 +1,167 @@
+// lineage2dot chuyển đồ thị nguồn gốc do GetLineageGraph trả về (JSON) sang định dạng Graphviz DOT.
+// Công cụ chạy offline và chỉ dùng thư viện chuẩn.
+//
+//	peer chaincode query -C $CHANNEL_NAME -n $CC_NAME -c '{"Args":["GetLineageGraph","ASSET-1"]}' > lineage.json
+//	lineage2dot -in lineage.json | dot -Tsvg > lineage.svg
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Các cấu trúc dưới đây khớp với LineageGraph, LineageNode, LineageEdge của chaincode.
+type quantity struct {
+	Unit  string  `json:"unit"`
+	Value float64 `json:"value"`
+}
+
+type lineageNode struct {
+	ID               string    `json:"id"`
+	Type             string    `json:"type"`
+	ProductName      string    `json:"productName"`
+	SKU              string    `json:"sku"`
+	Status           string    `json:"status"`
+	FacilityID       string    `json:"facilityID"`
+	OriginalQuantity *quantity `json:"originalQuantity"`
+	CurrentQuantity  *quantity `json:"currentQuantity"`
+}
+
+type lineageEdge struct {
+	From     string    `json:"from"`
+	To       string    `json:"to"`
+	Type     string    `json:"type"`
+	Quantity *quantity `json:"quantity"`
+}
+
+type lineageGraph struct {
+	RootAssetID string        `json:"rootAssetID"`
+	Nodes       []lineageNode `json:"nodes"`
+	Edges       []lineageEdge `json:"edges"`
+}
+
+// Màu nền của nút asset theo trạng thái; trạng thái khác dùng màu trắng.
+var statusColors = map[string]string{
+	"ON_HOLD":                    "orange",
+	"SOLD":                       "lightgrey",
+	"SHIPPED_FULL":               "lightgrey",
+	"PROCESSED_AND_SPLIT":        "lightgrey",
+	"SPLIT_INTO_UNITS_COMPLETED": "lightgrey",
+}
+
+func main() {
+	in := flag.String("in", "-", "lineage graph JSON file (- for stdin)")
+	out := flag.String("out", "-", "DOT output file (- for stdout)")
+	rankdir := flag.String("rankdir", "LR", "Graphviz rank direction (LR or TB)")
+	flag.Parse()
+
+	if err := run(*in, *out, *rankdir); err != nil {
+		fmt.Fprintf(os.Stderr, "lineage2dot: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func run(in string, out string, rankdir string) error {
+	var reader io.Reader = os.Stdin
+	if in != "-" {
+		file, err := os.Open(in)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		reader = file
+	}
+	var graph lineageGraph
+	if err := json.NewDecoder(reader).Decode(&graph); err != nil {
+		return fmt.Errorf("failed to decode lineage graph: %v", err)
+	}
+
+	var writer io.Writer = os.Stdout
+	if out != "-" {
+		file, err := os.Create(out)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		writer = file
+	}
+	_, err := io.WriteString(writer, renderDOT(&graph, rankdir))
+	return err
+}
+
+// renderDOT dựng đồ thị DOT: nút asset ghi sản phẩm, số lượng, trạng thái và cơ sở; nút lô vận chuyển có hình riêng;
+// asset gốc được viền đậm.
+func renderDOT(graph *lineageGraph, rankdir string) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "digraph lineage {\n")
+	fmt.Fprintf(&b, "  rankdir=%s;\n", rankdir)
+	fmt.Fprintf(&b, "  node [shape=box, style=\"rounded,filled\", fillcolor=white, fontname=\"Helvetica\", fontsize=10];\n")
+	fmt.Fprintf(&b, "  edge [fontname=\"Helvetica\", fontsize=9];\n")
+
+	for _, node := range graph.Nodes {
+		var lines []string
+		attributes := []string{}
+		if node.Type == "SHIPMENT" {
+			lines = append(lines, "Shipment "+node.ID, node.Status)
+			attributes = append(attributes, "shape=cds", "fillcolor=lightblue")
+		} else {
+			lines = append(lines, node.ID)
+			product := node.ProductName
+			if node.SKU != "" {
+				product = strings.TrimSpace(fmt.Sprintf("%s (%s)", product, node.SKU))
+			}
+			if product != "" {
+				lines = append(lines, product)
+			}
+			if node.CurrentQuantity != nil && node.OriginalQuantity != nil {
+				lines = append(lines, fmt.Sprintf("%s / %s", formatQuantity(node.CurrentQuantity), formatQuantity(node.OriginalQuantity)))
+			}
+			lines = append(lines, node.Status)
+			if node.FacilityID != "" {
+				lines = append(lines, "@ "+node.FacilityID)
+			}
+			if color, ok := statusColors[node.Status]; ok {
+				attributes = append(attributes, "fillcolor="+color)
+			}
+		}
+		if node.ID == graph.RootAssetID {
+			attributes = append(attributes, "penwidth=3")
+		}
+		for i := range lines {
+			lines[i] = escape(lines[i])
+		}
+		attributes = append([]string{fmt.Sprintf("label=\"%s\"", strings.Join(lines, `\n`))}, attributes...)
+		fmt.Fprintf(&b, "  \"%s\" [%s];\n", escape(node.ID), strings.Join(attributes, ", "))
+	}
+
+	for _, edge := range graph.Edges {
+		label := edge.Type
+		if edge.Quantity != nil {
+			label += `\n` + escape(formatQuantity(edge.Quantity))
+		}
+		style := ""
+		if edge.Type == "PICKED_UP" || edge.Type == "DELIVERED" {
+			style = ", style=dashed"
+		}
+		fmt.Fprintf(&b, "  \"%s\" -> \"%s\" [label=\"%s\"%s];\n", escape(edge.From), escape(edge.To), label, style)
+	}
+	fmt.Fprintf(&b, "}\n")
+	return b.String()
+}
+
+// formatQuantity hiển thị số lượng dạng "12.5 kg".
+func formatQuantity(q *quantity) string {
+	return strings.TrimSpace(strconv.FormatFloat(q.Value, 'f', -1, 64) + " " + q.Unit)
+}
+
+// escape thoát các ký tự đặc biệt trong chuỗi DOT đặt trong dấu nháy kép.
+func escape(value string) string {
+	value = strings.ReplaceAll(value, `\`, `\\`)
+	value = strings.ReplaceAll(value, `"`, `\"`)
+	return strings.ReplaceAll(value, "\n", `\n`)
+}